@@ -12,6 +12,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 )
@@ -56,22 +57,46 @@ var indexCmd = &cobra.Command{
 		idx.RegisterParser(string(parser.LanguagePython), parser.NewPythonParser())
 		idx.RegisterParser(string(parser.LanguageJavaScript), parser.NewJavaScriptParser())
 		idx.RegisterParser(string(parser.LanguageTypeScript), parser.NewTypeScriptParser())
+		idx.RegisterParser(string(parser.LanguageRust), parser.NewRustParser())
+		idx.RegisterParser(string(parser.LanguageJava), parser.NewJavaParser())
+		idx.RegisterParser(string(parser.LanguageKotlin), parser.NewKotlinParser())
+		idx.RegisterParser(string(parser.LanguageC), parser.NewCParser())
+		idx.RegisterParser(string(parser.LanguageCPP), parser.NewCPPParser())
+		idx.RegisterParser(string(parser.LanguageCSharp), parser.NewCSharpParser())
+		idx.RegisterParser(string(parser.LanguageRuby), parser.NewRubyParser())
+		idx.RegisterParser(string(parser.LanguagePHP), parser.NewPHPParser())
+		idx.RegisterParser(string(parser.LanguageVue), parser.NewVueParser())
+		idx.RegisterParser(string(parser.LanguageSvelte), parser.NewSvelteParser())
+		idx.RegisterParser(string(parser.LanguageMarkdown), parser.NewMarkdownParser())
+		idx.RegisterParser(string(parser.LanguageSQL), parser.NewSQLParser())
+		idx.RegisterParser(string(parser.LanguageProto), parser.NewProtoParser())
+		idx.RegisterParser(string(parser.LanguageNotebook), parser.NewNotebookParser())
 
 		fmt.Printf("Indexing project at: %s\n", dir)
-		return idx.IndexProject(dir)
+		return idx.IndexProject(cmd.Context(), dir)
 	},
 }
 
 var mcpCmd = &cobra.Command{
 	Use:   "mcp",
-	Short: "Start MCP server over stdio",
+	Short: "Start MCP server over stdio, or streamable HTTP with --http",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		dir, _ := cmd.Flags().GetString("dir")
+		addr, _ := cmd.Flags().GetString("http")
+		token, _ := cmd.Flags().GetString("auth-token")
 		server, err := mcp.NewServer(dir)
 		if err != nil {
 			return err
 		}
-		return server.Run()
+		server.SetVersion(Version)
+		if addr == "" {
+			return server.Run()
+		}
+		// NewServer has loaded ~/.codebase/config.json into the environment.
+		if token == "" {
+			token = config.Get("CODEBASE_MCP_TOKEN", "mcp_auth_token")
+		}
+		return server.RunHTTP(addr, token)
 	},
 }
 
@@ -88,6 +113,7 @@ var queryCmd = &cobra.Command{
 		q, _ := cmd.Flags().GetString("q")
 		topK, _ := cmd.Flags().GetInt("top_k")
 		dir, _ := cmd.Flags().GetString("dir")
+		documentation, _ := cmd.Flags().GetString("documentation")
 		if topK <= 0 {
 			topK = 10
 		}
@@ -101,13 +127,14 @@ var queryCmd = &cobra.Command{
 
 		// Use the same search logic as MCP
 		queryArgs := map[string]interface{}{
-			"query":        q,
-			"top_k":        topK,
-			"project_path": dir,
+			"query":         q,
+			"top_k":         topK,
+			"project_path":  dir,
+			"documentation": documentation,
 		}
 		argsJSON, _ := json.Marshal(queryArgs)
 
-		result, err := server.HandleCodebaseRetrieval(argsJSON)
+		result, err := server.HandleCodebaseRetrieval(cmd.Context(), argsJSON)
 		if err != nil {
 			return err
 		}
@@ -120,6 +147,62 @@ var queryCmd = &cobra.Command{
 	},
 }
 
+var outlineCmd = &cobra.Command{
+	Use:   "outline [path]",
+	Short: "List the indexed symbols of a file or directory (same as MCP file-outline)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.LoadFromUserConfig(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		}
+
+		dir, _ := cmd.Flags().GetString("dir")
+		repoMap, _ := cmd.Flags().GetBool("repo-map")
+		maxTokens, _ := cmd.Flags().GetInt("max-tokens")
+
+		outlineArgs := map[string]interface{}{
+			"project_path": dir,
+			"max_tokens":   maxTokens,
+		}
+		if repoMap {
+			outlineArgs["mode"] = "repo-map"
+		}
+		if len(args) > 0 {
+			// Paths on the command line are relative to the working directory.
+			path, err := filepath.Abs(args[0])
+			if err != nil {
+				return err
+			}
+			outlineArgs["path"] = path
+		} else if !repoMap {
+			return fmt.Errorf("a path is required unless --repo-map is set")
+		}
+
+		server, err := mcp.NewServer(dir)
+		if err != nil {
+			return err
+		}
+		defer server.Close()
+
+		argsJSON, _ := json.Marshal(outlineArgs)
+		result, err := server.HandleFileOutline(cmd.Context(), argsJSON)
+		if err != nil {
+			return err
+		}
+
+		// The repo map is already text meant to be read as is.
+		if repoMap {
+			if m, ok := result.(map[string]interface{}); ok {
+				fmt.Print(m["map"])
+				return nil
+			}
+		}
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+		return nil
+	},
+}
+
 var clearIndexCmd = &cobra.Command{
 	Use:   "clear-index",
 	Short: "Delete the entire Qdrant collection used for codebase index",
@@ -148,7 +231,7 @@ var clearIndexCmd = &cobra.Command{
 		defer qc.Close()
 
 		fmt.Printf("Deleting collection: %s\n", collection)
-		if err := qc.DeleteCollection(collection); err != nil {
+		if err := qc.DeleteCollection(cmd.Context(), collection); err != nil {
 			return err
 		}
 
@@ -222,7 +305,13 @@ func init() {
 	queryCmd.Flags().String("q", "", "Natural language query")
 	queryCmd.Flags().Int("top_k", 10, "Maximum number of results to return")
 	queryCmd.Flags().String("dir", ".", "Project root directory (must match the directory passed to 'codebase index')")
+	queryCmd.Flags().String("documentation", "include", "Markdown documentation results: include, exclude or only")
+	outlineCmd.Flags().String("dir", ".", "Project root directory (must match the directory passed to 'codebase index')")
+	outlineCmd.Flags().Bool("repo-map", false, "Summarize the project (or path) as a token-budgeted tree")
+	outlineCmd.Flags().Int("max-tokens", 1024, "Approximate token budget of the repo map")
 	mcpCmd.Flags().String("dir", ".", "Project root directory (server scopes searches to this directory)")
+	mcpCmd.Flags().String("http", "", "Serve the streamable HTTP transport on this address (e.g. 127.0.0.1:8080; a bare :8080 listens on 127.0.0.1) instead of stdio. Other interfaces require --auth-token")
+	mcpCmd.Flags().String("auth-token", "", "Bearer token required by the HTTP transport (default $CODEBASE_MCP_TOKEN)")
 	clearIndexCmd.Flags().String("dir", ".", "Project root directory to clear from Qdrant")
 
 	updateCmd.Flags().Bool("check", false, "Check for updates without installing")
@@ -232,6 +321,7 @@ func init() {
 	rootCmd.AddCommand(indexCmd)
 	rootCmd.AddCommand(mcpCmd)
 	rootCmd.AddCommand(queryCmd)
+	rootCmd.AddCommand(outlineCmd)
 	rootCmd.AddCommand(clearIndexCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(updateCmd)
@@ -5,7 +5,9 @@ import (
 	"codebase/internal/models"
 	"codebase/internal/qdrant"
 	"codebase/internal/utils"
+	"context"
 	"encoding/json"
+	"strings"
 
 	qdrantpb "github.com/qdrant/go-client/qdrant"
 )
@@ -13,8 +15,13 @@ import (
 type Analyzer struct {
 	qdrant     *qdrant.Client
 	collection string
+	progress   ProgressFunc
 }
 
+// ProgressFunc receives the progress of FindDuplicates: the number of chunk
+// pairs compared so far, out of total.
+type ProgressFunc func(done, total int)
+
 func NewAnalyzer(qc *qdrant.Client, _ interface{}, collection string) *Analyzer {
 	return &Analyzer{
 		qdrant:     qc,
@@ -22,14 +29,33 @@ func NewAnalyzer(qc *qdrant.Client, _ interface{}, collection string) *Analyzer
 	}
 }
 
-func (a *Analyzer) FindDuplicates(plan models.QueryPlan) ([]models.DuplicateGroup, error) {
-	chunks, vectors, err := a.fetchAllVectors(plan.Filter)
+// SetProgressFunc sets a function called as FindDuplicates compares chunks,
+// at most once per percent of the work.
+func (a *Analyzer) SetProgressFunc(fn ProgressFunc) {
+	a.progress = fn
+}
+
+func (a *Analyzer) FindDuplicates(ctx context.Context, plan models.QueryPlan) ([]models.DuplicateGroup, error) {
+	chunks, vectors, err := a.fetchAllVectors(ctx, plan.Filter)
 	if err != nil {
 		return nil, err
 	}
 
 	var candidates []models.PairCandidate
-	for i := 0; i < len(vectors); i++ {
+	n := len(vectors)
+	totalPairs := n * (n - 1) / 2
+	compared, reported := 0, -1
+	for i := 0; i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if a.progress != nil && compared < totalPairs {
+			if percent := 100 * compared / totalPairs; percent > reported {
+				reported = percent
+				a.progress(compared, totalPairs)
+			}
+		}
+		compared += n - i - 1
 		for j := i + 1; j < len(vectors); j++ {
 			score := utils.CosineSim(vectors[i], vectors[j])
 			if score >= plan.Threshold && !isTrivialPair(chunks[i], chunks[j]) {
@@ -42,13 +68,17 @@ func (a *Analyzer) FindDuplicates(plan models.QueryPlan) ([]models.DuplicateGrou
 		}
 	}
 
+	if a.progress != nil && totalPairs > 0 {
+		a.progress(totalPairs, totalPairs)
+	}
+
 	confirmed := a.filterDuplicatePairs(candidates)
 	groups := buildDuplicateGroups(confirmed)
 
 	return groups, nil
 }
 
-func (a *Analyzer) fetchAllVectors(filter models.QueryFilter) ([]models.CodeChunkPayload, [][]float32, error) {
+func (a *Analyzer) fetchAllVectors(ctx context.Context, filter models.QueryFilter) ([]models.CodeChunkPayload, [][]float32, error) {
 	var chunks []models.CodeChunkPayload
 	var vectors [][]float32
 
@@ -60,7 +90,7 @@ func (a *Analyzer) fetchAllVectors(filter models.QueryFilter) ([]models.CodeChun
 	}
 
 	for {
-		points, nextOffset, err := a.qdrant.Scroll(collection, limit, offset)
+		points, nextOffset, err := a.qdrant.Scroll(ctx, collection, limit, offset)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -109,6 +139,32 @@ func matchesFilter(chunk models.CodeChunkPayload, filter models.QueryFilter) boo
 		}
 	}
 
+	if len(filter.PathPrefix) > 0 {
+		found := false
+		for _, prefix := range filter.PathPrefix {
+			if strings.HasPrefix(chunk.FilePath, prefix) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(filter.NodeTypes) > 0 {
+		found := false
+		for _, nodeType := range filter.NodeTypes {
+			if chunk.NodeType == nodeType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
 	lines := chunk.EndLine - chunk.StartLine + 1
 	if filter.MinLines > 0 && lines < filter.MinLines {
 		return false
@@ -39,8 +39,13 @@ func NewClient() *Client {
 	}
 }
 
-func (c *Client) Embed(text string) ([]float32, error) {
-	resp, err := c.client.CreateEmbeddings(context.Background(), openai.EmbeddingRequest{
+// Model returns the name of the embedding model.
+func (c *Client) Model() string {
+	return string(c.model)
+}
+
+func (c *Client) Embed(ctx context.Context, text string) ([]float32, error) {
+	resp, err := c.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
 		Model: c.model,
 		Input: []string{text},
 	})
@@ -53,11 +58,11 @@ func (c *Client) Embed(text string) ([]float32, error) {
 	return resp.Data[0].Embedding, nil
 }
 
-func (c *Client) EmbedBatch(texts []string) ([][]float32, error) {
+func (c *Client) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
 	if len(texts) == 0 {
 		return nil, nil
 	}
-	resp, err := c.client.CreateEmbeddings(context.Background(), openai.EmbeddingRequest{
+	resp, err := c.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
 		Model: c.model,
 		Input: texts,
 	})
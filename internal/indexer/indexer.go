@@ -6,15 +6,20 @@ import (
 	"codebase/internal/parser"
 	"codebase/internal/qdrant"
 	"codebase/internal/utils"
+	"context"
 	"crypto/sha256"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	qdrantpb "github.com/qdrant/go-client/qdrant"
 )
@@ -26,6 +31,11 @@ const (
 	BatchSize             = 10
 )
 
+// payloadVersion identifies the format of the point payloads. It is saved in
+// the project status, and an index of an older format is rebuilt in full
+// rather than incrementally. Version 1 stores list fields as Qdrant lists.
+const payloadVersion = 1
+
 // CollectionName returns the Qdrant collection name for a given project ID.
 // If projectID is empty, the shared default collection is used.
 func CollectionName(projectID string) string {
@@ -36,12 +46,69 @@ func CollectionName(projectID string) string {
 	return fmt.Sprintf("%s%s", collectionPrefix, projectID)
 }
 
+// Phases of an indexing run reported in progress events.
+const (
+	PhaseScan   = "scan"   // source files hashed and compared with the last run
+	PhaseEmbed  = "embed"  // a file's chunks were embedded
+	PhaseUpsert = "upsert" // a file was processed and its points upserted
+)
+
+// ProgressEvent describes the progress of an indexing run. The counts cover
+// the files to index in the current run.
+type ProgressEvent struct {
+	Phase          string
+	File           string // file the event is about; empty for PhaseScan
+	FilesDone      int
+	FilesTotal     int
+	ChunksEmbedded int
+	ChunksUpserted int
+}
+
+// ProgressFunc receives progress events. It is called from the indexing
+// workers, possibly from several goroutines at once.
+type ProgressFunc func(ProgressEvent)
+
 type Indexer struct {
 	qdrant     *qdrant.Client
 	embeddings *embeddings.Client
 	parsers    map[string]parser.LanguageParser
 	projectID  string
 	collection string
+	rootDir    string
+	out        io.Writer
+	progress   ProgressFunc
+
+	// Progress of the current run: files processed out of files to index,
+	// and chunks embedded and upserted so far.
+	filesDone      atomic.Int64
+	filesTotal     atomic.Int64
+	chunksEmbedded atomic.Int64
+	chunksUpserted atomic.Int64
+
+	failedMu sync.Mutex
+	failed   []string
+}
+
+// ProjectStatus records the outcome of the last completed indexing run of a
+// project.
+type ProjectStatus struct {
+	LastIndexed time.Time `json:"last_indexed"`
+	Files       int       `json:"files"`
+	FailedFiles []string  `json:"failed_files,omitempty"`
+	Model       string    `json:"model,omitempty"`
+	// PayloadVersion is the payloadVersion of the points written.
+	PayloadVersion int `json:"payload_version,omitempty"`
+}
+
+// changeSet is the difference between the source files on disk and the
+// file hashes saved by the last indexing run.
+type changeSet struct {
+	hashes  map[string]string // current hash of every file, keyed by normalized path
+	changed []string          // added or modified files
+	deleted []string          // normalized paths of removed files
+	// outdated is set when the index was written with an older payload
+	// format: every file then counts as changed.
+	outdated bool
 }
 
 func NewIndexer(qc *qdrant.Client, ec *embeddings.Client) *Indexer {
@@ -49,14 +116,50 @@ func NewIndexer(qc *qdrant.Client, ec *embeddings.Client) *Indexer {
 		qdrant:     qc,
 		embeddings: ec,
 		parsers:    make(map[string]parser.LanguageParser),
+		out:        os.Stdout,
 	}
 }
 
+// SetOutput redirects progress messages, which go to stdout by default.
+func (idx *Indexer) SetOutput(w io.Writer) {
+	idx.out = w
+}
+
+// SetProgressFunc registers fn to receive progress events. It must be
+// called before indexing starts.
+func (idx *Indexer) SetProgressFunc(fn ProgressFunc) {
+	idx.progress = fn
+}
+
+// report sends a progress event to the registered ProgressFunc, if any.
+func (idx *Indexer) report(phase, file string) {
+	if idx.progress == nil {
+		return
+	}
+	idx.progress(ProgressEvent{
+		Phase:          phase,
+		File:           file,
+		FilesDone:      int(idx.filesDone.Load()),
+		FilesTotal:     int(idx.filesTotal.Load()),
+		ChunksEmbedded: int(idx.chunksEmbedded.Load()),
+		ChunksUpserted: int(idx.chunksUpserted.Load()),
+	})
+}
+
+// Progress reports how many of the files to index in the current run have
+// been processed.
+func (idx *Indexer) Progress() (done, total int) {
+	return int(idx.filesDone.Load()), int(idx.filesTotal.Load())
+}
+
 func (idx *Indexer) RegisterParser(lang string, p parser.LanguageParser) {
 	idx.parsers[lang] = p
 }
 
-func (idx *Indexer) IndexProject(rootPath string) error {
+// IndexProject indexes the files of rootPath that changed since the last
+// run. Cancelling ctx aborts the embedding and Qdrant calls in flight and
+// skips the remaining files, which the next run indexes.
+func (idx *Indexer) IndexProject(ctx context.Context, rootPath string) error {
 	normalizedRoot, err := utils.NormalizeProjectRoot(rootPath)
 	if err != nil {
 		return fmt.Errorf("failed to normalize project root: %w", err)
@@ -68,70 +171,63 @@ func (idx *Indexer) IndexProject(rootPath string) error {
 	}
 	idx.projectID = projectID
 	idx.collection = CollectionName(projectID)
+	idx.rootDir = normalizedRoot
 	shortID := projectID
 	if len(shortID) > 12 {
 		shortID = projectID[:12]
 	}
-	fmt.Printf("→ Project fingerprint: %s\n", shortID)
-	fmt.Printf("→ Using collection: %s\n", idx.collection)
+	fmt.Fprintf(idx.out, "→ Project fingerprint: %s\n", shortID)
+	fmt.Fprintf(idx.out, "→ Using collection: %s\n", idx.collection)
 
 	files, err := utils.GetAllSourceFiles(normalizedRoot)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("✓ Found %d source files\n", len(files))
+	fmt.Fprintf(idx.out, "✓ Found %d source files\n", len(files))
 
 	if len(files) == 0 {
-		fmt.Println("⚠ No source files found to index")
+		fmt.Fprintln(idx.out, "⚠ No source files found to index")
 		return nil
 	}
 
-	// Load previous file hashes for incremental indexing.
-	prevHashes, err := loadFileHashes(projectID)
+	changes, err := detectChanges(projectID, normalizedRoot, files)
 	if err != nil {
-		return fmt.Errorf("failed to load file hashes: %w", err)
-	}
-	prevHashes = canonicalizeHashKeys(prevHashes, normalizedRoot)
-
-	currentHashes := make(map[string]string, len(files))
-	var changedFiles []string
-
-	for _, f := range files {
-		hash, herr := hashFile(f)
-		if herr != nil {
-			fmt.Fprintf(os.Stderr, "✗ Failed to hash %s: %v\n", f, herr)
-			continue
-		}
-		key := normalizeFilePath(f)
-		currentHashes[key] = hash
-		if prev, ok := prevHashes[key]; !ok || prev != hash {
-			changedFiles = append(changedFiles, f)
-		}
+		return err
 	}
+	currentHashes, changedFiles, deletedFiles := changes.hashes, changes.changed, changes.deleted
 
-	var deletedFiles []string
-	for path := range prevHashes {
-		if _, ok := currentHashes[path]; !ok {
-			deletedFiles = append(deletedFiles, path)
-		}
+	if changes.outdated {
+		fmt.Fprintln(idx.out, "→ Index payload format changed, reindexing all files")
 	}
+	fmt.Fprintf(idx.out, "→ Incremental index: %d added/modified, %d deleted, %d total files\n", len(changedFiles), len(deletedFiles), len(files))
 
-	fmt.Printf("→ Incremental index: %d added/modified, %d deleted, %d total files\n", len(changedFiles), len(deletedFiles), len(files))
+	idx.filesDone.Store(0)
+	idx.filesTotal.Store(int64(len(changedFiles)))
+	idx.chunksEmbedded.Store(0)
+	idx.chunksUpserted.Store(0)
+	idx.failedMu.Lock()
+	idx.failed = nil
+	idx.failedMu.Unlock()
+	idx.report(PhaseScan, "")
 
 	if len(changedFiles) == 0 && len(deletedFiles) == 0 {
-		fmt.Println("✓ No changes detected, index is already up to date")
-		return nil
+		fmt.Fprintln(idx.out, "✓ No changes detected, index is already up to date")
+		return idx.saveStatus(len(currentHashes))
 	}
 
 	// Delete vectors for files that have been removed from the filesystem.
 	for _, normalizedPath := range deletedFiles {
 		displayPath := filepath.FromSlash(normalizedPath)
-		if err := idx.deleteFilePoints(normalizedPath); err != nil {
+		if err := idx.deleteFilePoints(ctx, normalizedPath); err != nil {
 			fmt.Fprintf(os.Stderr, "✗ Error deleting vectors for removed file %s: %v\n", displayPath, err)
 		} else {
-			fmt.Printf("✓ Deleted vectors for removed file %s\n", displayPath)
+			fmt.Fprintf(idx.out, "✓ Deleted vectors for removed file %s\n", displayPath)
 		}
 	}
+	// Nothing is saved yet: the next run retries the deletions.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	// Index only added or modified files.
 	if len(changedFiles) > 0 {
@@ -142,7 +238,7 @@ func (idx *Indexer) IndexProject(rootPath string) error {
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				idx.processWorker(fileCh)
+				idx.processWorker(ctx, fileCh)
 			}()
 		}
 
@@ -153,23 +249,134 @@ func (idx *Indexer) IndexProject(rootPath string) error {
 		wg.Wait()
 	}
 
+	// Files that failed are left out of the saved hashes so that the next
+	// run retries them.
+	idx.failedMu.Lock()
+	for _, f := range idx.failed {
+		delete(currentHashes, normalizeFilePath(f))
+	}
+	idx.failedMu.Unlock()
+
 	if err := saveFileHashes(idx.projectID, currentHashes); err != nil {
 		return fmt.Errorf("failed to save file hashes: %w", err)
 	}
+	if err := idx.saveStatus(len(currentHashes)); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-	fmt.Println("✓ Indexing completed")
+	fmt.Fprintln(idx.out, "✓ Indexing completed")
 	return nil
 }
 
-func (idx *Indexer) processWorker(fileCh <-chan string) {
+// processWorker indexes files from fileCh. Once ctx is cancelled, the
+// remaining files are counted as failed so that their hashes are not saved.
+func (idx *Indexer) processWorker(ctx context.Context, fileCh <-chan string) {
 	for path := range fileCh {
-		if err := idx.processFile(path); err != nil {
+		if ctx.Err() != nil {
+			idx.failedMu.Lock()
+			idx.failed = append(idx.failed, path)
+			idx.failedMu.Unlock()
+			continue
+		}
+		if err := idx.processFile(ctx, path); err != nil {
 			fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", path, err)
+			idx.failedMu.Lock()
+			idx.failed = append(idx.failed, path)
+			idx.failedMu.Unlock()
 		}
+		idx.filesDone.Add(1)
+		idx.report(PhaseUpsert, path)
+	}
+}
+
+// detectChanges compares files with the hashes saved by the last indexing
+// run of the project. All files are changed if that run wrote an older
+// payload format, or predates the index status.
+func detectChanges(projectID, normalizedRoot string, files []string) (*changeSet, error) {
+	// Load previous file hashes for incremental indexing.
+	prevHashes, err := loadFileHashes(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load file hashes: %w", err)
+	}
+	prevHashes = canonicalizeHashKeys(prevHashes, normalizedRoot)
+	status, err := LoadProjectStatus(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load index status: %w", err)
+	}
+
+	changes := &changeSet{
+		hashes:   make(map[string]string, len(files)),
+		outdated: len(prevHashes) > 0 && (status == nil || status.PayloadVersion < payloadVersion),
+	}
+	for _, f := range files {
+		hash, herr := hashFile(f)
+		if herr != nil {
+			fmt.Fprintf(os.Stderr, "✗ Failed to hash %s: %v\n", f, herr)
+			continue
+		}
+		key := normalizeFilePath(f)
+		changes.hashes[key] = hash
+		if prev, ok := prevHashes[key]; !ok || prev != hash || changes.outdated {
+			changes.changed = append(changes.changed, f)
+		}
+	}
+
+	for path := range prevHashes {
+		if _, ok := changes.hashes[path]; !ok {
+			changes.deleted = append(changes.deleted, path)
+		}
+	}
+	sort.Strings(changes.deleted)
+	return changes, nil
+}
+
+// PendingChanges lists the source files under rootPath that were added or
+// modified since the project was last indexed, and the indexed files that
+// have since been deleted (as normalized paths).
+func PendingChanges(rootPath string) (changed, deleted []string, err error) {
+	normalizedRoot, err := utils.NormalizeProjectRoot(rootPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to normalize project root: %w", err)
+	}
+	projectID, err := utils.ComputeProjectID(normalizedRoot)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute project id: %w", err)
+	}
+	files, err := utils.GetAllSourceFiles(normalizedRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes, err := detectChanges(projectID, normalizedRoot, files)
+	if err != nil {
+		return nil, nil, err
 	}
+	return changes.changed, changes.deleted, nil
 }
 
-func (idx *Indexer) processFile(path string) error {
+// saveStatus records the completion of an indexing run of files files.
+func (idx *Indexer) saveStatus(files int) error {
+	status := &ProjectStatus{
+		LastIndexed:    time.Now().UTC(),
+		Files:          files,
+		PayloadVersion: payloadVersion,
+	}
+	idx.failedMu.Lock()
+	status.FailedFiles = append(status.FailedFiles, idx.failed...)
+	idx.failedMu.Unlock()
+	sort.Strings(status.FailedFiles)
+	if idx.embeddings != nil {
+		status.Model = idx.embeddings.Model()
+	}
+	if err := saveProjectStatus(idx.projectID, status); err != nil {
+		return fmt.Errorf("failed to save index status: %w", err)
+	}
+	return nil
+}
+
+func (idx *Indexer) processFile(ctx context.Context, path string) error {
 	if idx.collection == "" {
 		return fmt.Errorf("collection name is not set on indexer")
 	}
@@ -178,7 +385,7 @@ func (idx *Indexer) processFile(path string) error {
 
 	// For modified files, clear any existing vectors for this file before
 	// re-indexing so that removed functions do not leave stale points.
-	if err := idx.deleteFilePoints(normalizedPath); err != nil {
+	if err := idx.deleteFilePoints(ctx, normalizedPath); err != nil {
 		fmt.Fprintf(os.Stderr, "✗ Error deleting existing vectors for %s: %v\n", path, err)
 	}
 
@@ -207,12 +414,14 @@ func (idx *Indexer) processFile(path string) error {
 		return nil
 	}
 
-	fmt.Printf("→ Processing %s (%d functions)\n", path, len(funcs))
+	fmt.Fprintf(idx.out, "→ Processing %s (%d functions)\n", path, len(funcs))
 
 	// Build embedding texts that combine code with richer AST metadata for
 	// hybrid retrieval (symbol, import, and signature level signals).
 	contents := make([]string, 0, len(funcs))
-	for _, fn := range funcs {
+	resolvedImports := make([][]string, len(funcs))
+	for i, fn := range funcs {
+		resolvedImports[i] = idx.projectRelativePaths(fn.ResolvedImports)
 		metaLines := []string{
 			fmt.Sprintf("file_path: %s", normalizedPath),
 			fmt.Sprintf("language: %s", lang),
@@ -225,6 +434,9 @@ func (idx *Indexer) processFile(path string) error {
 		if len(fn.Imports) > 0 {
 			metaLines = append(metaLines, fmt.Sprintf("imports: %s", strings.Join(fn.Imports, ", ")))
 		}
+		if len(resolvedImports[i]) > 0 {
+			metaLines = append(metaLines, fmt.Sprintf("resolved_imports: %s", strings.Join(resolvedImports[i], ", ")))
+		}
 		if fn.Signature != "" {
 			metaLines = append(metaLines, fmt.Sprintf("signature: %s", fn.Signature))
 		}
@@ -249,12 +461,66 @@ func (idx *Indexer) processFile(path string) error {
 		if fn.HasErrorReturn {
 			metaLines = append(metaLines, "has_error_return: true")
 		}
+		if len(fn.Decorators) > 0 {
+			metaLines = append(metaLines, fmt.Sprintf("decorators: %s", strings.Join(fn.Decorators, ", ")))
+		}
+		if fn.IsAsync {
+			metaLines = append(metaLines, "async: true")
+		}
+		if fn.IsGenerator {
+			metaLines = append(metaLines, "generator: true")
+		}
+		if len(fn.Raises) > 0 {
+			metaLines = append(metaLines, fmt.Sprintf("raises: %s", strings.Join(fn.Raises, ", ")))
+		}
+		if len(fn.TypeParams) > 0 {
+			metaLines = append(metaLines, fmt.Sprintf("type_params: %s", strings.Join(fn.TypeParams, ", ")))
+		}
+		if len(fn.Members) > 0 {
+			metaLines = append(metaLines, fmt.Sprintf("members: %s", strings.Join(fn.Members, "; ")))
+		}
+		if fn.Exported {
+			metaLines = append(metaLines, "exported: true")
+		}
+		if fn.PropsType != "" {
+			metaLines = append(metaLines, fmt.Sprintf("props: %s", fn.PropsType))
+		}
+		if len(fn.Hooks) > 0 {
+			metaLines = append(metaLines, fmt.Sprintf("hooks: %s", strings.Join(fn.Hooks, ", ")))
+		}
+		if len(fn.JSXElements) > 0 {
+			metaLines = append(metaLines, fmt.Sprintf("renders: %s", strings.Join(fn.JSXElements, ", ")))
+		}
+		if len(fn.PropNames) > 0 {
+			metaLines = append(metaLines, fmt.Sprintf("prop_names: %s", strings.Join(fn.PropNames, ", ")))
+		}
+		if len(fn.Emits) > 0 {
+			metaLines = append(metaLines, fmt.Sprintf("emits: %s", strings.Join(fn.Emits, ", ")))
+		}
+		if fn.HeadingPath != "" {
+			metaLines = append(metaLines, fmt.Sprintf("heading_path: %s", fn.HeadingPath))
+		}
+		if len(fn.CodeLanguages) > 0 {
+			metaLines = append(metaLines, fmt.Sprintf("code_languages: %s", strings.Join(fn.CodeLanguages, ", ")))
+		}
+		if len(fn.Tables) > 0 {
+			metaLines = append(metaLines, fmt.Sprintf("tables: %s", strings.Join(fn.Tables, ", ")))
+		}
+		if len(fn.Columns) > 0 {
+			metaLines = append(metaLines, fmt.Sprintf("columns: %s", strings.Join(fn.Columns, ", ")))
+		}
+		if len(fn.Options) > 0 {
+			metaLines = append(metaLines, fmt.Sprintf("options: %s", strings.Join(fn.Options, "; ")))
+		}
+		if fn.CellIndex > 0 {
+			metaLines = append(metaLines, fmt.Sprintf("cell: %d", fn.CellIndex))
+		}
 
 		text := fmt.Sprintf("%s\n\n%s", strings.Join(metaLines, "\n"), fn.Content)
 		contents = append(contents, text)
 	}
 
-	vectors, err := idx.embeddings.EmbedBatch(contents)
+	vectors, err := idx.embeddings.EmbedBatch(ctx, contents)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "✗ Error embedding %s: %v\n", path, err)
 		return err
@@ -262,11 +528,13 @@ func (idx *Indexer) processFile(path string) error {
 	if len(vectors) == 0 || len(vectors[0]) == 0 {
 		return fmt.Errorf("no embedding vectors returned for %s", path)
 	}
+	idx.chunksEmbedded.Add(int64(len(vectors)))
+	idx.report(PhaseEmbed, path)
 
 	// Ensure Qdrant collection lazily using the actual embedding dimension so we
 	// don't need a separate probe request.
 	vectorSize := uint64(len(vectors[0]))
-	if err := idx.qdrant.EnsureCollection(idx.collection, vectorSize); err != nil {
+	if err := idx.qdrant.EnsureCollection(ctx, idx.collection, vectorSize); err != nil {
 		return err
 	}
 
@@ -275,23 +543,43 @@ func (idx *Indexer) processFile(path string) error {
 		hash := utils.HashContent(fn.Content)
 		id := contentHashToPointID(hash)
 		payload := models.CodeChunkPayload{
-			FilePath:       normalizedPath,
-			Language:       lang,
-			NodeType:       fn.NodeType,
-			NodeName:       fn.Name,
-			StartLine:      fn.StartLine,
-			EndLine:        fn.EndLine,
-			CodeHash:       hash,
-			Content:        fn.Content,
-			PackageName:    fn.PackageName,
-			Imports:        fn.Imports,
-			Signature:      fn.Signature,
-			Receiver:       fn.Receiver,
-			Doc:            fn.Doc,
-			Callees:        fn.Callees,
-			ParamTypes:     fn.ParamTypes,
-			ReturnTypes:    fn.ReturnTypes,
-			HasErrorReturn: fn.HasErrorReturn,
+			FilePath:        normalizedPath,
+			Language:        lang,
+			NodeType:        fn.NodeType,
+			NodeName:        fn.Name,
+			StartLine:       fn.StartLine,
+			EndLine:         fn.EndLine,
+			CodeHash:        hash,
+			Content:         fn.Content,
+			PackageName:     fn.PackageName,
+			Imports:         fn.Imports,
+			ResolvedImports: resolvedImports[i],
+			Signature:       fn.Signature,
+			Receiver:        fn.Receiver,
+			Doc:             fn.Doc,
+			Callees:         fn.Callees,
+			ParamTypes:      fn.ParamTypes,
+			ReturnTypes:     fn.ReturnTypes,
+			HasErrorReturn:  fn.HasErrorReturn,
+			Decorators:      fn.Decorators,
+			IsAsync:         fn.IsAsync,
+			IsGenerator:     fn.IsGenerator,
+			Raises:          fn.Raises,
+			Members:         fn.Members,
+			TypeParams:      fn.TypeParams,
+			Exported:        fn.Exported,
+			PropsType:       fn.PropsType,
+			Hooks:           fn.Hooks,
+			JSXElements:     fn.JSXElements,
+			PropNames:       fn.PropNames,
+			Emits:           fn.Emits,
+			HeadingPath:     fn.HeadingPath,
+			CodeLanguages:   fn.CodeLanguages,
+			Tables:          fn.Tables,
+			Columns:         fn.Columns,
+			SQLQueries:      fn.SQLQueries,
+			Options:         fn.Options,
+			CellIndex:       fn.CellIndex,
 		}
 
 		payloadMap := map[string]interface{}{
@@ -305,6 +593,7 @@ func (idx *Indexer) processFile(path string) error {
 			"content":          payload.Content,
 			"package_name":     payload.PackageName,
 			"imports":          payload.Imports,
+			"resolved_imports": payload.ResolvedImports,
 			"signature":        payload.Signature,
 			"receiver":         payload.Receiver,
 			"doc":              payload.Doc,
@@ -312,6 +601,25 @@ func (idx *Indexer) processFile(path string) error {
 			"param_types":      payload.ParamTypes,
 			"return_types":     payload.ReturnTypes,
 			"has_error_return": payload.HasErrorReturn,
+			"decorators":       payload.Decorators,
+			"is_async":         payload.IsAsync,
+			"is_generator":     payload.IsGenerator,
+			"raises":           payload.Raises,
+			"members":          payload.Members,
+			"type_params":      payload.TypeParams,
+			"exported":         payload.Exported,
+			"props_type":       payload.PropsType,
+			"hooks":            payload.Hooks,
+			"jsx_elements":     payload.JSXElements,
+			"prop_names":       payload.PropNames,
+			"emits":            payload.Emits,
+			"heading_path":     payload.HeadingPath,
+			"code_languages":   payload.CodeLanguages,
+			"tables":           payload.Tables,
+			"columns":          payload.Columns,
+			"sql_queries":      payload.SQLQueries,
+			"options":          payload.Options,
+			"cell_index":       payload.CellIndex,
 		}
 
 		points = append(points, &qdrantpb.PointStruct{
@@ -331,13 +639,14 @@ func (idx *Indexer) processFile(path string) error {
 		})
 	}
 
-	err = idx.qdrant.Upsert(idx.collection, points)
+	err = idx.qdrant.Upsert(ctx, idx.collection, points)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "✗ Error upserting %s: %v\n", path, err)
 		return err
 	}
+	idx.chunksUpserted.Add(int64(len(points)))
 
-	fmt.Printf("✓ Indexed %s (%d vectors)\n", path, len(points))
+	fmt.Fprintf(idx.out, "✓ Indexed %s (%d vectors)\n", path, len(points))
 	return nil
 }
 
@@ -361,6 +670,12 @@ func hashFile(path string) (string, error) {
 	return utils.HashContent(string(data)), nil
 }
 
+// NormalizeFilePath returns path in the form stored in the file_path payload
+// field.
+func NormalizeFilePath(path string) string {
+	return normalizeFilePath(path)
+}
+
 func normalizeFilePath(path string) string {
 	path = strings.TrimSpace(path)
 	if path == "" {
@@ -381,6 +696,27 @@ func normalizeFilePath(path string) string {
 	return normalized
 }
 
+// projectRelativePaths converts absolute file paths to slash-separated paths
+// relative to the project root, dropping any that fall outside of it.
+func (idx *Indexer) projectRelativePaths(paths []string) []string {
+	if len(paths) == 0 || idx.rootDir == "" {
+		return nil
+	}
+	var out []string
+	for _, p := range paths {
+		if runtime.GOOS == "windows" {
+			// The normalized root is lower-cased on Windows.
+			p = strings.ToLower(p)
+		}
+		rel, err := filepath.Rel(idx.rootDir, p)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		out = append(out, filepath.ToSlash(rel))
+	}
+	return out
+}
+
 func canonicalizeHashKeys(hashes map[string]string, normalizedRoot string) map[string]string {
 	if len(hashes) == 0 {
 		return hashes
@@ -449,6 +785,14 @@ func saveFileHashes(projectID string, hashes map[string]string) error {
 }
 
 func fileHashStatePath(projectID string) (string, error) {
+	return projectStatePath(projectID, "file_hashes")
+}
+
+func projectStatusPath(projectID string) (string, error) {
+	return projectStatePath(projectID, "index_status")
+}
+
+func projectStatePath(projectID, kind string) (string, error) {
 	stateDir, err := utils.UserStateDir()
 	if err != nil {
 		return "", err
@@ -456,29 +800,61 @@ func fileHashStatePath(projectID string) (string, error) {
 	if projectID == "" {
 		projectID = "default"
 	}
-	fileName := fmt.Sprintf("%s_file_hashes.json", projectID)
+	fileName := fmt.Sprintf("%s_%s.json", projectID, kind)
 	return filepath.Join(stateDir, fileName), nil
 }
 
-// ClearProjectState removes any local on-disk state associated with a project.
-// Currently this is the file-hash map used for incremental indexing.
-func ClearProjectState(projectID string) error {
-	statePath, err := fileHashStatePath(projectID)
+// LoadProjectStatus returns the status saved by the last completed indexing
+// run of a project, or nil if it has not been indexed.
+func LoadProjectStatus(projectID string) (*ProjectStatus, error) {
+	statePath, err := projectStatusPath(projectID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if err := os.Remove(statePath); err != nil {
+	data, err := os.ReadFile(statePath)
+	if err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			return nil, nil
 		}
+		return nil, err
+	}
+	var status ProjectStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func saveProjectStatus(projectID string, status *ProjectStatus) error {
+	statePath, err := projectStatusPath(projectID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
 		return err
 	}
+	return os.WriteFile(statePath, data, 0o644)
+}
+
+// ClearProjectState removes any local on-disk state associated with a project:
+// the file-hash map used for incremental indexing and the index status.
+func ClearProjectState(projectID string) error {
+	for _, statePath := range []func(string) (string, error){fileHashStatePath, projectStatusPath} {
+		path, err := statePath(projectID)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
 	return nil
 }
 
 // deleteFilePoints removes all vectors in Qdrant whose payload file_path
 // matches the given path.
-func (idx *Indexer) deleteFilePoints(path string) error {
+func (idx *Indexer) deleteFilePoints(ctx context.Context, path string) error {
 	if idx.collection == "" {
 		return fmt.Errorf("collection name is not set on indexer")
 	}
@@ -499,5 +875,5 @@ func (idx *Indexer) deleteFilePoints(path string) error {
 		},
 	}
 
-	return idx.qdrant.DeleteByFilter(idx.collection, filter)
+	return idx.qdrant.DeleteByFilter(ctx, idx.collection, filter)
 }
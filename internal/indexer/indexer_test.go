@@ -1,8 +1,11 @@
 package indexer
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/binary"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -204,3 +207,217 @@ func TestFileHashStatePathDefaultProjectID(t *testing.T) {
 	}
 }
 
+func TestProjectStatusAndPendingChanges(t *testing.T) {
+	// This test sets HOME/USERPROFILE, so do not run in parallel.
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	t.Setenv("USERPROFILE", tmpHome)
+
+	root, err := utils.NormalizeProjectRoot(t.TempDir())
+	if err != nil {
+		t.Fatalf("NormalizeProjectRoot: %v", err)
+	}
+	projectID, err := utils.ComputeProjectID(root)
+	if err != nil {
+		t.Fatalf("ComputeProjectID: %v", err)
+	}
+	indexed := filepath.Join(root, "indexed.go")
+	added := filepath.Join(root, "added.go")
+	for _, path := range []string{indexed, added} {
+		if err := os.WriteFile(path, []byte("package p\n"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	indexedHash, err := hashFile(indexed)
+	if err != nil {
+		t.Fatalf("hashFile: %v", err)
+	}
+	if status, err := LoadProjectStatus(projectID); err != nil || status != nil {
+		t.Fatalf("LoadProjectStatus (missing)=%v, %v; want nil, nil", status, err)
+	}
+	removed := normalizeFilePath(filepath.Join(root, "removed.go"))
+	if err := saveFileHashes(projectID, map[string]string{
+		normalizeFilePath(indexed): indexedHash,
+		removed:                    "stale",
+	}); err != nil {
+		t.Fatalf("saveFileHashes: %v", err)
+	}
+	if err := saveProjectStatus(projectID, &ProjectStatus{PayloadVersion: payloadVersion}); err != nil {
+		t.Fatalf("saveProjectStatus: %v", err)
+	}
+
+	changed, deleted, err := PendingChanges(root)
+	if err != nil {
+		t.Fatalf("PendingChanges: %v", err)
+	}
+	if len(changed) != 1 || normalizeFilePath(changed[0]) != normalizeFilePath(added) {
+		t.Fatalf("changed=%v, want [%s]", changed, added)
+	}
+	if len(deleted) != 1 || deleted[0] != removed {
+		t.Fatalf("deleted=%v, want [%s]", deleted, removed)
+	}
+
+	idx := &Indexer{projectID: projectID, failed: []string{added}}
+	if err := idx.saveStatus(2); err != nil {
+		t.Fatalf("saveStatus: %v", err)
+	}
+	status, err := LoadProjectStatus(projectID)
+	if err != nil || status == nil {
+		t.Fatalf("LoadProjectStatus: %v, %v", status, err)
+	}
+	if status.Files != 2 || len(status.FailedFiles) != 1 || status.LastIndexed.IsZero() || status.PayloadVersion != payloadVersion {
+		t.Fatalf("status=%+v", status)
+	}
+
+	if err := ClearProjectState(projectID); err != nil {
+		t.Fatalf("ClearProjectState: %v", err)
+	}
+	if status, err := LoadProjectStatus(projectID); err != nil || status != nil {
+		t.Fatalf("LoadProjectStatus (cleared)=%v, %v; want nil, nil", status, err)
+	}
+}
+
+func TestProjectRelativePaths(t *testing.T) {
+	t.Parallel()
+
+	root, err := utils.NormalizeProjectRoot(t.TempDir())
+	if err != nil {
+		t.Fatalf("NormalizeProjectRoot: %v", err)
+	}
+	idx := &Indexer{rootDir: root}
+
+	got := idx.projectRelativePaths([]string{
+		filepath.Join(root, "src", "lib", "api.ts"),
+		filepath.Join(filepath.Dir(root), "outside.ts"),
+	})
+	if len(got) != 1 || got[0] != "src/lib/api.ts" {
+		t.Fatalf("projectRelativePaths = %q, want [src/lib/api.ts]", got)
+	}
+	if got := (&Indexer{}).projectRelativePaths([]string{"/a/b.ts"}); got != nil {
+		t.Fatalf("projectRelativePaths without root = %q, want nil", got)
+	}
+}
+
+func TestReportProgress(t *testing.T) {
+	t.Parallel()
+
+	idx := &Indexer{}
+	idx.report(PhaseScan, "") // no ProgressFunc registered
+
+	var events []ProgressEvent
+	idx.SetProgressFunc(func(ev ProgressEvent) {
+		events = append(events, ev)
+	})
+	idx.filesTotal.Store(3)
+	idx.report(PhaseScan, "")
+	idx.chunksEmbedded.Add(4)
+	idx.report(PhaseEmbed, "a.go")
+	idx.chunksUpserted.Add(4)
+	idx.filesDone.Add(1)
+	idx.report(PhaseUpsert, "a.go")
+
+	want := []ProgressEvent{
+		{Phase: PhaseScan, FilesTotal: 3},
+		{Phase: PhaseEmbed, File: "a.go", FilesTotal: 3, ChunksEmbedded: 4},
+		{Phase: PhaseUpsert, File: "a.go", FilesDone: 1, FilesTotal: 3, ChunksEmbedded: 4, ChunksUpserted: 4},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+}
+
+func TestPendingChangesAfterPayloadFormatChange(t *testing.T) {
+	// This test sets HOME/USERPROFILE, so do not run in parallel.
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	t.Setenv("USERPROFILE", tmpHome)
+
+	root, err := utils.NormalizeProjectRoot(t.TempDir())
+	if err != nil {
+		t.Fatalf("NormalizeProjectRoot: %v", err)
+	}
+	projectID, err := utils.ComputeProjectID(root)
+	if err != nil {
+		t.Fatalf("ComputeProjectID: %v", err)
+	}
+	path := filepath.Join(root, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		t.Fatalf("hashFile: %v", err)
+	}
+	if err := saveFileHashes(projectID, map[string]string{normalizeFilePath(path): hash}); err != nil {
+		t.Fatalf("saveFileHashes: %v", err)
+	}
+
+	// The file is unchanged, but an index without a status or with an
+	// older payload format is rebuilt.
+	for _, tt := range []struct {
+		name    string
+		status  *ProjectStatus
+		pending int
+	}{
+		{"no status", nil, 1},
+		{"older format", &ProjectStatus{}, 1},
+		{"current format", &ProjectStatus{PayloadVersion: payloadVersion}, 0},
+	} {
+		if tt.status != nil {
+			if err := saveProjectStatus(projectID, tt.status); err != nil {
+				t.Fatalf("saveProjectStatus: %v", err)
+			}
+		}
+		changed, deleted, err := PendingChanges(root)
+		if err != nil {
+			t.Fatalf("%s: PendingChanges: %v", tt.name, err)
+		}
+		if len(changed) != tt.pending || len(deleted) != 0 {
+			t.Errorf("%s: changed=%v deleted=%v, want %d changed", tt.name, changed, deleted, tt.pending)
+		}
+	}
+}
+
+func TestIndexProjectCancelled(t *testing.T) {
+	// This test sets HOME/USERPROFILE, so do not run in parallel.
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	t.Setenv("USERPROFILE", tmpHome)
+
+	root, err := utils.NormalizeProjectRoot(t.TempDir())
+	if err != nil {
+		t.Fatalf("NormalizeProjectRoot: %v", err)
+	}
+	for _, name := range []string{"a.go", "b.go"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("package p\n"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	// Without Qdrant or embeddings, any file processed would fail: a
+	// cancelled run must skip them all.
+	idx := NewIndexer(nil, nil)
+	idx.SetOutput(io.Discard)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := idx.IndexProject(ctx, root); !errors.Is(err, context.Canceled) {
+		t.Fatalf("IndexProject = %v, want context.Canceled", err)
+	}
+	if done, total := idx.Progress(); done != 0 || total != 2 {
+		t.Errorf("Progress = %d/%d, want 0/2", done, total)
+	}
+
+	// Skipped files are left for the next run.
+	changed, _, err := PendingChanges(root)
+	if err != nil {
+		t.Fatalf("PendingChanges: %v", err)
+	}
+	if len(changed) != 2 {
+		t.Errorf("pending changes = %v, want both files", changed)
+	}
+}
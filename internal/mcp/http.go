@@ -0,0 +1,537 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// httpEndpoint is the path of the streamable HTTP endpoint.
+	httpEndpoint = "/mcp"
+
+	sessionHeader         = "Mcp-Session-Id"
+	protocolVersionHeader = "Mcp-Protocol-Version"
+
+	// maxHTTPBody bounds the size of a POSTed message or batch.
+	maxHTTPBody = 4 << 20
+
+	// sessionIdleTimeout is how long a session without requests or an open
+	// stream is kept before it is dropped.
+	sessionIdleTimeout = time.Hour
+
+	// sseKeepAlive is the interval of comments sent on an idle SSE stream so
+	// that proxies do not close it.
+	sseKeepAlive = 30 * time.Second
+)
+
+// httpSession is a client of the streamable HTTP transport, created by its
+// initialize request and named by the Mcp-Session-Id header.
+type httpSession struct {
+	server *Server
+	id     string
+	closed chan struct{} // closed when the session is deleted
+
+	mu       sync.Mutex
+	lastSeen time.Time
+	stream   *messageWriter // open GET stream for server messages, if any
+}
+
+// scope prefixes the session's request IDs for cancellation.
+func (sess *httpSession) scope() string {
+	return sess.id + ":"
+}
+
+// notify writes a message to the session's GET stream, when one is open.
+func (sess *httpSession) notify(method string, params interface{}) bool {
+	sess.mu.Lock()
+	stream := sess.stream
+	sess.mu.Unlock()
+	if stream == nil {
+		return false
+	}
+	stream.write(encodeNotification(method, params))
+	return true
+}
+
+func (sess *httpSession) touch() {
+	sess.mu.Lock()
+	sess.lastSeen = time.Now()
+	sess.mu.Unlock()
+}
+
+// RunHTTP serves MCP over the streamable HTTP transport on addr, at the /mcp
+// endpoint, until the process is interrupted. Each client gets a session;
+// responses and server messages are streamed as server-sent events. When
+// token is set, every request must carry it as a bearer token; without one,
+// the server only listens on a loopback address.
+func (s *Server) RunHTTP(addr, token string) error {
+	defer s.Close()
+
+	addr, err := listenAddr(addr, token)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(httpEndpoint, s.httpHandler(token))
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	// Open streams would otherwise hold up Shutdown until its timeout.
+	srv.RegisterOnShutdown(s.closeSessions)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	fmt.Fprintf(os.Stderr, "[MCP] Serving streamable HTTP on %s (endpoint %s)\n", addr, httpEndpoint)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// listenAddr returns the address to listen on: a bare port listens on
+// 127.0.0.1. Other interfaces are refused unless a token is required, since
+// the tools can reindex and clear the index.
+func listenAddr(addr, token string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid --http address %q: %w", addr, err)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if token == "" && !isLoopback(host) {
+		return "", fmt.Errorf("refusing to serve on %s without an auth token: set --auth-token or CODEBASE_MCP_TOKEN, or listen on 127.0.0.1", addr)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// isLoopback reports whether host, a host name or IP address without a
+// port, names the local machine.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// httpHandler implements the streamable HTTP transport: POST sends messages,
+// GET opens a stream for server messages and DELETE ends the session.
+func (s *Server) httpHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowedOrigin(r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		// Without a token, a rebound DNS name could point a browser at the
+		// server: only answer requests addressed to the local machine.
+		if token == "" && !allowedHost(r) {
+			http.Error(w, "host not allowed", http.StatusForbidden)
+			return
+		}
+		if !authorized(r, token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="codebase-mcp"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			s.handleHTTPPost(w, r)
+		case http.MethodGet:
+			s.handleHTTPStream(w, r)
+		case http.MethodDelete:
+			s.handleHTTPDelete(w, r)
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// allowedOrigin only lets pages served from the local machine call the
+// server from a browser. Requests without an Origin header do not come from
+// a browser.
+func allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return isLoopback(u.Hostname())
+}
+
+// allowedHost reports whether the request's Host header names the local
+// machine.
+func allowedHost(r *http.Request) bool {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return isLoopback(host)
+}
+
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return true
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// handleHTTPPost handles a message or batch of messages. Notifications and
+// responses are accepted without a body; requests are answered on an SSE
+// stream that also carries their progress notifications, or as plain JSON
+// when the client does not accept event streams.
+func (s *Server) handleHTTPPost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxHTTPBody))
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, -32700, err.Error())
+		return
+	}
+	reqs, err := parseHTTPMessages(body)
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, -32700, "Parse error")
+		return
+	}
+
+	var sess *httpSession
+	if reqs[0].Method == "initialize" {
+		if len(reqs) > 1 {
+			writeHTTPError(w, http.StatusBadRequest, -32600, "initialize must not be batched")
+			return
+		}
+		sess = s.newSession()
+		w.Header().Set(sessionHeader, sess.id)
+	} else {
+		// Clients send the negotiated revision after initialize.
+		if v := r.Header.Get(protocolVersionHeader); v != "" && !slices.Contains(supportedProtocolVersions, v) {
+			http.Error(w, "unsupported "+protocolVersionHeader+": "+v, http.StatusBadRequest)
+			return
+		}
+		if sess = s.lookupSession(w, r); sess == nil {
+			return
+		}
+	}
+	sess.touch()
+	defer sess.touch()
+
+	hasRequests := false
+	for _, req := range reqs {
+		if req.ID != nil && req.Method != "" {
+			hasRequests = true
+		}
+	}
+
+	var wg sync.WaitGroup
+	if !hasRequests {
+		// Nothing can be answered: only notifications are handled. A
+		// method call without an ID would have nowhere to write to.
+		for _, req := range reqs {
+			if strings.HasPrefix(req.Method, "notifications/") {
+				s.dispatch(&wg, nil, req, sess)
+			}
+		}
+		wg.Wait()
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var sink io.Writer
+	collector := &jsonCollector{}
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		sink = newSSEWriter(w)
+	} else {
+		sink = collector
+	}
+
+	writer := newMessageWriter(sink)
+	for _, req := range reqs {
+		if req.Method != "" {
+			s.dispatch(&wg, writer, req, sess)
+		}
+	}
+	wg.Wait()
+
+	if sink == collector {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(collector.body(len(reqs) > 1))
+	}
+}
+
+// handleHTTPStream opens the session's SSE stream for messages that are not
+// responses to a request, and keeps it open until the client disconnects
+// or the session ends.
+func (s *Server) handleHTTPStream(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		http.Error(w, "expected Accept: text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	sess := s.lookupSession(w, r)
+	if sess == nil {
+		return
+	}
+
+	// Messages for the stream are written under its lock, so holding it
+	// keeps them from going out before the headers.
+	sse := newSSEWriter(w)
+	stream := newMessageWriter(sse)
+	stream.mu.Lock()
+	sess.mu.Lock()
+	if sess.stream != nil {
+		sess.mu.Unlock()
+		stream.mu.Unlock()
+		http.Error(w, "session already has an open stream", http.StatusConflict)
+		return
+	}
+	sess.stream = stream
+	sess.mu.Unlock()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	sse.flush()
+	stream.mu.Unlock()
+
+	defer func() {
+		sess.mu.Lock()
+		sess.stream = nil
+		sess.lastSeen = time.Now()
+		sess.mu.Unlock()
+		// Waits for a message being written to the stream.
+		stream.close()
+	}()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			stream.mu.Lock()
+			sse.comment("keepalive")
+			stream.mu.Unlock()
+		case <-r.Context().Done():
+			return
+		case <-sess.closed:
+			return
+		}
+	}
+}
+
+// handleHTTPDelete ends a session and cancels its in-flight requests.
+func (s *Server) handleHTTPDelete(w http.ResponseWriter, r *http.Request) {
+	sess := s.lookupSession(w, r)
+	if sess == nil {
+		return
+	}
+	s.sessionsMu.Lock()
+	_, ok := s.sessions[sess.id]
+	delete(s.sessions, sess.id)
+	s.sessionsMu.Unlock()
+	if ok {
+		s.endSession(sess)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newSession creates a session with a random ID. Sessions left idle for
+// sessionIdleTimeout are dropped at the same time.
+func (s *Server) newSession() *httpSession {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	sess := &httpSession{
+		server:   s,
+		id:       hex.EncodeToString(b[:]),
+		closed:   make(chan struct{}),
+		lastSeen: time.Now(),
+	}
+
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	for id, other := range s.sessions {
+		other.mu.Lock()
+		idle := other.stream == nil && time.Since(other.lastSeen) > sessionIdleTimeout
+		other.mu.Unlock()
+		if idle {
+			delete(s.sessions, id)
+			s.endSession(other)
+		}
+	}
+	s.sessions[sess.id] = sess
+	return sess
+}
+
+// lookupSession returns the session named by the request, or writes the
+// error response and returns nil.
+func (s *Server) lookupSession(w http.ResponseWriter, r *http.Request) *httpSession {
+	id := r.Header.Get(sessionHeader)
+	if id == "" {
+		http.Error(w, "missing "+sessionHeader+" header", http.StatusBadRequest)
+		return nil
+	}
+	s.sessionsMu.Lock()
+	sess := s.sessions[id]
+	s.sessionsMu.Unlock()
+	if sess == nil {
+		// The client must start a new session with initialize.
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return nil
+	}
+	return sess
+}
+
+// endSession closes the session's stream and cancels its in-flight
+// requests. The session must already be removed from s.sessions.
+func (s *Server) endSession(sess *httpSession) {
+	close(sess.closed)
+	s.cancelRequests(sess.scope())
+	s.removeClient(sess)
+}
+
+// closeSessions ends every session, on server shutdown.
+func (s *Server) closeSessions() {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	for id, sess := range s.sessions {
+		delete(s.sessions, id)
+		s.endSession(sess)
+	}
+}
+
+// parseHTTPMessages decodes a POST body holding a message or a batch.
+func parseHTTPMessages(body []byte) ([]*JSONRPCRequest, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var reqs []*JSONRPCRequest
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			return nil, err
+		}
+		if len(reqs) == 0 {
+			return nil, fmt.Errorf("empty batch")
+		}
+		return reqs, nil
+	}
+	var req JSONRPCRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	return []*JSONRPCRequest{&req}, nil
+}
+
+func writeHTTPError(w http.ResponseWriter, status, code int, message string) {
+	data, _ := json.Marshal(JSONRPCResponse{
+		JSONRPC: "2.0",
+		Error:   &RPCError{Code: code, Message: message},
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// sseWriter turns the newline-terminated messages written by writeMessage
+// into server-sent events. JSON-encoded messages never contain a raw
+// newline, so each line is one message.
+type sseWriter struct {
+	w       http.ResponseWriter
+	pending []byte
+}
+
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	return &sseWriter{w: w}
+}
+
+func (sw *sseWriter) Write(p []byte) (int, error) {
+	sw.pending = append(sw.pending, p...)
+	for {
+		i := bytes.IndexByte(sw.pending, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := fmt.Fprintf(sw.w, "event: message\ndata: %s\n\n", sw.pending[:i]); err != nil {
+			return 0, err
+		}
+		sw.pending = sw.pending[i+1:]
+	}
+	sw.flush()
+	return len(p), nil
+}
+
+func (sw *sseWriter) comment(text string) {
+	fmt.Fprintf(sw.w, ": %s\n\n", text)
+	sw.flush()
+}
+
+func (sw *sseWriter) flush() {
+	if f, ok := sw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// jsonCollector keeps the responses written for a POST answered with plain
+// JSON. Notifications are dropped: there is no stream to carry them.
+type jsonCollector struct {
+	responses [][]byte
+	pending   []byte
+}
+
+func (c *jsonCollector) Write(p []byte) (int, error) {
+	c.pending = append(c.pending, p...)
+	for {
+		i := bytes.IndexByte(c.pending, '\n')
+		if i < 0 {
+			break
+		}
+		line := append([]byte(nil), c.pending[:i]...)
+		c.pending = c.pending[i+1:]
+		var msg struct {
+			Method string `json:"method"`
+		}
+		if json.Unmarshal(line, &msg) == nil && msg.Method == "" {
+			c.responses = append(c.responses, line)
+		}
+	}
+	return len(p), nil
+}
+
+// body returns the responses as one message, or as an array for a batch.
+func (c *jsonCollector) body(batch bool) []byte {
+	if !batch && len(c.responses) == 1 {
+		return c.responses[0]
+	}
+	return append(append([]byte("["), bytes.Join(c.responses, []byte(","))...), ']')
+}
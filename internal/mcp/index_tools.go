@@ -0,0 +1,385 @@
+package mcp
+
+import (
+	"codebase/internal/indexer"
+	"codebase/internal/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	indexModeIncremental = "incremental"
+	indexModeFull        = "full"
+)
+
+// maxPendingFiles caps the pending changes listed by index-status.
+const maxPendingFiles = 20
+
+// indexJob is an indexing run in the background, started by the reindex
+// tool or the file watcher. At most one job runs per collection.
+type indexJob struct {
+	root       string
+	projectID  string
+	collection string
+	mode       string
+	indexer    *indexer.Indexer
+	startedAt  time.Time
+	done       chan struct{} // closed when the job finishes
+	ctx        context.Context
+	cancel     context.CancelFunc // aborts the job
+
+	mu         sync.Mutex
+	running    bool
+	rerun      bool // changes arrived while running; index again when done
+	finishedAt time.Time
+	err        error
+	listeners  map[*progressReporter]bool
+	indexed    []string // files processed by the job
+}
+
+// startIndexJob starts indexing root into collection unless a job for the
+// collection is already running. An incremental request made while a job
+// runs is queued as one more incremental pass, so that changes made during
+// the run are not missed. It returns the job and whether it was started.
+func (s *Server) startIndexJob(root, collection, mode string) (*indexJob, bool, error) {
+	projectID, err := utils.ComputeProjectID(root)
+	if err != nil {
+		return nil, false, err
+	}
+
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+	if s.clearing[collection] {
+		return nil, false, fmt.Errorf("the index of %s is being cleared", root)
+	}
+	if job := s.jobs[collection]; job != nil {
+		job.mu.Lock()
+		running := job.running
+		if running && mode == indexModeIncremental {
+			job.rerun = true
+		}
+		job.mu.Unlock()
+		if running {
+			return job, false, nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &indexJob{
+		ctx:        ctx,
+		cancel:     cancel,
+		root:       root,
+		projectID:  projectID,
+		collection: collection,
+		mode:       mode,
+		indexer:    s.newIndexer(),
+		startedAt:  time.Now().UTC(),
+		done:       make(chan struct{}),
+		running:    true,
+		listeners:  make(map[*progressReporter]bool),
+	}
+	job.indexer.SetProgressFunc(job.publish)
+	s.jobs[collection] = job
+	go s.runIndexJob(job)
+	return job, true, nil
+}
+
+func (s *Server) runIndexJob(job *indexJob) {
+	defer job.cancel()
+	var err error
+	if job.mode == indexModeFull {
+		err = s.clearIndex(job.ctx, job.collection, job.projectID)
+	}
+	for err == nil {
+		if err = job.indexer.IndexProject(job.ctx, job.root); err != nil {
+			break
+		}
+		job.mu.Lock()
+		rerun := job.rerun
+		job.rerun = false
+		job.mu.Unlock()
+		if !rerun {
+			break
+		}
+	}
+
+	job.mu.Lock()
+	job.running = false
+	job.finishedAt = time.Now().UTC()
+	job.err = err
+	indexed := job.indexed
+	job.mu.Unlock()
+	close(job.done)
+	s.filesIndexed(job.collection, indexed)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[MCP WARN] Indexing %s failed: %v\n", job.root, err)
+	} else {
+		fmt.Fprintf(os.Stderr, "[MCP] Indexing %s completed.\n", job.root)
+	}
+}
+
+// publish forwards an indexer progress event to the job's listeners.
+func (job *indexJob) publish(ev indexer.ProgressEvent) {
+	job.mu.Lock()
+	if ev.Phase == indexer.PhaseUpsert {
+		job.indexed = append(job.indexed, ev.File)
+	}
+	listeners := make([]*progressReporter, 0, len(job.listeners))
+	for p := range job.listeners {
+		listeners = append(listeners, p)
+	}
+	job.mu.Unlock()
+	for _, p := range listeners {
+		p.indexProgress(ev)
+	}
+}
+
+// wait blocks until the job finishes or ctx is done, sending the job's
+// progress to p meanwhile. Cancelling ctx stops waiting, not the job.
+func (job *indexJob) wait(ctx context.Context, p *progressReporter) error {
+	job.mu.Lock()
+	job.listeners[p] = true
+	job.mu.Unlock()
+	defer func() {
+		job.mu.Lock()
+		delete(job.listeners, p)
+		job.mu.Unlock()
+		// publish may still hold p from before the removal.
+		p.finish()
+	}()
+
+	select {
+	case <-job.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// snapshot describes the job for tool results.
+func (job *indexJob) snapshot() map[string]interface{} {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	done, total := job.indexer.Progress()
+	result := map[string]interface{}{
+		"mode":        job.mode,
+		"state":       "running",
+		"started_at":  job.startedAt.Format(time.RFC3339),
+		"files_done":  done,
+		"files_total": total,
+	}
+	if !job.running {
+		result["state"] = "completed"
+		result["finished_at"] = job.finishedAt.Format(time.RFC3339)
+		if job.err != nil {
+			result["state"] = "failed"
+			result["error"] = job.err.Error()
+		}
+	}
+	return result
+}
+
+// clearIndex deletes a project's collection and its local indexing state.
+func (s *Server) clearIndex(ctx context.Context, collection, projectID string) error {
+	exists, err := s.qdrantClient.CollectionExists(ctx, collection)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if err := s.qdrantClient.DeleteCollection(ctx, collection); err != nil {
+			return err
+		}
+	}
+	return indexer.ClearProjectState(projectID)
+}
+
+// projectArgs is the argument shared by the index management tools.
+type projectArgs struct {
+	ProjectPath string `json:"project_path"`
+}
+
+// handleIndexStatus reports the state of a project's index: its collection,
+// the last completed run, changes not indexed yet and any running job.
+func (s *Server) handleIndexStatus(ctx context.Context, args json.RawMessage) (interface{}, error) {
+	var input projectArgs
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, err
+	}
+	collection, root, err := s.resolveProject(input.ProjectPath)
+	if err != nil {
+		return nil, err
+	}
+	projectID, err := utils.ComputeProjectID(root)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{
+		"project_path": root,
+		"collection":   collection,
+		"model":        s.embedClient.Model(),
+	}
+
+	exists, err := s.qdrantClient.CollectionExists(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+	result["exists"] = exists
+	if exists {
+		points, err := s.qdrantClient.Count(ctx, collection)
+		if err != nil {
+			return nil, err
+		}
+		result["points"] = points
+	}
+
+	status, err := indexer.LoadProjectStatus(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if status != nil {
+		result["last_indexed"] = status.LastIndexed.Format(time.RFC3339)
+		result["indexed_files"] = status.Files
+		result["indexed_model"] = status.Model
+		failed := make([]string, 0, len(status.FailedFiles))
+		for _, f := range status.FailedFiles {
+			failed = append(failed, displayPath(root, f))
+		}
+		result["failed_files"] = failed
+	}
+
+	changed, deleted, err := indexer.PendingChanges(root)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, f := range changed {
+		if len(files) < maxPendingFiles {
+			files = append(files, displayPath(root, f))
+		}
+	}
+	for _, f := range deleted {
+		if len(files) < maxPendingFiles {
+			files = append(files, displayPath(root, filepath.FromSlash(f))+" (deleted)")
+		}
+	}
+	result["pending_changes"] = map[string]interface{}{
+		"added_or_modified": len(changed),
+		"deleted":           len(deleted),
+		"files":             files,
+	}
+
+	s.jobsMu.Lock()
+	job := s.jobs[collection]
+	s.jobsMu.Unlock()
+	if job != nil {
+		result["job"] = job.snapshot()
+	}
+	return result, nil
+}
+
+// handleReindex starts indexing a project in the background. The call
+// returns at once and index-status reports the job's progress, unless the
+// client asked for progress notifications: then it waits for the job, and
+// cancelling the call aborts the job if the call started it.
+func (s *Server) handleReindex(ctx context.Context, args json.RawMessage, progress *progressReporter) (interface{}, error) {
+	var input struct {
+		projectArgs
+		Mode string `json:"mode"`
+	}
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, err
+	}
+	switch input.Mode {
+	case "":
+		input.Mode = indexModeIncremental
+	case indexModeIncremental, indexModeFull:
+	default:
+		return nil, fmt.Errorf("invalid mode %q: expected incremental or full", input.Mode)
+	}
+	collection, root, err := s.resolveProject(input.ProjectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	job, started, err := s.startIndexJob(root, collection, input.Mode)
+	if err != nil {
+		return nil, err
+	}
+	status := "started"
+	if !started {
+		status = "already_running"
+	}
+	if progress != nil {
+		// Waiting for the job must not keep other requests from running.
+		releaseSlot(ctx)
+		if err := job.wait(ctx, progress); err != nil {
+			if started {
+				job.cancel()
+			}
+			return nil, err
+		}
+	}
+	return map[string]interface{}{
+		"status":       status,
+		"project_path": root,
+		"collection":   collection,
+		"job":          job.snapshot(),
+	}, nil
+}
+
+// handleClearIndex deletes a project's collection and local indexing state.
+func (s *Server) handleClearIndex(ctx context.Context, args json.RawMessage) (interface{}, error) {
+	var input projectArgs
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, err
+	}
+	collection, root, err := s.resolveProject(input.ProjectPath)
+	if err != nil {
+		return nil, err
+	}
+	projectID, err := utils.ComputeProjectID(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// Marking the collection keeps a job from starting while the index is
+	// cleared, without holding jobsMu over the calls to Qdrant.
+	s.jobsMu.Lock()
+	if s.clearing[collection] {
+		s.jobsMu.Unlock()
+		return nil, fmt.Errorf("the index of %s is already being cleared", root)
+	}
+	if job := s.jobs[collection]; job != nil {
+		job.mu.Lock()
+		running := job.running
+		job.mu.Unlock()
+		if running {
+			s.jobsMu.Unlock()
+			return nil, fmt.Errorf("indexing of %s is in progress; clear the index after it finishes", root)
+		}
+	}
+	s.clearing[collection] = true
+	s.jobsMu.Unlock()
+	defer func() {
+		s.jobsMu.Lock()
+		delete(s.clearing, collection)
+		s.jobsMu.Unlock()
+	}()
+
+	if err := s.clearIndex(ctx, collection, projectID); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"project_path": root,
+		"collection":   collection,
+		"cleared":      true,
+	}, nil
+}
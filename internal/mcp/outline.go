@@ -0,0 +1,314 @@
+package mcp
+
+import (
+	"codebase/internal/indexer"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	qdrantpb "github.com/qdrant/go-client/qdrant"
+)
+
+// outlineFields are the payload fields read by file-outline.
+var outlineFields = []string{
+	"file_path", "language", "node_type", "node_name", "receiver", "signature",
+	"doc", "start_line", "end_line", "callees", "exported", "cell_index",
+}
+
+// repoMapSkippedKinds are chunk kinds left out of the repository map: they
+// describe prose or loose statements rather than declarations.
+var repoMapSkippedKinds = map[string]bool{
+	"documentation": true,
+	"cell":          true,
+	"statements":    true,
+	"migration":     true,
+}
+
+const defaultRepoMapTokens = 1024
+
+// outlineSymbol is an indexed chunk as listed by file-outline.
+type outlineSymbol struct {
+	payload  map[string]interface{}
+	filePath string // relative to the project root
+	name     string
+	kind     string
+	line     int64
+}
+
+// handleFileOutline lists the indexed symbols of a file or directory, or
+// renders a token-budgeted map of the project in "repo-map" mode.
+func (s *Server) handleFileOutline(ctx context.Context, args json.RawMessage) (interface{}, error) {
+	var input struct {
+		Path        string `json:"path"`
+		Mode        string `json:"mode"`
+		MaxTokens   int    `json:"max_tokens"`
+		ProjectPath string `json:"project_path"`
+	}
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, err
+	}
+	if input.Mode != "" && input.Mode != "outline" && input.Mode != "repo-map" {
+		return nil, fmt.Errorf("invalid mode %q: expected outline or repo-map", input.Mode)
+	}
+	if input.Mode != "repo-map" && input.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	collection, root, err := s.resolveProject(input.ProjectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	target := ""
+	var filter *qdrantpb.Filter
+	if input.Path != "" {
+		abs := input.Path
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(root, abs)
+		}
+		if filepath.Clean(abs) != filepath.Clean(root) {
+			target = indexer.NormalizeFilePath(abs)
+			// The text condition is a substring match on file_path, which
+			// covers every file below a directory.
+			filter = &qdrantpb.Filter{Should: []*qdrantpb.Condition{
+				qdrantpb.NewMatchKeyword("file_path", target),
+				qdrantpb.NewMatchText("file_path", target+"/"),
+			}}
+		}
+	}
+
+	payloads, err := s.scrollPayloads(ctx, collection, filter, outlineFields)
+	if err != nil {
+		return nil, err
+	}
+	var symbols []outlineSymbol
+	for _, payload := range payloads {
+		filePath, _ := payload["file_path"].(string)
+		if target != "" && filePath != target && !strings.HasPrefix(filePath, target+"/") {
+			continue
+		}
+		name, _ := payload["node_name"].(string)
+		kind, _ := payload["node_type"].(string)
+		line, _ := payload["start_line"].(int64)
+		symbols = append(symbols, outlineSymbol{
+			payload:  payload,
+			filePath: filepath.ToSlash(displayPath(root, filePath)),
+			name:     name,
+			kind:     kind,
+			line:     line,
+		})
+	}
+	sort.SliceStable(symbols, func(i, j int) bool {
+		if symbols[i].filePath != symbols[j].filePath {
+			return symbols[i].filePath < symbols[j].filePath
+		}
+		return symbols[i].line < symbols[j].line
+	})
+
+	if input.Mode == "repo-map" {
+		if input.MaxTokens <= 0 {
+			input.MaxTokens = defaultRepoMapTokens
+		}
+		return buildRepoMap(symbols, input.MaxTokens), nil
+	}
+
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("no indexed symbols under %s", input.Path)
+	}
+	var files []map[string]interface{}
+	for _, sym := range symbols {
+		if len(files) == 0 || files[len(files)-1]["file_path"] != sym.filePath {
+			files = append(files, map[string]interface{}{
+				"file_path": sym.filePath,
+				"language":  sym.payload["language"],
+				"symbols":   []map[string]interface{}{},
+			})
+		}
+		entry := map[string]interface{}{
+			"name":       sym.name,
+			"kind":       sym.kind,
+			"start_line": sym.payload["start_line"],
+			"end_line":   sym.payload["end_line"],
+		}
+		if signature, ok := sym.payload["signature"].(string); ok && signature != "" {
+			entry["signature"] = signature
+		}
+		if doc := firstDocLine(sym.payload["doc"]); doc != "" {
+			entry["doc"] = doc
+		}
+		if receiver, ok := sym.payload["receiver"].(string); ok && receiver != "" {
+			entry["parent"] = receiver
+		}
+		if cell, ok := sym.payload["cell_index"].(int64); ok && cell > 0 {
+			entry["cell"] = fmt.Sprintf("cell %d", cell)
+		}
+		file := files[len(files)-1]
+		file["symbols"] = append(file["symbols"].([]map[string]interface{}), entry)
+	}
+	return map[string]interface{}{
+		"path":  input.Path,
+		"files": files,
+	}, nil
+}
+
+// HandleFileOutline is the exported version for CLI access
+func (s *Server) HandleFileOutline(ctx context.Context, args json.RawMessage) (interface{}, error) {
+	return s.handleFileOutline(ctx, args)
+}
+
+func firstDocLine(doc interface{}) string {
+	text, _ := doc.(string)
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(line)
+}
+
+// estimateTokens approximates the token count of text at four bytes per
+// token.
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// buildRepoMap renders the project as an indented tree of directories,
+// files and their most important symbols within maxTokens. Every file is
+// listed when the budget allows; symbols are then added in order of how
+// often the project calls them, exported and top-level declarations first
+// among equals.
+func buildRepoMap(symbols []outlineSymbol, maxTokens int) map[string]interface{} {
+	var files []string
+	refs := make(map[string]int)
+	for _, sym := range symbols {
+		if len(files) == 0 || files[len(files)-1] != sym.filePath {
+			files = append(files, sym.filePath)
+		}
+		for _, callee := range payloadStrings(sym.payload["callees"]) {
+			refs[callee[strings.LastIndex(callee, ".")+1:]]++
+		}
+	}
+
+	var candidates []int
+	for i, sym := range symbols {
+		if !repoMapSkippedKinds[sym.kind] {
+			candidates = append(candidates, i)
+		}
+	}
+	score := func(sym outlineSymbol) int {
+		bare := normalizeSymbolName(sym.name)
+		bare = bare[strings.LastIndex(bare, ".")+1:]
+		n := refs[bare] * 4
+		if exported, _ := sym.payload["exported"].(bool); exported {
+			n += 2
+		}
+		if receiver, _ := sym.payload["receiver"].(string); receiver == "" {
+			n++
+		}
+		return n
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return score(symbols[candidates[a]]) > score(symbols[candidates[b]])
+	})
+
+	shown := make(map[int]bool)
+	text := renderRepoMap(symbols, files, shown)
+	used := estimateTokens(text)
+	dirsCut := false
+	if used > maxTokens {
+		// Not even the file list fits: summarize directories instead.
+		text, dirsCut = renderRepoMapDirs(files, maxTokens)
+	} else {
+		for _, i := range candidates {
+			cost := estimateTokens(repoMapSymbolLine(symbols[i], strings.Count(symbols[i].filePath, "/")+1))
+			if used+cost > maxTokens {
+				continue
+			}
+			shown[i] = true
+			used += cost
+		}
+		text = renderRepoMap(symbols, files, shown)
+	}
+
+	return map[string]interface{}{
+		"mode":          "repo-map",
+		"max_tokens":    maxTokens,
+		"files":         len(files),
+		"symbols_shown": len(shown),
+		"symbols_total": len(candidates),
+		"truncated":     dirsCut || len(shown) < len(candidates),
+		"map":           text,
+	}
+}
+
+// renderRepoMap writes files as a tree, each followed by its shown symbols.
+func renderRepoMap(symbols []outlineSymbol, files []string, shown map[int]bool) string {
+	bySymbolFile := make(map[string][]int)
+	for i := range symbols {
+		if shown[i] {
+			bySymbolFile[symbols[i].filePath] = append(bySymbolFile[symbols[i].filePath], i)
+		}
+	}
+
+	var b strings.Builder
+	var prevDirs []string
+	for _, file := range files {
+		parts := strings.Split(file, "/")
+		dirs := parts[:len(parts)-1]
+		common := 0
+		for common < len(dirs) && common < len(prevDirs) && dirs[common] == prevDirs[common] {
+			common++
+		}
+		for depth := common; depth < len(dirs); depth++ {
+			fmt.Fprintf(&b, "%s%s/\n", strings.Repeat("  ", depth), dirs[depth])
+		}
+		fmt.Fprintf(&b, "%s%s\n", strings.Repeat("  ", len(dirs)), parts[len(parts)-1])
+		for _, i := range bySymbolFile[file] {
+			b.WriteString(repoMapSymbolLine(symbols[i], len(parts)))
+		}
+		prevDirs = dirs
+	}
+	return b.String()
+}
+
+// repoMapSymbolLine renders a symbol as its signature, or its kind and name.
+func repoMapSymbolLine(sym outlineSymbol, depth int) string {
+	text, _ := sym.payload["signature"].(string)
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		text = sym.kind + " " + sym.name
+	}
+	if len(text) > 120 {
+		cut := 117
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "..."
+	}
+	return fmt.Sprintf("%s%s\n", strings.Repeat("  ", depth), text)
+}
+
+// renderRepoMapDirs lists the directories of files with their file counts,
+// stopping at maxTokens. It reports whether directories were left out.
+func renderRepoMapDirs(files []string, maxTokens int) (string, bool) {
+	counts := make(map[string]int)
+	var dirs []string
+	for _, file := range files {
+		dir := filepath.ToSlash(filepath.Dir(file))
+		if counts[dir] == 0 {
+			dirs = append(dirs, dir)
+		}
+		counts[dir]++
+	}
+	sort.Strings(dirs)
+
+	var b strings.Builder
+	for _, dir := range dirs {
+		line := fmt.Sprintf("%s/ (%d files)\n", dir, counts[dir])
+		if estimateTokens(b.String()+line) > maxTokens {
+			return b.String(), true
+		}
+		b.WriteString(line)
+	}
+	return b.String(), false
+}
@@ -0,0 +1,72 @@
+package mcp
+
+import (
+	"codebase/internal/indexer"
+	"fmt"
+	"sync"
+)
+
+// progressReporter sends notifications/progress for a request whose _meta
+// carries a progressToken. Progress must increase with every notification,
+// so it counts the indexing steps reported so far: one scan step per pass
+// plus an embed and an upsert step per file.
+type progressReporter struct {
+	s      *Server
+	writer *messageWriter
+	token  interface{}
+
+	mu    sync.Mutex
+	steps int
+	base  int  // steps reported before the current pass
+	done  bool // the request has been answered; later events are dropped
+}
+
+// newProgressReporter returns nil when the request has no progressToken, so
+// that callers can skip reporting.
+func newProgressReporter(s *Server, writer *messageWriter, token interface{}) *progressReporter {
+	if token == nil {
+		return nil
+	}
+	return &progressReporter{s: s, writer: writer, token: token}
+}
+
+// pairsProgress reports the progress of the duplicate analysis.
+func (p *progressReporter) pairsProgress(done, total int) {
+	p.s.writeNotification(p.writer, "notifications/progress", map[string]interface{}{
+		"progressToken": p.token,
+		"progress":      done,
+		"total":         total,
+		"message":       fmt.Sprintf("compared %d/%d chunk pairs", done, total),
+	})
+}
+
+// finish stops indexProgress from reporting, waiting for a notification
+// being written. An event published concurrently is dropped rather than sent
+// after the request's response.
+func (p *progressReporter) finish() {
+	p.mu.Lock()
+	p.done = true
+	p.mu.Unlock()
+}
+
+// indexProgress reports an indexer progress event.
+func (p *progressReporter) indexProgress(ev indexer.ProgressEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return
+	}
+
+	if ev.Phase == indexer.PhaseScan {
+		p.base = p.steps
+	}
+	p.steps++
+	total := max(p.base+1+2*ev.FilesTotal, p.steps)
+	p.s.writeNotification(p.writer, "notifications/progress", map[string]interface{}{
+		"progressToken": p.token,
+		"progress":      p.steps,
+		"total":         total,
+		"message": fmt.Sprintf("%s: %d/%d files, %d chunks embedded, %d upserted",
+			ev.Phase, ev.FilesDone, ev.FilesTotal, ev.ChunksEmbedded, ev.ChunksUpserted),
+	})
+}
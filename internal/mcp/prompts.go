@@ -0,0 +1,449 @@
+package mcp
+
+import (
+	"codebase/internal/analyzer"
+	"codebase/internal/indexer"
+	"codebase/internal/models"
+	"codebase/internal/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	qdrantpb "github.com/qdrant/go-client/qdrant"
+)
+
+const (
+	// maxPromptSnippetLines bounds the lines of each code snippet quoted in
+	// a prompt.
+	maxPromptSnippetLines = 60
+
+	// maxPromptDuplicateGroups bounds the duplicate groups quoted by
+	// find-duplicates, and maxPromptGroupChunks the chunks of each group.
+	maxPromptDuplicateGroups = 10
+	maxPromptGroupChunks     = 5
+
+	// maxDiffBlocks bounds the added blocks of a diff searched by
+	// review-diff, and minDiffBlockLines is the size of the smallest one.
+	maxDiffBlocks     = 5
+	minDiffBlockLines = 3
+)
+
+// promptArgument describes an argument of a prompt in prompts/list.
+type promptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+// prompt is a built-in prompt template. build gathers the context the
+// prompt needs and renders the text of its user message, reporting its
+// progress when the client asked for it.
+type prompt struct {
+	name        string
+	description string
+	arguments   []promptArgument
+	build       func(s *Server, ctx context.Context, args map[string]string, progress *progressReporter) (string, error)
+}
+
+var projectPathArgument = promptArgument{
+	Name:        "project_path",
+	Description: "Absolute path to the project root. Defaults to the directory the server was started in.",
+}
+
+var prompts = []prompt{
+	{
+		name:        "explain-module",
+		description: "Explain what a file or directory does, with its indexed outline and most relevant code.",
+		arguments: []promptArgument{
+			{Name: "path", Description: "File or directory to explain, relative to the project root or absolute.", Required: true},
+			projectPathArgument,
+		},
+		build: (*Server).explainModulePrompt,
+	},
+	{
+		name:        "find-duplicates",
+		description: "Review groups of near-duplicate code under a path and suggest how to consolidate them.",
+		arguments: []promptArgument{
+			{Name: "path", Description: "File or directory to search for duplicated logic, relative to the project root or absolute.", Required: true},
+			{Name: "threshold", Description: "Minimum cosine similarity of two chunks to count as duplicates (default 0.9)."},
+			{Name: "min_lines", Description: "Ignore chunks shorter than this many lines (default 5)."},
+			projectPathArgument,
+		},
+		build: (*Server).findDuplicatesPrompt,
+	},
+	{
+		name:        "plan-refactor",
+		description: "Plan a refactor of a symbol from its definitions and every place that calls it.",
+		arguments: []promptArgument{
+			{Name: "symbol", Description: "Name of the symbol, optionally qualified (\"Server.Run\", \"utils.NormalizeProjectRoot\").", Required: true},
+			{Name: "goal", Description: "What the refactor should achieve."},
+			projectPathArgument,
+		},
+		build: (*Server).planRefactorPrompt,
+	},
+	{
+		name:        "review-diff",
+		description: "Review a diff for code that duplicates or should reuse existing code in the project.",
+		arguments: []promptArgument{
+			{Name: "diff", Description: "Unified diff to review, as printed by git diff.", Required: true},
+			projectPathArgument,
+		},
+		build: (*Server).reviewDiffPrompt,
+	},
+}
+
+func (s *Server) handlePromptsList(writer *messageWriter, req *JSONRPCRequest) {
+	list := make([]map[string]interface{}, 0, len(prompts))
+	for _, p := range prompts {
+		list = append(list, map[string]interface{}{
+			"name":        p.name,
+			"description": p.description,
+			"arguments":   p.arguments,
+		})
+	}
+	s.writeResponse(writer, req.ID, map[string]interface{}{"prompts": list})
+}
+
+// handlePromptsGet renders a prompt with its arguments. The context it
+// quotes is fetched from the index when the prompt is requested.
+func (s *Server) handlePromptsGet(ctx context.Context, writer *messageWriter, req *JSONRPCRequest) {
+	var params struct {
+		Name      string            `json:"name"`
+		Arguments map[string]string `json:"arguments"`
+		Meta      struct {
+			ProgressToken interface{} `json:"progressToken"`
+		} `json:"_meta"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		s.writeError(writer, req.ID, -32602, "Invalid params")
+		return
+	}
+
+	var p *prompt
+	for i := range prompts {
+		if prompts[i].name == params.Name {
+			p = &prompts[i]
+			break
+		}
+	}
+	if p == nil {
+		s.writeError(writer, req.ID, -32602, "Unknown prompt")
+		return
+	}
+	for _, arg := range p.arguments {
+		if arg.Required && strings.TrimSpace(params.Arguments[arg.Name]) == "" {
+			s.writeError(writer, req.ID, -32602, fmt.Sprintf("Missing required argument: %s", arg.Name))
+			return
+		}
+	}
+
+	text, err := p.build(s, ctx, params.Arguments, newProgressReporter(s, writer, params.Meta.ProgressToken))
+	// A cancelled request gets no response.
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		s.writeError(writer, req.ID, -32603, err.Error())
+		return
+	}
+	s.writeResponse(writer, req.ID, map[string]interface{}{
+		"description": p.description,
+		"messages": []map[string]interface{}{
+			{
+				"role": "user",
+				"content": map[string]interface{}{
+					"type": "text",
+					"text": text,
+				},
+			},
+		},
+	})
+}
+
+// explainModulePrompt quotes the outline of a file or directory and the
+// chunks of it that best describe it.
+func (s *Server) explainModulePrompt(ctx context.Context, args map[string]string, _ *progressReporter) (string, error) {
+	path := args["path"]
+	toolArgs, _ := json.Marshal(map[string]string{"path": path, "project_path": args["project_path"]})
+	outline, err := s.handleFileOutline(ctx, toolArgs)
+	if err != nil {
+		return "", err
+	}
+	result, _ := outline.(map[string]interface{})
+	files, ok := result["files"].([]map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("no outline for %s", path)
+	}
+	collection, root, err := s.resolveProject(args["project_path"])
+	if err != nil {
+		return "", err
+	}
+	target := indexer.NormalizeFilePath(absPath(root, path))
+	snippets, err := s.simpleSearchWithCollection(ctx, "purpose and main entry points of "+path, 5, collection, root, &qdrantpb.Filter{
+		Should: []*qdrantpb.Condition{
+			qdrantpb.NewMatchKeyword("file_path", target),
+			qdrantpb.NewMatchText("file_path", target+"/"),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Explain what `%s` does: its responsibilities, its main types and functions, how they fit together and how the rest of the project uses it. Cite code as path:line.\n\n", path)
+	b.WriteString("## Outline\n\n")
+	writeOutline(&b, files)
+	b.WriteString("\n## Relevant code\n\n")
+	hits, _ := snippets.([]map[string]interface{})
+	for _, hit := range hits {
+		writeSearchHit(&b, hit, maxPromptSnippetLines)
+	}
+	return b.String(), nil
+}
+
+// findDuplicatesPrompt quotes the groups of similar chunks under a path, as
+// found by the duplicate analyzer.
+func (s *Server) findDuplicatesPrompt(ctx context.Context, args map[string]string, progress *progressReporter) (string, error) {
+	threshold := 0.9
+	if value := args["threshold"]; value != "" {
+		t, err := strconv.ParseFloat(value, 64)
+		if err != nil || t <= 0 || t > 1 {
+			return "", fmt.Errorf("invalid threshold %q: expected a number in (0, 1]", value)
+		}
+		threshold = t
+	}
+	minLines := 5
+	if value := args["min_lines"]; value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return "", fmt.Errorf("invalid min_lines %q: expected a positive integer", value)
+		}
+		minLines = n
+	}
+	collection, root, err := s.resolveProject(args["project_path"])
+	if err != nil {
+		return "", err
+	}
+
+	path := args["path"]
+	abs := absPath(root, path)
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	prefix := indexer.NormalizeFilePath(abs)
+	if info.IsDir() && filepath.Clean(abs) != filepath.Clean(root) {
+		prefix += "/"
+	}
+
+	a := analyzer.NewAnalyzer(s.qdrantClient, nil, collection)
+	if progress != nil {
+		a.SetProgressFunc(progress.pairsProgress)
+	}
+	groups, err := a.FindDuplicates(ctx, models.QueryPlan{
+		Intent:    models.IntentDuplicate,
+		Filter:    models.QueryFilter{PathPrefix: []string{prefix}, MinLines: minLines},
+		Threshold: threshold,
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].AvgScore > groups[j].AvgScore
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Review the duplicated logic found in `%s`. For each group, say whether the duplication is accidental, what a shared abstraction would look like and where it should live, and which call sites would change. Skip groups that are only superficially similar.\n\n", path)
+	if len(groups) == 0 {
+		fmt.Fprintf(&b, "No chunks of at least %d lines with a similarity of %.2f or more were found.\n", minLines, threshold)
+		return b.String(), nil
+	}
+	if len(groups) > maxPromptDuplicateGroups {
+		fmt.Fprintf(&b, "Showing the %d most similar of %d groups.\n\n", maxPromptDuplicateGroups, len(groups))
+		groups = groups[:maxPromptDuplicateGroups]
+	}
+	for i, group := range groups {
+		fmt.Fprintf(&b, "## Group %d (%d chunks, average similarity %.2f)\n\n", i+1, len(group.Chunks), group.AvgScore)
+		chunks := group.Chunks
+		if len(chunks) > maxPromptGroupChunks {
+			chunks = chunks[:maxPromptGroupChunks]
+		}
+		for _, chunk := range chunks {
+			location := fmt.Sprintf("%s:%d-%d", filepath.ToSlash(displayPath(root, chunk.FilePath)), chunk.StartLine, chunk.EndLine)
+			if chunk.NodeName != "" {
+				location += " " + chunk.NodeName
+			}
+			writeSnippet(&b, location, chunk.Language, chunk.Content, maxPromptSnippetLines)
+		}
+	}
+	return b.String(), nil
+}
+
+// planRefactorPrompt quotes the definitions of a symbol and lists its call
+// sites.
+func (s *Server) planRefactorPrompt(ctx context.Context, args map[string]string, _ *progressReporter) (string, error) {
+	symbol := args["symbol"]
+	collection, root, err := s.resolveProject(args["project_path"])
+	if err != nil {
+		return "", err
+	}
+	definitions, err := s.readSymbolResource(ctx, collection, symbol)
+	if err != nil {
+		return "", err
+	}
+	toolArgs, _ := json.Marshal(map[string]interface{}{"name": symbol, "limit": 50, "project_path": args["project_path"]})
+	found, err := s.handleFindSymbol(ctx, toolArgs)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan a refactor of `%s`.", symbol)
+	if goal := strings.TrimSpace(args["goal"]); goal != "" {
+		fmt.Fprintf(&b, " Goal: %s", goal)
+	}
+	b.WriteString("\n\nDescribe the new design, then list the changes step by step, each with the files and call sites it touches, in an order that keeps the code building. Point out callers that rely on behavior the refactor could change.\n\n## Definitions\n\n")
+	for _, def := range definitions {
+		// The URI is file://path#Lstart-Lend.
+		u, err := url.Parse(def["uri"].(string))
+		if err != nil {
+			return "", err
+		}
+		path := filepath.ToSlash(displayPath(root, filepath.FromSlash(uriPath(u))))
+		lines := strings.ReplaceAll(strings.TrimPrefix(u.Fragment, "L"), "-L", "-")
+		writeSnippet(&b, path+":"+lines, utils.DetectLanguage(path), def["text"].(string), maxPromptSnippetLines)
+	}
+	references, _ := found.(map[string]interface{})["references"].([]map[string]interface{})
+	fmt.Fprintf(&b, "## Call sites (%d)\n\n", len(references))
+	if len(references) == 0 {
+		b.WriteString("No indexed code calls it.\n")
+	}
+	for _, ref := range references {
+		fmt.Fprintf(&b, "- %s:%v %v (calls %v)\n", filepath.ToSlash(ref["file_path"].(string)), ref["start_line"], ref["name"], ref["callee"])
+	}
+	return b.String(), nil
+}
+
+// reviewDiffPrompt searches the project for code similar to each block of
+// lines a diff adds.
+func (s *Server) reviewDiffPrompt(ctx context.Context, args map[string]string, _ *progressReporter) (string, error) {
+	collection, root, err := s.resolveProject(args["project_path"])
+	if err != nil {
+		return "", err
+	}
+	blocks := diffAdditions(args["diff"])
+	if len(blocks) > maxDiffBlocks {
+		blocks = blocks[:maxDiffBlocks]
+	}
+
+	var b strings.Builder
+	b.WriteString("Review this diff for code that duplicates existing code in the project or should reuse it. For each added block, compare it with the similar existing code below and say whether to reuse, extend or keep it; then note any other issues.\n\n")
+	fmt.Fprintf(&b, "```diff\n%s\n```\n\n", strings.TrimRight(args["diff"], "\n"))
+	if len(blocks) == 0 {
+		fmt.Fprintf(&b, "The diff adds no block of %d lines or more to search for.\n", minDiffBlockLines)
+		return b.String(), nil
+	}
+	for _, block := range blocks {
+		code := strings.Join(block.lines, "\n")
+		hits, err := s.simpleSearchWithCollection(ctx, code, 3, collection, root, nil)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "## Added to %s at line %d\n\n", block.file, block.line)
+		var similar []map[string]interface{}
+		for _, hit := range hits.([]map[string]interface{}) {
+			if filepath.ToSlash(hit["file_path"].(string)) != block.file {
+				similar = append(similar, hit)
+			}
+		}
+		if len(similar) == 0 {
+			b.WriteString("No similar code found elsewhere.\n\n")
+		}
+		for _, hit := range similar {
+			writeSearchHit(&b, hit, maxPromptSnippetLines)
+		}
+	}
+	return b.String(), nil
+}
+
+// diffBlock is a run of lines added by a diff.
+type diffBlock struct {
+	file  string // as named by the diff, without its b/ prefix
+	line  int    // first line in the new file
+	lines []string
+}
+
+// diffAdditions returns the runs of added lines of a unified diff that have
+// at least minDiffBlockLines non-blank lines.
+func diffAdditions(diff string) []diffBlock {
+	var blocks []diffBlock
+	var current *diffBlock
+	file := ""
+	line := 0
+	flush := func() {
+		if current == nil {
+			return
+		}
+		nonBlank := 0
+		for _, l := range current.lines {
+			if strings.TrimSpace(l) != "" {
+				nonBlank++
+			}
+		}
+		if nonBlank >= minDiffBlockLines {
+			blocks = append(blocks, *current)
+		}
+		current = nil
+	}
+
+	for _, text := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(text, "+++ "):
+			flush()
+			file = strings.TrimPrefix(strings.TrimSpace(text[4:]), "b/")
+			if file == "/dev/null" {
+				file = ""
+			}
+		case strings.HasPrefix(text, "--- "), strings.HasPrefix(text, "diff "):
+			flush()
+		case strings.HasPrefix(text, "@@ "):
+			flush()
+			// @@ -a,b +c,d @@: the new file's hunk starts at line c.
+			if _, after, ok := strings.Cut(text, " +"); ok {
+				start, _, _ := strings.Cut(after, " ")
+				start, _, _ = strings.Cut(start, ",")
+				line, _ = strconv.Atoi(start)
+			}
+		case strings.HasPrefix(text, "+"):
+			if file == "" {
+				continue
+			}
+			if current == nil {
+				current = &diffBlock{file: file, line: line}
+			}
+			current.lines = append(current.lines, text[1:])
+			line++
+		case strings.HasPrefix(text, "-"):
+			flush()
+		default:
+			flush()
+			line++
+		}
+	}
+	flush()
+	return blocks
+}
+
+// absPath resolves path against root unless it is absolute.
+func absPath(root, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(root, path)
+}
@@ -0,0 +1,425 @@
+package mcp
+
+import (
+	"codebase/internal/indexer"
+	"codebase/internal/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	symbolURIPrefix = "codebase://symbol/"
+
+	// resourcesPageSize is the number of files per resources/list page.
+	resourcesPageSize = 200
+
+	// maxResourceBytes bounds the size of a file read as a resource.
+	maxResourceBytes = 2 << 20
+
+	// maxSymbolDefinitions bounds the definitions read for a symbol resource.
+	maxSymbolDefinitions = 10
+
+	// errResourceNotFound is the JSON-RPC error code for unknown resources.
+	errResourceNotFound = -32002
+)
+
+// subscription is the set of clients subscribed to a resource.
+type subscription struct {
+	clients map[client]bool
+	files   map[string]bool // for symbols: normalized paths of their definitions
+}
+
+// handleResourcesList lists the indexed files of the default project as
+// file:// resources, a page at a time.
+func (s *Server) handleResourcesList(ctx context.Context, writer *messageWriter, req *JSONRPCRequest) {
+	var params struct {
+		Cursor string `json:"cursor"`
+	}
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			s.writeError(writer, req.ID, -32602, "Invalid params")
+			return
+		}
+	}
+	offset := 0
+	if params.Cursor != "" {
+		n, err := strconv.Atoi(params.Cursor)
+		if err != nil || n < 0 {
+			s.writeError(writer, req.ID, -32602, "Invalid cursor")
+			return
+		}
+		offset = n
+	}
+
+	payloads, err := s.scrollPayloads(ctx, s.collectionName(), nil, []string{"file_path", "language"})
+	if err != nil {
+		s.writeError(writer, req.ID, -32603, err.Error())
+		return
+	}
+	languages := make(map[string]string)
+	for _, payload := range payloads {
+		filePath, _ := payload["file_path"].(string)
+		language, _ := payload["language"].(string)
+		if filePath != "" {
+			languages[filePath] = language
+		}
+	}
+	files := make([]string, 0, len(languages))
+	for filePath := range languages {
+		files = append(files, filePath)
+	}
+	sort.Strings(files)
+
+	resources := []map[string]interface{}{}
+	end := min(offset+resourcesPageSize, len(files))
+	for _, filePath := range files[min(offset, end):end] {
+		resources = append(resources, map[string]interface{}{
+			"uri":      fileURI(filePath),
+			"name":     filepath.ToSlash(displayPath(s.rootDir, filePath)),
+			"mimeType": languageMIMEType(languages[filePath]),
+		})
+	}
+	result := map[string]interface{}{"resources": resources}
+	if end < len(files) {
+		result["nextCursor"] = strconv.Itoa(end)
+	}
+	s.writeResponse(writer, req.ID, result)
+}
+
+func (s *Server) handleResourceTemplatesList(writer *messageWriter, req *JSONRPCRequest) {
+	s.writeResponse(writer, req.ID, map[string]interface{}{
+		"resourceTemplates": []map[string]interface{}{
+			{
+				"uriTemplate": symbolURIPrefix + "{name}",
+				"name":        "symbol",
+				"description": "Source of the definitions of a symbol, looked up by name like find-symbol (\"NormalizeProjectRoot\", \"Server.Run\").",
+				"mimeType":    "text/plain",
+			},
+		},
+	})
+}
+
+// handleResourcesRead returns a file from disk, or the source of a symbol's
+// definitions as located by the index.
+func (s *Server) handleResourcesRead(ctx context.Context, writer *messageWriter, req *JSONRPCRequest) {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		s.writeError(writer, req.ID, -32602, "Invalid params")
+		return
+	}
+
+	var contents []map[string]interface{}
+	var err error
+	if name, ok := strings.CutPrefix(params.URI, symbolURIPrefix); ok {
+		contents, err = s.readSymbolResource(ctx, s.collectionName(), name)
+	} else {
+		contents, err = s.readFileResource(params.URI)
+	}
+	if err != nil {
+		s.writeError(writer, req.ID, errResourceNotFound, err.Error())
+		return
+	}
+	s.writeResponse(writer, req.ID, map[string]interface{}{"contents": contents})
+}
+
+func (s *Server) readFileResource(uri string) ([]map[string]interface{}, error) {
+	path, err := s.resourcePath(uri)
+	if err != nil {
+		return nil, err
+	}
+	// A symlink inside the project must not expose a file outside of it,
+	// or one that is not indexed.
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("resource not found: %s", uri)
+	}
+	root, err := filepath.EvalSymlinks(s.rootDir)
+	if err != nil {
+		return nil, err
+	}
+	if !s.servesFile(root, resolved) {
+		return nil, fmt.Errorf("resource not available: %s", uri)
+	}
+	info, err := os.Stat(resolved)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("resource not found: %s", uri)
+	}
+	if info.Size() > maxResourceBytes {
+		return nil, fmt.Errorf("resource too large: %s (%d bytes)", uri, info.Size())
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return nil, err
+	}
+	return []map[string]interface{}{{
+		"uri":      uri,
+		"mimeType": languageMIMEType(utils.DetectLanguage(path)),
+		"text":     string(data),
+	}}, nil
+}
+
+// readSymbolResource reads the line range of each definition of a symbol
+// from disk. Notebook cells, whose lines are relative to the cell, and
+// files that can no longer be read use the indexed content instead.
+func (s *Server) readSymbolResource(ctx context.Context, collection, name string) ([]map[string]interface{}, error) {
+	name, err := url.PathUnescape(name)
+	if err != nil {
+		return nil, err
+	}
+	query := normalizeSymbolName(name)
+	if query == "" {
+		return nil, fmt.Errorf("symbol name is required")
+	}
+	definitions, err := s.symbolDefinitions(ctx, collection, query, slices.Concat(symbolFields, []string{"content"}))
+	if err != nil {
+		return nil, err
+	}
+	if len(definitions) == 0 {
+		return nil, fmt.Errorf("symbol not found: %s", name)
+	}
+	if len(definitions) > maxSymbolDefinitions {
+		definitions = definitions[:maxSymbolDefinitions]
+	}
+
+	var contents []map[string]interface{}
+	for _, payload := range definitions {
+		filePath, _ := payload["file_path"].(string)
+		language, _ := payload["language"].(string)
+		start, _ := payload["start_line"].(int64)
+		end, _ := payload["end_line"].(int64)
+		cell, _ := payload["cell_index"].(int64)
+
+		text, ok := "", false
+		if cell == 0 {
+			text, ok = readLines(filePath, int(start), int(end))
+		}
+		if !ok {
+			text, _ = payload["content"].(string)
+		}
+		contents = append(contents, map[string]interface{}{
+			"uri":      fmt.Sprintf("%s#L%d-L%d", fileURI(filePath), start, end),
+			"mimeType": languageMIMEType(language),
+			"text":     text,
+		})
+	}
+	return contents, nil
+}
+
+// readLines returns lines start through end (1-based) of a file.
+func readLines(path string, start, end int) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil || start < 1 || end < start {
+		return "", false
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if end > len(lines) {
+		return "", false
+	}
+	return strings.TrimRight(strings.Join(lines[start-1:end], ""), "\n"), true
+}
+
+// handleResourcesSubscribe registers the client for
+// notifications/resources/updated about a resource: a file is updated when
+// it changes on disk, a symbol when a file defining it is reindexed.
+func (s *Server) handleResourcesSubscribe(ctx context.Context, writer *messageWriter, req *JSONRPCRequest) {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		s.writeError(writer, req.ID, -32602, "Invalid params")
+		return
+	}
+	c := clientFrom(ctx)
+	if c == nil {
+		s.writeError(writer, req.ID, -32603, "subscriptions need a connected client")
+		return
+	}
+
+	var files map[string]bool
+	if name, ok := strings.CutPrefix(params.URI, symbolURIPrefix); ok {
+		name, err := url.PathUnescape(name)
+		if err != nil {
+			s.writeError(writer, req.ID, -32602, "Invalid params")
+			return
+		}
+		definitions, err := s.symbolDefinitions(ctx, s.collectionName(), normalizeSymbolName(name), symbolFields)
+		if err != nil {
+			s.writeError(writer, req.ID, -32603, err.Error())
+			return
+		}
+		files = make(map[string]bool)
+		for _, payload := range definitions {
+			filePath, _ := payload["file_path"].(string)
+			files[filePath] = true
+		}
+	} else if _, err := s.resourcePath(params.URI); err != nil {
+		s.writeError(writer, req.ID, errResourceNotFound, err.Error())
+		return
+	}
+
+	key := subscriptionKey(params.URI)
+	s.subsMu.Lock()
+	sub := s.subs[key]
+	if sub == nil {
+		sub = &subscription{clients: make(map[client]bool)}
+		s.subs[key] = sub
+	}
+	sub.clients[c] = true
+	if files != nil {
+		sub.files = files
+	}
+	s.subsMu.Unlock()
+	s.writeResponse(writer, req.ID, map[string]interface{}{})
+}
+
+func (s *Server) handleResourcesUnsubscribe(ctx context.Context, writer *messageWriter, req *JSONRPCRequest) {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		s.writeError(writer, req.ID, -32602, "Invalid params")
+		return
+	}
+	key := subscriptionKey(params.URI)
+	s.subsMu.Lock()
+	if sub := s.subs[key]; sub != nil {
+		delete(sub.clients, clientFrom(ctx))
+		if len(sub.clients) == 0 {
+			delete(s.subs, key)
+		}
+	}
+	s.subsMu.Unlock()
+	s.writeResponse(writer, req.ID, map[string]interface{}{})
+}
+
+// removeClient drops the subscriptions of a client that went away.
+func (s *Server) removeClient(c client) {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+	for uri, sub := range s.subs {
+		delete(sub.clients, c)
+		if len(sub.clients) == 0 {
+			delete(s.subs, uri)
+		}
+	}
+}
+
+// filesChanged notifies the subscribers of files that changed on disk.
+func (s *Server) filesChanged(paths []string) {
+	for _, path := range paths {
+		s.notifyResourceUpdated(fileURI(indexer.NormalizeFilePath(path)))
+	}
+}
+
+// filesIndexed notifies the subscribers of symbols defined in files that
+// were just reindexed into collection.
+func (s *Server) filesIndexed(collection string, paths []string) {
+	if collection != s.collectionName() || len(paths) == 0 {
+		return
+	}
+	var uris []string
+	s.subsMu.Lock()
+	for uri, sub := range s.subs {
+		for _, path := range paths {
+			if sub.files[indexer.NormalizeFilePath(path)] {
+				uris = append(uris, uri)
+				break
+			}
+		}
+	}
+	s.subsMu.Unlock()
+	for _, uri := range uris {
+		s.notifyResourceUpdated(uri)
+	}
+}
+
+func (s *Server) notifyResourceUpdated(uri string) {
+	s.subsMu.Lock()
+	var clients []client
+	if sub := s.subs[uri]; sub != nil {
+		for c := range sub.clients {
+			clients = append(clients, c)
+		}
+	}
+	s.subsMu.Unlock()
+	for _, c := range clients {
+		c.notify("notifications/resources/updated", map[string]interface{}{"uri": uri})
+	}
+}
+
+// resourcePath returns the path of a file:// URI, which must name a file of
+// the project that the indexer would index.
+func (s *Server) resourcePath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported resource URI: %s", uri)
+	}
+	path := filepath.Clean(filepath.FromSlash(uriPath(u)))
+	if !s.servesFile(s.rootDir, path) {
+		return "", fmt.Errorf("resource not available: %s", uri)
+	}
+	return path, nil
+}
+
+// servesFile reports whether path is inside root and is a source file that
+// is neither excluded nor ignored.
+func (s *Server) servesFile(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	return utils.IsSourceFile(rel, s.ignorePatterns)
+}
+
+// uriPath returns the path of a file:// URI, without the slash before a
+// Windows drive letter: file:///C:/src is C:/src.
+func uriPath(u *url.URL) string {
+	path := u.Path
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return path
+}
+
+// subscriptionKey identifies a resource for subscriptions. File URIs are
+// rebuilt from their normalized path so that they match the paths reported
+// by the file watcher.
+func subscriptionKey(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return fileURI(indexer.NormalizeFilePath(filepath.FromSlash(uriPath(u))))
+}
+
+// fileURI returns the file:// URI of an absolute path.
+func fileURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// A Windows path: file:///C:/src.
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+func languageMIMEType(language string) string {
+	switch language {
+	case "":
+		return "text/plain"
+	case "markdown":
+		return "text/markdown"
+	case "notebook":
+		return "application/x-ipynb+json"
+	}
+	return "text/x-" + language
+}
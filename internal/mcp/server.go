@@ -9,17 +9,24 @@ import (
 	"codebase/internal/parser"
 	"codebase/internal/qdrant"
 	"codebase/internal/utils"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	qdrantpb "github.com/qdrant/go-client/qdrant"
 )
 
 type JSONRPCRequest struct {
@@ -45,18 +52,45 @@ type RPCError struct {
 // New code should reference models.CodeChunkPayload directly.
 type CodeChunkPayload = models.CodeChunkPayload
 
+// supportedProtocolVersions lists the MCP revisions the server implements,
+// newest first.
+var supportedProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// errInvalidHeader is returned by readMessage for a malformed message header.
+// The stream stays usable: reading continues with the next message.
+var errInvalidHeader = errors.New("invalid Content-Length")
+
+// maxConcurrentRequests bounds the requests handled at the same time. More
+// requests wait in line until a slot frees up.
+const maxConcurrentRequests = 8
+
 type Server struct {
 	qdrantClient *qdrant.Client
 	embedClient  *embeddings.Client
 	collection   string
+	version      string
 
 	rootDir        string
-	indexer        *indexer.Indexer
 	ignorePatterns []string
 
+	jobsMu   sync.Mutex
+	jobs     map[string]*indexJob // running or last indexing job per collection
+	clearing map[string]bool      // collections being cleared by clear-index
+
+	slots      chan struct{}
+	inflightMu sync.Mutex
+	inflight   map[string]context.CancelFunc // cancels in-flight requests by ID
+
+	subsMu sync.Mutex
+	subs   map[string]*subscription // resource subscriptions by URI
+
+	sessionsMu sync.Mutex
+	sessions   map[string]*httpSession // streamable HTTP sessions by ID
+
 	watcher   *fsnotify.Watcher
 	watchDone chan struct{}
 	watchWg   sync.WaitGroup
+	closeOnce sync.Once
 }
 
 // Close releases any resources held by the server. Safe to call multiple
@@ -66,16 +100,28 @@ func (s *Server) Close() {
 	if s == nil {
 		return
 	}
-	if s.watcher != nil {
-		if s.watchDone != nil {
-			close(s.watchDone)
+	s.closeOnce.Do(func() {
+		s.jobsMu.Lock()
+		for _, job := range s.jobs {
+			job.cancel()
 		}
-		s.watchWg.Wait()
-		_ = s.watcher.Close()
-	}
-	if s.qdrantClient != nil {
-		s.qdrantClient.Close()
-	}
+		s.jobsMu.Unlock()
+		if s.watcher != nil {
+			if s.watchDone != nil {
+				close(s.watchDone)
+			}
+			s.watchWg.Wait()
+			_ = s.watcher.Close()
+		}
+		if s.qdrantClient != nil {
+			s.qdrantClient.Close()
+		}
+	})
+}
+
+// SetVersion sets the server version reported to clients by initialize.
+func (s *Server) SetVersion(version string) {
+	s.version = version
 }
 
 func (s *Server) collectionName() string {
@@ -85,6 +131,32 @@ func (s *Server) collectionName() string {
 	return s.collection
 }
 
+// newIndexer creates an indexer with every language parser registered. Its
+// progress messages go to stderr, since stdout carries the protocol.
+func (s *Server) newIndexer() *indexer.Indexer {
+	idx := indexer.NewIndexer(s.qdrantClient, s.embedClient)
+	idx.SetOutput(os.Stderr)
+	idx.RegisterParser(string(parser.LanguageGo), parser.NewGoParser())
+	idx.RegisterParser(string(parser.LanguagePython), parser.NewPythonParser())
+	idx.RegisterParser(string(parser.LanguageJavaScript), parser.NewJavaScriptParser())
+	idx.RegisterParser(string(parser.LanguageTypeScript), parser.NewTypeScriptParser())
+	idx.RegisterParser(string(parser.LanguageRust), parser.NewRustParser())
+	idx.RegisterParser(string(parser.LanguageJava), parser.NewJavaParser())
+	idx.RegisterParser(string(parser.LanguageKotlin), parser.NewKotlinParser())
+	idx.RegisterParser(string(parser.LanguageC), parser.NewCParser())
+	idx.RegisterParser(string(parser.LanguageCPP), parser.NewCPPParser())
+	idx.RegisterParser(string(parser.LanguageCSharp), parser.NewCSharpParser())
+	idx.RegisterParser(string(parser.LanguageRuby), parser.NewRubyParser())
+	idx.RegisterParser(string(parser.LanguagePHP), parser.NewPHPParser())
+	idx.RegisterParser(string(parser.LanguageVue), parser.NewVueParser())
+	idx.RegisterParser(string(parser.LanguageSvelte), parser.NewSvelteParser())
+	idx.RegisterParser(string(parser.LanguageMarkdown), parser.NewMarkdownParser())
+	idx.RegisterParser(string(parser.LanguageSQL), parser.NewSQLParser())
+	idx.RegisterParser(string(parser.LanguageProto), parser.NewProtoParser())
+	idx.RegisterParser(string(parser.LanguageNotebook), parser.NewNotebookParser())
+	return idx
+}
+
 func NewServer(rootDir string) (*Server, error) {
 	// Best-effort load of shared config from ~/.codebase/config.json.
 	// Values from this file are applied as environment variables (if not
@@ -119,15 +191,14 @@ func NewServer(rootDir string) (*Server, error) {
 		collection:     collection,
 		rootDir:        normalizedRoot,
 		ignorePatterns: utils.LoadGitIgnorePatterns(normalizedRoot),
+		jobs:           make(map[string]*indexJob),
+		clearing:       make(map[string]bool),
+		slots:          make(chan struct{}, maxConcurrentRequests),
+		inflight:       make(map[string]context.CancelFunc),
+		sessions:       make(map[string]*httpSession),
+		subs:           make(map[string]*subscription),
 	}
 
-	idx := indexer.NewIndexer(qc, ec)
-	idx.RegisterParser(string(parser.LanguageGo), parser.NewGoParser())
-	idx.RegisterParser(string(parser.LanguagePython), parser.NewPythonParser())
-	idx.RegisterParser(string(parser.LanguageJavaScript), parser.NewJavaScriptParser())
-	idx.RegisterParser(string(parser.LanguageTypeScript), parser.NewTypeScriptParser())
-	s.indexer = idx
-
 	if err := s.startWatcher(); err != nil {
 		fmt.Fprintf(os.Stderr, "[MCP WARN] Failed to start file watcher: %v\n", err)
 	}
@@ -135,54 +206,256 @@ func NewServer(rootDir string) (*Server, error) {
 	return s, nil
 }
 
+// Run serves MCP over stdio until the client closes stdin, sends exit, or
+// the process is interrupted. The file watcher is stopped on return.
 func (s *Server) Run() error {
 	defer s.Close()
 
-	reader := bufio.NewReader(os.Stdin)
-	writer := bufio.NewWriter(os.Stdout)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return s.Serve(ctx, os.Stdin, os.Stdout)
+}
 
+// Serve reads messages from in and writes responses to out. When in ends,
+// in-flight requests are allowed to finish; on exit or when ctx is done
+// they are cancelled first.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	writer := newMessageWriter(out)
+	c := &stdioClient{s: s, writer: writer}
+	defer s.removeClient(c)
+
+	type message struct {
+		payload []byte
+		err     error
+	}
+	messages := make(chan message)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(messages)
+		for {
+			payload, err := readMessage(reader)
+			select {
+			case messages <- message{payload, err}:
+			case <-done:
+				return
+			}
+			if err != nil && !errors.Is(err, errInvalidHeader) {
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
 	for {
-		payload, err := readMessage(reader)
-		if err != nil {
-			if err == io.EOF {
-				break
+		var msg message
+		select {
+		case msg = <-messages:
+		case <-ctx.Done():
+			s.cancelRequests("")
+			return nil
+		}
+
+		if msg.err != nil {
+			if errors.Is(msg.err, errInvalidHeader) {
+				s.writeError(writer, nil, -32700, msg.err.Error())
+				continue
 			}
-			s.writeError(writer, nil, -32700, err.Error())
-			continue
+			// Let in-flight requests finish and write their responses.
+			if msg.err == io.EOF {
+				return nil
+			}
+			return msg.err
 		}
 
 		var req JSONRPCRequest
-		if err := json.Unmarshal(payload, &req); err != nil {
+		if err := json.Unmarshal(msg.payload, &req); err != nil {
 			s.writeError(writer, nil, -32700, "Parse error")
 			continue
 		}
+		if req.Method == "exit" {
+			s.cancelRequests("")
+			return nil
+		}
+		s.dispatch(&wg, writer, &req, c)
+	}
+}
+
+// client is a connected MCP client: the stdio peer or an HTTP session.
+type client interface {
+	// scope prefixes the client's request IDs, which are only unique
+	// within a client.
+	scope() string
+	// notify sends a message outside of any request. It reports whether
+	// the client could be reached.
+	notify(method string, params interface{}) bool
+}
+
+type clientKey struct{}
+
+// clientFrom returns the client that sent the request handled with ctx.
+func clientFrom(ctx context.Context) client {
+	c, _ := ctx.Value(clientKey{}).(client)
+	return c
+}
+
+type slotKey struct{}
 
-		s.handleRequest(writer, &req)
+// releaseSlot gives up the request's place among the maxConcurrentRequests
+// handled at once, for a handler that is about to wait for a long time
+// without doing any work itself.
+func releaseSlot(ctx context.Context) {
+	if release, ok := ctx.Value(slotKey{}).(func()); ok {
+		release()
 	}
+}
 
-	return nil
+// stdioClient is the peer of the stdio transport.
+type stdioClient struct {
+	s      *Server
+	writer *messageWriter
+}
+
+func (c *stdioClient) scope() string {
+	return ""
+}
+
+func (c *stdioClient) notify(method string, params interface{}) bool {
+	c.s.writeNotification(c.writer, method, params)
+	return true
+}
+
+// dispatch handles a request in the background so that a slow tool call
+// does not hold up the requests behind it. Lifecycle messages, pings and
+// cancellations are handled in order as they arrive. The request's context
+// carries the client that sent it.
+func (s *Server) dispatch(wg *sync.WaitGroup, writer *messageWriter, req *JSONRPCRequest, c client) {
+	base := context.WithValue(context.Background(), clientKey{}, c)
+	switch req.Method {
+	case "initialize", "notifications/initialized", "ping":
+		s.handleRequest(base, writer, req)
+		return
+	case "notifications/cancelled":
+		s.handleCancelled(req, c.scope())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(base)
+	key := requestKey(req.ID)
+	if key != "" {
+		key = c.scope() + key
+		s.inflightMu.Lock()
+		s.inflight[key] = cancel
+		s.inflightMu.Unlock()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() {
+			if key != "" {
+				s.inflightMu.Lock()
+				delete(s.inflight, key)
+				s.inflightMu.Unlock()
+			}
+			cancel()
+		}()
+
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			// Cancelled while waiting for a slot.
+			return
+		}
+		var once sync.Once
+		release := func() { once.Do(func() { <-s.slots }) }
+		defer release()
+		s.handleRequest(context.WithValue(ctx, slotKey{}, release), writer, req)
+	}()
+}
+
+// handleCancelled aborts the in-flight request named by a
+// notifications/cancelled message. Unknown or finished requests are ignored.
+func (s *Server) handleCancelled(req *JSONRPCRequest, scope string) {
+	var params struct {
+		RequestID interface{} `json:"requestId"`
+		Reason    string      `json:"reason"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+	key := requestKey(params.RequestID)
+	if key == "" {
+		return
+	}
+
+	s.inflightMu.Lock()
+	cancel := s.inflight[scope+key]
+	s.inflightMu.Unlock()
+	if cancel != nil {
+		if params.Reason != "" {
+			fmt.Fprintf(os.Stderr, "[MCP] Cancelling request %s: %s\n", key, params.Reason)
+		}
+		cancel()
+	}
 }
 
-func (s *Server) handleRequest(writer *bufio.Writer, req *JSONRPCRequest) {
+// cancelRequests cancels the in-flight requests whose key starts with
+// prefix; an empty prefix cancels them all.
+func (s *Server) cancelRequests(prefix string) {
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+	for key, cancel := range s.inflight {
+		if strings.HasPrefix(key, prefix) {
+			cancel()
+		}
+	}
+}
+
+// requestKey identifies a request by its JSON-RPC ID, which may be a string
+// or a number. Notifications have no ID and no key.
+func requestKey(id interface{}) string {
+	if id == nil {
+		return ""
+	}
+	data, err := json.Marshal(id)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func (s *Server) handleRequest(ctx context.Context, writer *messageWriter, req *JSONRPCRequest) {
 	switch req.Method {
 	case "initialize":
 		s.handleInitialize(writer, req)
 	case "tools/list":
 		s.handleToolsList(writer, req)
 	case "tools/call":
-		s.handleToolsCall(writer, req)
+		s.handleToolsCall(ctx, writer, req)
 	case "resources/list":
-		s.handleResourcesList(writer, req)
+		s.handleResourcesList(ctx, writer, req)
+	case "resources/templates/list":
+		s.handleResourceTemplatesList(writer, req)
+	case "resources/read":
+		s.handleResourcesRead(ctx, writer, req)
+	case "resources/subscribe":
+		s.handleResourcesSubscribe(ctx, writer, req)
+	case "resources/unsubscribe":
+		s.handleResourcesUnsubscribe(ctx, writer, req)
 	case "prompts/list":
 		s.handlePromptsList(writer, req)
+	case "prompts/get":
+		s.handlePromptsGet(ctx, writer, req)
 	case "ping":
 		s.handlePing(writer, req)
 	case "shutdown":
+		// Not part of MCP; kept for clients that send it before exit.
 		s.writeResponse(writer, req.ID, map[string]interface{}{})
 	case "notifications/initialized":
 		return
-	case "exit":
-		os.Exit(0)
 	default:
 		if req.ID != nil {
 			s.writeError(writer, req.ID, -32601, "Method not found")
@@ -190,41 +463,52 @@ func (s *Server) handleRequest(writer *bufio.Writer, req *JSONRPCRequest) {
 	}
 }
 
-func (s *Server) handleInitialize(writer *bufio.Writer, req *JSONRPCRequest) {
+// handleInitialize negotiates the protocol revision: the client's when the
+// server supports it, otherwise the newest one the server implements.
+func (s *Server) handleInitialize(writer *messageWriter, req *JSONRPCRequest) {
+	var params struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			s.writeError(writer, req.ID, -32602, "Invalid params")
+			return
+		}
+	}
+
+	version := s.version
+	if version == "" {
+		version = "dev"
+	}
 	result := map[string]interface{}{
-		"protocolVersion": "2024-11-05",
+		"protocolVersion": negotiateProtocolVersion(params.ProtocolVersion),
 		"serverInfo": map[string]string{
 			"name":    "codebase-mcp",
-			"version": "1.0.0",
+			"version": version,
 		},
 		"capabilities": map[string]interface{}{
-			"tools":     map[string]interface{}{},
-			"resources": map[string]interface{}{},
-			"prompts":   map[string]interface{}{},
+			"tools": map[string]interface{}{},
+			"resources": map[string]interface{}{
+				"subscribe": true,
+			},
+			"prompts": map[string]interface{}{},
 		},
 	}
 	s.writeResponse(writer, req.ID, result)
 }
 
-func (s *Server) handleResourcesList(writer *bufio.Writer, req *JSONRPCRequest) {
-	s.writeResponse(writer, req.ID, map[string]interface{}{
-		"resources": []interface{}{},
-	})
-}
-
-func (s *Server) handlePromptsList(writer *bufio.Writer, req *JSONRPCRequest) {
-	s.writeResponse(writer, req.ID, map[string]interface{}{
-		"prompts": []interface{}{},
-	})
+func negotiateProtocolVersion(requested string) string {
+	if slices.Contains(supportedProtocolVersions, requested) {
+		return requested
+	}
+	return supportedProtocolVersions[0]
 }
 
-func (s *Server) handlePing(writer *bufio.Writer, req *JSONRPCRequest) {
-	s.writeResponse(writer, req.ID, map[string]interface{}{
-		"status": "ok",
-	})
+func (s *Server) handlePing(writer *messageWriter, req *JSONRPCRequest) {
+	s.writeResponse(writer, req.ID, map[string]interface{}{})
 }
 
-func (s *Server) handleToolsList(writer *bufio.Writer, req *JSONRPCRequest) {
+func (s *Server) handleToolsList(writer *messageWriter, req *JSONRPCRequest) {
 	tools := []map[string]interface{}{
 		{
 			"name":        "codebase-retrieval",
@@ -244,18 +528,125 @@ func (s *Server) handleToolsList(writer *bufio.Writer, req *JSONRPCRequest) {
 						"type":        "string",
 						"description": "Optional absolute path to the project root directory to search. If not provided, uses the default directory specified when starting the MCP server.",
 					},
+					"documentation": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"include", "exclude", "only"},
+						"description": "How to treat Markdown documentation sections (node_type \"documentation\"): include them with code (default), exclude them, or search only documentation.",
+					},
 				},
 				"required": []string{"query"},
 			},
 		},
+		{
+			"name":        "find-symbol",
+			"description": "Find where a symbol is defined and where it is called, by exact name. Use this instead of codebase-retrieval when you already know the name of a function, method, type or other declaration (e.g. \"NormalizeProjectRoot\", \"Server.Run\" or \"utils.NormalizeProjectRoot\"). Definitions are matched on the indexed name, receiver and package (exact matches first, then case-insensitive and fuzzy ones); references are chunks whose callees end in the symbol's name. Each result has its kind, signature and location.",
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"type":        "string",
+						"description": "Symbol name, optionally qualified by its receiver type or package (\"Server.Run\", \"(*Server).Run\", \"utils.NormalizeProjectRoot\").",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum number of definitions and of references to return (default 20).",
+					},
+					"include_references": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Whether to also return the chunks that call the symbol (default true).",
+					},
+					"project_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional absolute path to the project root directory to search. If not provided, uses the default directory specified when starting the MCP server.",
+					},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			"name":        "file-outline",
+			"description": "List the symbols of a file or directory from the index without reading the files: name, kind, signature, line range and first doc line of each declaration. Use it to learn the structure of code before opening it. With mode \"repo-map\" it instead summarizes the whole project (or the given directory) as a compact tree of files and their most referenced symbols that fits in max_tokens.",
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"path": map[string]interface{}{
+						"type":        "string",
+						"description": "File or directory to outline, relative to the project root or absolute. Required unless mode is \"repo-map\".",
+					},
+					"mode": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"outline", "repo-map"},
+						"description": "\"outline\" (default) lists every symbol of path; \"repo-map\" renders a token-budgeted tree of the project.",
+					},
+					"max_tokens": map[string]interface{}{
+						"type":        "integer",
+						"description": "Approximate token budget of the repo map (default 1024).",
+					},
+					"project_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional absolute path to the project root directory. If not provided, uses the default directory specified when starting the MCP server.",
+					},
+				},
+			},
+		},
+		{
+			"name":        "index-status",
+			"description": "Report the state of a project's index: Qdrant collection and point count, when it was last indexed and with which embedding model, files that failed to index, files changed since then, and the progress of a running indexing job. Use it to decide whether results may be stale and a reindex is needed.",
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"project_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional absolute path to the project root directory. If not provided, uses the default directory specified when starting the MCP server.",
+					},
+				},
+			},
+		},
+		{
+			"name":        "reindex",
+			"description": "Index a project in the background, e.g. a newly opened project_path or one whose index is stale. \"incremental\" (default) indexes only added, modified and deleted files; \"full\" clears the index first. Returns immediately; poll index-status for progress. When the call carries a progressToken, it instead waits for the job to finish and sends progress notifications.",
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"mode": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"incremental", "full"},
+						"description": "Incremental (default) or full reindex.",
+					},
+					"project_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional absolute path to the project root directory. If not provided, uses the default directory specified when starting the MCP server.",
+					},
+				},
+			},
+		},
+		{
+			"name":        "clear-index",
+			"description": "Delete a project's Qdrant collection and local indexing state. Searches return nothing until the project is reindexed.",
+			"inputSchema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"project_path": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional absolute path to the project root directory. If not provided, uses the default directory specified when starting the MCP server.",
+					},
+				},
+			},
+		},
+	}
+	for _, tool := range tools {
+		tool["outputSchema"] = toolOutputSchemas[tool["name"].(string)]
 	}
 	s.writeResponse(writer, req.ID, map[string]interface{}{"tools": tools})
 }
 
-func (s *Server) handleToolsCall(writer *bufio.Writer, req *JSONRPCRequest) {
+func (s *Server) handleToolsCall(ctx context.Context, writer *messageWriter, req *JSONRPCRequest) {
 	var params struct {
 		Name      string          `json:"name"`
 		Arguments json.RawMessage `json:"arguments"`
+		Meta      struct {
+			ProgressToken interface{} `json:"progressToken"`
+		} `json:"_meta"`
 	}
 
 	if err := json.Unmarshal(req.Params, &params); err != nil {
@@ -263,46 +654,60 @@ func (s *Server) handleToolsCall(writer *bufio.Writer, req *JSONRPCRequest) {
 		return
 	}
 
+	// Tools without required arguments may be called without any.
+	if len(params.Arguments) == 0 {
+		params.Arguments = json.RawMessage("{}")
+	}
+
 	var result interface{}
 	var err error
 
 	switch params.Name {
 	case "codebase-retrieval":
-		result, err = s.handleCodebaseRetrieval(params.Arguments)
+		result, err = s.handleCodebaseRetrieval(ctx, params.Arguments)
+	case "find-symbol":
+		result, err = s.handleFindSymbol(ctx, params.Arguments)
+	case "file-outline":
+		result, err = s.handleFileOutline(ctx, params.Arguments)
+	case "index-status":
+		result, err = s.handleIndexStatus(ctx, params.Arguments)
+	case "reindex":
+		result, err = s.handleReindex(ctx, params.Arguments, newProgressReporter(s, writer, params.Meta.ProgressToken))
+	case "clear-index":
+		result, err = s.handleClearIndex(ctx, params.Arguments)
 	default:
 		s.writeError(writer, req.ID, -32602, "Unknown tool")
 		return
 	}
 
+	// A cancelled request gets no response.
+	if ctx.Err() != nil {
+		return
+	}
+
 	if err != nil {
-		s.writeError(writer, req.ID, -32603, err.Error())
+		s.writeResponse(writer, req.ID, toolError(err))
 		return
 	}
 
-	s.writeResponse(writer, req.ID, map[string]interface{}{
-		"content": []map[string]interface{}{
-			{
-				"type": "text",
-				"text": formatResult(result),
-			},
-		},
-	})
+	s.writeResponse(writer, req.ID, toolResult(params.Name, result))
 }
 
-func (s *Server) handleCodebaseRetrieval(args json.RawMessage) (interface{}, error) {
-	return s.handleSearchCode(args)
+func (s *Server) handleCodebaseRetrieval(ctx context.Context, args json.RawMessage) (interface{}, error) {
+	return s.handleSearchCode(ctx, args)
 }
 
 // HandleCodebaseRetrieval is the exported version for CLI access
-func (s *Server) HandleCodebaseRetrieval(args json.RawMessage) (interface{}, error) {
-	return s.handleCodebaseRetrieval(args)
+func (s *Server) HandleCodebaseRetrieval(ctx context.Context, args json.RawMessage) (interface{}, error) {
+	return s.handleCodebaseRetrieval(ctx, args)
 }
 
-func (s *Server) handleSearchCode(args json.RawMessage) (interface{}, error) {
+func (s *Server) handleSearchCode(ctx context.Context, args json.RawMessage) (interface{}, error) {
 	var input struct {
-		Query       string `json:"query"`
-		TopK        int    `json:"top_k"`
-		ProjectPath string `json:"project_path"`
+		Query         string `json:"query"`
+		TopK          int    `json:"top_k"`
+		ProjectPath   string `json:"project_path"`
+		Documentation string `json:"documentation"`
 	}
 	if err := json.Unmarshal(args, &input); err != nil {
 		return nil, err
@@ -311,34 +716,58 @@ func (s *Server) handleSearchCode(args json.RawMessage) (interface{}, error) {
 	if input.TopK == 0 {
 		input.TopK = 5
 	}
+	filter, err := documentationFilter(input.Documentation)
+	if err != nil {
+		return nil, err
+	}
 
-	// Determine which collection and root to use based on project_path
-	collection := s.collectionName()
-	searchRoot := s.rootDir
+	collection, searchRoot, err := s.resolveProject(input.ProjectPath)
+	if err != nil {
+		return nil, err
+	}
 
-	if input.ProjectPath != "" {
-		normalized, err := utils.NormalizeProjectRoot(input.ProjectPath)
-		if err != nil {
-			return nil, fmt.Errorf("invalid project_path: %w", err)
-		}
-		searchRoot = normalized
+	// Perform simple semantic search without query planning
+	return s.simpleSearchWithCollection(ctx, input.Query, input.TopK, collection, searchRoot, filter)
+}
 
-		projectID, err := utils.ComputeProjectID(searchRoot)
-		if err != nil {
-			return nil, fmt.Errorf("failed to compute project ID: %w", err)
-		}
-		collection = indexer.CollectionName(projectID)
+// resolveProject returns the collection and root directory to use for a
+// tool call's optional project_path argument.
+func (s *Server) resolveProject(projectPath string) (collection string, root string, err error) {
+	if projectPath == "" {
+		return s.collectionName(), s.rootDir, nil
+	}
+	root, err = utils.NormalizeProjectRoot(projectPath)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid project_path: %w", err)
+	}
+	projectID, err := utils.ComputeProjectID(root)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to compute project ID: %w", err)
 	}
+	return indexer.CollectionName(projectID), root, nil
+}
 
-	// Perform simple semantic search without query planning
-	return s.simpleSearchWithCollection(input.Query, input.TopK, collection, searchRoot)
+// documentationFilter builds the payload filter for the documentation
+// search mode: "include" (or empty) searches everything, "exclude" leaves
+// out documentation chunks and "only" searches nothing else.
+func documentationFilter(mode string) (*qdrantpb.Filter, error) {
+	isDoc := qdrantpb.NewMatchKeyword("node_type", "documentation")
+	switch mode {
+	case "", "include":
+		return nil, nil
+	case "exclude":
+		return &qdrantpb.Filter{MustNot: []*qdrantpb.Condition{isDoc}}, nil
+	case "only":
+		return &qdrantpb.Filter{Must: []*qdrantpb.Condition{isDoc}}, nil
+	}
+	return nil, fmt.Errorf("invalid documentation mode %q: expected include, exclude or only", mode)
 }
 
 // simpleSearchWithCollection performs basic semantic search on a specific collection
 // It uses a diversity-aware strategy: fetching more candidates and prioritizing unique files
 // to ensure a broader coverage of the codebase.
-func (s *Server) simpleSearchWithCollection(query string, topK int, collection string, rootPath string) (interface{}, error) {
-	vec, err := s.embedClient.Embed(query)
+func (s *Server) simpleSearchWithCollection(ctx context.Context, query string, topK int, collection string, rootPath string, filter *qdrantpb.Filter) (interface{}, error) {
+	vec, err := s.embedClient.Embed(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -351,16 +780,16 @@ func (s *Server) simpleSearchWithCollection(query string, topK int, collection s
 		searchLimit = 20
 	}
 
-	results, err := s.qdrantClient.Search(collection, vec, uint64(searchLimit))
+	results, err := s.qdrantClient.SearchWithFilter(ctx, collection, vec, uint64(searchLimit), filter)
 	if err != nil {
 		return nil, err
 	}
 
 	type candidate struct {
-		payload  map[string]interface{}
-		score    float32
-		fileKey  string
-		relPath  string
+		payload map[string]interface{}
+		score   float32
+		fileKey string
+		relPath string
 	}
 
 	var candidates []candidate
@@ -400,14 +829,15 @@ func (s *Server) simpleSearchWithCollection(query string, topK int, collection s
 		}
 
 		candidates = append(candidates, candidate{
-			payload:  payload,
-			score:    hit.Score,
-			fileKey:  fileKey,
-			relPath:  relPath,
+			payload: payload,
+			score:   hit.Score,
+			fileKey: fileKey,
+			relPath: relPath,
 		})
 	}
 
 	var finalResults []map[string]interface{}
+	var picked []candidate
 	fileCounts := make(map[string]int)
 	maxChunksPerFilePass1 := 1 // Pass 1: enforce unique-file-first
 	usedIndices := make(map[int]bool)
@@ -424,7 +854,15 @@ func (s *Server) simpleSearchWithCollection(query string, topK int, collection s
 				"end_line":   item.payload["end_line"],
 				"content":    item.payload["content"],
 				"score":      item.score,
+				"node_type":  item.payload["node_type"],
 			})
+			if headingPath, ok := item.payload["heading_path"].(string); ok && headingPath != "" {
+				finalResults[len(finalResults)-1]["heading_path"] = headingPath
+			}
+			if cell, ok := item.payload["cell_index"].(int64); ok && cell > 0 {
+				finalResults[len(finalResults)-1]["cell"] = fmt.Sprintf("cell %d", cell)
+			}
+			picked = append(picked, item)
 			fileCounts[item.fileKey]++
 			usedIndices[i] = true
 		}
@@ -443,26 +881,183 @@ func (s *Server) simpleSearchWithCollection(query string, topK int, collection s
 					"end_line":   item.payload["end_line"],
 					"content":    item.payload["content"],
 					"score":      item.score,
+					"node_type":  item.payload["node_type"],
 				})
+				if headingPath, ok := item.payload["heading_path"].(string); ok && headingPath != "" {
+					finalResults[len(finalResults)-1]["heading_path"] = headingPath
+				}
+				if cell, ok := item.payload["cell_index"].(int64); ok && cell > 0 {
+					finalResults[len(finalResults)-1]["cell"] = fmt.Sprintf("cell %d", cell)
+				}
+				picked = append(picked, item)
 				usedIndices[i] = true
 			}
 		}
 	}
 
+	// rpc chunks from .proto files are linked by name to their Go server
+	// implementations and client call sites.
+	for i, item := range picked {
+		if item.payload["node_type"] != "rpc" {
+			continue
+		}
+		rpcName, _ := item.payload["node_name"].(string)
+		_, method, ok := strings.Cut(rpcName, ".")
+		if !ok || method == "" {
+			continue
+		}
+		goChunks, err := s.scrollGoChunks(ctx, collection, method)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[MCP WARN] Failed to link rpc results: %v\n", err)
+		}
+		implementations, callers := linkRPC(item.payload, goChunks, rootPath)
+		if len(implementations) > 0 {
+			finalResults[i]["implementations"] = implementations
+		}
+		if len(callers) > 0 {
+			finalResults[i]["callers"] = callers
+		}
+	}
+
 	return finalResults, nil
 }
 
-func (s *Server) writeResponse(writer *bufio.Writer, id interface{}, result interface{}) {
+// scrollGoChunks returns the payloads of the Go chunks in the collection
+// whose name or callees contain method.
+func (s *Server) scrollGoChunks(ctx context.Context, collection, method string) ([]map[string]interface{}, error) {
+	filter := &qdrantpb.Filter{
+		Must: []*qdrantpb.Condition{qdrantpb.NewMatchKeyword("language", string(parser.LanguageGo))},
+		Should: []*qdrantpb.Condition{
+			qdrantpb.NewMatchText("node_name", method),
+			qdrantpb.NewMatchText("callees", method),
+		},
+	}
+	return s.scrollPayloads(ctx, collection, filter, []string{"file_path", "node_name", "start_line", "param_types", "callees", "content"})
+}
+
+// scrollPayloads returns the payloads of every point matching filter,
+// restricted to fields when given.
+func (s *Server) scrollPayloads(ctx context.Context, collection string, filter *qdrantpb.Filter, fields []string) ([]map[string]interface{}, error) {
+	var payloads []map[string]interface{}
+	var offset *qdrantpb.PointId
+	for {
+		points, next, err := s.qdrantClient.ScrollWithFilter(ctx, collection, filter, fields, 256, offset)
+		if err != nil {
+			return payloads, err
+		}
+		for _, point := range points {
+			payloads = append(payloads, qdrant.PayloadToMap(point.Payload))
+		}
+		if next == nil || len(points) == 0 {
+			return payloads, nil
+		}
+		offset = next
+	}
+}
+
+// linkRPC finds the Go methods implementing an rpc and the Go functions
+// calling it, formatted as "path:line name". A method implements the rpc
+// when it has the rpc's name and takes its request type (or the generated
+// stream type). A function calls the rpc when it calls a method of that name
+// and uses the generated client: it takes or creates a <Service>Client, or
+// takes or builds the request message. Generated *.pb.go code is ignored.
+func linkRPC(rpc map[string]interface{}, goChunks []map[string]interface{}, rootPath string) (implementations, callers []string) {
+	rpcName, _ := rpc["node_name"].(string)
+	service, method, ok := strings.Cut(rpcName, ".")
+	if !ok || method == "" {
+		return nil, nil
+	}
+	request := ""
+	if params := payloadStrings(rpc["param_types"]); len(params) > 0 {
+		request = strings.TrimPrefix(params[0], "stream ")
+		request = request[strings.LastIndex(request, ".")+1:]
+	}
+	streamType := service + "_" + method
+
+	for _, chunk := range goChunks {
+		filePath, _ := chunk["file_path"].(string)
+		if strings.HasSuffix(filePath, ".pb.go") {
+			continue
+		}
+		name, _ := chunk["node_name"].(string)
+		location := fmt.Sprintf("%s:%v %s", displayPath(rootPath, filePath), chunk["start_line"], name)
+
+		if strings.HasSuffix(name, ")."+method) {
+			for _, param := range payloadStrings(chunk["param_types"]) {
+				base := strings.TrimLeft(param[strings.LastIndex(param, ".")+1:], "*")
+				if (request != "" && base == request) || strings.Contains(param, streamType) {
+					implementations = append(implementations, location)
+					break
+				}
+			}
+			continue
+		}
+		callees := payloadStrings(chunk["callees"])
+		if slices.ContainsFunc(callees, func(callee string) bool { return strings.HasSuffix(callee, "."+method) }) &&
+			usesRPCClient(chunk, callees, service, request) {
+			callers = append(callers, location)
+		}
+	}
+	return implementations, callers
+}
+
+// usesRPCClient reports whether a Go chunk uses the generated client of
+// service: it takes a <service>Client or the request message, calls
+// New<service>Client, or builds a request message literal.
+func usesRPCClient(chunk map[string]interface{}, callees []string, service, request string) bool {
+	client := service + "Client"
+	for _, param := range payloadStrings(chunk["param_types"]) {
+		base := strings.TrimLeft(param[strings.LastIndex(param, ".")+1:], "*")
+		if base == client || (request != "" && base == request) {
+			return true
+		}
+	}
+	for _, callee := range callees {
+		if callee == "New"+client || strings.HasSuffix(callee, ".New"+client) {
+			return true
+		}
+	}
+	if request == "" {
+		return false
+	}
+	content, _ := chunk["content"].(string)
+	return regexp.MustCompile(`\b` + regexp.QuoteMeta(request) + `\s*\{`).MatchString(content)
+}
+
+// displayPath returns filePath relative to rootPath when it is an absolute
+// path inside the project.
+func displayPath(rootPath, filePath string) string {
+	if filepath.IsAbs(filePath) {
+		if rel, err := filepath.Rel(rootPath, filePath); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return filePath
+}
+
+// payloadStrings converts a list payload value to strings.
+func payloadStrings(value interface{}) []string {
+	items, _ := value.([]interface{})
+	var result []string
+	for _, item := range items {
+		if str, ok := item.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
+}
+
+func (s *Server) writeResponse(writer *messageWriter, id interface{}, result interface{}) {
 	resp := JSONRPCResponse{
 		JSONRPC: "2.0",
 		ID:      id,
 		Result:  result,
 	}
 	data, _ := json.Marshal(resp)
-	writeMessage(writer, data)
+	writer.write(data)
 }
 
-func (s *Server) writeError(writer *bufio.Writer, id interface{}, code int, message string) {
+func (s *Server) writeError(writer *messageWriter, id interface{}, code int, message string) {
 	resp := JSONRPCResponse{
 		JSONRPC: "2.0",
 		ID:      id,
@@ -472,12 +1067,21 @@ func (s *Server) writeError(writer *bufio.Writer, id interface{}, code int, mess
 		},
 	}
 	data, _ := json.Marshal(resp)
-	writeMessage(writer, data)
+	writer.write(data)
 }
 
-func formatResult(result interface{}) string {
-	data, _ := json.MarshalIndent(result, "", "  ")
-	return string(data)
+func (s *Server) writeNotification(writer *messageWriter, method string, params interface{}) {
+	writer.write(encodeNotification(method, params))
+}
+
+func encodeNotification(method string, params interface{}) []byte {
+	raw, _ := json.Marshal(params)
+	data, _ := json.Marshal(JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  raw,
+	})
+	return data
 }
 
 func readMessage(reader *bufio.Reader) ([]byte, error) {
@@ -497,7 +1101,7 @@ func readMessage(reader *bufio.Reader) ([]byte, error) {
 			value := strings.TrimSpace(trimmed[len("Content-Length:"):])
 			length, err := strconv.Atoi(value)
 			if err != nil {
-				return nil, fmt.Errorf("invalid Content-Length: %s", value)
+				return nil, fmt.Errorf("%w: %s", errInvalidHeader, value)
 			}
 
 			// Expect blank line before payload.
@@ -517,6 +1121,38 @@ func readMessage(reader *bufio.Reader) ([]byte, error) {
 	}
 }
 
+// messageWriter is a stream of messages to one client: stdout, the response
+// to an HTTP POST or a session's GET stream. Its lock only covers this
+// stream, so that a slow client does not hold up the others.
+type messageWriter struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	closed bool // set once the stream has ended; later messages are dropped
+}
+
+func newMessageWriter(w io.Writer) *messageWriter {
+	return &messageWriter{w: bufio.NewWriter(w)}
+}
+
+// write sends a message, unless there is no stream to carry it.
+func (mw *messageWriter) write(data []byte) {
+	if mw == nil {
+		return
+	}
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+	if !mw.closed {
+		writeMessage(mw.w, data)
+	}
+}
+
+// close ends the stream, waiting for a message being written.
+func (mw *messageWriter) close() {
+	mw.mu.Lock()
+	mw.closed = true
+	mw.mu.Unlock()
+}
+
 func writeMessage(writer *bufio.Writer, data []byte) {
 	writer.Write(data)
 	writer.WriteByte('\n')
@@ -562,7 +1198,7 @@ func (s *Server) addWatcherForDir(path string) {
 // project directory and spawns a goroutine that debounces change events
 // and triggers incremental re-indexing.
 func (s *Server) startWatcher() error {
-	if strings.TrimSpace(s.rootDir) == "" || s.indexer == nil {
+	if strings.TrimSpace(s.rootDir) == "" {
 		return nil
 	}
 
@@ -604,9 +1240,11 @@ func (s *Server) watchLoop() {
 		return
 	}
 
-	// Debounce rapid change bursts into a single incremental index run.
+	// Debounce rapid change bursts into a single incremental index run and
+	// one update notification per changed file.
 	debounce := 5 * time.Second
 	reindexRequested := false
+	changed := make(map[string]bool)
 	timer := time.NewTimer(debounce)
 	if !timer.Stop() {
 		<-timer.C
@@ -639,6 +1277,7 @@ func (s *Server) watchLoop() {
 			// on-disk state may have changed; schedule an incremental index.
 			if ev.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) != 0 {
 				reindexRequested = true
+				changed[ev.Name] = true
 				if !timer.Stop() {
 					select {
 					case <-timer.C:
@@ -650,6 +1289,12 @@ func (s *Server) watchLoop() {
 		case <-timer.C:
 			if reindexRequested {
 				reindexRequested = false
+				paths := make([]string, 0, len(changed))
+				for path := range changed {
+					paths = append(paths, path)
+				}
+				clear(changed)
+				s.filesChanged(paths)
 				s.runIncrementalIndex()
 			}
 			_ = timer.Reset(debounce)
@@ -657,16 +1302,13 @@ func (s *Server) watchLoop() {
 	}
 }
 
-
 func (s *Server) runIncrementalIndex() {
-	if s.indexer == nil || strings.TrimSpace(s.rootDir) == "" {
+	if strings.TrimSpace(s.rootDir) == "" {
 		return
 	}
 
 	fmt.Fprintf(os.Stderr, "[MCP] Detected file changes, running incremental index...\n")
-	if err := s.indexer.IndexProject(s.rootDir); err != nil {
+	if _, _, err := s.startIndexJob(s.rootDir, s.collectionName(), indexModeIncremental); err != nil {
 		fmt.Fprintf(os.Stderr, "[MCP WARN] Incremental index failed: %v\n", err)
-	} else {
-		fmt.Fprintf(os.Stderr, "[MCP] Incremental index completed.\n")
 	}
 }
@@ -0,0 +1,1287 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"codebase/internal/embeddings"
+	"codebase/internal/indexer"
+	"codebase/internal/utils"
+)
+
+// newTestServer returns a server without Qdrant, embeddings or a file
+// watcher: enough for the protocol, not for tools that query the index.
+func newTestServer() *Server {
+	return &Server{
+		version:  "test",
+		jobs:     make(map[string]*indexJob),
+		clearing: make(map[string]bool),
+		slots:    make(chan struct{}, maxConcurrentRequests),
+		inflight: make(map[string]context.CancelFunc),
+		sessions: make(map[string]*httpSession),
+		subs:     make(map[string]*subscription),
+	}
+}
+
+// pipeClient drives Server.Serve over pipes, like a client talking to the
+// stdio transport.
+type pipeClient struct {
+	t     *testing.T
+	in    *io.PipeWriter
+	lines chan string
+	done  chan error
+}
+
+func startPipeClient(t *testing.T, s *Server) *pipeClient {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &pipeClient{t: t, in: inW, lines: make(chan string, 16), done: make(chan error, 1)}
+
+	go func() {
+		err := s.Serve(context.Background(), inR, outW)
+		outW.Close()
+		c.done <- err
+	}()
+	go func() {
+		defer close(c.lines)
+		scanner := bufio.NewScanner(outR)
+		scanner.Buffer(make([]byte, 1<<20), 1<<20)
+		for scanner.Scan() {
+			c.lines <- scanner.Text()
+		}
+	}()
+	t.Cleanup(func() {
+		inW.Close()
+		// Drain output so that pending writes do not block Serve.
+		for range c.lines {
+		}
+	})
+	return c
+}
+
+func (c *pipeClient) send(format string, args ...interface{}) {
+	c.t.Helper()
+	if _, err := fmt.Fprintf(c.in, format+"\n", args...); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+}
+
+func (c *pipeClient) recv() map[string]interface{} {
+	c.t.Helper()
+	select {
+	case line, ok := <-c.lines:
+		if !ok {
+			c.t.Fatal("server closed its output")
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			c.t.Fatalf("invalid message %q: %v", line, err)
+		}
+		if msg["jsonrpc"] != "2.0" {
+			c.t.Fatalf("message without jsonrpc 2.0: %s", line)
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for a message")
+		return nil
+	}
+}
+
+// call sends a request and returns its response.
+func (c *pipeClient) call(id int, method, params string) map[string]interface{} {
+	c.t.Helper()
+	if params == "" {
+		params = "{}"
+	}
+	c.send(`{"jsonrpc":"2.0","id":%d,"method":%q,"params":%s}`, id, method, params)
+	msg := c.recv()
+	if got, _ := msg["id"].(float64); int(got) != id {
+		c.t.Fatalf("response id = %v, want %d", msg["id"], id)
+	}
+	return msg
+}
+
+func errorCode(t *testing.T, msg map[string]interface{}) int {
+	t.Helper()
+	rpcErr, ok := msg["error"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected an error response, got %v", msg)
+	}
+	code, _ := rpcErr["code"].(float64)
+	return int(code)
+}
+
+func TestInitializeNegotiatesProtocolVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		requested string
+		want      string
+	}{
+		{"2025-06-18", "2025-06-18"},
+		{"2025-03-26", "2025-03-26"},
+		{"2024-11-05", "2024-11-05"},
+		{"2099-01-01", supportedProtocolVersions[0]},
+		{"", supportedProtocolVersions[0]},
+	}
+	c := startPipeClient(t, newTestServer())
+	for i, tt := range tests {
+		msg := c.call(i+1, "initialize", fmt.Sprintf(`{"protocolVersion":%q,"capabilities":{},"clientInfo":{"name":"test","version":"1"}}`, tt.requested))
+		result, _ := msg["result"].(map[string]interface{})
+		if got := result["protocolVersion"]; got != tt.want {
+			t.Errorf("requested %q: protocolVersion = %v, want %q", tt.requested, got, tt.want)
+		}
+		info, _ := result["serverInfo"].(map[string]interface{})
+		if info["name"] != "codebase-mcp" || info["version"] != "test" {
+			t.Errorf("serverInfo = %v", info)
+		}
+		caps, _ := result["capabilities"].(map[string]interface{})
+		resources, _ := caps["resources"].(map[string]interface{})
+		_, tools := caps["tools"]
+		_, prompts := caps["prompts"]
+		if !tools || !prompts || resources["subscribe"] != true || len(caps) != 3 {
+			t.Errorf("capabilities = %v, want tools, prompts and resources with subscribe", caps)
+		}
+	}
+}
+
+func TestPingAndUnknownMethods(t *testing.T) {
+	t.Parallel()
+
+	c := startPipeClient(t, newTestServer())
+	if msg := c.call(1, "ping", ""); fmt.Sprint(msg["result"]) != "map[]" {
+		t.Fatalf("ping result = %v, want empty object", msg["result"])
+	}
+	// Capabilities that are not advertised have no methods.
+	if code := errorCode(t, c.call(2, "logging/setLevel", `{"level":"info"}`)); code != -32601 {
+		t.Fatalf("logging/setLevel error code = %d, want -32601", code)
+	}
+	// Notifications never get a response, not even an error.
+	c.send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	c.send(`{"jsonrpc":"2.0","method":"notifications/unknown"}`)
+	c.call(3, "ping", "")
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	c := startPipeClient(t, newTestServer())
+	c.send("not json")
+	if msg := c.recv(); errorCode(t, msg) != -32700 || msg["id"] != nil {
+		t.Fatalf("parse error response = %v", msg)
+	}
+	c.send("Content-Length: abc")
+	if msg := c.recv(); errorCode(t, msg) != -32700 {
+		t.Fatalf("header error response = %v", msg)
+	}
+	// The stream is still usable, including Content-Length framing.
+	body := `{"jsonrpc":"2.0","id":7,"method":"ping"}`
+	c.send("Content-Length: %d\r\n\r\n%s", len(body), body)
+	if msg := c.recv(); msg["id"] != float64(7) {
+		t.Fatalf("framed ping response = %v", msg)
+	}
+}
+
+func TestToolsListAndCallErrors(t *testing.T) {
+	t.Parallel()
+
+	c := startPipeClient(t, newTestServer())
+	result, _ := c.call(1, "tools/list", "")["result"].(map[string]interface{})
+	tools, _ := result["tools"].([]interface{})
+	names := make(map[string]bool)
+	for _, tool := range tools {
+		tool, _ := tool.(map[string]interface{})
+		name, _ := tool["name"].(string)
+		names[name] = true
+		schema, _ := tool["inputSchema"].(map[string]interface{})
+		if schema["type"] != "object" {
+			t.Errorf("tool %s: inputSchema type = %v, want object", name, schema["type"])
+		}
+		output, _ := tool["outputSchema"].(map[string]interface{})
+		if output["type"] != "object" {
+			t.Errorf("tool %s: outputSchema type = %v, want object", name, output["type"])
+		}
+	}
+	for _, name := range []string{"codebase-retrieval", "find-symbol", "file-outline", "index-status", "reindex", "clear-index"} {
+		if !names[name] {
+			t.Errorf("tools/list is missing %s", name)
+		}
+	}
+
+	if code := errorCode(t, c.call(2, "tools/call", `{"name":"no-such-tool"}`)); code != -32602 {
+		t.Fatalf("unknown tool error code = %d, want -32602", code)
+	}
+	if code := errorCode(t, c.call(3, "tools/call", `5`)); code != -32602 {
+		t.Fatalf("invalid params error code = %d, want -32602", code)
+	}
+
+	// A tool that fails reports it in its result.
+	msg := c.call(4, "tools/call", `{"name":"file-outline","arguments":{"mode":"tree"}}`)
+	result, _ = msg["result"].(map[string]interface{})
+	content, _ := result["content"].([]interface{})
+	if result["isError"] != true || len(content) != 1 {
+		t.Fatalf("failed tool call = %v, want an isError result", msg)
+	}
+	if text, _ := content[0].(map[string]interface{})["text"].(string); !strings.Contains(text, "invalid mode") {
+		t.Errorf("error text = %q", text)
+	}
+}
+
+func TestToolResultText(t *testing.T) {
+	t.Parallel()
+
+	search := toolResult("codebase-retrieval", []map[string]interface{}{{
+		"file_path":  "internal/utils/paths.go",
+		"start_line": int64(10),
+		"end_line":   int64(12),
+		"content":    "func Clean() {\n}",
+		"score":      float32(0.8123),
+		"node_type":  "function",
+	}})
+	structured, _ := search["structuredContent"].(map[string]interface{})
+	if results, _ := structured["results"].([]map[string]interface{}); len(results) != 1 {
+		t.Errorf("structuredContent = %v, want one result under results", search["structuredContent"])
+	}
+	want := "internal/utils/paths.go:10-12 (score 0.81)\n```go\nfunc Clean() {\n}\n```"
+	if text := search["content"].([]map[string]interface{})[0]["text"]; text != want {
+		t.Errorf("search text = %q, want %q", text, want)
+	}
+
+	empty := toolResult("codebase-retrieval", []map[string]interface{}(nil))
+	if results := empty["structuredContent"].(map[string]interface{})["results"]; results == nil {
+		t.Error("empty search results are null, want an empty list")
+	}
+
+	symbol := toolResult("find-symbol", map[string]interface{}{
+		"symbol": "Clean",
+		"definitions": []map[string]interface{}{{
+			"name": "Clean", "kind": "function", "file_path": "paths.go",
+			"start_line": int64(10), "end_line": int64(12), "signature": "func Clean()", "match": "exact",
+		}},
+		"references": []map[string]interface{}{{
+			"name": "main", "kind": "function", "file_path": "main.go",
+			"start_line": int64(3), "end_line": int64(5), "callee": "utils.Clean",
+		}},
+	})
+	want = "Definitions of Clean (1):\n- paths.go:10-12 function Clean: func Clean() [exact]\nReferences (1):\n- main.go:3-5 function main (calls utils.Clean)"
+	if text := symbol["content"].([]map[string]interface{})[0]["text"]; text != want {
+		t.Errorf("find-symbol text = %q, want %q", text, want)
+	}
+}
+
+func TestPromptsListAndGetErrors(t *testing.T) {
+	t.Parallel()
+
+	c := startPipeClient(t, newTestServer())
+	result, _ := c.call(1, "prompts/list", "")["result"].(map[string]interface{})
+	list, _ := result["prompts"].([]interface{})
+	required := make(map[string]string)
+	for _, p := range list {
+		p, _ := p.(map[string]interface{})
+		name, _ := p["name"].(string)
+		args, _ := p["arguments"].([]interface{})
+		for _, arg := range args {
+			arg, _ := arg.(map[string]interface{})
+			if arg["required"] == true {
+				required[name], _ = arg["name"].(string)
+			}
+		}
+	}
+	want := map[string]string{
+		"explain-module":  "path",
+		"find-duplicates": "path",
+		"plan-refactor":   "symbol",
+		"review-diff":     "diff",
+	}
+	for name, arg := range want {
+		if required[name] != arg {
+			t.Errorf("prompt %s: required argument = %q, want %q", name, required[name], arg)
+		}
+	}
+
+	if code := errorCode(t, c.call(2, "prompts/get", `{"name":"no-such-prompt"}`)); code != -32602 {
+		t.Fatalf("unknown prompt error code = %d, want -32602", code)
+	}
+	if code := errorCode(t, c.call(3, "prompts/get", `{"name":"plan-refactor","arguments":{"goal":"split it"}}`)); code != -32602 {
+		t.Fatalf("missing argument error code = %d, want -32602", code)
+	}
+}
+
+func TestDiffAdditions(t *testing.T) {
+	t.Parallel()
+
+	diff := `diff --git a/util.go b/util.go
+--- a/util.go
++++ b/util.go
+@@ -10,3 +10,8 @@ func helper() {
+ 	return nil
+ }
++
++func clamp(n, lo, hi int) int {
++	return max(lo, min(n, hi))
++}
++// short
+ // trailing
+@@ -40,2 +45,3 @@
+ x := 1
++y := 2
+ z := 3
+diff --git a/old.go b/old.go
+--- a/old.go
++++ /dev/null
+@@ -1,3 +0,0 @@
+-package old
+-
+-func gone() {}
+`
+	blocks := diffAdditions(diff)
+	if len(blocks) != 1 {
+		t.Fatalf("diffAdditions returned %d blocks, want 1: %+v", len(blocks), blocks)
+	}
+	block := blocks[0]
+	if block.file != "util.go" || block.line != 12 || len(block.lines) != 5 {
+		t.Errorf("block = %+v, want util.go at line 12 with 5 lines", block)
+	}
+	if block.lines[1] != "func clamp(n, lo, hi int) int {" {
+		t.Errorf("block line 2 = %q", block.lines[1])
+	}
+}
+
+func TestCancelledRequestGetsNoResponse(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer()
+	// With no free slot, the request waits in line until it is cancelled.
+	s.slots = make(chan struct{})
+	c := startPipeClient(t, s)
+	c.send(`{"jsonrpc":"2.0","id":"slow","method":"tools/list"}`)
+	c.send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"slow","reason":"test"}}`)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.inflightMu.Lock()
+		n := len(s.inflight)
+		s.inflightMu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("cancelled request is still in flight")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Pings are handled in order, so the next message answers the ping.
+	c.call(1, "ping", "")
+}
+
+func TestServeReturnsOnExitAndEOF(t *testing.T) {
+	t.Parallel()
+
+	for _, end := range []string{"exit", "eof"} {
+		c := startPipeClient(t, newTestServer())
+		c.call(1, "initialize", `{"protocolVersion":"2025-06-18"}`)
+		if end == "exit" {
+			c.send(`{"jsonrpc":"2.0","method":"exit"}`)
+		} else {
+			c.in.Close()
+		}
+		select {
+		case err := <-c.done:
+			if err != nil {
+				t.Fatalf("%s: Serve returned %v", end, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: Serve did not return", end)
+		}
+	}
+}
+
+func TestHTTPSessionLifecycle(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer()
+	ts := httptest.NewServer(s.httpHandler("secret"))
+	defer ts.Close()
+
+	post := func(session, body string, header map[string]string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if session != "" {
+			req.Header.Set(sessionHeader, session)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`, map[string]string{"Authorization": "Bearer wrong"})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("wrong token: status %d, want 401", resp.StatusCode)
+	}
+
+	resp = post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`, nil)
+	session := resp.Header.Get(sessionHeader)
+	if resp.StatusCode != http.StatusOK || session == "" {
+		t.Fatalf("initialize: status %d, session %q", resp.StatusCode, session)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(data), "event: message\ndata: ") || !strings.Contains(string(data), `"protocolVersion":"2025-03-26"`) {
+		t.Fatalf("initialize response = %q", data)
+	}
+
+	if resp := post(session, `{"jsonrpc":"2.0","method":"notifications/initialized"}`, nil); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("notification: status %d, want 202", resp.StatusCode)
+	}
+	// A method call without an ID gets no response and must not bring the
+	// server down.
+	if resp := post(session, `{"jsonrpc":"2.0","method":"tools/list"}`, nil); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("request without ID: status %d, want 202", resp.StatusCode)
+	}
+	if resp := post(session, `{"jsonrpc":"2.0","id":2,"method":"ping"}`, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("ping after request without ID: status %d, want 200", resp.StatusCode)
+	}
+	if resp := post("", `{"jsonrpc":"2.0","id":2,"method":"ping"}`, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("missing session: status %d, want 400", resp.StatusCode)
+	}
+	if resp := post(session, `{"jsonrpc":"2.0","id":2,"method":"ping"}`, map[string]string{protocolVersionHeader: "1999-01-01"}); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unsupported protocol version: status %d, want 400", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set(sessionHeader, session)
+	del, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE: %v", err)
+	}
+	del.Body.Close()
+	if del.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE: status %d, want 204", del.StatusCode)
+	}
+	if resp := post(session, `{"jsonrpc":"2.0","id":3,"method":"ping"}`, nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("deleted session: status %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestHTTPStalledStreamDoesNotBlockOtherSessions(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer()
+	ts := httptest.NewServer(s.httpHandler(""))
+	// Registered first, so that it runs after the response bodies are closed.
+	t.Cleanup(ts.Close)
+
+	request := func(method, session, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL, strings.NewReader(body))
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		if session != "" {
+			req.Header.Set(sessionHeader, session)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+	stalled := request(http.MethodPost, "", initialize).Header.Get(sessionHeader)
+	other := request(http.MethodPost, "", initialize).Header.Get(sessionHeader)
+	if resp := request(http.MethodGet, stalled, ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET stream: status %d", resp.StatusCode)
+	}
+
+	// A write to the stalled session's stream that never completes.
+	s.sessionsMu.Lock()
+	sess := s.sessions[stalled]
+	s.sessionsMu.Unlock()
+	sess.mu.Lock()
+	stream := sess.stream
+	sess.mu.Unlock()
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	done := make(chan *http.Response, 1)
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(`{"jsonrpc":"2.0","id":2,"method":"ping"}`))
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		req.Header.Set(sessionHeader, other)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			resp = nil
+		}
+		done <- resp
+	}()
+	select {
+	case resp := <-done:
+		if resp == nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("ping on another session = %v", resp)
+		}
+		resp.Body.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("a stalled stream held up another session")
+	}
+}
+
+func TestFileResourcesOnlyServeIndexableFiles(t *testing.T) {
+	t.Parallel()
+
+	root, err := utils.NormalizeProjectRoot(t.TempDir())
+	if err != nil {
+		t.Fatalf("NormalizeProjectRoot: %v", err)
+	}
+	outside := filepath.Join(t.TempDir(), "secret.go")
+	files := map[string]string{
+		".env":              "TOKEN=secret\n",
+		".git/config":       "[core]\n",
+		"secrets/keys.go":   "package secrets\n",
+		"docs/README.md":    "# Docs\n",
+		"node_modules/x.js": "module.exports = 1\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	if err := os.WriteFile(outside, []byte("package secret\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	// Symlinks with source extensions, to a file outside the project and to
+	// a file that is not indexed.
+	if err := os.Symlink(outside, filepath.Join(root, "linked.go")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(root, ".env"), filepath.Join(root, "env.go")); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+
+	s := newTestServer()
+	s.rootDir = root
+	s.ignorePatterns = []string{"secrets/"}
+	c := startPipeClient(t, s)
+
+	for i, name := range []string{".env", ".git/config", "secrets/keys.go", "node_modules/x.js", "linked.go", "env.go"} {
+		uri := fileURI(filepath.Join(root, name))
+		if code := errorCode(t, c.call(i+1, "resources/read", fmt.Sprintf(`{"uri":%q}`, uri))); code != errResourceNotFound {
+			t.Errorf("read %s: error code = %d, want %d", name, code, errResourceNotFound)
+		}
+	}
+	result, _ := c.call(10, "resources/read", fmt.Sprintf(`{"uri":%q}`, fileURI(filepath.Join(root, "docs/README.md"))))["result"].(map[string]interface{})
+	if contents, _ := result["contents"].([]interface{}); len(contents) != 1 {
+		t.Errorf("read docs/README.md: contents = %v", result)
+	}
+}
+
+func TestURIPath(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"file:///home/me/src/main.go":   "/home/me/src/main.go",
+		"file:///C:/src/main.go":        "C:/src/main.go",
+		"file:///c:/My%20Code/x.go":     "c:/My Code/x.go",
+		"file:///tmp/a%23b.go#fragment": "/tmp/a#b.go",
+	}
+	for uri, want := range tests {
+		u, err := url.Parse(uri)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", uri, err)
+		}
+		if got := uriPath(u); got != want {
+			t.Errorf("uriPath(%q) = %q, want %q", uri, got, want)
+		}
+	}
+	if got := fileURI("C:/src/main.go"); got != "file:///C:/src/main.go" {
+		t.Errorf("fileURI(C:/src/main.go) = %q", got)
+	}
+}
+
+func TestFileResources(t *testing.T) {
+	t.Parallel()
+
+	root, err := utils.NormalizeProjectRoot(t.TempDir())
+	if err != nil {
+		t.Fatalf("NormalizeProjectRoot: %v", err)
+	}
+	path := filepath.Join(root, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	s := newTestServer()
+	s.rootDir = root
+	c := startPipeClient(t, s)
+	uri := fileURI(path)
+
+	result, _ := c.call(1, "resources/templates/list", "")["result"].(map[string]interface{})
+	templates, _ := result["resourceTemplates"].([]interface{})
+	if len(templates) != 1 || templates[0].(map[string]interface{})["uriTemplate"] != "codebase://symbol/{name}" {
+		t.Fatalf("resourceTemplates = %v", templates)
+	}
+
+	result, _ = c.call(2, "resources/read", fmt.Sprintf(`{"uri":%q}`, uri))["result"].(map[string]interface{})
+	contents, _ := result["contents"].([]interface{})
+	if len(contents) != 1 {
+		t.Fatalf("contents = %v", contents)
+	}
+	content := contents[0].(map[string]interface{})
+	if content["text"] != "package main\n" || content["mimeType"] != "text/x-go" || content["uri"] != uri {
+		t.Fatalf("content = %v", content)
+	}
+	for i, other := range []string{fileURI(filepath.Join(filepath.Dir(root), "outside.go")), fileURI(filepath.Join(root, "missing.go")), "https://example.com/x"} {
+		if code := errorCode(t, c.call(3+i, "resources/read", fmt.Sprintf(`{"uri":%q}`, other))); code != errResourceNotFound {
+			t.Fatalf("read %s: error code = %d, want %d", other, code, errResourceNotFound)
+		}
+	}
+
+	c.call(10, "resources/subscribe", fmt.Sprintf(`{"uri":%q}`, uri))
+	s.filesChanged([]string{path, filepath.Join(root, "other.go")})
+	msg := c.recv()
+	params, _ := msg["params"].(map[string]interface{})
+	if msg["method"] != "notifications/resources/updated" || params["uri"] != uri {
+		t.Fatalf("update notification = %v", msg)
+	}
+
+	c.call(11, "resources/unsubscribe", fmt.Sprintf(`{"uri":%q}`, uri))
+	s.filesChanged([]string{path})
+	// Nothing was sent for the change, so the next message answers the ping.
+	c.call(12, "ping", "")
+}
+
+// recordingClient records the notifications sent to it.
+type recordingClient struct {
+	updated []string
+}
+
+func (c *recordingClient) scope() string { return "recording:" }
+
+func (c *recordingClient) notify(method string, params interface{}) bool {
+	if method == "notifications/resources/updated" {
+		c.updated = append(c.updated, params.(map[string]interface{})["uri"].(string))
+	}
+	return true
+}
+
+func TestSymbolSubscriptionsFollowReindexedFiles(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer()
+	s.collection = "codebase_test"
+	c := &recordingClient{}
+	s.subs["codebase://symbol/Run"] = &subscription{
+		clients: map[client]bool{c: true},
+		files:   map[string]bool{"/repo/server.go": true},
+	}
+
+	s.filesIndexed("codebase_other", []string{"/repo/server.go"})
+	s.filesIndexed("codebase_test", []string{"/repo/main.go"})
+	if len(c.updated) != 0 {
+		t.Fatalf("unexpected updates %v", c.updated)
+	}
+	s.filesIndexed("codebase_test", []string{"/repo/main.go", "/repo/server.go"})
+	if len(c.updated) != 1 || c.updated[0] != "codebase://symbol/Run" {
+		t.Fatalf("updates = %v, want the symbol", c.updated)
+	}
+
+	s.removeClient(c)
+	if len(s.subs) != 0 {
+		t.Fatalf("subscriptions left after removeClient: %v", s.subs)
+	}
+}
+
+func TestIndexJobRefusedWhileClearing(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer()
+	s.clearing["codebase_test"] = true
+	if _, _, err := s.startIndexJob(t.TempDir(), "codebase_test", indexModeIncremental); err == nil {
+		t.Fatal("startIndexJob succeeded while the collection was being cleared")
+	}
+	if len(s.jobs) != 0 {
+		t.Errorf("jobs = %v, want none", s.jobs)
+	}
+}
+
+func TestHTTPRejectsForeignOriginsAndHosts(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer()
+	open := httptest.NewServer(s.httpHandler(""))
+	defer open.Close()
+	withToken := httptest.NewServer(s.httpHandler("secret"))
+	defer withToken.Close()
+
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+	tests := []struct {
+		name   string
+		url    string
+		header map[string]string
+		host   string
+		want   int
+	}{
+		{"no token", withToken.URL, nil, "", http.StatusUnauthorized},
+		{"wrong token", withToken.URL, map[string]string{"Authorization": "Bearer wrong"}, "", http.StatusUnauthorized},
+		{"not a bearer token", withToken.URL, map[string]string{"Authorization": "secret"}, "", http.StatusUnauthorized},
+		{"token", withToken.URL, map[string]string{"Authorization": "Bearer secret"}, "", http.StatusOK},
+		{"local origin", open.URL, map[string]string{"Origin": "http://localhost:3000"}, "", http.StatusOK},
+		{"foreign origin", open.URL, map[string]string{"Origin": "http://evil.example"}, "", http.StatusForbidden},
+		{"rebound host", open.URL, map[string]string{"Origin": "http://evil.example:8080"}, "evil.example:8080", http.StatusForbidden},
+		{"rebound host without origin", open.URL, nil, "evil.example:8080", http.StatusForbidden},
+		{"foreign host with token", withToken.URL, map[string]string{"Authorization": "Bearer secret"}, "mcp.example:8080", http.StatusOK},
+		{"foreign origin with token", withToken.URL, map[string]string{"Authorization": "Bearer secret", "Origin": "http://evil.example"}, "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, tt.url, strings.NewReader(initialize))
+		req.Header.Set("Accept", "application/json")
+		for k, v := range tt.header {
+			req.Header.Set(k, v)
+		}
+		if tt.host != "" {
+			req.Host = tt.host
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestListenAddr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		addr, token string
+		want        string
+		wantErr     bool
+	}{
+		{addr: ":8080", want: "127.0.0.1:8080"},
+		{addr: "localhost:8080", want: "localhost:8080"},
+		{addr: "[::1]:8080", want: "[::1]:8080"},
+		{addr: "0.0.0.0:8080", wantErr: true},
+		{addr: "192.168.1.5:8080", wantErr: true},
+		{addr: "0.0.0.0:8080", token: "secret", want: "0.0.0.0:8080"},
+		{addr: "8080", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := listenAddr(tt.addr, tt.token)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("listenAddr(%q, %q) = %q, %v; want %q, error %v", tt.addr, tt.token, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRequestsWaitForAFreeSlot(t *testing.T) {
+	t.Parallel()
+
+	root, err := utils.NormalizeProjectRoot(t.TempDir())
+	if err != nil {
+		t.Fatalf("NormalizeProjectRoot: %v", err)
+	}
+	path := filepath.Join(root, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	s := newTestServer()
+	s.rootDir = root
+	// One slot, taken: requests wait in line, pings do not.
+	s.slots = make(chan struct{}, 1)
+	s.slots <- struct{}{}
+	c := startPipeClient(t, s)
+
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":%q}}`, fileURI(path))
+	c.call(2, "ping", "")
+	<-s.slots
+	if msg := c.recv(); msg["id"] != float64(1) || msg["result"] == nil {
+		t.Fatalf("queued request response = %v", msg)
+	}
+}
+
+func TestConcurrentWritesAreSerialized(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer()
+	var out bytes.Buffer
+	c := &stdioClient{s: s, writer: &messageWriter{w: bufio.NewWriterSize(&out, 64)}}
+	params := map[string]string{"text": strings.Repeat("x", 1000)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				c.notify("notifications/message", params)
+			}
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 400 {
+		t.Fatalf("wrote %d lines, want 400", len(lines))
+	}
+	for _, line := range lines {
+		var msg map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("interleaved message %.80q: %v", line, err)
+		}
+	}
+}
+
+func TestCancellationAbortsEmbeddingRequest(t *testing.T) {
+	// This test sets OPENAI_* variables, so do not run in parallel.
+	started := make(chan struct{})
+	aborted := make(chan struct{})
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server notices a closed connection once the body is read.
+		io.Copy(io.Discard, r.Body)
+		close(started)
+		<-r.Context().Done()
+		close(aborted)
+	}))
+	defer api.Close()
+	t.Setenv("OPENAI_API_KEY", "test")
+	t.Setenv("OPENAI_BASE_URL", api.URL)
+
+	s := newTestServer()
+	s.embedClient = embeddings.NewClient()
+	c := startPipeClient(t, s)
+	c.send(`{"jsonrpc":"2.0","id":"search","method":"tools/call","params":{"name":"codebase-retrieval","arguments":{"query":"anything"}}}`)
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the embedding request was not sent")
+	}
+	c.send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"search"}}`)
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelling the tool call did not abort the embedding request")
+	}
+	// The cancelled call gets no response, so the next message answers the
+	// ping.
+	c.call(1, "ping", "")
+}
+
+func TestReindexWaitDoesNotHoldASlot(t *testing.T) {
+	t.Parallel()
+
+	root, err := utils.NormalizeProjectRoot(t.TempDir())
+	if err != nil {
+		t.Fatalf("NormalizeProjectRoot: %v", err)
+	}
+	path := filepath.Join(root, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	s := newTestServer()
+	s.rootDir = root
+	s.slots = make(chan struct{}, 1)
+	collection, _, err := s.resolveProject(root)
+	if err != nil {
+		t.Fatalf("resolveProject: %v", err)
+	}
+	// A job already running for the project, which the reindex call waits
+	// for.
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &indexJob{
+		root:       root,
+		collection: collection,
+		mode:       indexModeIncremental,
+		indexer:    indexer.NewIndexer(nil, nil),
+		startedAt:  time.Now(),
+		done:       make(chan struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
+		running:    true,
+		listeners:  make(map[*progressReporter]bool),
+	}
+	s.jobs[collection] = job
+	c := startPipeClient(t, s)
+
+	c.send(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"reindex","arguments":{"project_path":%q},"_meta":{"progressToken":"p"}}}`, root)
+	for waiting := false; !waiting; {
+		job.mu.Lock()
+		waiting = len(job.listeners) > 0
+		job.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	// The only slot is free while the call waits, so this request runs.
+	msg := c.call(2, "resources/read", fmt.Sprintf(`{"uri":%q}`, fileURI(path)))
+	if msg["result"] == nil {
+		t.Fatalf("resources/read while reindex waits = %v", msg)
+	}
+
+	job.mu.Lock()
+	job.running = false
+	job.mu.Unlock()
+	close(job.done)
+	msg = c.recv()
+	if result, _ := msg["result"].(map[string]interface{}); msg["id"] != float64(1) || result["isError"] == true {
+		t.Fatalf("reindex response = %v", msg)
+	}
+}
+
+func TestNoProgressAfterWaitReturns(t *testing.T) {
+	t.Parallel()
+
+	s := newTestServer()
+	var out bytes.Buffer
+	p := newProgressReporter(s, newMessageWriter(&out), "p")
+	job := &indexJob{
+		done:      make(chan struct{}),
+		listeners: make(map[*progressReporter]bool),
+	}
+
+	// Events keep coming while the request stops waiting.
+	stop := make(chan struct{})
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				job.publish(indexer.ProgressEvent{Phase: indexer.PhaseEmbed, FilesTotal: 1})
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waited := make(chan error, 1)
+	go func() { waited <- job.wait(ctx, p) }()
+	for registered := false; !registered; {
+		job.mu.Lock()
+		registered = len(job.listeners) > 0
+		job.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-waited; err != context.Canceled {
+		t.Fatalf("wait = %v, want %v", err, context.Canceled)
+	}
+	// The response would be written now; no progress may follow it.
+	sent := out.Len()
+	time.Sleep(10 * time.Millisecond)
+	close(stop)
+	<-published
+	if out.Len() != sent {
+		t.Fatalf("wrote %d bytes of progress after wait returned", out.Len()-sent)
+	}
+}
+
+func TestLinkRPC(t *testing.T) {
+	t.Parallel()
+
+	rpc := map[string]interface{}{
+		"node_name":   "Greeter.SayHello",
+		"param_types": []interface{}{"hello.HelloRequest"},
+	}
+	chunk := func(file, name string, params, callees []string, content string) map[string]interface{} {
+		toList := func(items []string) []interface{} {
+			list := make([]interface{}, len(items))
+			for i, item := range items {
+				list[i] = item
+			}
+			return list
+		}
+		return map[string]interface{}{
+			"file_path":   "/repo/" + file,
+			"node_name":   name,
+			"start_line":  int64(1),
+			"param_types": toList(params),
+			"callees":     toList(callees),
+			"content":     content,
+		}
+	}
+	goChunks := []map[string]interface{}{
+		chunk("server.go", "(*server).SayHello", []string{"context.Context", "*pb.HelloRequest"}, nil, ""),
+		chunk("greeter.go", "(*greeter).SayHello", []string{"string"}, nil, ""),
+		chunk("client.go", "greet", []string{"context.Context", "pb.GreeterClient"}, []string{"client.SayHello"}, ""),
+		chunk("client.go", "dial", []string{"string"}, []string{"grpc.Dial", "pb.NewGreeterClient", "c.SayHello"}, ""),
+		chunk("client.go", "build", nil, []string{"s.conn.SayHello"}, "s.conn.SayHello(ctx, &pb.HelloRequest{Name: n})"),
+		// A method of the same name on a type unrelated to the service.
+		chunk("greeter.go", "welcome", []string{"*greeter"}, []string{"g.SayHello"}, "g.SayHello(name)"),
+	}
+	goChunks = append(goChunks, map[string]interface{}{
+		"file_path":   "/repo/hello.pb.go",
+		"node_name":   "(*greeterClient).SayHello",
+		"param_types": []interface{}{"context.Context", "*HelloRequest"},
+		"callees":     []interface{}{"c.cc.Invoke"},
+	})
+
+	implementations, callers := linkRPC(rpc, goChunks, "/repo")
+	if want := []string{"server.go:1 (*server).SayHello"}; !reflect.DeepEqual(implementations, want) {
+		t.Errorf("implementations = %q, want %q", implementations, want)
+	}
+	if want := []string{"client.go:1 greet", "client.go:1 dial", "client.go:1 build"}; !reflect.DeepEqual(callers, want) {
+		t.Errorf("callers = %q, want %q", callers, want)
+	}
+}
+
+func TestMatchSymbolDefinitions(t *testing.T) {
+	t.Parallel()
+
+	symbol := func(file string, line int64, name, receiver, pkg string) map[string]interface{} {
+		return map[string]interface{}{
+			"file_path":    file,
+			"start_line":   line,
+			"node_name":    name,
+			"receiver":     receiver,
+			"package_name": pkg,
+		}
+	}
+	candidates := func() []map[string]interface{} {
+		return []map[string]interface{}{
+			symbol("a.go", 10, "(*Server).Run", "*Server", "mcp"),
+			symbol("b.go", 5, "run", "", "main"),
+			symbol("c.go", 1, "X", "", "utils"),
+			symbol("c.go", 9, "NormalizeProjectRoot", "", "utils"),
+			symbol("d.go", 3, "(*Server).Stop", "*Server", "mcp"),
+			symbol("e.go", 7, "Runner", "", "jobs"),
+		}
+	}
+	type match struct {
+		name, match string
+	}
+	tests := []struct {
+		query string
+		want  []match
+	}{
+		// Definitions come best match first, then by location.
+		{"Run", []match{{"(*Server).Run", "exact"}, {"run", "case-insensitive"}, {"Runner", "fuzzy"}}},
+		{"run", []match{{"run", "exact"}, {"(*Server).Run", "case-insensitive"}, {"Runner", "fuzzy"}}},
+		// A qualified name is exact only for members of that type or
+		// package; the bare name still fuzzy matches other symbols.
+		{"Server.Run", []match{{"(*Server).Run", "exact"}, {"run", "fuzzy"}, {"Runner", "fuzzy"}}},
+		{"mcp.Server.Run", []match{{"(*Server).Run", "exact"}, {"run", "fuzzy"}, {"Runner", "fuzzy"}}},
+		{"server.run", []match{{"(*Server).Run", "case-insensitive"}, {"run", "fuzzy"}, {"Runner", "fuzzy"}}},
+		{"utils.X", []match{{"X", "exact"}}},
+		// Members of a type or package are fuzzy matches.
+		{"Server", []match{{"(*Server).Run", "fuzzy"}, {"(*Server).Stop", "fuzzy"}}},
+		{"normroot", []match{{"NormalizeProjectRoot", "fuzzy"}}},
+		// The letters must come in order.
+		{"rootnorm", nil},
+		{"Missing", nil},
+	}
+	for _, test := range tests {
+		var got []match
+		for _, def := range matchSymbolDefinitions(normalizeSymbolName(test.query), candidates()) {
+			got = append(got, match{def["node_name"].(string), def["match"].(string)})
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("matchSymbolDefinitions(%q) = %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestMatchSymbolReferences(t *testing.T) {
+	t.Parallel()
+
+	caller := func(file string, line int64, callees ...string) map[string]interface{} {
+		list := make([]interface{}, len(callees))
+		for i, callee := range callees {
+			list[i] = callee
+		}
+		return map[string]interface{}{
+			"file_path":  "/repo/" + file,
+			"start_line": line,
+			"node_name":  "caller",
+			"callees":    list,
+		}
+	}
+	candidates := []map[string]interface{}{
+		caller("b.go", 20, "s.client.Upsert"),
+		caller("b.go", 4, "Upsert"),
+		caller("a.go", 8, "fmt.Println", "utils.X"),
+		caller("c.go", 1, "s.client.UpsertAll", "upsert"),
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// A bare name matches qualified callees, sorted by location.
+		{"Upsert", []string{"b.go:4 Upsert", "b.go:20 s.client.Upsert"}},
+		{"Client.Upsert", []string{"b.go:4 Upsert", "b.go:20 s.client.Upsert"}},
+		{"utils.X", []string{"a.go:8 utils.X"}},
+		{"Println", []string{"a.go:8 fmt.Println"}},
+		{"Missing", []string{}},
+	}
+	for _, test := range tests {
+		got := []string{}
+		for _, ref := range matchSymbolReferences(test.query, candidates, "/repo") {
+			got = append(got, fmt.Sprintf("%s:%v %s", ref["file_path"], ref["start_line"], ref["callee"]))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("matchSymbolReferences(%q) = %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+func TestFindSymbolTextListsDefinitionsBeforeReferences(t *testing.T) {
+	t.Parallel()
+
+	text := findSymbolText(map[string]interface{}{
+		"symbol": "Run",
+		"definitions": []map[string]interface{}{
+			{"file_path": "a.go", "start_line": 1, "end_line": 3, "kind": "function_declaration", "name": "Run", "match": "exact"},
+		},
+		"references": []map[string]interface{}{
+			{"file_path": "b.go", "start_line": 5, "end_line": 9, "kind": "function_declaration", "name": "main", "callee": "app.Run"},
+		},
+	})
+	want := "Definitions of Run (1):\n" +
+		"- a.go:1-3 function_declaration Run [exact]\n" +
+		"References (1):\n" +
+		"- b.go:5-9 function_declaration main (calls app.Run)"
+	if text != want {
+		t.Errorf("findSymbolText = %q, want %q", text, want)
+	}
+}
+
+func TestBuildRepoMap(t *testing.T) {
+	t.Parallel()
+
+	symbol := func(file string, line int64, name, signature string, callees ...string) outlineSymbol {
+		list := make([]interface{}, len(callees))
+		for i, callee := range callees {
+			list[i] = callee
+		}
+		return outlineSymbol{
+			payload:  map[string]interface{}{"signature": signature, "exported": true, "callees": list},
+			filePath: file,
+			name:     name,
+			kind:     "function_declaration",
+			line:     line,
+		}
+	}
+	symbols := []outlineSymbol{
+		symbol("cmd/root.go", 1, "Execute", "func Execute() error", "mcp.Serve"),
+		symbol("internal/mcp/server.go", 1, "Serve", "func Serve() error"),
+		symbol("internal/mcp/tools.go", 1, "List", "func List() []string"),
+		symbol("main.go", 1, "main", "func main()", "cmd.Execute"),
+	}
+
+	repoMap := buildRepoMap(symbols, 1000)
+	want := "cmd/\n" +
+		"  root.go\n" +
+		"    func Execute() error\n" +
+		"internal/\n" +
+		"  mcp/\n" +
+		"    server.go\n" +
+		"      func Serve() error\n" +
+		"    tools.go\n" +
+		"      func List() []string\n" +
+		"main.go\n" +
+		"  func main()\n"
+	if repoMap["map"] != want {
+		t.Errorf("map =\n%s\nwant\n%s", repoMap["map"], want)
+	}
+	if repoMap["truncated"] != false || repoMap["symbols_shown"] != 4 || repoMap["files"] != 4 {
+		t.Errorf("everything fits: truncated = %v, symbols_shown = %v, files = %v", repoMap["truncated"], repoMap["symbols_shown"], repoMap["files"])
+	}
+
+	// The file list fits with room for the called symbols only.
+	fileList := estimateTokens(renderRepoMap(symbols, []string{"cmd/root.go", "internal/mcp/server.go", "internal/mcp/tools.go", "main.go"}, nil))
+	repoMap = buildRepoMap(symbols, fileList+14)
+	if repoMap["truncated"] != true || repoMap["symbols_shown"] != 2 || repoMap["symbols_total"] != 4 {
+		t.Errorf("tight budget: truncated = %v, symbols_shown = %v of %v", repoMap["truncated"], repoMap["symbols_shown"], repoMap["symbols_total"])
+	}
+	if text := repoMap["map"].(string); !strings.Contains(text, "func Execute() error") || !strings.Contains(text, "func Serve() error") || strings.Contains(text, "func main()") {
+		t.Errorf("tight budget map shows the wrong symbols:\n%s", text)
+	}
+
+	// Not even the file list fits: directories are summarized.
+	repoMap = buildRepoMap(symbols, 14)
+	if want := "./ (1 files)\ncmd/ (1 files)\ninternal/mcp/ (2 files)\n"; repoMap["map"] != want {
+		t.Errorf("directory summary = %q, want %q", repoMap["map"], want)
+	}
+	if repoMap["truncated"] != true || repoMap["symbols_shown"] != 0 {
+		t.Errorf("directory summary: truncated = %v, symbols_shown = %v", repoMap["truncated"], repoMap["symbols_shown"])
+	}
+
+	// A complete directory summary drops no symbols when there are none.
+	var docs []outlineSymbol
+	for _, name := range []string{"a.md", "b.md", "c.md", "d.md", "e.md"} {
+		docs = append(docs, outlineSymbol{payload: map[string]interface{}{}, filePath: "docs/" + name, kind: "documentation"})
+	}
+	repoMap = buildRepoMap(docs, 6)
+	if repoMap["map"] != "docs/ (5 files)\n" || repoMap["truncated"] != false {
+		t.Errorf("documentation only: map = %q, truncated = %v", repoMap["map"], repoMap["truncated"])
+	}
+}
+
+func TestRepoMapSymbolLineCutsOnRuneBoundary(t *testing.T) {
+	t.Parallel()
+
+	signature := "func F(s string) // " + strings.Repeat("é", 60)
+	line := repoMapSymbolLine(outlineSymbol{payload: map[string]interface{}{"signature": signature}}, 0)
+	if !utf8.ValidString(line) {
+		t.Fatalf("repoMapSymbolLine cut a rune: %q", line)
+	}
+	if !strings.HasSuffix(line, "...\n") || len(line) > 121 {
+		t.Errorf("repoMapSymbolLine = %q", line)
+	}
+}
+
+func TestOutlineText(t *testing.T) {
+	t.Parallel()
+
+	text := outlineText(map[string]interface{}{
+		"path": "internal/mcp",
+		"files": []map[string]interface{}{
+			{"file_path": "internal/mcp/a.go", "symbols": []map[string]interface{}{
+				{"name": "Run", "kind": "function_declaration", "start_line": 3, "signature": "func Run(\n\tctx context.Context) error", "doc": "Run runs."},
+				{"name": "T", "kind": "type_declaration", "start_line": 9},
+			}},
+			{"file_path": "internal/mcp/b.go", "symbols": []map[string]interface{}{
+				{"name": "x", "kind": "var_declaration", "start_line": 1},
+			}},
+		},
+	})
+	want := "internal/mcp/a.go\n" +
+		"  3: func Run( ctx context.Context) error // Run runs.\n" +
+		"  9: type_declaration T\n" +
+		"internal/mcp/b.go\n" +
+		"  1: var_declaration x"
+	if text != want {
+		t.Errorf("outlineText =\n%s\nwant\n%s", text, want)
+	}
+
+	text = outlineText(map[string]interface{}{"map": "a.go\n", "truncated": true, "symbols_shown": 1, "symbols_total": 3})
+	if want := "a.go\n(1 of 3 symbols shown)\n"; text != want {
+		t.Errorf("outlineText(repo map) = %q, want %q", text, want)
+	}
+}
@@ -0,0 +1,275 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	qdrantpb "github.com/qdrant/go-client/qdrant"
+)
+
+// symbolFields are the payload fields read by find-symbol. Chunk content is
+// left out so that scrolling many candidates stays cheap.
+var symbolFields = []string{
+	"file_path", "language", "node_type", "node_name", "receiver", "package_name",
+	"signature", "start_line", "end_line", "callees", "cell_index",
+}
+
+// Match qualities of a definition, best first.
+const (
+	symbolMatchExact           = "exact"
+	symbolMatchCaseInsensitive = "case-insensitive"
+	symbolMatchFuzzy           = "fuzzy"
+)
+
+var symbolMatchRank = map[string]int{
+	symbolMatchExact:           0,
+	symbolMatchCaseInsensitive: 1,
+	symbolMatchFuzzy:           2,
+}
+
+// handleFindSymbol looks up the definitions of a symbol by name and the
+// chunks that call it.
+func (s *Server) handleFindSymbol(ctx context.Context, args json.RawMessage) (interface{}, error) {
+	var input struct {
+		Name              string `json:"name"`
+		Limit             int    `json:"limit"`
+		IncludeReferences *bool  `json:"include_references"`
+		ProjectPath       string `json:"project_path"`
+	}
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, err
+	}
+
+	query := normalizeSymbolName(input.Name)
+	if query == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if input.Limit <= 0 {
+		input.Limit = 20
+	}
+	bare := query[strings.LastIndex(query, ".")+1:]
+
+	collection, root, err := s.resolveProject(input.ProjectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	payloads, err := s.symbolDefinitions(ctx, collection, query, symbolFields)
+	if err != nil {
+		return nil, err
+	}
+	if len(payloads) > input.Limit {
+		payloads = payloads[:input.Limit]
+	}
+	definitions := make([]map[string]interface{}, 0, len(payloads))
+	for _, payload := range payloads {
+		entry := symbolEntry(payload, root)
+		entry["match"] = payload["match"]
+		definitions = append(definitions, entry)
+	}
+
+	result := map[string]interface{}{
+		"symbol":      input.Name,
+		"definitions": definitions,
+	}
+	if input.IncludeReferences == nil || *input.IncludeReferences {
+		callers, err := s.scrollPayloads(ctx, collection, &qdrantpb.Filter{
+			Must: []*qdrantpb.Condition{qdrantpb.NewMatchText("callees", bare)},
+		}, symbolFields)
+		if err != nil {
+			return nil, err
+		}
+		references := matchSymbolReferences(query, callers, root)
+		if len(references) > input.Limit {
+			references = references[:input.Limit]
+		}
+		result["references"] = references
+	}
+	return result, nil
+}
+
+// symbolDefinitions returns the payloads of the chunks defining the symbol
+// named by query, best matches first. Each payload gets its match quality
+// under "match".
+func (s *Server) symbolDefinitions(ctx context.Context, collection, query string, fields []string) ([]map[string]interface{}, error) {
+	bare := query[strings.LastIndex(query, ".")+1:]
+
+	// Documentation sections and notebook cells are not symbols.
+	notSymbols := []*qdrantpb.Condition{
+		qdrantpb.NewMatchKeywords("node_type", "documentation", "cell"),
+	}
+
+	// Candidates contain the name (a substring match when node_name has no
+	// full-text index) or are members of a type or package of that name.
+	candidates, err := s.scrollPayloads(ctx, collection, &qdrantpb.Filter{
+		Should: []*qdrantpb.Condition{
+			qdrantpb.NewMatchText("node_name", bare),
+			qdrantpb.NewMatchKeywords("receiver", bare, "*"+bare),
+			qdrantpb.NewMatchKeyword("package_name", bare),
+		},
+		MustNot: notSymbols,
+	}, fields)
+	if err != nil {
+		return nil, err
+	}
+	definitions := matchSymbolDefinitions(query, candidates)
+	if len(definitions) == 0 {
+		// Nothing contains the name as written: fall back to a
+		// case-insensitive fuzzy match over every symbol.
+		all, err := s.scrollPayloads(ctx, collection, &qdrantpb.Filter{MustNot: notSymbols}, fields)
+		if err != nil {
+			return nil, err
+		}
+		definitions = matchSymbolDefinitions(query, all)
+	}
+	return definitions, nil
+}
+
+// normalizeSymbolName turns the ways a symbol is written into a dotted name:
+// "(*Server).Run" and "Server::run" become "Server.Run" and "Server.run".
+func normalizeSymbolName(name string) string {
+	name = strings.NewReplacer("(", "", ")", "", "*", "", "&", "", "::", ".", "#", ".", "->", ".").Replace(name)
+	return strings.Trim(strings.TrimSpace(name), ".")
+}
+
+// symbolNames returns the names a chunk can be referred to by: its node
+// name, the bare name, and the name qualified by receiver and package.
+func symbolNames(payload map[string]interface{}) []string {
+	nodeName, _ := payload["node_name"].(string)
+	receiver, _ := payload["receiver"].(string)
+	pkg, _ := payload["package_name"].(string)
+
+	name := normalizeSymbolName(nodeName)
+	bare := name[strings.LastIndex(name, ".")+1:]
+	receiver = normalizeSymbolName(receiver)
+
+	names := []string{name, bare}
+	if receiver != "" {
+		names = append(names, receiver+"."+bare)
+	}
+	if pkg != "" {
+		names = append(names, pkg+"."+bare, pkg+"."+name)
+		if receiver != "" {
+			names = append(names, pkg+"."+receiver+"."+bare)
+		}
+	}
+	return names
+}
+
+// symbolMatch rates how well query names a chunk, or returns "" when it does
+// not. Members of a type or package of that name are fuzzy matches.
+func symbolMatch(query string, payload map[string]interface{}) string {
+	names := symbolNames(payload)
+	for _, name := range names {
+		if name == query {
+			return symbolMatchExact
+		}
+	}
+	lower := strings.ToLower(query)
+	for _, name := range names {
+		if strings.ToLower(name) == lower {
+			return symbolMatchCaseInsensitive
+		}
+	}
+	receiver, _ := payload["receiver"].(string)
+	pkg, _ := payload["package_name"].(string)
+	if strings.EqualFold(normalizeSymbolName(receiver), query) || strings.EqualFold(pkg, query) {
+		return symbolMatchFuzzy
+	}
+	// The bare name is a case-insensitive subsequence match, so "normroot"
+	// finds NormalizeProjectRoot.
+	bare := strings.ToLower(names[1])
+	queryBare := lower[strings.LastIndex(lower, ".")+1:]
+	if isSubsequence(queryBare, bare) {
+		return symbolMatchFuzzy
+	}
+	return ""
+}
+
+func isSubsequence(sub, s string) bool {
+	i := 0
+	for j := 0; i < len(sub) && j < len(s); j++ {
+		if sub[i] == s[j] {
+			i++
+		}
+	}
+	return i == len(sub)
+}
+
+// matchSymbolDefinitions filters candidates to those named by query, best
+// matches first.
+func matchSymbolDefinitions(query string, candidates []map[string]interface{}) []map[string]interface{} {
+	var definitions []map[string]interface{}
+	for _, payload := range candidates {
+		match := symbolMatch(query, payload)
+		if match == "" {
+			continue
+		}
+		payload["match"] = match
+		definitions = append(definitions, payload)
+	}
+	sort.SliceStable(definitions, func(i, j int) bool {
+		ri, rj := symbolMatchRank[definitions[i]["match"].(string)], symbolMatchRank[definitions[j]["match"].(string)]
+		if ri != rj {
+			return ri < rj
+		}
+		return symbolLocationLess(definitions[i], definitions[j])
+	})
+	return definitions
+}
+
+// matchSymbolReferences returns the chunks with a callee naming the symbol:
+// the symbol itself or any qualified call ending in its bare name
+// ("s.client.Upsert" for "Upsert").
+func matchSymbolReferences(query string, candidates []map[string]interface{}, root string) []map[string]interface{} {
+	bare := query[strings.LastIndex(query, ".")+1:]
+	references := []map[string]interface{}{}
+	for _, payload := range candidates {
+		for _, callee := range payloadStrings(payload["callees"]) {
+			if callee == query || callee == bare || strings.HasSuffix(callee, "."+bare) {
+				entry := symbolEntry(payload, root)
+				entry["callee"] = callee
+				references = append(references, entry)
+				break
+			}
+		}
+	}
+	sort.SliceStable(references, func(i, j int) bool {
+		return symbolLocationLess(references[i], references[j])
+	})
+	return references
+}
+
+// symbolEntry formats a chunk as a find-symbol result.
+func symbolEntry(payload map[string]interface{}, root string) map[string]interface{} {
+	filePath, _ := payload["file_path"].(string)
+	entry := map[string]interface{}{
+		"name":       payload["node_name"],
+		"kind":       payload["node_type"],
+		"language":   payload["language"],
+		"file_path":  displayPath(root, filePath),
+		"start_line": payload["start_line"],
+		"end_line":   payload["end_line"],
+	}
+	if signature, ok := payload["signature"].(string); ok && signature != "" {
+		entry["signature"] = signature
+	}
+	if cell, ok := payload["cell_index"].(int64); ok && cell > 0 {
+		entry["cell"] = fmt.Sprintf("cell %d", cell)
+	}
+	return entry
+}
+
+func symbolLocationLess(a, b map[string]interface{}) bool {
+	pathA, _ := a["file_path"].(string)
+	pathB, _ := b["file_path"].(string)
+	if pathA != pathB {
+		return pathA < pathB
+	}
+	lineA, _ := a["start_line"].(int64)
+	lineB, _ := b["start_line"].(int64)
+	return lineA < lineB
+}
@@ -0,0 +1,313 @@
+package mcp
+
+import (
+	"codebase/internal/utils"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Output schemas of the tools, declared in tools/list. Each tool call
+// returns its result as structuredContent matching the schema, and a compact
+// text rendering of it for clients that only read content.
+
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func arraySchema(items map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": items}
+}
+
+var (
+	stringSchema  = map[string]interface{}{"type": "string"}
+	integerSchema = map[string]interface{}{"type": "integer"}
+	numberSchema  = map[string]interface{}{"type": "number"}
+	booleanSchema = map[string]interface{}{"type": "boolean"}
+)
+
+// symbolSchema describes a find-symbol definition or reference.
+var symbolSchema = objectSchema(map[string]interface{}{
+	"name":       stringSchema,
+	"kind":       stringSchema,
+	"language":   stringSchema,
+	"file_path":  stringSchema,
+	"start_line": integerSchema,
+	"end_line":   integerSchema,
+	"signature":  stringSchema,
+	"cell":       stringSchema,
+	"match":      map[string]interface{}{"type": "string", "enum": []string{symbolMatchExact, symbolMatchCaseInsensitive, symbolMatchFuzzy}},
+	"callee":     stringSchema,
+}, "name", "kind", "file_path", "start_line", "end_line")
+
+// jobSchema describes an indexing job as reported by index-status and
+// reindex.
+var jobSchema = objectSchema(map[string]interface{}{
+	"mode":        map[string]interface{}{"type": "string", "enum": []string{indexModeIncremental, indexModeFull}},
+	"state":       map[string]interface{}{"type": "string", "enum": []string{"running", "completed", "failed"}},
+	"started_at":  stringSchema,
+	"finished_at": stringSchema,
+	"files_done":  integerSchema,
+	"files_total": integerSchema,
+	"error":       stringSchema,
+}, "mode", "state", "started_at", "files_done", "files_total")
+
+var toolOutputSchemas = map[string]map[string]interface{}{
+	"codebase-retrieval": objectSchema(map[string]interface{}{
+		"results": arraySchema(objectSchema(map[string]interface{}{
+			"file_path":       stringSchema,
+			"start_line":      integerSchema,
+			"end_line":        integerSchema,
+			"content":         stringSchema,
+			"score":           numberSchema,
+			"node_type":       stringSchema,
+			"heading_path":    stringSchema,
+			"cell":            stringSchema,
+			"implementations": arraySchema(stringSchema),
+			"callers":         arraySchema(stringSchema),
+		}, "file_path", "start_line", "end_line", "content", "score")),
+	}, "results"),
+
+	"find-symbol": objectSchema(map[string]interface{}{
+		"symbol":      stringSchema,
+		"definitions": arraySchema(symbolSchema),
+		"references":  arraySchema(symbolSchema),
+	}, "symbol", "definitions"),
+
+	// file-outline returns a list of files, or a repository map in
+	// "repo-map" mode.
+	"file-outline": {
+		"type": "object",
+		"oneOf": []interface{}{
+			objectSchema(map[string]interface{}{
+				"path": stringSchema,
+				"files": arraySchema(objectSchema(map[string]interface{}{
+					"file_path": stringSchema,
+					"language":  stringSchema,
+					"symbols": arraySchema(objectSchema(map[string]interface{}{
+						"name":       stringSchema,
+						"kind":       stringSchema,
+						"start_line": integerSchema,
+						"end_line":   integerSchema,
+						"signature":  stringSchema,
+						"doc":        stringSchema,
+						"parent":     stringSchema,
+						"cell":       stringSchema,
+					}, "name", "kind", "start_line", "end_line")),
+				}, "file_path", "symbols")),
+			}, "path", "files"),
+			objectSchema(map[string]interface{}{
+				"mode":          map[string]interface{}{"const": "repo-map"},
+				"max_tokens":    integerSchema,
+				"files":         integerSchema,
+				"symbols_shown": integerSchema,
+				"symbols_total": integerSchema,
+				"truncated":     booleanSchema,
+				"map":           stringSchema,
+			}, "mode", "map"),
+		},
+	},
+
+	"index-status": objectSchema(map[string]interface{}{
+		"project_path":  stringSchema,
+		"collection":    stringSchema,
+		"model":         stringSchema,
+		"exists":        booleanSchema,
+		"points":        integerSchema,
+		"last_indexed":  stringSchema,
+		"indexed_files": integerSchema,
+		"indexed_model": stringSchema,
+		"failed_files":  arraySchema(stringSchema),
+		"pending_changes": objectSchema(map[string]interface{}{
+			"added_or_modified": integerSchema,
+			"deleted":           integerSchema,
+			"files":             arraySchema(stringSchema),
+		}, "added_or_modified", "deleted", "files"),
+		"job": jobSchema,
+	}, "project_path", "collection", "exists", "pending_changes"),
+
+	"reindex": objectSchema(map[string]interface{}{
+		"status":       map[string]interface{}{"type": "string", "enum": []string{"started", "already_running"}},
+		"project_path": stringSchema,
+		"collection":   stringSchema,
+		"job":          jobSchema,
+	}, "status", "project_path", "collection", "job"),
+
+	"clear-index": objectSchema(map[string]interface{}{
+		"project_path": stringSchema,
+		"collection":   stringSchema,
+		"cleared":      booleanSchema,
+	}, "project_path", "collection", "cleared"),
+}
+
+// toolResult wraps the result of a tool as a tools/call result.
+func toolResult(name string, result interface{}) map[string]interface{} {
+	var text string
+	switch name {
+	case "codebase-retrieval":
+		hits, _ := result.([]map[string]interface{})
+		if hits == nil {
+			hits = []map[string]interface{}{}
+		}
+		// structuredContent is an object.
+		result = map[string]interface{}{"results": hits}
+		text = searchText(hits)
+	case "find-symbol":
+		text = findSymbolText(result.(map[string]interface{}))
+	case "file-outline":
+		text = outlineText(result.(map[string]interface{}))
+	default:
+		data, _ := json.Marshal(result)
+		text = string(data)
+	}
+	return map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": text,
+			},
+		},
+		"structuredContent": result,
+	}
+}
+
+// toolError reports a failed tool call as a result, so that the model sees
+// the error and can correct the call.
+func toolError(err error) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": err.Error(),
+			},
+		},
+		"isError": true,
+	}
+}
+
+// searchText renders codebase-retrieval results as fenced snippets.
+func searchText(hits []map[string]interface{}) string {
+	if len(hits) == 0 {
+		return "No results."
+	}
+	var b strings.Builder
+	for _, hit := range hits {
+		writeSearchHit(&b, hit, 0)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// findSymbolText lists the definitions and references of a symbol, one
+// per line.
+func findSymbolText(result map[string]interface{}) string {
+	var b strings.Builder
+	definitions, _ := result["definitions"].([]map[string]interface{})
+	fmt.Fprintf(&b, "Definitions of %s (%d):\n", result["symbol"], len(definitions))
+	for _, def := range definitions {
+		b.WriteString(symbolLine(def))
+		fmt.Fprintf(&b, " [%v]\n", def["match"])
+	}
+	if references, ok := result["references"].([]map[string]interface{}); ok {
+		fmt.Fprintf(&b, "References (%d):\n", len(references))
+		for _, ref := range references {
+			b.WriteString(symbolLine(ref))
+			fmt.Fprintf(&b, " (calls %v)\n", ref["callee"])
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// symbolLine renders a find-symbol entry as "- path:start-end kind name:
+// signature".
+func symbolLine(entry map[string]interface{}) string {
+	path, _ := entry["file_path"].(string)
+	line := fmt.Sprintf("- %s:%v-%v", filepath.ToSlash(path), entry["start_line"], entry["end_line"])
+	if cell, ok := entry["cell"].(string); ok {
+		line += " (" + cell + ")"
+	}
+	line += fmt.Sprintf(" %v %v", entry["kind"], entry["name"])
+	if signature, ok := entry["signature"].(string); ok {
+		line += ": " + strings.Join(strings.Fields(signature), " ")
+	}
+	return line
+}
+
+// outlineText renders a file-outline result: the repository map as is, or
+// each file followed by its symbols.
+func outlineText(result map[string]interface{}) string {
+	if text, ok := result["map"].(string); ok {
+		if truncated, _ := result["truncated"].(bool); truncated {
+			text += fmt.Sprintf("(%v of %v symbols shown)\n", result["symbols_shown"], result["symbols_total"])
+		}
+		return text
+	}
+	var b strings.Builder
+	files, _ := result["files"].([]map[string]interface{})
+	writeOutline(&b, files)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// writeOutline writes each file of a file-outline result followed by its
+// symbols, one per line with its start line.
+func writeOutline(b *strings.Builder, files []map[string]interface{}) {
+	for _, file := range files {
+		fmt.Fprintf(b, "%s\n", file["file_path"])
+		for _, sym := range file["symbols"].([]map[string]interface{}) {
+			text, _ := sym["signature"].(string)
+			if text == "" {
+				text = fmt.Sprintf("%s %s", sym["kind"], sym["name"])
+			}
+			fmt.Fprintf(b, "  %v: %s", sym["start_line"], strings.Join(strings.Fields(text), " "))
+			if doc, ok := sym["doc"].(string); ok {
+				fmt.Fprintf(b, " // %s", doc)
+			}
+			b.WriteString("\n")
+		}
+	}
+}
+
+// writeSearchHit writes a codebase-retrieval result as a snippet under a
+// path:start-end heading, followed by the Go code linked to an rpc.
+func writeSearchHit(b *strings.Builder, hit map[string]interface{}, maxLines int) {
+	path := filepath.ToSlash(hit["file_path"].(string))
+	location := fmt.Sprintf("%s:%v-%v", path, hit["start_line"], hit["end_line"])
+	if cell, ok := hit["cell"].(string); ok {
+		location += " (" + cell + ")"
+	}
+	if heading, ok := hit["heading_path"].(string); ok {
+		location += " " + heading
+	}
+	if score, ok := hit["score"].(float32); ok {
+		location += fmt.Sprintf(" (score %.2f)", score)
+	}
+	content, _ := hit["content"].(string)
+	writeSnippet(b, location, utils.DetectLanguage(path), content, maxLines)
+	for _, key := range []string{"implementations", "callers"} {
+		if links, ok := hit[key].([]string); ok {
+			fmt.Fprintf(b, "%s: %s\n\n", key, strings.Join(links, ", "))
+		}
+	}
+}
+
+// writeSnippet writes code as a fenced block under its location, cut to
+// maxLines lines unless maxLines is 0.
+func writeSnippet(b *strings.Builder, location, language, code string, maxLines int) {
+	lines := strings.Split(strings.TrimRight(code, "\n"), "\n")
+	if maxLines > 0 && len(lines) > maxLines {
+		lines = append(lines[:maxLines], fmt.Sprintf("... (%d more lines)", len(lines)-maxLines))
+	}
+	code = strings.Join(lines, "\n")
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "%s\n%s%s\n%s\n%s\n\n", location, fence, language, code, fence)
+}
@@ -1,40 +1,80 @@
 package models
 
 type CodeChunkPayload struct {
-	FilePath      string   `json:"file_path"`
-	Language      string   `json:"language"`
-	NodeType      string   `json:"node_type"`
-	NodeName      string   `json:"node_name"`
-	StartLine     int      `json:"start_line"`
-	EndLine       int      `json:"end_line"`
-	CodeHash      string   `json:"code_hash"`
-	Content       string   `json:"content"`
-	PackageName   string   `json:"package_name"`
-	Imports       []string `json:"imports"`
-	Signature     string   `json:"signature"`
-	Receiver      string   `json:"receiver"`
-	Doc           string   `json:"doc"`
-	Callees       []string `json:"callees"`
-	ParamTypes    []string `json:"param_types"`
-	ReturnTypes   []string `json:"return_types"`
-	HasErrorReturn bool    `json:"has_error_return"`
+	FilePath        string   `json:"file_path"`
+	Language        string   `json:"language"`
+	NodeType        string   `json:"node_type"`
+	NodeName        string   `json:"node_name"`
+	StartLine       int      `json:"start_line"`
+	EndLine         int      `json:"end_line"`
+	CodeHash        string   `json:"code_hash"`
+	Content         string   `json:"content"`
+	PackageName     string   `json:"package_name"`
+	Imports         []string `json:"imports"`
+	ResolvedImports []string `json:"resolved_imports"`
+	Signature       string   `json:"signature"`
+	Receiver        string   `json:"receiver"`
+	Doc             string   `json:"doc"`
+	Callees         []string `json:"callees"`
+	ParamTypes      []string `json:"param_types"`
+	ReturnTypes     []string `json:"return_types"`
+	HasErrorReturn  bool     `json:"has_error_return"`
+	Decorators      []string `json:"decorators"`
+	IsAsync         bool     `json:"is_async"`
+	IsGenerator     bool     `json:"is_generator"`
+	Raises          []string `json:"raises"`
+	Members         []string `json:"members"`
+	TypeParams      []string `json:"type_params"`
+	Exported        bool     `json:"exported"`
+	PropsType       string   `json:"props_type"`
+	Hooks           []string `json:"hooks"`
+	JSXElements     []string `json:"jsx_elements"`
+	PropNames       []string `json:"prop_names"`
+	Emits           []string `json:"emits"`
+	HeadingPath     string   `json:"heading_path"`
+	CodeLanguages   []string `json:"code_languages"`
+	Tables          []string `json:"tables"`
+	Columns         []string `json:"columns"`
+	SQLQueries      []string `json:"sql_queries"`
+	Options         []string `json:"options"`
+	CellIndex       int      `json:"cell_index"`
 }
 
 type FunctionNode struct {
-	Name           string
-	NodeType       string
-	StartLine      int
-	EndLine        int
-	Content        string
-	PackageName    string
-	Imports        []string
-	Signature      string
-	Receiver       string
-	Doc            string
-	Callees        []string
-	ParamTypes     []string
-	ReturnTypes    []string
-	HasErrorReturn bool
+	Name            string
+	NodeType        string
+	StartLine       int
+	EndLine         int
+	Content         string
+	PackageName     string
+	Imports         []string
+	ResolvedImports []string
+	Signature       string
+	Receiver        string
+	Doc             string
+	Callees         []string
+	ParamTypes      []string
+	ReturnTypes     []string
+	HasErrorReturn  bool
+	Decorators      []string
+	IsAsync         bool
+	IsGenerator     bool
+	Raises          []string
+	Members         []string
+	TypeParams      []string
+	Exported        bool
+	PropsType       string
+	Hooks           []string
+	JSXElements     []string
+	PropNames       []string
+	Emits           []string
+	HeadingPath     string
+	CodeLanguages   []string
+	Tables          []string
+	Columns         []string
+	SQLQueries      []string
+	Options         []string
+	CellIndex       int
 }
 
 type IntentType string
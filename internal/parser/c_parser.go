@@ -0,0 +1,910 @@
+package parser
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CParser implements LanguageParser for C and C++. Both languages share one
+// implementation; lang only determines the reported language name.
+type CParser struct {
+	lang Language
+}
+
+// NewCParser creates a new C parser
+func NewCParser() *CParser {
+	return &CParser{lang: LanguageC}
+}
+
+// NewCPPParser creates a new C++ parser
+func NewCPPParser() *CParser {
+	return &CParser{lang: LanguageCPP}
+}
+
+// Language returns the language name
+func (p *CParser) Language() string {
+	return string(p.lang)
+}
+
+// cKeywords are never reported as callees or function names.
+var cKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "return": true, "sizeof": true,
+	"alignof": true, "alignas": true, "decltype": true, "catch": true, "new": true, "delete": true,
+	"static_cast": true, "dynamic_cast": true, "const_cast": true, "reinterpret_cast": true,
+	"static_assert": true, "typeid": true, "noexcept": true, "throw": true, "defined": true,
+	"__attribute__": true, "__declspec": true, "_Alignas": true, "_Static_assert": true,
+	"do": true, "else": true, "case": true, "goto": true, "co_await": true, "co_return": true,
+}
+
+// cBuiltinTypes are type keywords that can never be parameter names.
+var cBuiltinTypes = map[string]bool{
+	"void": true, "char": true, "short": true, "int": true, "long": true, "float": true,
+	"double": true, "signed": true, "unsigned": true, "bool": true, "_Bool": true,
+	"wchar_t": true, "char8_t": true, "char16_t": true, "char32_t": true, "auto": true,
+}
+
+// cSpecifiers are declaration specifiers that precede the return type.
+var cSpecifiers = map[string]bool{
+	"static": true, "inline": true, "extern": true, "virtual": true, "explicit": true,
+	"constexpr": true, "consteval": true, "constinit": true, "friend": true, "__inline": true,
+	"__forceinline": true, "register": true, "thread_local": true, "_Noreturn": true,
+}
+
+// cTypeKeywords maps class-like keywords to node types.
+var cTypeKeywords = map[string]string{
+	"class":  "class",
+	"struct": "struct",
+	"union":  "union",
+	"enum":   "enum",
+}
+
+type cScope struct {
+	namespace string // enclosing namespace path ("a::b")
+	class     string // enclosing class name ("Outer::Inner")
+	public    bool   // current access level inside a class
+}
+
+type cDeclParser struct {
+	code      []byte
+	lex       *cLexResult
+	tokens    []cToken
+	imports   []string
+	functions []FunctionNode
+}
+
+// ExtractFunctions extracts function definitions and declarations, class and
+// struct types with their methods (including out-of-line Class::method
+// definitions) from C and C++ source code. Only the first branch of each
+// preprocessor conditional is parsed so that alternative branches cannot
+// unbalance braces.
+func (p *CParser) ExtractFunctions(filePath string, code []byte) ([]FunctionNode, error) {
+	lex := lexCLike(code, cLexOptions{preprocessor: true, stringPrefix: cppRawString})
+	lex.tokens = dropInactiveBranches(lex.tokens, lex.directives)
+	cp := &cDeclParser{code: code, lex: lex, tokens: lex.tokens}
+	cp.imports = cIncludes(lex.directives)
+	cp.parseScope(0, len(lex.tokens), cScope{public: true})
+
+	sort.SliceStable(cp.functions, func(i, j int) bool {
+		return cp.functions[i].StartByte < cp.functions[j].StartByte
+	})
+	return cp.functions, nil
+}
+
+// parseScope walks the declarations in tokens[from:to] and returns the names
+// of the functions and types declared at this level.
+func (p *cDeclParser) parseScope(from, to int, scope cScope) []string {
+	var members []string
+	i := from
+	for i < to {
+		tok := p.tokens[i]
+		if tok.text == ";" || tok.text == "}" {
+			i++
+			continue
+		}
+
+		// Access specifiers: "public:", "private:", "public slots:".
+		if scope.class != "" && (tok.text == "public" || tok.text == "private" || tok.text == "protected" || tok.text == "signals" || tok.text == "slots") {
+			j := i + 1
+			if j < to && p.tokens[j].text == "slots" {
+				j++
+			}
+			if j < to && p.tokens[j].text == ":" {
+				switch tok.text {
+				case "public":
+					scope.public = true
+				case "private", "protected":
+					scope.public = false
+				}
+				i = j + 1
+				continue
+			}
+		}
+
+		// A macro invocation alone on its line (Q_OBJECT, DECLARE_X(y)).
+		if end := p.skipMacroLine(i, to); end > i {
+			i = end
+			continue
+		}
+
+		stmtStart := i
+		var typeParams, attrs []string
+		for i < to {
+			switch {
+			case p.tokens[i].text == "template" && i+1 < to && p.tokens[i+1].text == "<":
+				end := matchingClose(p.tokens, i+1)
+				if end < 0 {
+					return members
+				}
+				typeParams = tokenListTexts(p.code, p.tokens, i+2, end)
+				i = end + 1
+				continue
+			case p.tokens[i].text == "[" && i+1 < to && p.tokens[i+1].text == "[":
+				end := matchingClose(p.tokens, i)
+				if end < 0 {
+					return members
+				}
+				attrs = append(attrs, tokenSpanText(p.code, p.tokens, i+2, end-1))
+				i = end + 1
+				continue
+			}
+			break
+		}
+		if i >= to {
+			break
+		}
+
+		switch p.tokens[i].text {
+		case "namespace":
+			j := i + 1
+			for j < to && p.tokens[j].text != "{" && p.tokens[j].text != ";" && p.tokens[j].text != "=" {
+				j++
+			}
+			if j < to && p.tokens[j].text == "{" {
+				end := matchingClose(p.tokens, j)
+				if end < 0 {
+					return members
+				}
+				inner := scope
+				if name := strings.ReplaceAll(tokenSpanText(p.code, p.tokens, i+1, j), " ", ""); name != "" {
+					inner.namespace = joinCQualified(scope.namespace, name)
+				}
+				p.parseScope(j+1, end, inner)
+				i = end + 1
+				continue
+			}
+			i = p.skipStatement(j, to)
+			continue
+		case "extern":
+			if i+2 < to && p.tokens[i+1].kind == cTokString && p.tokens[i+2].text == "{" {
+				end := matchingClose(p.tokens, i+2)
+				if end < 0 {
+					return members
+				}
+				members = append(members, p.parseScope(i+3, end, scope)...)
+				i = end + 1
+				continue
+			}
+		case "using", "static_assert", "_Static_assert", "friend", "return":
+			i = p.skipStatement(i, to)
+			continue
+		}
+
+		end, name := p.parseDeclaration(stmtStart, i, to, scope, typeParams, attrs)
+		if name != "" && !slices.Contains(members, name) {
+			members = append(members, name)
+		}
+		if end <= i {
+			end = i + 1
+		}
+		i = end
+	}
+	return members
+}
+
+// parseDeclaration handles one declaration starting at declStart (after
+// templates and attributes). It returns the index after the declaration and
+// the declared function or type name.
+func (p *cDeclParser) parseDeclaration(stmtStart, declStart, to int, scope cScope, typeParams, attrs []string) (int, string) {
+	typeKeyword := -1
+	for i := declStart; i < to; i++ {
+		tok := p.tokens[i]
+		switch tok.text {
+		case ";":
+			return i + 1, ""
+		case "=":
+			return p.skipStatement(i, to), ""
+		case "{":
+			if typeKeyword >= 0 {
+				return p.parseType(stmtStart, declStart, typeKeyword, i, to, scope, typeParams, attrs)
+			}
+			if i > declStart && p.tokens[i-1].text == ")" {
+				// The body of a function not recognized above: resume
+				// after it rather than at the next ';' in a later
+				// declaration.
+				if end := matchingClose(p.tokens, i); end > 0 {
+					return end + 1, ""
+				}
+			}
+			return p.skipStatement(i, to), ""
+		case "<":
+			if end := matchingClose(p.tokens, i); end > 0 {
+				i = end
+			}
+		case "[":
+			if end := matchingClose(p.tokens, i); end > 0 {
+				i = end
+			}
+		case "(":
+			closeIdx := matchingClose(p.tokens, i)
+			if closeIdx < 0 {
+				return to, ""
+			}
+			if end, name, ok := p.parseFunction(stmtStart, declStart, i, closeIdx, to, scope, typeParams, attrs); ok {
+				return end, name
+			}
+			if i+1 < closeIdx && p.tokens[i+1].text == "*" {
+				// A pointer declarator, which may hold a function
+				// returning a function pointer: "int (*name(int))(int)".
+				continue
+			}
+			i = closeIdx
+		default:
+			if _, ok := cTypeKeywords[tok.text]; ok && typeKeyword < 0 {
+				typeKeyword = i
+			}
+		}
+	}
+	return to, ""
+}
+
+// parseFunction checks whether the parenthesis at open starts the parameter
+// list of a function definition or declaration.
+func (p *cDeclParser) parseFunction(stmtStart, declStart, open, closeIdx, to int, scope cScope, typeParams, attrs []string) (int, string, bool) {
+	nameIdx := open - 1
+	if nameIdx < declStart {
+		return 0, "", false
+	}
+	// Operator overloads: operator==, operator(), operator new.
+	opIdx := -1
+	for k := nameIdx; k >= declStart && k >= nameIdx-3; k-- {
+		if p.tokens[k].text == "operator" {
+			opIdx = k
+			break
+		}
+	}
+	if opIdx < 0 && (p.tokens[nameIdx].kind != cTokIdent || cKeywords[p.tokens[nameIdx].text] || cBuiltinTypes[p.tokens[nameIdx].text]) {
+		return 0, "", false
+	}
+	if opIdx >= 0 && p.tokens[open].text == "(" && nameIdx == opIdx {
+		// "operator()(...)": the first group is part of the name.
+		return 0, "", false
+	}
+
+	nameStart := nameIdx
+	if opIdx >= 0 {
+		nameStart = opIdx
+	}
+	if nameStart > declStart && p.tokens[nameStart-1].text == "~" {
+		nameStart--
+	}
+	// Qualifiers: ns::Class<T>::method.
+	for nameStart-2 >= declStart && p.tokens[nameStart-1].text == "::" {
+		q := nameStart - 2
+		if p.tokens[q].text == ">" {
+			depth := 0
+			for ; q >= declStart; q-- {
+				if p.tokens[q].text == ">" {
+					depth++
+				} else if p.tokens[q].text == "<" {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			q--
+		}
+		if q < declStart || p.tokens[q].kind != cTokIdent {
+			break
+		}
+		nameStart = q
+	}
+	if nameStart-1 >= declStart && p.tokens[nameStart-1].text == "::" {
+		nameStart--
+	}
+
+	// A function returning a function pointer: "int (*name(params))(int)".
+	// The declarator is wrapped in parentheses and followed by the
+	// parameters of the returned function.
+	outerOpen, outerClose := -1, -1
+	if nameStart-2 >= declStart && p.tokens[nameStart-1].text == "*" && p.tokens[nameStart-2].text == "(" && matchingClose(p.tokens, nameStart-2) == closeIdx+1 {
+		outerOpen, outerClose = nameStart-2, closeIdx+1
+	}
+
+	// Qualifiers after the parameter list, up to the body or terminator.
+	k := closeIdx + 1
+	if outerOpen >= 0 {
+		k = outerClose + 1
+		for k < to && (p.tokens[k].text == "(" || p.tokens[k].text == "[") {
+			end := matchingClose(p.tokens, k)
+			if end < 0 {
+				return 0, "", false
+			}
+			k = end + 1
+		}
+	}
+	var trailingReturn []string
+	for k < to {
+		text := p.tokens[k].text
+		switch {
+		case text == "const" || text == "volatile" || text == "override" || text == "final" || text == "&" || text == "&&" || text == "mutable":
+			k++
+			continue
+		case text == "noexcept" || text == "throw" || text == "__attribute__" || text == "requires":
+			k++
+			if k < to && p.tokens[k].text == "(" {
+				if end := matchingClose(p.tokens, k); end > 0 {
+					k = end + 1
+				}
+			}
+			continue
+		case text == "[" && k+1 < to && p.tokens[k+1].text == "[":
+			if end := matchingClose(p.tokens, k); end > 0 {
+				k = end + 1
+				continue
+			}
+		case text == "->":
+			start := k + 1
+			k = start
+			for k < to && p.tokens[k].text != "{" && p.tokens[k].text != ";" && p.tokens[k].text != "=" {
+				if p.tokens[k].text == "<" || p.tokens[k].text == "(" {
+					if end := matchingClose(p.tokens, k); end > 0 {
+						k = end
+					}
+				}
+				k++
+			}
+			if ret := tokenSpanText(p.code, p.tokens, start, k); ret != "" {
+				trailingReturn = []string{ret}
+			}
+			continue
+		case p.tokens[k].kind == cTokIdent && isCMacroName(text):
+			// Annotation macros such as Q_DECL_OVERRIDE or NOEXCEPT.
+			k++
+			continue
+		}
+		break
+	}
+
+	isDefinition := false
+	bodyStart := -1
+	switch {
+	case k < to && p.tokens[k].text == "{":
+		isDefinition = true
+		bodyStart = k
+	case k < to && p.tokens[k].text == ":" && k+1 < to && p.tokens[k+1].text != ":":
+		// Constructor initializer list: ": a(x), b{y} {".
+		bodyStart = p.skipInitializerList(k+1, to)
+		if bodyStart < 0 {
+			return 0, "", false
+		}
+		isDefinition = true
+	case k < to && (p.tokens[k].text == ";" || p.tokens[k].text == "="):
+		// Prototype, pure virtual (= 0), defaulted or deleted function.
+	case k < to && p.tokens[k].text == ",":
+		// A declarator list such as "int a(1), b;" is a variable.
+		return 0, "", false
+	default:
+		return 0, "", false
+	}
+
+	baseName := tokenSpanText(p.code, p.tokens, nameIdx, open)
+	if nameIdx > 0 && p.tokens[nameIdx-1].text == "~" {
+		baseName = "~" + baseName
+	}
+	if opIdx >= 0 {
+		baseName = strings.ReplaceAll(tokenSpanText(p.code, p.tokens, opIdx, open), " ", "")
+		if baseName == "operator" {
+			baseName = "operator" + tokenSpanText(p.code, p.tokens, open, closeIdx+1)
+		}
+	}
+	qualifiedName := strings.ReplaceAll(tokenSpanText(p.code, p.tokens, nameStart, open), " ", "")
+	qualifiedName = strings.TrimPrefix(qualifiedName, "::")
+	if opIdx >= 0 && strings.HasSuffix(qualifiedName, "operator") {
+		qualifiedName += tokenSpanText(p.code, p.tokens, open, closeIdx+1)
+	}
+	receiver := ""
+	if idx := strings.LastIndex(qualifiedName, "::"+baseName); idx > 0 {
+		receiver = stripCTemplateArgs(qualifiedName[:idx])
+		qualifiedName = receiver + "::" + baseName
+	} else if scope.class != "" {
+		receiver = scope.class
+	}
+
+	// Return type: tokens between the specifiers and the name.
+	retEnd := nameStart
+	if outerOpen >= 0 {
+		retEnd = outerOpen
+	}
+	retStart := declStart
+	static := false
+	for retStart < retEnd {
+		text := p.tokens[retStart].text
+		if text == "static" {
+			static = true
+		}
+		if cSpecifiers[text] || (p.tokens[retStart].kind == cTokIdent && isCMacroName(text) && retStart+1 < retEnd) {
+			retStart++
+			continue
+		}
+		if text == "__attribute__" || text == "__declspec" || text == "alignas" {
+			if retStart+1 < retEnd && p.tokens[retStart+1].text == "(" {
+				if end := matchingClose(p.tokens, retStart+1); end > 0 {
+					retStart = end + 1
+					continue
+				}
+			}
+		}
+		break
+	}
+	returnText := tokenSpanText(p.code, p.tokens, retStart, retEnd)
+	if outerOpen >= 0 && returnText != "" {
+		returnText += " (*)" + tokenSpanText(p.code, p.tokens, outerClose+1, k)
+	}
+
+	simpleClass := receiver
+	if idx := strings.LastIndex(simpleClass, "::"); idx >= 0 {
+		simpleClass = simpleClass[idx+2:]
+	}
+	isCtor := receiver != "" && (baseName == simpleClass || baseName == "~"+simpleClass)
+
+	macroBlock := false
+	if returnText == "" && !isCtor {
+		if !isDefinition || !isCMacroName(baseName) {
+			return 0, "", false
+		}
+		// Macro-defined blocks such as TEST(Suite, Name) { ... }.
+		qualifiedName = strings.ReplaceAll(tokenSpanText(p.code, p.tokens, nameIdx, closeIdx+1), " ", "")
+		qualifiedName = strings.ReplaceAll(qualifiedName, ",", ", ")
+		macroBlock = true
+	}
+
+	end := k
+	if !isDefinition {
+		end = p.skipStatement(k, to) - 1
+		if end < k {
+			end = k
+		}
+	}
+	var bodyEnd int
+	if isDefinition {
+		bodyEnd = matchingClose(p.tokens, bodyStart)
+		if bodyEnd < 0 {
+			return to, "", true
+		}
+		end = bodyEnd
+	}
+
+	name := qualifiedName
+	if scope.class != "" && !strings.Contains(qualifiedName, "::") {
+		name = scope.class + "::" + qualifiedName
+	}
+
+	nodeType := "function"
+	switch {
+	case !isDefinition:
+		nodeType = "declaration"
+	case isCtor && strings.Contains(name, "~"):
+		nodeType = "destructor"
+	case isCtor:
+		nodeType = "constructor"
+	case receiver != "":
+		nodeType = "method"
+	}
+
+	node := newCFunctionNode(p.code, p.lex.lineStarts, name, nodeType, p.tokens[stmtStart].start, p.tokens[end].end)
+	node.PackageName = scope.namespace
+	node.Imports = append([]string(nil), p.imports...)
+	sigEnd := k
+	if isDefinition {
+		sigEnd = bodyStart
+		if p.tokens[k].text == ":" {
+			sigEnd = k
+		}
+	}
+	node.Signature = tokenSpanText(p.code, p.tokens, declStart, sigEnd)
+	node.Receiver = receiver
+	node.Doc = p.docBefore(stmtStart)
+	if isDefinition {
+		node.Callees = cCallees(p.tokens[bodyStart+1:bodyEnd], cKeywords)
+	}
+	if !macroBlock {
+		node.ParamTypes = cParamTypes(p.code, p.tokens, open+1, closeIdx)
+	}
+	switch {
+	case len(trailingReturn) > 0:
+		node.ReturnTypes = trailingReturn
+	case returnText != "" && returnText != "void":
+		node.ReturnTypes = []string{returnText}
+	}
+	node.Decorators = attrs
+	node.TypeParams = typeParams
+	if scope.class != "" {
+		node.Exported = scope.public
+	} else {
+		node.Exported = !static
+	}
+	p.functions = append(p.functions, node)
+	return end + 1, baseName, true
+}
+
+// parseType handles class, struct, union and enum definitions whose body
+// opens at bodyIdx.
+func (p *cDeclParser) parseType(stmtStart, declStart, kwIdx, bodyIdx, to int, scope cScope, typeParams, attrs []string) (int, string) {
+	kind := cTypeKeywords[p.tokens[kwIdx].text]
+	nameEnd := bodyIdx
+	// Base clause or enum underlying type: "class A : public B {".
+	for k := kwIdx + 1; k < bodyIdx; k++ {
+		if p.tokens[k].text == ":" {
+			nameEnd = k
+			break
+		}
+	}
+	name := ""
+	for k := nameEnd - 1; k > kwIdx; k-- {
+		tok := p.tokens[k]
+		if tok.kind == cTokIdent && tok.text != "final" && tok.text != "class" && tok.text != "struct" {
+			name = tok.text
+			break
+		}
+		if tok.text == ">" || tok.text == ")" || tok.text == "]" {
+			// Skip template arguments of specializations and attribute lists.
+			depth := 0
+			for ; k > kwIdx; k-- {
+				switch p.tokens[k].text {
+				case ">", ")", "]":
+					depth++
+				case "<", "(", "[":
+					depth--
+				}
+				if depth == 0 {
+					break
+				}
+			}
+		}
+	}
+
+	bodyEnd := matchingClose(p.tokens, bodyIdx)
+	if bodyEnd < 0 {
+		return to, ""
+	}
+	end := bodyEnd
+	// typedef struct { ... } Name;
+	if name == "" && bodyEnd+1 < to && p.tokens[bodyEnd+1].kind == cTokIdent {
+		name = p.tokens[bodyEnd+1].text
+	}
+	if stmtEnd := p.skipStatement(bodyEnd+1, to); stmtEnd-1 > end && p.tokens[stmtEnd-1].text == ";" {
+		end = stmtEnd - 1
+	}
+	if name == "" {
+		return end + 1, ""
+	}
+
+	qualified := joinCQualified(scope.class, name)
+	var members []string
+	if kind == "enum" {
+		for _, part := range splitTokensTopLevel(p.tokens, bodyIdx+1, bodyEnd, ",", true) {
+			if part[0] < part[1] && p.tokens[part[0]].kind == cTokIdent {
+				members = append(members, p.tokens[part[0]].text)
+			}
+		}
+	} else {
+		members = p.parseScope(bodyIdx+1, bodyEnd, cScope{namespace: scope.namespace, class: qualified, public: kind != "class"})
+	}
+
+	node := newCFunctionNode(p.code, p.lex.lineStarts, qualified, kind, p.tokens[stmtStart].start, p.tokens[end].end)
+	node.PackageName = scope.namespace
+	node.Imports = append([]string(nil), p.imports...)
+	node.Signature = tokenSpanText(p.code, p.tokens, declStart, bodyIdx)
+	node.Doc = p.docBefore(stmtStart)
+	node.Decorators = attrs
+	node.TypeParams = typeParams
+	node.Members = members
+	node.Exported = scope.class == "" || scope.public
+	if scope.class != "" {
+		node.Receiver = scope.class
+	}
+	p.functions = append(p.functions, node)
+	return end + 1, name
+}
+
+// skipInitializerList skips "a(x), b{y}" after a constructor's ':' and
+// returns the index of the body's "{", or -1.
+func (p *cDeclParser) skipInitializerList(i, to int) int {
+	for i < to {
+		// Member or base name, possibly qualified or templated.
+		for i < to && (p.tokens[i].kind == cTokIdent || p.tokens[i].text == "::") {
+			i++
+			if i < to && p.tokens[i].text == "<" {
+				if end := matchingClose(p.tokens, i); end > 0 {
+					i = end + 1
+				}
+			}
+		}
+		if i >= to || (p.tokens[i].text != "(" && p.tokens[i].text != "{") {
+			return -1
+		}
+		end := matchingClose(p.tokens, i)
+		if end < 0 {
+			return -1
+		}
+		i = end + 1
+		if i < to && p.tokens[i].text == "..." {
+			i++
+		}
+		if i < to && p.tokens[i].text == "," {
+			i++
+			continue
+		}
+		if i < to && p.tokens[i].text == "{" {
+			return i
+		}
+		return -1
+	}
+	return -1
+}
+
+// skipStatement advances past the statement containing tokens[i]: up to and
+// including the next ';' at depth zero, skipping bracketed groups.
+func (p *cDeclParser) skipStatement(i, to int) int {
+	for i < to {
+		switch p.tokens[i].text {
+		case ";":
+			return i + 1
+		case "(", "[", "{":
+			end := matchingClose(p.tokens, i)
+			if end < 0 {
+				return to
+			}
+			i = end
+		case "}":
+			return i
+		}
+		i++
+	}
+	return to
+}
+
+// skipMacroLine returns the index after a macro invocation that occupies its
+// own line without a terminating ';' (e.g. Q_OBJECT), or i if there is none.
+func (p *cDeclParser) skipMacroLine(i, to int) int {
+	if p.tokens[i].kind != cTokIdent || !isCMacroName(p.tokens[i].text) {
+		return i
+	}
+	end := i + 1
+	if end < to && p.tokens[end].text == "(" && p.tokens[end].line == p.tokens[i].line {
+		closeIdx := matchingClose(p.tokens, end)
+		if closeIdx < 0 {
+			return i
+		}
+		end = closeIdx + 1
+	}
+	if end >= to || p.tokens[end].line > p.tokens[end-1].line && p.tokens[end].text != "{" && p.tokens[end].text != ";" {
+		return end
+	}
+	return i
+}
+
+func (p *cDeclParser) docBefore(tokenIdx int) string {
+	return docCommentBefore(p.code, p.lex.comments, p.tokens[tokenIdx].start, func(string) bool { return true })
+}
+
+// isCMacroName reports whether name follows the ALL_CAPS macro convention.
+func isCMacroName(name string) bool {
+	hasLetter := false
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		switch {
+		case ch >= 'A' && ch <= 'Z':
+			hasLetter = true
+		case ch == '_' || isASCIIDigit(ch):
+		default:
+			return false
+		}
+	}
+	return hasLetter && len(name) > 1
+}
+
+func joinCQualified(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "::" + name
+}
+
+// stripCTemplateArgs removes template argument lists: "Foo<T>::Bar<U>" ->
+// "Foo::Bar".
+func stripCTemplateArgs(name string) string {
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		default:
+			if depth == 0 {
+				b.WriteByte(name[i])
+			}
+		}
+	}
+	return b.String()
+}
+
+// cParamTypes returns the parameter types in tokens[from:to], dropping
+// parameter names and default values.
+func cParamTypes(code []byte, tokens []cToken, from, to int) []string {
+	var types []string
+	for _, part := range splitTokensTopLevel(tokens, from, to, ",", true) {
+		start, end := part[0], part[1]
+		for k := start; k < end; k++ {
+			if tokens[k].text == "=" {
+				end = k
+				break
+			}
+		}
+		if end-start == 1 && tokens[start].text == "void" {
+			continue
+		}
+		arraySuffix := ""
+		for end-start > 1 && tokens[end-1].text == "]" {
+			depth := 0
+			for end > start {
+				end--
+				if tokens[end].text == "]" {
+					depth++
+				} else if tokens[end].text == "[" {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			arraySuffix += "[]"
+		}
+		// Drop the trailing parameter name unless it is part of the type.
+		if end-start > 1 && tokens[end-1].kind == cTokIdent && !cBuiltinTypes[tokens[end-1].text] && tokens[end-2].text != "::" && tokens[end-1].text != "const" {
+			end--
+		}
+		if t := tokenSpanText(code, tokens, start, end); t != "" {
+			types = append(types, t+arraySuffix)
+		}
+	}
+	return types
+}
+
+// cIncludes returns the headers named by #include (and Objective-C #import)
+// directives, without their delimiters.
+func cIncludes(directives []cComment) []string {
+	seen := make(map[string]struct{})
+	var includes []string
+	for _, d := range directives {
+		text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(d.text), "#"))
+		var rest string
+		switch {
+		case strings.HasPrefix(text, "include_next"):
+			rest = text[len("include_next"):]
+		case strings.HasPrefix(text, "include"):
+			rest = text[len("include"):]
+		case strings.HasPrefix(text, "import"):
+			rest = text[len("import"):]
+		default:
+			continue
+		}
+		rest = strings.TrimSpace(rest)
+		if len(rest) < 2 {
+			continue
+		}
+		closing := byte('"')
+		if rest[0] == '<' {
+			closing = '>'
+		} else if rest[0] != '"' {
+			continue
+		}
+		end := strings.IndexByte(rest[1:], closing)
+		if end < 0 {
+			continue
+		}
+		header := rest[1 : end+1]
+		if _, ok := seen[header]; ok {
+			continue
+		}
+		seen[header] = struct{}{}
+		includes = append(includes, header)
+	}
+	sort.Strings(includes)
+	return includes
+}
+
+// dropInactiveBranches removes the tokens of #elif/#else branches so that
+// only the first branch of each conditional is parsed.
+func dropInactiveBranches(tokens []cToken, directives []cComment) []cToken {
+	type span struct{ start, end int }
+	var skipped []span
+	depth := 0
+	skipDepth := 0 // conditional depth at which skipping started, 0 if none
+	skipStart := 0
+	for _, d := range directives {
+		text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(d.text), "#"))
+		word := text
+		if idx := strings.IndexAny(text, " \t("); idx >= 0 {
+			word = text[:idx]
+		}
+		switch word {
+		case "if", "ifdef", "ifndef":
+			depth++
+		case "elif", "else", "elifdef", "elifndef":
+			if skipDepth == 0 {
+				skipDepth = depth
+				skipStart = d.end
+			}
+		case "endif":
+			if skipDepth != 0 && depth == skipDepth {
+				skipped = append(skipped, span{skipStart, d.start})
+				skipDepth = 0
+			}
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	if skipDepth != 0 {
+		skipped = append(skipped, span{skipStart, int(^uint(0) >> 1)})
+	}
+	if len(skipped) == 0 {
+		return tokens
+	}
+
+	kept := tokens[:0:0]
+	s := 0
+	for _, tok := range tokens {
+		for s < len(skipped) && tok.start >= skipped[s].end {
+			s++
+		}
+		if s < len(skipped) && tok.start >= skipped[s].start {
+			continue
+		}
+		kept = append(kept, tok)
+	}
+	return kept
+}
+
+// cppRawString matches C++11 raw string literals: R"delim( ... )delim",
+// optionally prefixed by u8, u, U or L.
+func cppRawString(code []byte, pos int) int {
+	i := pos
+	for _, prefix := range []string{"u8", "u", "U", "L"} {
+		if strings.HasPrefix(string(code[i:min(i+len(prefix), len(code))]), prefix) {
+			i += len(prefix)
+			break
+		}
+	}
+	if i+1 >= len(code) || code[i] != 'R' || code[i+1] != '"' {
+		return -1
+	}
+	open := i + 2
+	paren := open
+	for paren < len(code) && code[paren] != '(' && paren-open <= 16 {
+		paren++
+	}
+	if paren >= len(code) || code[paren] != '(' {
+		return -1
+	}
+	closing := ")" + string(code[open:paren]) + "\""
+	if end := strings.Index(string(code[paren+1:]), closing); end >= 0 {
+		return paren + 1 + end + len(closing)
+	}
+	return len(code)
+}
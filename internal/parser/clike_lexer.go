@@ -0,0 +1,492 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+// The brace-delimited languages (Rust, Java, Kotlin, C/C++, C#, PHP) share a
+// lexical core: identifiers, string and character literals, // and /* */
+// comments and balanced brackets. lexCLike turns such source into a flat token
+// stream plus the comments it skipped, and each language parser walks the
+// tokens with the helpers below. Language differences are expressed through
+// cLexOptions rather than separate lexers.
+
+type cTokenKind int
+
+const (
+	cTokIdent cTokenKind = iota
+	cTokNumber
+	cTokString
+	cTokPunct
+)
+
+type cToken struct {
+	kind  cTokenKind
+	text  string
+	start int // byte offset of the first character
+	end   int // byte offset just past the token
+	line  int // 1-indexed line of the first character
+}
+
+// cComment is a comment (or preprocessor directive) skipped by the lexer.
+type cComment struct {
+	text    string
+	start   int
+	end     int
+	line    int
+	endLine int
+}
+
+type cLexOptions struct {
+	// nestedComments allows /* /* */ */ nesting (Rust, Kotlin).
+	nestedComments bool
+	// lifetimes treats 'a as a single identifier token instead of a
+	// character literal (Rust).
+	lifetimes bool
+	// preprocessor collects lines starting with '#' as directives (C, C++, C#).
+	preprocessor bool
+	// hashComments treats '#' as a line comment start (PHP), except "#[".
+	hashComments bool
+	// identChars lists extra characters allowed inside identifiers, such as
+	// '$' for Java/PHP variables.
+	identChars string
+	// stringPrefix returns the end offset of a language-specific string
+	// literal starting at pos (raw, verbatim or triple-quoted strings), or -1.
+	stringPrefix func(code []byte, pos int) int
+}
+
+type cLexResult struct {
+	tokens     []cToken
+	comments   []cComment
+	directives []cComment
+	lineStarts []int
+}
+
+// cMultiCharPuncts are punctuators kept as a single token. Angle brackets are
+// intentionally never merged so that generic argument lists stay balanced.
+var cMultiCharPuncts = []string{"::", "->", "=>", "?.", "...", "..", "&&", "||", "==", "!=", "<=", "+=", "-=", "*=", "/="}
+
+func lexCLike(code []byte, opts cLexOptions) *cLexResult {
+	res := &cLexResult{lineStarts: buildLineOffsets(code)}
+	atLineStart := true
+	pos := 0
+
+	lineAt := func(offset int) int {
+		return sort.Search(len(res.lineStarts), func(i int) bool { return res.lineStarts[i] > offset })
+	}
+
+	for pos < len(code) {
+		ch := code[pos]
+		if ch == '\n' {
+			atLineStart = true
+			pos++
+			continue
+		}
+		if ch == ' ' || ch == '\t' || ch == '\r' || ch == '\f' || ch == '\v' {
+			pos++
+			continue
+		}
+
+		start := pos
+		line := lineAt(start)
+		if opts.preprocessor && atLineStart && ch == '#' {
+			for pos < len(code) && code[pos] != '\n' {
+				if code[pos] == '\\' && pos+1 < len(code) && code[pos+1] == '\n' {
+					pos += 2
+					continue
+				}
+				if code[pos] == '/' && pos+1 < len(code) && code[pos+1] == '*' {
+					pos = skipCBlockComment(code, pos, false)
+					continue
+				}
+				pos++
+			}
+			res.directives = append(res.directives, cComment{text: string(code[start:pos]), start: start, end: pos, line: line, endLine: lineAt(pos)})
+			continue
+		}
+		atLineStart = false
+
+		switch {
+		case ch == '/' && pos+1 < len(code) && code[pos+1] == '/',
+			opts.hashComments && ch == '#' && !(pos+1 < len(code) && code[pos+1] == '['):
+			for pos < len(code) && code[pos] != '\n' {
+				pos++
+			}
+			res.comments = append(res.comments, cComment{text: string(code[start:pos]), start: start, end: pos, line: line, endLine: line})
+			continue
+		case ch == '/' && pos+1 < len(code) && code[pos+1] == '*':
+			pos = skipCBlockComment(code, pos, opts.nestedComments)
+			endLine := lineAt(pos - 1)
+			res.comments = append(res.comments, cComment{text: string(code[start:pos]), start: start, end: pos, line: line, endLine: endLine})
+			continue
+		}
+
+		if opts.stringPrefix != nil {
+			if end := opts.stringPrefix(code, pos); end > pos {
+				res.tokens = append(res.tokens, cToken{kind: cTokString, text: string(code[start:end]), start: start, end: end, line: line})
+				pos = end
+				continue
+			}
+		}
+
+		switch {
+		case ch == '"':
+			pos = skipCQuoted(code, pos, '"')
+			res.tokens = append(res.tokens, cToken{kind: cTokString, text: string(code[start:pos]), start: start, end: pos, line: line})
+		case ch == '\'':
+			if opts.lifetimes && pos+2 < len(code) && isCIdentStart(code[pos+1], "") && code[pos+2] != '\'' {
+				// Lifetime or loop label: 'a, 'static. A char literal like
+				// 'a' has its closing quote right after one character.
+				pos++
+				for pos < len(code) && isCIdentPart(code[pos], "") {
+					pos++
+				}
+				res.tokens = append(res.tokens, cToken{kind: cTokIdent, text: string(code[start:pos]), start: start, end: pos, line: line})
+				continue
+			}
+			pos = skipCQuoted(code, pos, '\'')
+			res.tokens = append(res.tokens, cToken{kind: cTokString, text: string(code[start:pos]), start: start, end: pos, line: line})
+		case isCIdentStart(ch, opts.identChars):
+			for pos < len(code) && isCIdentPart(code[pos], opts.identChars) {
+				pos++
+			}
+			res.tokens = append(res.tokens, cToken{kind: cTokIdent, text: string(code[start:pos]), start: start, end: pos, line: line})
+		case isASCIIDigit(ch):
+			for pos < len(code) && (isCIdentPart(code[pos], "") || code[pos] == '.' && pos+1 < len(code) && isASCIIDigit(code[pos+1])) {
+				pos++
+			}
+			res.tokens = append(res.tokens, cToken{kind: cTokNumber, text: string(code[start:pos]), start: start, end: pos, line: line})
+		case ch >= 0x80:
+			// Non-ASCII bytes only appear in identifiers outside of strings
+			// and comments; keep them attached to the identifier.
+			for pos < len(code) && (code[pos] >= 0x80 || isCIdentPart(code[pos], opts.identChars)) {
+				pos++
+			}
+			res.tokens = append(res.tokens, cToken{kind: cTokIdent, text: string(code[start:pos]), start: start, end: pos, line: line})
+		default:
+			text := string(ch)
+			for _, p := range cMultiCharPuncts {
+				if strings.HasPrefix(string(code[pos:min(pos+len(p), len(code))]), p) {
+					text = p
+					break
+				}
+			}
+			pos += len(text)
+			res.tokens = append(res.tokens, cToken{kind: cTokPunct, text: text, start: start, end: pos, line: line})
+		}
+	}
+	return res
+}
+
+func skipCBlockComment(code []byte, pos int, nested bool) int {
+	depth := 0
+	for pos < len(code) {
+		switch {
+		case code[pos] == '/' && pos+1 < len(code) && code[pos+1] == '*':
+			if depth == 0 || nested {
+				depth++
+			}
+			pos += 2
+		case code[pos] == '*' && pos+1 < len(code) && code[pos+1] == '/':
+			depth--
+			pos += 2
+			if depth == 0 {
+				return pos
+			}
+		default:
+			pos++
+		}
+	}
+	return len(code)
+}
+
+// skipCQuoted skips a quoted literal with backslash escapes starting at pos.
+func skipCQuoted(code []byte, pos int, quote byte) int {
+	pos++
+	for pos < len(code) {
+		switch code[pos] {
+		case '\\':
+			pos += 2
+			continue
+		case quote:
+			return pos + 1
+		case '\n':
+			if quote == '\'' {
+				// An unterminated char literal; do not swallow the file.
+				return pos
+			}
+		}
+		pos++
+	}
+	return len(code)
+}
+
+func isCIdentStart(ch byte, extra string) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (extra != "" && strings.IndexByte(extra, ch) >= 0)
+}
+
+func isCIdentPart(ch byte, extra string) bool {
+	return isCIdentStart(ch, extra) || isASCIIDigit(ch)
+}
+
+// matchingClose returns the index of the token closing the bracket at
+// tokens[i] ("(", "[", "{" or "<"), or -1. For "<" only angle brackets are
+// counted and the scan stops at tokens that cannot appear in type arguments.
+func matchingClose(tokens []cToken, i int) int {
+	open := tokens[i].text
+	var closeText string
+	switch open {
+	case "(":
+		closeText = ")"
+	case "[":
+		closeText = "]"
+	case "{":
+		closeText = "}"
+	case "<":
+		closeText = ">"
+	default:
+		return -1
+	}
+	depth := 0
+	for j := i; j < len(tokens); j++ {
+		tok := tokens[j]
+		if tok.kind != cTokPunct {
+			continue
+		}
+		if open == "<" {
+			switch tok.text {
+			case "<":
+				depth++
+			case ">":
+				depth--
+				if depth == 0 {
+					return j
+				}
+			case ";", "{", "}", "&&", "||":
+				return -1
+			case "(", "[":
+				if k := matchingClose(tokens, j); k > j {
+					j = k
+				}
+			}
+			continue
+		}
+		switch tok.text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+			if depth == 0 {
+				if tok.text != closeText {
+					return -1
+				}
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// tokenSpanText returns the source text covered by tokens[from:to] with
+// whitespace runs collapsed to single spaces.
+func tokenSpanText(code []byte, tokens []cToken, from, to int) string {
+	if from >= to || from < 0 || to > len(tokens) {
+		return ""
+	}
+	return collapseWhitespace(string(code[tokens[from].start:tokens[to-1].end]))
+}
+
+// docCommentBefore returns the text of the doc comments ending right before
+// offset (separated only by whitespace or the given attribute tokens).
+// accept reports whether a comment counts as documentation.
+func docCommentBefore(code []byte, comments []cComment, offset int, accept func(text string) bool) string {
+	idx := sort.Search(len(comments), func(i int) bool { return comments[i].start >= offset })
+	var parts []string
+	end := offset
+	for i := idx - 1; i >= 0; i-- {
+		c := comments[i]
+		if strings.TrimSpace(string(code[c.end:end])) != "" {
+			break
+		}
+		// A blank line between comment and declaration ends the doc block.
+		if strings.Count(string(code[c.end:end]), "\n") > 1 {
+			break
+		}
+		if !accept(c.text) {
+			break
+		}
+		parts = append([]string{c.text}, parts...)
+		end = c.start
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return cleanCDocComment(strings.Join(parts, "\n"))
+}
+
+// cleanCDocComment strips comment markers ("///", "//", "--", "/**", "*/",
+// leading "*") and returns the trimmed documentation text.
+func cleanCDocComment(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "///"):
+			line = strings.TrimPrefix(line, "///")
+		case strings.HasPrefix(line, "//"):
+			line = strings.TrimPrefix(line, "//")
+		case strings.HasPrefix(line, "#"):
+			line = strings.TrimPrefix(line, "#")
+		case strings.HasPrefix(line, "--"):
+			line = strings.TrimPrefix(line, "--")
+		}
+		line = strings.TrimPrefix(line, "/**")
+		line = strings.TrimPrefix(line, "/*")
+		line = strings.TrimSuffix(line, "*/")
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "*") {
+			line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cCallees collects the names of calls made within tokens: "foo(",
+// "obj.method(", "pkg::func(" and, for Rust, "macro!(". Names in skip
+// (control-flow keywords) are ignored. The result is sorted and deduplicated.
+func cCallees(tokens []cToken, skip map[string]bool) []string {
+	seen := make(map[string]struct{})
+	var callees []string
+	for i, tok := range tokens {
+		if tok.kind != cTokPunct || tok.text != "(" || i == 0 {
+			continue
+		}
+		j := i - 1
+		if tokens[j].text == "!" && j > 0 && tokens[j-1].kind == cTokIdent {
+			name := tokens[j-1].text + "!"
+			if _, ok := seen[name]; !ok {
+				seen[name] = struct{}{}
+				callees = append(callees, name)
+			}
+			continue
+		}
+		// Skip generic arguments between the name and "(": foo::<T>(, bar<T>(.
+		if tokens[j].text == ">" {
+			depth := 0
+			for ; j >= 0; j-- {
+				if tokens[j].text == ">" {
+					depth++
+				} else if tokens[j].text == "<" {
+					depth--
+					if depth == 0 {
+						break
+					}
+				} else if tokens[j].text == ";" || tokens[j].text == "{" || tokens[j].text == "}" {
+					j = -1
+					break
+				}
+			}
+			j--
+			if j >= 0 && tokens[j].text == "::" {
+				j--
+			}
+		}
+		if j < 0 || tokens[j].kind != cTokIdent || skip[tokens[j].text] {
+			continue
+		}
+		parts := []string{tokens[j].text}
+		sep := "."
+		for k := j - 1; k >= 1; k -= 2 {
+			if (tokens[k].text != "." && tokens[k].text != "::" && tokens[k].text != "->" && tokens[k].text != "?.") || tokens[k-1].kind != cTokIdent {
+				break
+			}
+			if tokens[k].text == "::" {
+				sep = "::"
+			}
+			parts = append([]string{tokens[k-1].text}, parts...)
+		}
+		name := strings.Join(parts, sep)
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		callees = append(callees, name)
+	}
+	sort.Strings(callees)
+	return callees
+}
+
+// splitTokensTopLevel splits tokens[from:to] on top-level occurrences of sep,
+// returning [start, end) index pairs. Brackets including angle brackets
+// (when angles is true) shield separators.
+func splitTokensTopLevel(tokens []cToken, from, to int, sep string, angles bool) [][2]int {
+	var parts [][2]int
+	depth := 0
+	start := from
+	for i := from; i < to; i++ {
+		switch tokens[i].text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		case "<":
+			if angles {
+				depth++
+			}
+		case ">":
+			if angles && depth > 0 {
+				depth--
+			}
+		case sep:
+			if depth == 0 {
+				parts = append(parts, [2]int{start, i})
+				start = i + 1
+			}
+		}
+	}
+	if start < to {
+		parts = append(parts, [2]int{start, to})
+	}
+	return parts
+}
+
+// tokenListTexts splits tokens[from:to] on top-level commas and returns the
+// text of each element.
+func tokenListTexts(code []byte, tokens []cToken, from, to int) []string {
+	var out []string
+	for _, part := range splitTokensTopLevel(tokens, from, to, ",", true) {
+		if text := tokenSpanText(code, tokens, part[0], part[1]); text != "" {
+			out = append(out, text)
+		}
+	}
+	return out
+}
+
+// newCFunctionNode fills the positional fields of a FunctionNode spanning
+// code[start:end].
+func newCFunctionNode(code []byte, lineStarts []int, name, nodeType string, start, end int) FunctionNode {
+	lineAt := func(offset int) int {
+		return sort.Search(len(lineStarts), func(i int) bool { return lineStarts[i] > offset })
+	}
+	endLine := lineAt(end - 1)
+	if end <= start {
+		endLine = lineAt(start)
+	}
+	return FunctionNode{
+		Name:      name,
+		NodeType:  nodeType,
+		StartLine: lineAt(start),
+		EndLine:   endLine,
+		Content:   string(code[start:end]),
+		StartByte: start,
+		EndByte:   end,
+	}
+}
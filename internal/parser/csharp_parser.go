@@ -0,0 +1,1065 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+// CSharpParser implements LanguageParser for C# language
+type CSharpParser struct{}
+
+// NewCSharpParser creates a new C# parser
+func NewCSharpParser() *CSharpParser {
+	return &CSharpParser{}
+}
+
+// Language returns the language name
+func (p *CSharpParser) Language() string {
+	return string(LanguageCSharp)
+}
+
+// csharpKeywords are never reported as callees.
+var csharpKeywords = map[string]bool{
+	"if": true, "for": true, "foreach": true, "while": true, "switch": true, "catch": true,
+	"return": true, "new": true, "throw": true, "lock": true, "using": true, "fixed": true,
+	"typeof": true, "sizeof": true, "nameof": true, "default": true, "checked": true,
+	"unchecked": true, "stackalloc": true, "base": true, "this": true, "when": true,
+	"do": true, "else": true, "try": true, "is": true, "as": true, "await": true,
+}
+
+// csharpStatementKeywords cannot start a local function declaration.
+var csharpStatementKeywords = map[string]bool{
+	"return": true, "var": true, "if": true, "while": true, "for": true, "foreach": true,
+	"switch": true, "using": true, "lock": true, "throw": true, "yield": true, "await": true,
+	"try": true, "catch": true, "finally": true, "do": true, "else": true, "goto": true,
+	"break": true, "continue": true, "case": true, "default": true, "new": true,
+	"checked": true, "unchecked": true, "fixed": true, "const": true, "base": true, "this": true,
+}
+
+// csharpModifiers are declaration modifiers skipped before a member's type.
+var csharpModifiers = map[string]bool{
+	"public": true, "private": true, "protected": true, "internal": true, "static": true,
+	"abstract": true, "sealed": true, "virtual": true, "override": true, "readonly": true,
+	"async": true, "extern": true, "unsafe": true, "new": true, "partial": true,
+	"volatile": true, "const": true, "fixed": true, "required": true, "file": true,
+	"event": true, "implicit": true, "explicit": true,
+}
+
+// csharpLocalModifiers may precede a local function.
+var csharpLocalModifiers = map[string]bool{
+	"static": true, "async": true, "unsafe": true, "extern": true,
+}
+
+// csharpTypeKeywords maps type declaration keywords to node types.
+var csharpTypeKeywords = map[string]string{
+	"class":     "class",
+	"struct":    "struct",
+	"interface": "interface",
+	"enum":      "enum",
+	"record":    "record",
+}
+
+// csharpTaskTypes are return types that make a method awaitable.
+var csharpTaskTypes = map[string]bool{
+	"Task": true, "ValueTask": true, "IAsyncEnumerable": true, "IAsyncEnumerator": true,
+}
+
+type csharpScope struct {
+	name      string // qualified type name, e.g. "Outer.Inner"
+	kind      string // node type of the enclosing declaration
+	namespace string
+}
+
+type csharpModifierSet struct {
+	public, private, async, event bool
+}
+
+type csharpDeclParser struct {
+	code      []byte
+	lex       *cLexResult
+	tokens    []cToken
+	imports   []string
+	functions []FunctionNode
+}
+
+// ExtractFunctions extracts namespaces' classes, records, structs, interfaces,
+// enums, methods, constructors, properties with bodies and local functions
+// from C# source code.
+func (p *CSharpParser) ExtractFunctions(filePath string, code []byte) ([]FunctionNode, error) {
+	lex := lexCLike(code, cLexOptions{preprocessor: true, stringPrefix: csharpString})
+	lex.tokens = dropInactiveBranches(lex.tokens, lex.directives)
+	cp := &csharpDeclParser{code: code, lex: lex, tokens: lex.tokens}
+	cp.imports = csharpUsings(lex.tokens)
+	cp.parseMembers(0, len(lex.tokens), csharpScope{})
+
+	sort.SliceStable(cp.functions, func(i, j int) bool {
+		return cp.functions[i].StartByte < cp.functions[j].StartByte
+	})
+	return cp.functions, nil
+}
+
+// parseMembers walks declarations in tokens[from:to] and returns the names of
+// the members and nested types declared at this level.
+func (p *csharpDeclParser) parseMembers(from, to int, scope csharpScope) []string {
+	var members []string
+	i := from
+	for i < to {
+		tok := p.tokens[i]
+		if tok.text == ";" || tok.text == "}" {
+			i++
+			continue
+		}
+		if scope.name == "" && (tok.text == "using" || tok.text == "global" || tok.text == "extern" && i+1 < to && p.tokens[i+1].text == "alias") {
+			i = p.skipStatement(i, to)
+			continue
+		}
+		if tok.text == "[" && i+2 < to && p.tokens[i+2].text == ":" && (p.tokens[i+1].text == "assembly" || p.tokens[i+1].text == "module") {
+			// Assembly attributes describe the file, not the next type.
+			end := matchingClose(p.tokens, i)
+			if end < 0 {
+				return members
+			}
+			i = end + 1
+			continue
+		}
+		if tok.text == "namespace" {
+			j := i + 1
+			for j < to && p.tokens[j].text != "{" && p.tokens[j].text != ";" {
+				j++
+			}
+			name := joinCSharpName(scope.namespace, strings.ReplaceAll(tokenSpanText(p.code, p.tokens, i+1, j), " ", ""))
+			if j >= to || p.tokens[j].text == ";" {
+				// File-scoped namespace: applies to the rest of the file.
+				scope.namespace = name
+				i = j + 1
+				continue
+			}
+			end := matchingClose(p.tokens, j)
+			if end < 0 {
+				return members
+			}
+			p.parseMembers(j+1, end, csharpScope{namespace: name})
+			i = end + 1
+			continue
+		}
+
+		declStart := i
+		var attributes []string
+		for i < to && p.tokens[i].text == "[" {
+			end := matchingClose(p.tokens, i)
+			if end < 0 {
+				return members
+			}
+			attributes = append(attributes, csharpAttributes(p.code, p.tokens, i+1, end)...)
+			i = end + 1
+		}
+		modStart := i
+		var mods csharpModifierSet
+		for i < to && csharpModifiers[p.tokens[i].text] {
+			switch p.tokens[i].text {
+			case "public":
+				mods.public = true
+			case "private":
+				mods.private = true
+			case "async":
+				mods.async = true
+			case "event":
+				mods.event = true
+			}
+			i++
+		}
+		if i >= to {
+			break
+		}
+
+		if p.tokens[i].text == "delegate" {
+			i = p.skipStatement(i, to)
+			continue
+		}
+		if kind, ok := csharpTypeKeywords[p.tokens[i].text]; ok && i+1 < to && p.tokens[i+1].kind == cTokIdent {
+			end, name := p.parseType(declStart, modStart, i, to, kind, scope, attributes, mods)
+			members = appendNonEmpty(members, name)
+			i = end
+			continue
+		}
+		if p.tokens[i].text == "{" {
+			end := matchingClose(p.tokens, i)
+			if end < 0 {
+				return members
+			}
+			i = end + 1
+			continue
+		}
+
+		end, name := p.parseMember(declStart, modStart, i, to, scope, attributes, mods)
+		members = appendNonEmpty(members, name)
+		if end <= i {
+			end = i + 1
+		}
+		i = end
+	}
+	return members
+}
+
+func (p *csharpDeclParser) parseType(declStart, modStart, kwIdx, to int, kind string, scope csharpScope, attributes []string, mods csharpModifierSet) (int, string) {
+	nameIdx := kwIdx + 1
+	// record class / record struct
+	if kind == "record" && (p.tokens[nameIdx].text == "class" || p.tokens[nameIdx].text == "struct") && nameIdx+1 < to {
+		nameIdx++
+	}
+	name := p.tokens[nameIdx].text
+	qualified := joinCSharpName(scope.name, name)
+	j := nameIdx + 1
+	var typeParams, paramTypes []string
+	if j < to && p.tokens[j].text == "<" {
+		if end := matchingClose(p.tokens, j); end > 0 {
+			typeParams = tokenListTexts(p.code, p.tokens, j+1, end)
+			j = end + 1
+		}
+	}
+	// Primary constructor, base list and constraint clauses.
+	clauseStart := j
+	for j < to && p.tokens[j].text != "{" && p.tokens[j].text != ";" {
+		if p.tokens[j].text == "(" || p.tokens[j].text == "<" {
+			if end := matchingClose(p.tokens, j); end > 0 {
+				if p.tokens[j].text == "(" && j == clauseStart {
+					paramTypes = csharpParamTypes(p.code, p.tokens, j+1, end)
+				}
+				j = end
+			}
+		}
+		j++
+	}
+	if j >= to {
+		return to, ""
+	}
+	typeParams = applyCSharpConstraints(typeParams, p.csharpConstraints(clauseStart, j))
+
+	end := j
+	var members []string
+	if p.tokens[j].text == "{" {
+		bodyEnd := matchingClose(p.tokens, j)
+		if bodyEnd < 0 {
+			return to, ""
+		}
+		end = bodyEnd
+		if kind == "enum" {
+			for _, part := range splitTokensTopLevel(p.tokens, j+1, bodyEnd, ",", true) {
+				k := part[0]
+				for k < part[1] && p.tokens[k].text == "[" {
+					if close := matchingClose(p.tokens, k); close > 0 {
+						k = close + 1
+					} else {
+						break
+					}
+				}
+				if k < part[1] && p.tokens[k].kind == cTokIdent {
+					members = append(members, p.tokens[k].text)
+				}
+			}
+		} else {
+			members = p.parseMembers(j+1, bodyEnd, csharpScope{name: qualified, kind: kind, namespace: scope.namespace})
+		}
+	}
+
+	node := newCFunctionNode(p.code, p.lex.lineStarts, qualified, kind, p.tokens[declStart].start, p.tokens[end].end)
+	node.PackageName = scope.namespace
+	node.Imports = append([]string(nil), p.imports...)
+	node.Signature = tokenSpanText(p.code, p.tokens, modStart, j)
+	node.Doc = p.docBefore(declStart)
+	node.Decorators = attributes
+	node.TypeParams = typeParams
+	node.ParamTypes = paramTypes
+	node.Members = members
+	node.Exported = mods.public || (scope.kind == "interface" && !mods.private)
+	if scope.name != "" {
+		node.Receiver = scope.name
+	}
+	p.functions = append(p.functions, node)
+	return end + 1, name
+}
+
+// parseMember handles a method, constructor, destructor, operator, property,
+// indexer, event or field starting at typeStart (after attributes and
+// modifiers).
+func (p *csharpDeclParser) parseMember(declStart, modStart, typeStart, to int, scope csharpScope, attributes []string, mods csharpModifierSet) (int, string) {
+	// Find the token that follows the member name: "(" for methods, "{" or
+	// "=>" for properties, "[" for indexers; "=", ";" and "," end fields.
+	k := typeStart
+	stop := -1
+	for ; k < to; k++ {
+		text := p.tokens[k].text
+		if text == "this" && k+1 < to && p.tokens[k+1].text == "[" && k > typeStart {
+			stop = k
+			break
+		}
+		if text == "(" && k > typeStart || text == "{" || text == "=>" {
+			stop = k
+			break
+		}
+		if text == "=" || text == ";" || text == "," || text == "}" {
+			break
+		}
+		if text == "<" || text == "[" || text == "(" {
+			if end := matchingClose(p.tokens, k); end > 0 {
+				k = end
+			}
+		}
+	}
+	if stop < 0 {
+		return p.skipStatement(typeStart, to), ""
+	}
+
+	if p.tokens[stop].text == "this" {
+		closeIdx := matchingClose(p.tokens, stop+1)
+		if closeIdx < 0 {
+			return to, ""
+		}
+		paramTypes := csharpParamTypes(p.code, p.tokens, stop+2, closeIdx)
+		return p.parseProperty(declStart, modStart, typeStart, stop, closeIdx+1, to, "this[]", scope, attributes, mods, paramTypes)
+	}
+	if p.tokens[stop].text != "(" {
+		nameIdx := stop - 1
+		if nameIdx < typeStart || p.tokens[nameIdx].kind != cTokIdent {
+			return p.skipStatement(typeStart, to), ""
+		}
+		nameStart := p.qualifiedNameStart(typeStart, nameIdx)
+		name := tokenSpanText(p.code, p.tokens, nameStart, stop)
+		name = strings.ReplaceAll(name, " ", "")
+		return p.parseProperty(declStart, modStart, typeStart, nameStart, stop, to, name, scope, attributes, mods, nil)
+	}
+
+	open := stop
+	nameIdx := open - 1
+	var typeParams []string
+	if p.tokens[nameIdx].text == ">" {
+		// Generic method: Name<T>(...)
+		depth := 0
+		lt := nameIdx
+		for ; lt > typeStart; lt-- {
+			if p.tokens[lt].text == ">" {
+				depth++
+			} else if p.tokens[lt].text == "<" {
+				depth--
+				if depth == 0 {
+					break
+				}
+			}
+		}
+		typeParams = tokenListTexts(p.code, p.tokens, lt+1, nameIdx)
+		nameIdx = lt - 1
+	}
+	if nameIdx < typeStart {
+		return p.skipStatement(typeStart, to), ""
+	}
+
+	var name string
+	nameStart := nameIdx
+	switch {
+	case nameIdx-1 >= typeStart && p.tokens[nameIdx-1].text == "operator":
+		// operator +, implicit operator int
+		nameStart = nameIdx - 1
+		name = "operator " + p.tokens[nameIdx].text
+	case nameIdx-2 >= typeStart && p.tokens[nameIdx-2].text == "operator" && p.tokens[nameIdx].kind == cTokPunct:
+		// Two-character operators lexed separately: operator >=, operator <<.
+		nameStart = nameIdx - 2
+		name = "operator " + p.tokens[nameIdx-1].text + p.tokens[nameIdx].text
+	case p.tokens[nameIdx].kind == cTokIdent:
+		nameStart = p.qualifiedNameStart(typeStart, nameIdx)
+		name = strings.ReplaceAll(tokenSpanText(p.code, p.tokens, nameStart, nameIdx+1), " ", "")
+	default:
+		return p.skipStatement(typeStart, to), ""
+	}
+
+	simpleName := scope.name
+	if idx := strings.LastIndex(simpleName, "."); idx >= 0 {
+		simpleName = simpleName[idx+1:]
+	}
+	nodeType := "method"
+	retStart := typeStart
+	var returnTypes []string
+	switch {
+	case nameStart > typeStart && p.tokens[nameStart-1].text == "~" && nameStart-1 == typeStart:
+		nodeType = "destructor"
+		name = "~" + name
+	case nameStart == typeStart:
+		if name != simpleName {
+			// A call or other statement, not a declaration.
+			return p.skipStatement(typeStart, to), ""
+		}
+		nodeType = "constructor"
+	default:
+		if ret := tokenSpanText(p.code, p.tokens, retStart, nameStart); ret != "void" {
+			returnTypes = []string{ret}
+		}
+	}
+	if scope.name == "" {
+		nodeType = "function"
+	}
+
+	paramsEnd := matchingClose(p.tokens, open)
+	if paramsEnd < 0 {
+		return to, ""
+	}
+	paramTypes := csharpParamTypes(p.code, p.tokens, open+1, paramsEnd)
+
+	// Constraint clauses and constructor initializers.
+	k = paramsEnd + 1
+	for k < to && p.tokens[k].text != "{" && p.tokens[k].text != "=>" && p.tokens[k].text != ";" {
+		if p.tokens[k].text == "(" || p.tokens[k].text == "<" {
+			if end := matchingClose(p.tokens, k); end > 0 {
+				k = end
+			}
+		}
+		k++
+	}
+	typeParams = applyCSharpConstraints(typeParams, p.csharpConstraints(paramsEnd+1, k))
+	sigEnd := k
+	if paramsEnd+1 < k && p.tokens[paramsEnd+1].text == ":" {
+		// Keep ": base(...)" out of the signature.
+		sigEnd = paramsEnd + 1
+	}
+	if k >= to || p.tokens[k].text == ";" {
+		// Abstract, interface, partial or extern method without a body.
+		return k + 1, name
+	}
+
+	bodyStart, bodyEnd, end := p.memberBody(k, to)
+	if bodyEnd < 0 {
+		return to, name
+	}
+	node := p.newMember(declStart, modStart, sigEnd, end, joinCSharpName(scope.name, name), nodeType, scope, attributes, mods)
+	node.Callees = cCallees(p.tokens[bodyStart:bodyEnd], csharpKeywords)
+	node.Raises = csharpThrows(p.code, p.tokens, bodyStart, bodyEnd)
+	node.ParamTypes = paramTypes
+	node.ReturnTypes = returnTypes
+	node.TypeParams = typeParams
+	node.IsAsync = mods.async || (len(returnTypes) > 0 && isCSharpTaskType(returnTypes[0]))
+	p.functions = append(p.functions, node)
+	p.parseLocalFunctions(bodyStart, bodyEnd, node.Name, scope)
+	return end + 1, name
+}
+
+// parseProperty handles a property, indexer or event whose accessor block or
+// expression body starts at bodyIdx. Auto-properties ({ get; set; }) have no
+// code to index and only contribute their name to the enclosing type.
+func (p *csharpDeclParser) parseProperty(declStart, modStart, typeStart, nameStart, bodyIdx, to int, name string, scope csharpScope, attributes []string, mods csharpModifierSet, paramTypes []string) (int, string) {
+	hasBody := false
+	bodyStart, bodyEnd, end := p.memberBody(bodyIdx, to)
+	if bodyEnd < 0 {
+		return to, name
+	}
+	if p.tokens[bodyIdx].text == "=>" {
+		hasBody = true
+	} else {
+		for k := bodyIdx + 1; k+1 < bodyEnd; k++ {
+			switch p.tokens[k].text {
+			case "get", "set", "init", "add", "remove":
+				if next := p.tokens[k+1].text; next == "{" || next == "=>" {
+					hasBody = true
+				}
+			}
+		}
+		// Initializer after the accessor block: { get; } = new();
+		if end+1 < to && p.tokens[end+1].text == "=" {
+			end = p.skipStatement(end+1, to) - 1
+		}
+	}
+	if !hasBody {
+		return end + 1, name
+	}
+
+	nodeType := "property"
+	if mods.event {
+		nodeType = "event"
+	}
+	node := p.newMember(declStart, modStart, bodyIdx, end, joinCSharpName(scope.name, name), nodeType, scope, attributes, mods)
+	node.Callees = cCallees(p.tokens[bodyStart:bodyEnd], csharpKeywords)
+	node.Raises = csharpThrows(p.code, p.tokens, bodyStart, bodyEnd)
+	node.ParamTypes = paramTypes
+	if ret := tokenSpanText(p.code, p.tokens, typeStart, nameStart); ret != "" {
+		node.ReturnTypes = []string{ret}
+	}
+	p.functions = append(p.functions, node)
+	return end + 1, name
+}
+
+// memberBody returns the token range of a "{ ... }" block or "=> expr;"
+// expression body starting at tokens[i], and the index of its last token.
+func (p *csharpDeclParser) memberBody(i, to int) (bodyStart, bodyEnd, end int) {
+	if p.tokens[i].text == "{" {
+		closeIdx := matchingClose(p.tokens, i)
+		return i + 1, closeIdx, closeIdx
+	}
+	stmtEnd := p.skipStatement(i, to)
+	last := stmtEnd - 1
+	if last < i {
+		last = i
+	}
+	return i + 1, last, last
+}
+
+func (p *csharpDeclParser) newMember(declStart, modStart, sigEnd, end int, qualified, nodeType string, scope csharpScope, attributes []string, mods csharpModifierSet) FunctionNode {
+	node := newCFunctionNode(p.code, p.lex.lineStarts, qualified, nodeType, p.tokens[declStart].start, p.tokens[end].end)
+	node.PackageName = scope.namespace
+	node.Imports = append([]string(nil), p.imports...)
+	node.Signature = tokenSpanText(p.code, p.tokens, modStart, sigEnd)
+	node.Receiver = scope.name
+	node.Doc = p.docBefore(declStart)
+	node.Decorators = attributes
+	// Interface members are implicitly public.
+	node.Exported = mods.public || (scope.kind == "interface" && !mods.private)
+	return node
+}
+
+// parseLocalFunctions finds local functions declared inside a method body:
+// statements of the form "[static] [async] Type Name<T>(params) { ... }" or
+// "... => expr;".
+func (p *csharpDeclParser) parseLocalFunctions(from, to int, owner string, scope csharpScope) {
+	for i := from; i < to; i++ {
+		if i > from {
+			if prev := p.tokens[i-1].text; prev != ";" && prev != "{" && prev != "}" {
+				continue
+			}
+		}
+		end, ok := p.parseLocalFunction(i, to, owner, scope)
+		if ok {
+			i = end
+		}
+	}
+}
+
+func (p *csharpDeclParser) parseLocalFunction(start, to int, owner string, scope csharpScope) (int, bool) {
+	k := start
+	var attributes []string
+	for k < to && p.tokens[k].text == "[" {
+		end := matchingClose(p.tokens, k)
+		if end < 0 {
+			return 0, false
+		}
+		attributes = append(attributes, csharpAttributes(p.code, p.tokens, k+1, end)...)
+		k = end + 1
+	}
+	modStart := k
+	var mods csharpModifierSet
+	for k < to && csharpLocalModifiers[p.tokens[k].text] {
+		if p.tokens[k].text == "async" {
+			mods.async = true
+		}
+		k++
+	}
+
+	// Return type: a tuple "(int, string)" or a (qualified, generic,
+	// nullable, array) type name.
+	typeStart := k
+	if k < to && p.tokens[k].text == "(" {
+		end := matchingClose(p.tokens, k)
+		if end < 0 {
+			return 0, false
+		}
+		k = end + 1
+	} else {
+		if k >= to || p.tokens[k].kind != cTokIdent || csharpStatementKeywords[p.tokens[k].text] || csharpKeywords[p.tokens[k].text] {
+			return 0, false
+		}
+		k++
+		for k < to {
+			text := p.tokens[k].text
+			switch {
+			case (text == "." || text == "::") && k+1 < to && p.tokens[k+1].kind == cTokIdent:
+				k += 2
+				continue
+			case text == "<":
+				end := matchingClose(p.tokens, k)
+				if end < 0 {
+					return 0, false
+				}
+				k = end + 1
+				continue
+			case text == "?" || text == "*":
+				k++
+				continue
+			case text == "[" && k+1 < to && (p.tokens[k+1].text == "]" || p.tokens[k+1].text == ","):
+				end := matchingClose(p.tokens, k)
+				if end < 0 {
+					return 0, false
+				}
+				k = end + 1
+				continue
+			}
+			break
+		}
+	}
+	nameIdx := k
+	if nameIdx >= to || p.tokens[nameIdx].kind != cTokIdent || csharpStatementKeywords[p.tokens[nameIdx].text] {
+		return 0, false
+	}
+	k++
+	var typeParams []string
+	if k < to && p.tokens[k].text == "<" {
+		end := matchingClose(p.tokens, k)
+		if end < 0 {
+			return 0, false
+		}
+		typeParams = tokenListTexts(p.code, p.tokens, k+1, end)
+		k = end + 1
+	}
+	if k >= to || p.tokens[k].text != "(" {
+		return 0, false
+	}
+	paramsEnd := matchingClose(p.tokens, k)
+	if paramsEnd < 0 {
+		return 0, false
+	}
+	bodyIdx := paramsEnd + 1
+	for bodyIdx < to && p.tokens[bodyIdx].text == "where" {
+		for bodyIdx < to && p.tokens[bodyIdx].text != "{" && p.tokens[bodyIdx].text != "=>" && p.tokens[bodyIdx].text != ";" {
+			bodyIdx++
+		}
+	}
+	if bodyIdx >= to || (p.tokens[bodyIdx].text != "{" && p.tokens[bodyIdx].text != "=>") {
+		return 0, false
+	}
+	typeParams = applyCSharpConstraints(typeParams, p.csharpConstraints(paramsEnd+1, bodyIdx))
+	bodyStart, bodyEnd, end := p.memberBody(bodyIdx, to)
+	if bodyEnd < 0 {
+		return 0, false
+	}
+
+	name := p.tokens[nameIdx].text
+	node := p.newMember(start, modStart, bodyIdx, end, owner+"."+name, "local_function", scope, attributes, mods)
+	node.Exported = false
+	node.Callees = cCallees(p.tokens[bodyStart:bodyEnd], csharpKeywords)
+	node.Raises = csharpThrows(p.code, p.tokens, bodyStart, bodyEnd)
+	node.ParamTypes = csharpParamTypes(p.code, p.tokens, k+1, paramsEnd)
+	if ret := tokenSpanText(p.code, p.tokens, typeStart, nameIdx); ret != "void" {
+		node.ReturnTypes = []string{ret}
+	}
+	node.TypeParams = typeParams
+	node.IsAsync = mods.async || (len(node.ReturnTypes) > 0 && isCSharpTaskType(node.ReturnTypes[0]))
+	p.functions = append(p.functions, node)
+	p.parseLocalFunctions(bodyStart, bodyEnd, node.Name, scope)
+	return end, true
+}
+
+// qualifiedNameStart extends a member name backwards over an explicit
+// interface qualifier: "IDisposable.Dispose", "IComparer<T>.Compare".
+func (p *csharpDeclParser) qualifiedNameStart(typeStart, nameIdx int) int {
+	start := nameIdx
+	for start-2 > typeStart && p.tokens[start-1].text == "." {
+		q := start - 2
+		if p.tokens[q].text == ">" {
+			depth := 0
+			for ; q > typeStart; q-- {
+				if p.tokens[q].text == ">" {
+					depth++
+				} else if p.tokens[q].text == "<" {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			q--
+		}
+		// The qualifier must be preceded by the return type.
+		if q <= typeStart || p.tokens[q].kind != cTokIdent {
+			break
+		}
+		start = q
+	}
+	return start
+}
+
+// csharpConstraints parses "where T : class, new()" clauses in
+// tokens[from:to] into a map from type parameter to constraint list.
+func (p *csharpDeclParser) csharpConstraints(from, to int) map[string]string {
+	constraints := make(map[string]string)
+	for i := from; i+2 < to; i++ {
+		if p.tokens[i].text != "where" || p.tokens[i+1].kind != cTokIdent || p.tokens[i+2].text != ":" {
+			continue
+		}
+		end := i + 3
+		for end < to && p.tokens[end].text != "where" && p.tokens[end].text != ":" {
+			if p.tokens[end].text == "<" || p.tokens[end].text == "(" {
+				if close := matchingClose(p.tokens, end); close > 0 {
+					end = close
+				}
+			}
+			end++
+		}
+		constraints[p.tokens[i+1].text] = strings.Join(tokenListTexts(p.code, p.tokens, i+3, end), ", ")
+		i = end - 1
+	}
+	return constraints
+}
+
+// skipStatement advances past a field, event or other declaration. A braced
+// group ends the declaration unless it is part of an initializer.
+func (p *csharpDeclParser) skipStatement(i, to int) int {
+	assigned := false
+	for i < to {
+		switch p.tokens[i].text {
+		case ";":
+			return i + 1
+		case "=", "=>":
+			assigned = true
+		case "(", "[":
+			end := matchingClose(p.tokens, i)
+			if end < 0 {
+				return to
+			}
+			i = end
+		case "{":
+			end := matchingClose(p.tokens, i)
+			if end < 0 {
+				return to
+			}
+			if !assigned && (end+1 >= to || p.tokens[end+1].text != ";") {
+				return end + 1
+			}
+			i = end
+		case "}":
+			return i
+		}
+		i++
+	}
+	return to
+}
+
+func (p *csharpDeclParser) docBefore(tokenIdx int) string {
+	doc := docCommentBefore(p.code, p.lex.comments, p.tokens[tokenIdx].start, isCSharpDocComment)
+	return cleanCSharpXMLDoc(doc)
+}
+
+// isCSharpDocComment reports whether a comment is an XML doc comment.
+func isCSharpDocComment(text string) bool {
+	return strings.HasPrefix(text, "///") || isJavadocComment(text)
+}
+
+// cleanCSharpXMLDoc turns XML documentation into plain text: <summary> and
+// <remarks> wrappers are dropped, <param> and <returns> become labelled lines
+// and <see cref="X"/> references become X.
+func cleanCSharpXMLDoc(doc string) string {
+	if !strings.Contains(doc, "<") {
+		return doc
+	}
+	replacer := strings.NewReplacer(
+		"<summary>", "", "</summary>", "",
+		"<remarks>", "", "</remarks>", "",
+		"<returns>", "Returns: ", "</returns>", "",
+		"</param>", "", "</typeparam>", "", "</exception>", "",
+		"<para>", "", "</para>", "",
+		"<c>", "", "</c>", "",
+	)
+	doc = replacer.Replace(doc)
+
+	var b strings.Builder
+	for {
+		start := strings.Index(doc, "<")
+		if start < 0 {
+			b.WriteString(doc)
+			break
+		}
+		end := strings.Index(doc[start:], ">")
+		if end < 0 {
+			b.WriteString(doc)
+			break
+		}
+		b.WriteString(doc[:start])
+		tag := doc[start+1 : start+end]
+		// Keep the referenced name of <param name="x">, <see cref="X"/>.
+		if q := strings.IndexByte(tag, '"'); q >= 0 {
+			if q2 := strings.IndexByte(tag[q+1:], '"'); q2 >= 0 {
+				value := tag[q+1 : q+1+q2]
+				switch {
+				case strings.HasPrefix(tag, "param ") || strings.HasPrefix(tag, "typeparam "):
+					b.WriteString(value + ": ")
+				case strings.HasPrefix(tag, "exception "):
+					b.WriteString("Throws " + value + ": ")
+				default:
+					b.WriteString(value)
+				}
+			}
+		}
+		doc = doc[start+end+1:]
+	}
+
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// csharpUsings returns the namespaces and alias targets named by using
+// directives. Using statements and declarations inside method bodies
+// ("using var x = ...", "using (...)") are ignored.
+func csharpUsings(tokens []cToken) []string {
+	seen := make(map[string]struct{})
+	var usings []string
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].text != "using" {
+			continue
+		}
+		if i > 0 {
+			prev := tokens[i-1].text
+			if prev == "global" && i > 1 {
+				prev = tokens[i-2].text
+			}
+			if prev != ";" && prev != "}" && prev != "{" && prev != "global" && !strings.HasPrefix(prev, "[") && prev != "]" {
+				continue
+			}
+		}
+		k := i + 1
+		if k < len(tokens) && tokens[k].text == "static" {
+			k++
+		}
+		end := k
+		for end < len(tokens) && tokens[end].text != ";" && tokens[end].text != "{" && tokens[end].text != "(" {
+			end++
+		}
+		if end >= len(tokens) || tokens[end].text != ";" {
+			continue
+		}
+		from := k
+		if from+1 < end && tokens[from].kind == cTokIdent && tokens[from+1].text == "=" {
+			from += 2
+		}
+		var b strings.Builder
+		valid := from < end
+		for t := from; t < end && valid; t++ {
+			if tokens[t].kind == cTokIdent && t > from && tokens[t-1].kind == cTokIdent {
+				// "using Type name = ..." declares a variable.
+				valid = false
+			}
+			switch tokens[t].kind {
+			case cTokIdent:
+			case cTokPunct:
+				switch tokens[t].text {
+				case ".", "::", "<", ">", ",", "?", "[", "]":
+				default:
+					valid = false
+				}
+			default:
+				valid = false
+			}
+			b.WriteString(tokens[t].text)
+		}
+		if !valid {
+			continue
+		}
+		if _, ok := seen[b.String()]; !ok {
+			seen[b.String()] = struct{}{}
+			usings = append(usings, b.String())
+		}
+		i = end
+	}
+	sort.Strings(usings)
+	return usings
+}
+
+// csharpAttributes splits the contents of an attribute section "[A, B(x)]"
+// into individual attributes, dropping targets such as "return:".
+func csharpAttributes(code []byte, tokens []cToken, from, to int) []string {
+	if from+1 < to && tokens[from].kind == cTokIdent && tokens[from+1].text == ":" {
+		from += 2
+	}
+	return tokenListTexts(code, tokens, from, to)
+}
+
+// csharpParamTypes returns the parameter types in tokens[from:to], keeping
+// ref/out/in/params/this modifiers but dropping attributes, names and
+// default values.
+func csharpParamTypes(code []byte, tokens []cToken, from, to int) []string {
+	var types []string
+	for _, part := range splitTokensTopLevel(tokens, from, to, ",", true) {
+		start, end := part[0], part[1]
+		for start < end && tokens[start].text == "[" {
+			close := matchingClose(tokens, start)
+			if close < 0 || close >= end {
+				break
+			}
+			start = close + 1
+		}
+		for k := start; k < end; k++ {
+			if tokens[k].text == "=" {
+				end = k
+				break
+			}
+		}
+		if end-1 > start && tokens[end-1].kind == cTokIdent {
+			end--
+		}
+		if t := tokenSpanText(code, tokens, start, end); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// applyCSharpConstraints appends "where" constraints to the matching type
+// parameters: "T" becomes "T : class, new()".
+func applyCSharpConstraints(typeParams []string, constraints map[string]string) []string {
+	for i, param := range typeParams {
+		fields := strings.Fields(param)
+		if len(fields) == 0 {
+			continue
+		}
+		if c, ok := constraints[fields[len(fields)-1]]; ok {
+			typeParams[i] = param + " : " + c
+		}
+	}
+	return typeParams
+}
+
+// csharpThrows returns the exception types constructed by "throw new X(...)"
+// in tokens[from:to], sorted and deduplicated.
+func csharpThrows(code []byte, tokens []cToken, from, to int) []string {
+	seen := make(map[string]struct{})
+	var raises []string
+	for i := from; i+2 < to; i++ {
+		if tokens[i].text != "throw" || tokens[i+1].text != "new" {
+			continue
+		}
+		end := i + 2
+		for end < to && (tokens[end].kind == cTokIdent || tokens[end].text == ".") {
+			end++
+		}
+		if end > i+2 {
+			name := tokenSpanText(code, tokens, i+2, end)
+			if _, ok := seen[name]; !ok {
+				seen[name] = struct{}{}
+				raises = append(raises, name)
+			}
+		}
+	}
+	sort.Strings(raises)
+	return raises
+}
+
+// isCSharpTaskType reports whether a return type is awaitable: Task,
+// ValueTask<T>, IAsyncEnumerable<T> and their qualified forms.
+func isCSharpTaskType(returnType string) bool {
+	base := returnType
+	if idx := strings.IndexByte(base, '<'); idx >= 0 {
+		base = base[:idx]
+	}
+	base = strings.TrimSpace(base)
+	if idx := strings.LastIndex(base, "."); idx >= 0 {
+		base = base[idx+1:]
+	}
+	return csharpTaskTypes[base]
+}
+
+func joinCSharpName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	if name == "" {
+		return prefix
+	}
+	return prefix + "." + name
+}
+
+// csharpString matches verbatim (@"..."), interpolated ($"...{x}...") and
+// raw ("""...""") string literals, or returns -1 for anything else.
+func csharpString(code []byte, pos int) int {
+	i := pos
+	interpolated, verbatim := false, false
+	for i < len(code) && (code[i] == '$' || code[i] == '@') {
+		if code[i] == '$' {
+			interpolated = true
+		} else {
+			verbatim = true
+		}
+		i++
+	}
+	if i >= len(code) || code[i] != '"' {
+		return -1
+	}
+	quotes := 0
+	for i+quotes < len(code) && code[i+quotes] == '"' {
+		quotes++
+	}
+	if quotes >= 3 {
+		j := i + quotes
+		for j < len(code) {
+			if code[j] != '"' {
+				j++
+				continue
+			}
+			run := 0
+			for j+run < len(code) && code[j+run] == '"' {
+				run++
+			}
+			if run >= quotes {
+				return j + run
+			}
+			j += run
+		}
+		return len(code)
+	}
+	if i == pos {
+		return -1
+	}
+	j := i + 1
+	for j < len(code) {
+		c := code[j]
+		switch {
+		case c == '\\' && !verbatim:
+			j += 2
+		case c == '"':
+			if verbatim && j+1 < len(code) && code[j+1] == '"' {
+				j += 2
+				continue
+			}
+			return j + 1
+		case c == '{' && interpolated:
+			if j+1 < len(code) && code[j+1] == '{' {
+				j += 2
+				continue
+			}
+			j = skipCSharpInterpolation(code, j+1)
+		case c == '\n' && !verbatim:
+			return j
+		default:
+			j++
+		}
+	}
+	return len(code)
+}
+
+// skipCSharpInterpolation returns the offset after the '}' closing an
+// interpolation hole that starts at pos, skipping nested strings.
+func skipCSharpInterpolation(code []byte, pos int) int {
+	depth := 1
+	j := pos
+	for j < len(code) {
+		switch code[j] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return j + 1
+			}
+		case '"', '$', '@':
+			if end := csharpString(code, j); end > j {
+				j = end
+				continue
+			}
+			if code[j] == '"' {
+				j = skipCQuoted(code, j, '"')
+				continue
+			}
+		case '\'':
+			j = skipCQuoted(code, j, '\'')
+			continue
+		}
+		j++
+	}
+	return len(code)
+}
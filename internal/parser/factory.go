@@ -19,6 +19,20 @@ func NewParserFactory() *ParserFactory {
 			LanguagePython:     NewPythonParser(),
 			LanguageJavaScript: NewJavaScriptParser(),
 			LanguageTypeScript: NewTypeScriptParser(),
+			LanguageRust:       NewRustParser(),
+			LanguageJava:       NewJavaParser(),
+			LanguageKotlin:     NewKotlinParser(),
+			LanguageC:          NewCParser(),
+			LanguageCPP:        NewCPPParser(),
+			LanguageCSharp:     NewCSharpParser(),
+			LanguageRuby:       NewRubyParser(),
+			LanguagePHP:        NewPHPParser(),
+			LanguageVue:        NewVueParser(),
+			LanguageSvelte:     NewSvelteParser(),
+			LanguageMarkdown:   NewMarkdownParser(),
+			LanguageSQL:        NewSQLParser(),
+			LanguageProto:      NewProtoParser(),
+			LanguageNotebook:   NewNotebookParser(),
 		},
 	}
 }
@@ -54,6 +68,34 @@ func DetectLanguage(filePath string) Language {
 		return LanguageJavaScript
 	case ".ts", ".tsx":
 		return LanguageTypeScript
+	case ".rs":
+		return LanguageRust
+	case ".java":
+		return LanguageJava
+	case ".kt", ".kts":
+		return LanguageKotlin
+	case ".c", ".h":
+		return LanguageC
+	case ".cc", ".cpp", ".cxx", ".hh", ".hpp", ".hxx":
+		return LanguageCPP
+	case ".cs":
+		return LanguageCSharp
+	case ".rb", ".rake":
+		return LanguageRuby
+	case ".php":
+		return LanguagePHP
+	case ".vue":
+		return LanguageVue
+	case ".svelte":
+		return LanguageSvelte
+	case ".md", ".markdown":
+		return LanguageMarkdown
+	case ".sql":
+		return LanguageSQL
+	case ".proto":
+		return LanguageProto
+	case ".ipynb":
+		return LanguageNotebook
 	default:
 		return ""
 	}
@@ -66,6 +108,20 @@ func SupportedExtensions() []string {
 		".py",
 		".js", ".jsx", ".mjs", ".cjs",
 		".ts", ".tsx",
+		".rs",
+		".java",
+		".kt", ".kts",
+		".c", ".h",
+		".cc", ".cpp", ".cxx", ".hh", ".hpp", ".hxx",
+		".cs",
+		".rb", ".rake",
+		".php",
+		".vue",
+		".svelte",
+		".md", ".markdown",
+		".sql",
+		".proto",
+		".ipynb",
 	}
 }
 
@@ -6,7 +6,9 @@ import (
 	goparser "go/parser"
 	"go/token"
 	"go/types"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -94,6 +96,10 @@ func (p *GoParser) buildFunctionNode(decl *ast.FuncDecl, pkg string, imports []s
 	// Check if function returns error
 	hasErrorReturn := containsErrorReturn(returnTypes)
 
+	// SQL embedded in string literals links the function to the schema
+	queries := collectSQLLiterals(decl.Body)
+	tables, columns := sqlQueryRefs(queries)
+
 	return &FunctionNode{
 		Name:           name,
 		NodeType:       nodeType,
@@ -111,6 +117,9 @@ func (p *GoParser) buildFunctionNode(decl *ast.FuncDecl, pkg string, imports []s
 		ParamTypes:     paramTypes,
 		ReturnTypes:    returnTypes,
 		HasErrorReturn: hasErrorReturn,
+		SQLQueries:     queries,
+		Tables:         tables,
+		Columns:        columns,
 	}
 }
 
@@ -236,6 +245,62 @@ func collectCallees(body *ast.BlockStmt) []string {
 	return callees
 }
 
+// collectSQLLiterals returns the SQL statements held in string literals of a
+// function body. Literals concatenated with "+" are joined first.
+func collectSQLLiterals(body *ast.BlockStmt) []string {
+	if body == nil {
+		return nil
+	}
+
+	var queries []string
+	ast.Inspect(body, func(n ast.Node) bool {
+		expr, ok := n.(ast.Expr)
+		if !ok {
+			return true
+		}
+		text, ok := stringConstant(expr)
+		if !ok {
+			return true
+		}
+		if isSQLLiteral(text) {
+			query := collapseWhitespace(strings.TrimSpace(text))
+			if !slices.Contains(queries, query) {
+				queries = append(queries, query)
+			}
+		}
+		return false
+	})
+	return queries
+}
+
+// stringConstant evaluates a string literal or a "+" concatenation of them.
+func stringConstant(expr ast.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(e.Value)
+		return s, err == nil
+	case *ast.ParenExpr:
+		return stringConstant(e.X)
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false
+		}
+		left, ok := stringConstant(e.X)
+		if !ok {
+			return "", false
+		}
+		right, ok := stringConstant(e.Y)
+		if !ok {
+			return "", false
+		}
+		return left + right, true
+	}
+	return "", false
+}
+
 func formatCallExpr(expr ast.Expr) string {
 	if expr == nil {
 		return ""
@@ -0,0 +1,492 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+// JavaParser implements LanguageParser for Java language
+type JavaParser struct{}
+
+// NewJavaParser creates a new Java parser
+func NewJavaParser() *JavaParser {
+	return &JavaParser{}
+}
+
+// Language returns the language name
+func (p *JavaParser) Language() string {
+	return string(LanguageJava)
+}
+
+// javaKeywords are never reported as callees.
+var javaKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true, "return": true,
+	"new": true, "throw": true, "synchronized": true, "try": true, "do": true, "else": true,
+	"super": true, "this": true, "assert": true, "case": true, "instanceof": true,
+}
+
+// javaModifiers are declaration modifiers skipped before a member's type.
+var javaModifiers = map[string]bool{
+	"public": true, "protected": true, "private": true, "static": true, "final": true,
+	"abstract": true, "synchronized": true, "native": true, "transient": true,
+	"volatile": true, "strictfp": true, "default": true, "sealed": true,
+}
+
+// javaTypeKeywords maps type declaration keywords to node types.
+var javaTypeKeywords = map[string]string{
+	"class":     "class",
+	"interface": "interface",
+	"enum":      "enum",
+	"record":    "record",
+}
+
+type javaScope struct {
+	name string // qualified type name, e.g. "Outer.Inner"
+	kind string // node type of the enclosing declaration
+
+	// Record components, the implicit parameters of a compact constructor.
+	components     string // "(int x, int y)"
+	componentTypes []string
+}
+
+type javaDeclParser struct {
+	code      []byte
+	lex       *cLexResult
+	tokens    []cToken
+	pkg       string
+	imports   []string
+	functions []FunctionNode
+}
+
+// ExtractFunctions extracts classes, interfaces, enums, records, methods and
+// constructors from Java source code.
+func (p *JavaParser) ExtractFunctions(filePath string, code []byte) ([]FunctionNode, error) {
+	lex := lexCLike(code, cLexOptions{identChars: "$", stringPrefix: tripleQuotedString})
+	jp := &javaDeclParser{code: code, lex: lex, tokens: lex.tokens}
+	jp.parseHeader()
+	jp.parseMembers(0, len(lex.tokens), javaScope{})
+
+	sort.SliceStable(jp.functions, func(i, j int) bool {
+		return jp.functions[i].StartByte < jp.functions[j].StartByte
+	})
+	return jp.functions, nil
+}
+
+// parseHeader reads the package declaration and imports.
+func (p *javaDeclParser) parseHeader() {
+	for i := 0; i < len(p.tokens); i++ {
+		tok := p.tokens[i]
+		if tok.kind != cTokIdent || (tok.text != "package" && tok.text != "import") {
+			continue
+		}
+		// Only statements at the start of a line, not identifiers in code.
+		if i > 0 && p.tokens[i-1].text != ";" && p.tokens[i-1].text != "}" && p.tokens[i-1].text != ")" {
+			continue
+		}
+		end := i + 1
+		for end < len(p.tokens) && p.tokens[end].text != ";" {
+			end++
+		}
+		var b strings.Builder
+		for k := i + 1; k < end; k++ {
+			if p.tokens[k].text == "static" {
+				continue
+			}
+			b.WriteString(p.tokens[k].text)
+		}
+		if tok.text == "package" {
+			p.pkg = b.String()
+		} else if path := b.String(); path != "" {
+			p.imports = append(p.imports, path)
+		}
+		i = end
+	}
+	sort.Strings(p.imports)
+}
+
+// parseMembers walks declarations in tokens[from:to] and returns the names of
+// the methods and nested types declared at this level.
+func (p *javaDeclParser) parseMembers(from, to int, scope javaScope) []string {
+	var members []string
+	i := from
+	for i < to {
+		tok := p.tokens[i]
+		if tok.text == ";" {
+			i++
+			continue
+		}
+		if tok.kind == cTokIdent && (tok.text == "package" || tok.text == "import") && scope.name == "" {
+			for i < to && p.tokens[i].text != ";" {
+				i++
+			}
+			continue
+		}
+
+		declStart := i
+		annotations, next := collectJavaAnnotations(p.code, p.tokens, i, to)
+		i = next
+		modStart := i
+		exported := false
+		for i < to && javaModifiers[p.tokens[i].text] {
+			if p.tokens[i].text == "public" {
+				exported = true
+			}
+			i++
+		}
+		// non-sealed is lexed as three tokens.
+		if i+2 < to && p.tokens[i].text == "non" && p.tokens[i+1].text == "-" && p.tokens[i+2].text == "sealed" {
+			i += 3
+		}
+		if i >= to {
+			break
+		}
+
+		// Annotation type declarations: @interface Name { ... }
+		if p.tokens[i].text == "@" && i+1 < to && p.tokens[i+1].text == "interface" {
+			i++
+		}
+		if kind, ok := javaTypeKeywords[p.tokens[i].text]; ok && i+1 < to && p.tokens[i+1].kind == cTokIdent {
+			if p.tokens[i].text == "interface" && i > modStart && p.tokens[i-1].text == "@" {
+				kind = "annotation"
+			}
+			end, name := p.parseType(declStart, modStart, i, to, kind, scope, annotations, exported)
+			if name != "" {
+				members = append(members, name)
+			}
+			i = end
+			continue
+		}
+
+		if p.tokens[i].text == "{" {
+			// Instance or static initializer block.
+			end := matchingClose(p.tokens, i)
+			if end < 0 {
+				return members
+			}
+			i = end + 1
+			continue
+		}
+
+		end, name := p.parseMember(declStart, modStart, i, to, scope, annotations, exported)
+		if name != "" {
+			members = append(members, name)
+		}
+		i = end
+	}
+	return members
+}
+
+func (p *javaDeclParser) parseType(declStart, modStart, kwIdx, to int, kind string, scope javaScope, annotations []string, exported bool) (int, string) {
+	name := p.tokens[kwIdx+1].text
+	qualified := name
+	if scope.name != "" {
+		qualified = scope.name + "." + name
+	}
+	j := kwIdx + 2
+	var typeParams []string
+	if j < to && p.tokens[j].text == "<" {
+		if end := matchingClose(p.tokens, j); end > 0 {
+			typeParams = tokenListTexts(p.code, p.tokens, j+1, end)
+			j = end + 1
+		}
+	}
+	inner := javaScope{name: qualified, kind: kind}
+	if kind == "record" && j < to && p.tokens[j].text == "(" {
+		if end := matchingClose(p.tokens, j); end > 0 {
+			inner.components = tokenSpanText(p.code, p.tokens, j, end+1)
+			inner.componentTypes = javaParamTypes(p.code, p.tokens, j+1, end)
+		}
+	}
+	// Record components, extends/implements/permits clauses.
+	for j < to && p.tokens[j].text != "{" && p.tokens[j].text != ";" {
+		if p.tokens[j].text == "(" || p.tokens[j].text == "<" {
+			if end := matchingClose(p.tokens, j); end > 0 {
+				j = end
+			}
+		}
+		j++
+	}
+	if j >= to || p.tokens[j].text != "{" {
+		return j + 1, ""
+	}
+	bodyEnd := matchingClose(p.tokens, j)
+	if bodyEnd < 0 {
+		return to, ""
+	}
+
+	bodyStart := j + 1
+	var members []string
+	if kind == "enum" {
+		constants, next := p.enumConstants(bodyStart, bodyEnd)
+		members = append(members, constants...)
+		bodyStart = next
+	}
+	members = append(members, p.parseMembers(bodyStart, bodyEnd, inner)...)
+
+	node := newCFunctionNode(p.code, p.lex.lineStarts, qualified, kind, p.tokens[declStart].start, p.tokens[bodyEnd].end)
+	node.PackageName = p.pkg
+	node.Imports = append([]string(nil), p.imports...)
+	node.Signature = tokenSpanText(p.code, p.tokens, modStart, j)
+	node.Doc = p.docBefore(declStart)
+	node.Decorators = annotations
+	node.TypeParams = typeParams
+	node.Members = members
+	node.Exported = exported
+	if scope.name != "" {
+		node.Receiver = scope.name
+	}
+	p.functions = append(p.functions, node)
+	return bodyEnd + 1, name
+}
+
+// enumConstants returns the constant names at the start of an enum body and
+// the index just after the terminating ';' (or bodyEnd).
+func (p *javaDeclParser) enumConstants(from, to int) ([]string, int) {
+	var constants []string
+	expectName := true
+	i := from
+	for i < to {
+		tok := p.tokens[i]
+		switch {
+		case tok.text == ";":
+			return constants, i + 1
+		case tok.text == ",":
+			expectName = true
+		case tok.text == "@":
+			_, next := collectJavaAnnotations(p.code, p.tokens, i, to)
+			i = next
+			continue
+		case tok.text == "(" || tok.text == "{":
+			if end := matchingClose(p.tokens, i); end > 0 {
+				i = end
+			}
+		case tok.kind == cTokIdent && expectName:
+			constants = append(constants, tok.text)
+			expectName = false
+		}
+		i++
+	}
+	return constants, to
+}
+
+// parseMember handles a method, constructor or field starting at typeStart
+// (after annotations and modifiers).
+func (p *javaDeclParser) parseMember(declStart, modStart, typeStart, to int, scope javaScope, annotations []string, exported bool) (int, string) {
+	j := typeStart
+	var typeParams []string
+	if p.tokens[j].text == "<" {
+		if end := matchingClose(p.tokens, j); end > 0 {
+			typeParams = tokenListTexts(p.code, p.tokens, j+1, end)
+			j = end + 1
+		}
+	}
+	retStart := j
+
+	// Find the parameter list: the first "(" at depth zero before "=", ";"
+	// or "{" (fields and initializers stop earlier).
+	paren := -1
+	for k := j; k < to; k++ {
+		text := p.tokens[k].text
+		if text == "(" {
+			paren = k
+			break
+		}
+		if text == "=" || text == ";" || text == "{" || text == "}" {
+			break
+		}
+		if text == "<" || text == "[" {
+			if end := matchingClose(p.tokens, k); end > 0 {
+				k = end
+			}
+		}
+	}
+
+	simpleName := scope.name
+	if idx := strings.LastIndex(simpleName, "."); idx >= 0 {
+		simpleName = simpleName[idx+1:]
+	}
+
+	// Compact record constructor: "public Point {".
+	if paren < 0 && scope.kind == "record" && p.tokens[j].text == simpleName && j+1 < to && p.tokens[j+1].text == "{" {
+		bodyEnd := matchingClose(p.tokens, j+1)
+		if bodyEnd < 0 {
+			return to, ""
+		}
+		p.appendMethod(declStart, modStart, j+1, j+1, bodyEnd, simpleName, "constructor", scope, annotations, exported, typeParams, scope.componentTypes, nil, nil)
+		// Its signature shows the parameters it takes from the record
+		// header: "public Point(int x, int y)".
+		p.functions[len(p.functions)-1].Signature += scope.components
+		return bodyEnd + 1, simpleName
+	}
+
+	if paren < 0 || paren == retStart || p.tokens[paren-1].kind != cTokIdent {
+		return p.skipStatement(j, to), ""
+	}
+	name := p.tokens[paren-1].text
+	nodeType := "method"
+	var returnTypes []string
+	if paren-1 == retStart {
+		if name != simpleName {
+			return p.skipStatement(j, to), ""
+		}
+		nodeType = "constructor"
+	} else if ret := tokenSpanText(p.code, p.tokens, retStart, paren-1); ret != "void" {
+		returnTypes = []string{ret}
+	}
+
+	paramsEnd := matchingClose(p.tokens, paren)
+	if paramsEnd < 0 {
+		return to, ""
+	}
+	paramTypes := javaParamTypes(p.code, p.tokens, paren+1, paramsEnd)
+
+	k := paramsEnd + 1
+	// Legacy array dimensions after the parameter list: int foo()[].
+	for k+1 < to && p.tokens[k].text == "[" && p.tokens[k+1].text == "]" {
+		k += 2
+	}
+	var throws []string
+	if k < to && p.tokens[k].text == "throws" {
+		throwsStart := k + 1
+		k = throwsStart
+		for k < to && p.tokens[k].text != "{" && p.tokens[k].text != ";" {
+			k++
+		}
+		throws = tokenListTexts(p.code, p.tokens, throwsStart, k)
+	}
+	sigEnd := k
+	// Annotation members: "String value() default "";"
+	for k < to && p.tokens[k].text != "{" && p.tokens[k].text != ";" {
+		k++
+	}
+	if k >= to || p.tokens[k].text == ";" {
+		// Abstract or interface method without a body.
+		return k + 1, name
+	}
+	bodyEnd := matchingClose(p.tokens, k)
+	if bodyEnd < 0 {
+		return to, name
+	}
+	p.appendMethod(declStart, modStart, sigEnd, k, bodyEnd, name, nodeType, scope, annotations, exported, typeParams, paramTypes, returnTypes, throws)
+	return bodyEnd + 1, name
+}
+
+func (p *javaDeclParser) appendMethod(declStart, modStart, sigEnd, bodyStart, bodyEnd int, name, nodeType string, scope javaScope, annotations []string, exported bool, typeParams, paramTypes, returnTypes, throws []string) {
+	qualified := name
+	if scope.name != "" {
+		qualified = scope.name + "." + name
+	}
+	node := newCFunctionNode(p.code, p.lex.lineStarts, qualified, nodeType, p.tokens[declStart].start, p.tokens[bodyEnd].end)
+	node.PackageName = p.pkg
+	node.Imports = append([]string(nil), p.imports...)
+	node.Signature = tokenSpanText(p.code, p.tokens, modStart, sigEnd)
+	node.Receiver = scope.name
+	node.Doc = p.docBefore(declStart)
+	node.Callees = cCallees(p.tokens[bodyStart+1:bodyEnd], javaKeywords)
+	node.ParamTypes = paramTypes
+	node.ReturnTypes = returnTypes
+	node.Raises = throws
+	node.HasErrorReturn = len(throws) > 0
+	node.Decorators = annotations
+	node.TypeParams = typeParams
+	// Interface members are implicitly public.
+	node.Exported = exported || scope.kind == "interface"
+	p.functions = append(p.functions, node)
+}
+
+// skipStatement advances past a field declaration or other statement.
+func (p *javaDeclParser) skipStatement(i, to int) int {
+	for i < to {
+		switch p.tokens[i].text {
+		case ";":
+			return i + 1
+		case "(", "[", "{":
+			end := matchingClose(p.tokens, i)
+			if end < 0 {
+				return to
+			}
+			i = end
+		case "}":
+			return i + 1
+		}
+		i++
+	}
+	return to
+}
+
+func (p *javaDeclParser) docBefore(tokenIdx int) string {
+	return docCommentBefore(p.code, p.lex.comments, p.tokens[tokenIdx].start, isJavadocComment)
+}
+
+// isJavadocComment reports whether a comment is a Javadoc/KDoc block.
+func isJavadocComment(text string) bool {
+	return strings.HasPrefix(text, "/**") && text != "/**/"
+}
+
+// collectJavaAnnotations reads annotations starting at tokens[i] and returns
+// them without the leading "@" (e.g. `GetMapping("/users")`) together with
+// the index of the first token after them. Kotlin use-site targets such as
+// "@field:JsonProperty" are kept as written.
+func collectJavaAnnotations(code []byte, tokens []cToken, i, to int) ([]string, int) {
+	var annotations []string
+	for i+1 < to && tokens[i].text == "@" && tokens[i+1].kind == cTokIdent && tokens[i+1].text != "interface" {
+		start := i + 1
+		j := start + 1
+		for j+1 < to && (tokens[j].text == "." || tokens[j].text == ":") && tokens[j+1].kind == cTokIdent {
+			j += 2
+		}
+		if j < to && tokens[j].text == "(" && tokens[j].start == tokens[j-1].end {
+			if end := matchingClose(tokens, j); end > 0 {
+				j = end + 1
+			}
+		}
+		annotations = append(annotations, tokenSpanText(code, tokens, start, j))
+		i = j
+	}
+	return annotations, i
+}
+
+// javaParamTypes returns the parameter types in tokens[from:to]: everything
+// but the trailing parameter name, without annotations or "final".
+func javaParamTypes(code []byte, tokens []cToken, from, to int) []string {
+	var types []string
+	for _, part := range splitTokensTopLevel(tokens, from, to, ",", true) {
+		_, start := collectJavaAnnotations(code, tokens, part[0], part[1])
+		for start < part[1] && tokens[start].text == "final" {
+			start++
+		}
+		end := part[1]
+		if end-1 > start && tokens[end-1].kind == cTokIdent {
+			end--
+		}
+		if t := tokenSpanText(code, tokens, start, end); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// tripleQuotedString matches """...""" text blocks (Java) and raw strings
+// (Kotlin), which may contain unescaped quotes.
+func tripleQuotedString(code []byte, pos int) int {
+	if !strings.HasPrefix(string(code[pos:min(pos+3, len(code))]), `"""`) {
+		return -1
+	}
+	i := pos + 3
+	for i < len(code) {
+		if code[i] == '\\' {
+			i += 2
+			continue
+		}
+		if strings.HasPrefix(string(code[i:min(i+3, len(code))]), `"""`) {
+			i += 3
+			// Kotlin allows extra quotes right before the closing delimiter.
+			for i < len(code) && code[i] == '"' {
+				i++
+			}
+			return i
+		}
+		i++
+	}
+	return len(code)
+}
@@ -15,6 +15,7 @@ func (p *JavaScriptParser) Language() string {
 
 // ExtractFunctions extracts function, method, and arrow function definitions from JavaScript source code
 func (p *JavaScriptParser) ExtractFunctions(filePath string, code []byte) ([]FunctionNode, error) {
-	functions := extractJSFunctions(code, false)
+	functions := extractJSFunctions(code, false, isJSXFile(filePath))
+	setResolvedImports(filePath, functions)
 	return functions, nil
 }
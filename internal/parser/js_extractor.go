@@ -11,16 +11,22 @@ import (
 type jsFunctionExtractor struct {
 	code        []byte
 	tsAware     bool
+	jsxAware    bool
 	pos         int
 	lineOffsets []int
 	functions   []FunctionNode
 	imports     []string
+
+	// varAnnotation holds the type annotation of the variable currently
+	// being captured ("const Button: React.FC<Props> = ...").
+	varAnnotation string
 }
 
-func extractJSFunctions(code []byte, tsAware bool) []FunctionNode {
+func extractJSFunctions(code []byte, tsAware, jsxAware bool) []FunctionNode {
 	extractor := &jsFunctionExtractor{
 		code:        code,
 		tsAware:     tsAware,
+		jsxAware:    jsxAware,
 		lineOffsets: buildLineOffsets(code),
 		imports:     extractJSImports(code),
 	}
@@ -33,6 +39,15 @@ func (e *jsFunctionExtractor) scan() {
 		if e.skipWhitespaceCommentsOrStrings() {
 			continue
 		}
+		if e.jsxAware && isJSXStartAt(e.code, e.pos) {
+			if end := e.skipJSXElement(e.pos); end > e.pos {
+				e.pos = end
+				continue
+			}
+		}
+		if e.tsAware && e.tryMatchTypeDeclaration() {
+			continue
+		}
 		if e.tryMatchClass() {
 			continue
 		}
@@ -114,6 +129,9 @@ func (e *jsFunctionExtractor) tryMatchVariableFunction() bool {
 		return false
 	}
 
+	e.skipWhitespaceComments()
+	e.varAnnotation = e.skipOptionalTypeAnnotation()
+	defer func() { e.varAnnotation = "" }()
 	e.skipWhitespaceComments()
 	if e.pos >= len(e.code) || e.code[e.pos] != '=' {
 		return false
@@ -245,11 +263,22 @@ func (e *jsFunctionExtractor) captureArrowFunction(start int, name string) bool
 	e.pos += 2
 	e.skipWhitespaceComments()
 
-	if e.pos >= len(e.code) || e.code[e.pos] != '{' {
-		return false
+	bodyEnd := -1
+	switch {
+	case e.pos < len(e.code) && e.code[e.pos] == '{':
+		bodyEnd = e.scanBalanced(e.pos, '{', '}')
+	case e.jsxAware && e.pos < len(e.code) && e.code[e.pos] == '(':
+		// Concise body returning JSX: "() => (<div>...</div>)".
+		inner := e.pos + 1
+		for inner < len(e.code) && isJSWhitespace(e.code[inner]) {
+			inner++
+		}
+		if isJSXStartAt(e.code, inner) {
+			bodyEnd = e.scanBalanced(e.pos, '(', ')')
+		}
+	case e.jsxAware && isJSXStartAt(e.code, e.pos):
+		bodyEnd = e.skipJSXElement(e.pos)
 	}
-
-	bodyEnd := e.scanBalanced(e.pos, '{', '}')
 	if bodyEnd < 0 {
 		return false
 	}
@@ -541,6 +570,14 @@ func (e *jsFunctionExtractor) scanBalanced(start int, open, close byte) int {
 			} else {
 				pos = next
 			}
+		case '<':
+			if e.jsxAware && isJSXStartAt(e.code, pos) {
+				if end := e.skipJSXElement(pos); end > pos {
+					pos = end
+					continue
+				}
+			}
+			pos++
 		default:
 			pos++
 		}
@@ -707,6 +744,19 @@ func (e *jsFunctionExtractor) appendFunction(name, nodeType string, start, end i
 	doc := e.extractDocComment(start)
 	paramTypes := parseJSParamTypes(paramsText, e.tsAware)
 	returnTypes := parseJSReturnTypes(returnAnnotation)
+	_, exported := e.declarationModifiers(start)
+
+	// In .jsx/.tsx files, PascalCase functions that render JSX are React
+	// components; record their props type, hooks and rendered elements.
+	var propsType string
+	var hooks, jsxElements []string
+	if e.jsxAware && nodeType == "function" && isComponentName(name) {
+		if jsxElements = collectJSXElements(content); len(jsxElements) > 0 {
+			nodeType = "component"
+			propsType = componentPropsType(paramsText, e.varAnnotation)
+			hooks = collectHooks(callees)
+		}
+	}
 
 	e.functions = append(e.functions, FunctionNode{
 		Name:        name,
@@ -724,6 +774,10 @@ func (e *jsFunctionExtractor) appendFunction(name, nodeType string, start, end i
 		Callees:     callees,
 		ParamTypes:  paramTypes,
 		ReturnTypes: returnTypes,
+		Exported:    exported,
+		PropsType:   propsType,
+		Hooks:       hooks,
+		JSXElements: jsxElements,
 	})
 }
 
@@ -1103,9 +1157,12 @@ func extractJSImports(code []byte) []string {
 		if trimmed == "" || strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "/*") {
 			continue
 		}
-		if strings.HasPrefix(trimmed, "import ") {
+		reExport := strings.HasPrefix(trimmed, "export ") && strings.Contains(trimmed, " from ")
+		multiLineTail := strings.HasPrefix(trimmed, "} from ")
+		if strings.HasPrefix(trimmed, "import ") || reExport || multiLineTail {
 			// ES module imports: import x from 'mod'; import {a} from 'mod';
-			// We keep the module specifier in quotes.
+			// re-exports (export * from 'mod') and the closing line of a
+			// multi-line import list. We keep the module specifier in quotes.
 			if idx := strings.LastIndexAny(trimmed, "'\""); idx >= 0 {
 				q := trimmed[idx]
 				start := strings.LastIndex(trimmed[:idx], string(q))
@@ -1197,6 +1254,10 @@ func extractJSCallees(content string) []string {
 	return callees
 }
 
+func isJSWhitespace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n'
+}
+
 func isIdentifierStart(ch byte) bool {
 	return ch == '_' || ch == '$' || unicode.IsLetter(rune(ch))
 }
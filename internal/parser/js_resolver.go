@@ -0,0 +1,542 @@
+package parser
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jsResolveExtensions lists the file extensions tried, in order, when an
+// import specifier omits one.
+var jsResolveExtensions = []string{".ts", ".tsx", ".d.ts", ".js", ".jsx", ".mjs", ".cjs"}
+
+// jsExportConditions lists the package.json "exports" conditions we follow,
+// preferring source and type entries since those point at indexed files.
+var jsExportConditions = []string{"source", "types", "import", "module", "default", "require", "node", "browser"}
+
+// jsModuleResolver resolves JS/TS import specifiers to files on disk using
+// relative paths, tsconfig.json/jsconfig.json "baseUrl" and "paths",
+// package.json "exports" and npm/yarn workspaces. Parsed config files are
+// cached and re-read when their modification time changes.
+type jsModuleResolver struct {
+	mu    sync.Mutex
+	files map[string]*jsConfigFile
+}
+
+type jsConfigFile struct {
+	modTime time.Time
+	data    map[string]interface{}
+	// workspaces caches package name -> directory for a workspace root.
+	workspaces map[string]string
+}
+
+// jsCompilerPaths is the effective module resolution config of a tsconfig.
+type jsCompilerPaths struct {
+	baseURL string              // absolute, empty when unset
+	pathDir string              // directory "paths" targets are relative to
+	paths   map[string][]string // pattern -> targets
+}
+
+var defaultJSResolver = &jsModuleResolver{files: make(map[string]*jsConfigFile)}
+
+// resolveJSImports resolves the import specifiers of filePath and returns the
+// absolute paths of the files they refer to. Specifiers pointing outside the
+// project (e.g. packages in node_modules) are omitted.
+func resolveJSImports(filePath string, imports []string) []string {
+	if filePath == "" || len(imports) == 0 {
+		return nil
+	}
+	absFile, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	var resolved []string
+	for _, spec := range imports {
+		target := defaultJSResolver.resolve(absFile, spec)
+		if target == "" {
+			continue
+		}
+		if _, ok := seen[target]; ok {
+			continue
+		}
+		seen[target] = struct{}{}
+		resolved = append(resolved, target)
+	}
+	sort.Strings(resolved)
+	return resolved
+}
+
+// setResolvedImports resolves the file-level imports shared by all functions
+// of a file once and attaches the result to each of them.
+func setResolvedImports(filePath string, functions []FunctionNode) {
+	if len(functions) == 0 {
+		return
+	}
+	resolved := resolveJSImports(filePath, functions[0].Imports)
+	for i := range functions {
+		functions[i].ResolvedImports = resolved
+	}
+}
+
+func (r *jsModuleResolver) resolve(fromFile, spec string) string {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return ""
+	}
+	dir := filepath.Dir(fromFile)
+
+	if strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../") || spec == "." || spec == ".." {
+		return resolveJSFile(filepath.Join(dir, filepath.FromSlash(spec)))
+	}
+	if filepath.IsAbs(spec) {
+		return resolveJSFile(spec)
+	}
+
+	if cfg := r.compilerPaths(dir); cfg != nil {
+		if target := cfg.resolve(spec); target != "" {
+			return target
+		}
+	}
+
+	return r.resolvePackage(dir, spec)
+}
+
+// resolve applies the "paths" mapping and then "baseUrl" to spec.
+func (c *jsCompilerPaths) resolve(spec string) string {
+	// TypeScript picks the pattern with the longest prefix before "*".
+	var patterns []string
+	for pattern := range c.paths {
+		if _, ok := matchJSPattern(pattern, spec); ok {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		pi := strings.Index(patterns[i], "*")
+		pj := strings.Index(patterns[j], "*")
+		if pi < 0 {
+			pi = len(patterns[i])
+		}
+		if pj < 0 {
+			pj = len(patterns[j])
+		}
+		if pi != pj {
+			return pi > pj
+		}
+		return patterns[i] < patterns[j]
+	})
+	for _, pattern := range patterns {
+		wildcard, _ := matchJSPattern(pattern, spec)
+		for _, target := range c.paths[pattern] {
+			candidate := strings.Replace(target, "*", wildcard, 1)
+			if resolved := resolveJSFile(filepath.Join(c.pathDir, filepath.FromSlash(candidate))); resolved != "" {
+				return resolved
+			}
+		}
+	}
+	if c.baseURL != "" {
+		return resolveJSFile(filepath.Join(c.baseURL, filepath.FromSlash(spec)))
+	}
+	return ""
+}
+
+// compilerPaths loads the nearest tsconfig.json or jsconfig.json above dir,
+// following "extends" chains for baseUrl and paths.
+func (r *jsModuleResolver) compilerPaths(dir string) *jsCompilerPaths {
+	for d := dir; ; d = filepath.Dir(d) {
+		for _, name := range []string{"tsconfig.json", "jsconfig.json"} {
+			configPath := filepath.Join(d, name)
+			if _, err := os.Stat(configPath); err == nil {
+				cfg := &jsCompilerPaths{}
+				r.applyCompilerOptions(configPath, cfg, 0)
+				if cfg.baseURL == "" && len(cfg.paths) == 0 {
+					return nil
+				}
+				if cfg.pathDir == "" {
+					cfg.pathDir = cfg.baseURL
+				}
+				return cfg
+			}
+		}
+		if parent := filepath.Dir(d); parent == d {
+			return nil
+		}
+	}
+}
+
+func (r *jsModuleResolver) applyCompilerOptions(configPath string, cfg *jsCompilerPaths, depth int) {
+	const maxExtendsDepth = 8
+	data := r.loadJSON(configPath)
+	if data == nil || depth > maxExtendsDepth {
+		return
+	}
+	configDir := filepath.Dir(configPath)
+
+	// Settings from the base config apply first and are overridden below.
+	if ext, ok := data["extends"].(string); ok && (strings.HasPrefix(ext, ".") || filepath.IsAbs(ext)) {
+		base := ext
+		if !filepath.IsAbs(base) {
+			base = filepath.Join(configDir, filepath.FromSlash(ext))
+		}
+		if !strings.HasSuffix(base, ".json") {
+			base += ".json"
+		}
+		r.applyCompilerOptions(base, cfg, depth+1)
+	}
+
+	options, _ := data["compilerOptions"].(map[string]interface{})
+	if options == nil {
+		return
+	}
+	if baseURL, ok := options["baseUrl"].(string); ok {
+		cfg.baseURL = filepath.Join(configDir, filepath.FromSlash(baseURL))
+		cfg.pathDir = cfg.baseURL
+	}
+	if paths, ok := options["paths"].(map[string]interface{}); ok {
+		cfg.paths = make(map[string][]string, len(paths))
+		for pattern, raw := range paths {
+			targets, _ := raw.([]interface{})
+			for _, t := range targets {
+				if s, ok := t.(string); ok {
+					cfg.paths[pattern] = append(cfg.paths[pattern], s)
+				}
+			}
+		}
+		if cfg.baseURL == "" {
+			cfg.pathDir = configDir
+		}
+	}
+}
+
+// resolvePackage resolves a bare specifier against the enclosing package
+// (self-reference) and the packages of the enclosing workspace.
+func (r *jsModuleResolver) resolvePackage(dir, spec string) string {
+	name, subpath := splitJSPackageSpecifier(spec)
+	if name == "" {
+		return ""
+	}
+
+	for d := dir; ; d = filepath.Dir(d) {
+		manifestPath := filepath.Join(d, "package.json")
+		if manifest := r.loadJSON(manifestPath); manifest != nil {
+			if pkgName, _ := manifest["name"].(string); pkgName == name {
+				return resolveJSPackageEntry(d, manifest, subpath)
+			}
+			if packages := r.workspacePackages(manifestPath); packages != nil {
+				if pkgDir, ok := packages[name]; ok {
+					return resolveJSPackageEntry(pkgDir, r.loadJSON(filepath.Join(pkgDir, "package.json")), subpath)
+				}
+			}
+		}
+		if parent := filepath.Dir(d); parent == d {
+			return ""
+		}
+	}
+}
+
+// workspacePackages maps package names to directories for a package.json
+// declaring "workspaces" (either an array or {"packages": [...]}).
+func (r *jsModuleResolver) workspacePackages(manifestPath string) map[string]string {
+	manifest := r.loadJSON(manifestPath)
+	if manifest == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	entry := r.files[manifestPath]
+	if entry != nil && entry.workspaces != nil {
+		cached := entry.workspaces
+		r.mu.Unlock()
+		return cached
+	}
+	r.mu.Unlock()
+
+	var patterns []interface{}
+	switch ws := manifest["workspaces"].(type) {
+	case []interface{}:
+		patterns = ws
+	case map[string]interface{}:
+		patterns, _ = ws["packages"].([]interface{})
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	root := filepath.Dir(manifestPath)
+	packages := make(map[string]string)
+	for _, raw := range patterns {
+		pattern, ok := raw.(string)
+		if !ok || strings.HasPrefix(pattern, "!") {
+			continue
+		}
+		// "packages/**" is treated like "packages/*"; nested workspaces are rare.
+		pattern = strings.ReplaceAll(pattern, "**", "*")
+		matches, err := filepath.Glob(filepath.Join(root, filepath.FromSlash(pattern), "package.json"))
+		if err != nil {
+			continue
+		}
+		for _, m := range matches {
+			if pkg := r.loadJSON(m); pkg != nil {
+				if name, _ := pkg["name"].(string); name != "" {
+					packages[name] = filepath.Dir(m)
+				}
+			}
+		}
+	}
+
+	r.mu.Lock()
+	if entry != nil {
+		entry.workspaces = packages
+	}
+	r.mu.Unlock()
+	return packages
+}
+
+// loadJSON reads and caches a JSON config file, tolerating the comments and
+// trailing commas allowed in tsconfig.json. It returns nil if the file is
+// missing or malformed.
+func (r *jsModuleResolver) loadJSON(path string) map[string]interface{} {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return nil
+	}
+
+	r.mu.Lock()
+	if entry, ok := r.files[path]; ok && entry.modTime.Equal(info.ModTime()) {
+		r.mu.Unlock()
+		return entry.data
+	}
+	r.mu.Unlock()
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(stripJSONComments(raw), &data); err != nil {
+		data = nil
+	}
+
+	r.mu.Lock()
+	r.files[path] = &jsConfigFile{modTime: info.ModTime(), data: data}
+	r.mu.Unlock()
+	return data
+}
+
+// resolveJSPackageEntry resolves subpath ("" for the package root) inside a
+// package directory using "exports", then "module"/"main"/"types", then the
+// plain file layout.
+func resolveJSPackageEntry(pkgDir string, manifest map[string]interface{}, subpath string) string {
+	if manifest != nil {
+		if exports, ok := manifest["exports"]; ok {
+			key := "."
+			if subpath != "" {
+				key = "./" + subpath
+			}
+			if target := jsExportTarget(exports, key); target != "" {
+				return resolveJSFile(filepath.Join(pkgDir, filepath.FromSlash(target)))
+			}
+		}
+		if subpath == "" {
+			for _, field := range []string{"source", "types", "typings", "module", "main"} {
+				if entry, ok := manifest[field].(string); ok && entry != "" {
+					if resolved := resolveJSFile(filepath.Join(pkgDir, filepath.FromSlash(entry))); resolved != "" {
+						return resolved
+					}
+				}
+			}
+		}
+	}
+	return resolveJSFile(filepath.Join(pkgDir, filepath.FromSlash(subpath)))
+}
+
+// jsExportTarget looks up key ("." or "./sub") in a package.json "exports"
+// value, supporting shorthand strings, subpath patterns and conditions.
+func jsExportTarget(exports interface{}, key string) string {
+	switch v := exports.(type) {
+	case string:
+		if key == "." {
+			return v
+		}
+		return ""
+	case map[string]interface{}:
+		isSubpathMap := false
+		for k := range v {
+			if strings.HasPrefix(k, ".") {
+				isSubpathMap = true
+				break
+			}
+		}
+		if !isSubpathMap {
+			if key != "." {
+				return ""
+			}
+			return jsConditionTarget(v)
+		}
+		if target, ok := v[key]; ok {
+			return jsConditionTarget(target)
+		}
+		var patterns []string
+		for k := range v {
+			if strings.Contains(k, "*") {
+				patterns = append(patterns, k)
+			}
+		}
+		sort.Slice(patterns, func(i, j int) bool { return len(patterns[i]) > len(patterns[j]) })
+		for _, pattern := range patterns {
+			if wildcard, ok := matchJSPattern(pattern, key); ok {
+				if target := jsConditionTarget(v[pattern]); target != "" {
+					return strings.ReplaceAll(target, "*", wildcard)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func jsConditionTarget(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		for _, item := range v {
+			if target := jsConditionTarget(item); target != "" {
+				return target
+			}
+		}
+	case map[string]interface{}:
+		for _, cond := range jsExportConditions {
+			if nested, ok := v[cond]; ok {
+				if target := jsConditionTarget(nested); target != "" {
+					return target
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// resolveJSFile applies Node/TypeScript file conventions to a path: the exact
+// file, the path with a known extension appended, a ".js" specifier that
+// refers to a ".ts" source, and finally an index file inside a directory.
+func resolveJSFile(path string) string {
+	path = filepath.Clean(path)
+	if isRegularFile(path) {
+		return path
+	}
+	for _, ext := range jsResolveExtensions {
+		if isRegularFile(path + ext) {
+			return path + ext
+		}
+	}
+	switch ext := filepath.Ext(path); ext {
+	case ".js", ".jsx", ".mjs", ".cjs":
+		stem := strings.TrimSuffix(path, ext)
+		for _, tsExt := range []string{".ts", ".tsx", ".mts", ".cts"} {
+			if isRegularFile(stem + tsExt) {
+				return stem + tsExt
+			}
+		}
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		for _, ext := range jsResolveExtensions {
+			index := filepath.Join(path, "index"+ext)
+			if isRegularFile(index) {
+				return index
+			}
+		}
+	}
+	return ""
+}
+
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// matchJSPattern matches spec against a pattern containing at most one "*"
+// and returns the text matched by the wildcard.
+func matchJSPattern(pattern, spec string) (string, bool) {
+	star := strings.Index(pattern, "*")
+	if star < 0 {
+		return "", pattern == spec
+	}
+	prefix, suffix := pattern[:star], pattern[star+1:]
+	if len(spec) < len(prefix)+len(suffix) || !strings.HasPrefix(spec, prefix) || !strings.HasSuffix(spec, suffix) {
+		return "", false
+	}
+	return spec[len(prefix) : len(spec)-len(suffix)], true
+}
+
+// splitJSPackageSpecifier splits "@scope/pkg/sub/path" into the package name
+// "@scope/pkg" and the subpath "sub/path".
+func splitJSPackageSpecifier(spec string) (string, string) {
+	parts := strings.Split(spec, "/")
+	n := 1
+	if strings.HasPrefix(spec, "@") {
+		if len(parts) < 2 {
+			return "", ""
+		}
+		n = 2
+	}
+	if len(parts) < n {
+		return "", ""
+	}
+	return strings.Join(parts[:n], "/"), strings.Join(parts[n:], "/")
+}
+
+// stripJSONComments removes // and /* */ comments and trailing commas so
+// tsconfig-style JSON can be decoded with encoding/json.
+func stripJSONComments(src []byte) []byte {
+	out := make([]byte, 0, len(src))
+	inString := false
+	for i := 0; i < len(src); i++ {
+		ch := src[i]
+		if inString {
+			out = append(out, ch)
+			if ch == '\\' && i+1 < len(src) {
+				i++
+				out = append(out, src[i])
+			} else if ch == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case ch == '"':
+			inString = true
+			out = append(out, ch)
+		case ch == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			if i < len(src) {
+				out = append(out, '\n')
+			}
+		case ch == '/' && i+1 < len(src) && src[i+1] == '*':
+			i += 2
+			for i+1 < len(src) && !(src[i] == '*' && src[i+1] == '/') {
+				i++
+			}
+			i++
+		case ch == '}' || ch == ']':
+			// Drop a trailing comma before the closing bracket.
+			j := len(out) - 1
+			for j >= 0 && (out[j] == ' ' || out[j] == '\t' || out[j] == '\r' || out[j] == '\n') {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+			out = append(out, ch)
+		default:
+			out = append(out, ch)
+		}
+	}
+	return out
+}
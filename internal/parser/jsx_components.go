@@ -0,0 +1,223 @@
+package parser
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// jsxComponentTypeRegex extracts the props type from component annotations
+// such as "React.FC<ButtonProps>" or "FunctionComponent<Props>".
+var jsxComponentTypeRegex = regexp.MustCompile(`^(?:React\.)?(?:FC|FunctionComponent|VFC|ComponentType)<(.+)>$`)
+
+// isJSXFile reports whether JSX syntax is expected in the given file.
+func isJSXFile(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	return ext == ".jsx" || ext == ".tsx"
+}
+
+// isJSXStartAt reports whether the '<' at pos opens a JSX element rather than
+// a comparison or a type argument list. JSX can only appear where an
+// expression starts, so the previous significant token must be a punctuator
+// or a keyword like "return". The type parameters of a generic arrow
+// function, written "<T,>" or "<T extends U>" in TSX, are not JSX either.
+func isJSXStartAt(code []byte, pos int) bool {
+	if pos+1 >= len(code) || code[pos] != '<' {
+		return false
+	}
+	next := code[pos+1]
+	if next != '>' && !((next >= 'a' && next <= 'z') || (next >= 'A' && next <= 'Z')) {
+		return false
+	}
+	if isTypeParameterListAt(code, pos) {
+		return false
+	}
+
+	i := pos - 1
+	for i >= 0 && (code[i] == ' ' || code[i] == '\t' || code[i] == '\r' || code[i] == '\n') {
+		i--
+	}
+	if i < 0 {
+		return true
+	}
+	switch code[i] {
+	case '(', ',', '=', ':', '?', '&', '|', '{', '}', '[', '>', ';', '!':
+		return true
+	}
+	if !isIdentifierPart(code[i]) {
+		return false
+	}
+	end := i + 1
+	for i >= 0 && isIdentifierPart(code[i]) {
+		i--
+	}
+	switch string(code[i+1 : end]) {
+	case "return", "yield", "default", "case", "await":
+		return true
+	}
+	return false
+}
+
+// isTypeParameterListAt reports whether the '<' at pos starts a type
+// parameter list: a name followed by ',' or by the "extends" keyword.
+func isTypeParameterListAt(code []byte, pos int) bool {
+	i := pos + 1
+	for i < len(code) && isIdentifierPart(code[i]) {
+		i++
+	}
+	for i < len(code) && (code[i] == ' ' || code[i] == '\t' || code[i] == '\r' || code[i] == '\n') {
+		i++
+	}
+	if i < len(code) && code[i] == ',' {
+		return true
+	}
+	end := i + len("extends")
+	return end < len(code) && string(code[i:end]) == "extends" && !isIdentifierPart(code[end])
+}
+
+// skipJSXElement returns the offset just past the JSX element or fragment
+// starting at start, or -1 if it cannot be matched. Text children are not
+// treated as string literals, so apostrophes in copy do not derail scanning.
+func (e *jsFunctionExtractor) skipJSXElement(start int) int {
+	pos := start + 1
+	if pos < len(e.code) && e.code[pos] == '>' {
+		return e.skipJSXChildren(pos + 1)
+	}
+	nameStart := pos
+	for pos < len(e.code) && isJSXNamePart(e.code[pos]) {
+		pos++
+	}
+	if pos == nameStart {
+		return -1
+	}
+	for pos < len(e.code) {
+		ch := e.code[pos]
+		switch {
+		case ch == '/' && pos+1 < len(e.code) && e.code[pos+1] == '>':
+			return pos + 2
+		case ch == '>':
+			return e.skipJSXChildren(pos + 1)
+		case ch == '"' || ch == '\'':
+			pos = skipStringLiteralFrom(e.code, pos)
+		case ch == '{':
+			end := e.scanBalanced(pos, '{', '}')
+			if end < 0 {
+				return -1
+			}
+			pos = end
+		default:
+			pos++
+		}
+	}
+	return -1
+}
+
+func (e *jsFunctionExtractor) skipJSXChildren(pos int) int {
+	for pos < len(e.code) {
+		switch e.code[pos] {
+		case '{':
+			end := e.scanBalanced(pos, '{', '}')
+			if end < 0 {
+				return -1
+			}
+			pos = end
+		case '<':
+			if pos+1 < len(e.code) && e.code[pos+1] == '/' {
+				closeEnd := pos + 2
+				for closeEnd < len(e.code) && e.code[closeEnd] != '>' {
+					closeEnd++
+				}
+				if closeEnd >= len(e.code) {
+					return -1
+				}
+				return closeEnd + 1
+			}
+			end := e.skipJSXElement(pos)
+			if end < 0 {
+				return -1
+			}
+			pos = end
+		default:
+			pos++
+		}
+	}
+	return -1
+}
+
+func isJSXNamePart(ch byte) bool {
+	return isIdentifierPart(ch) || ch == '.' || ch == ':' || ch == '-'
+}
+
+// collectJSXElements lists the element names rendered in a snippet, in order
+// of first appearance (e.g. "div", "Modal", "Form.Item"). String literals are
+// not skipped because quotes inside JSX text are not string delimiters.
+func collectJSXElements(content string) []string {
+	code := []byte(content)
+	seen := make(map[string]struct{})
+	var elements []string
+	for i := 0; i < len(code); i++ {
+		if code[i] != '<' || !isJSXStartAt(code, i) {
+			continue
+		}
+		end := i + 1
+		for end < len(code) && isJSXNamePart(code[end]) {
+			end++
+		}
+		name := string(code[i+1 : end])
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		elements = append(elements, name)
+	}
+	return elements
+}
+
+// collectHooks returns the React hooks called in a function: callees whose
+// name is "use" followed by an upper-case letter.
+func collectHooks(callees []string) []string {
+	var hooks []string
+	for _, callee := range callees {
+		if len(callee) > 3 && strings.HasPrefix(callee, "use") && callee[3] >= 'A' && callee[3] <= 'Z' {
+			hooks = append(hooks, callee)
+		}
+	}
+	return hooks
+}
+
+// componentPropsType derives the props type of a component from the
+// variable annotation (React.FC<Props>) or the first parameter, which may be
+// typed ("props: Props", "{ a, b }: Props") or an untyped destructuring.
+func componentPropsType(paramsText, varAnnotation string) string {
+	if m := jsxComponentTypeRegex.FindStringSubmatch(strings.TrimSpace(varAnnotation)); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	paramsText = strings.TrimSpace(paramsText)
+	if strings.HasPrefix(paramsText, "(") && strings.HasSuffix(paramsText, ")") {
+		paramsText = paramsText[1 : len(paramsText)-1]
+	}
+	params := splitJSParameters(paramsText)
+	if len(params) == 0 {
+		return ""
+	}
+	first := strings.TrimSpace(stripJSDefaultValue(params[0]))
+	if idx := findTopLevelColon(first); idx >= 0 {
+		return strings.TrimSpace(first[idx+1:])
+	}
+	if strings.HasPrefix(first, "{") {
+		return collapseWhitespace(first)
+	}
+	return ""
+}
+
+// isComponentName reports whether name follows React's component naming
+// convention (PascalCase, optionally qualified like "Card.Header").
+func isComponentName(name string) bool {
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return name != "" && name[0] >= 'A' && name[0] <= 'Z'
+}
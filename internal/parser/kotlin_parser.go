@@ -0,0 +1,539 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+// KotlinParser implements LanguageParser for Kotlin language
+type KotlinParser struct{}
+
+// NewKotlinParser creates a new Kotlin parser
+func NewKotlinParser() *KotlinParser {
+	return &KotlinParser{}
+}
+
+// Language returns the language name
+func (p *KotlinParser) Language() string {
+	return string(LanguageKotlin)
+}
+
+// kotlinKeywords are never reported as callees.
+var kotlinKeywords = map[string]bool{
+	"if": true, "when": true, "for": true, "while": true, "return": true, "throw": true,
+	"catch": true, "try": true, "else": true, "super": true, "this": true, "object": true,
+	"is": true, "in": true, "as": true, "fun": true, "val": true, "var": true, "do": true,
+}
+
+// kotlinModifiers are declaration modifiers skipped before a keyword.
+var kotlinModifiers = map[string]bool{
+	"public": true, "private": true, "protected": true, "internal": true, "open": true,
+	"final": true, "abstract": true, "sealed": true, "data": true, "enum": true,
+	"annotation": true, "inner": true, "override": true, "suspend": true, "inline": true,
+	"noinline": true, "crossinline": true, "tailrec": true, "operator": true, "infix": true,
+	"external": true, "lateinit": true, "const": true, "expect": true, "actual": true,
+	"value": true, "companion": true, "vararg": true, "reified": true,
+}
+
+// kotlinContinuationEnd lists tokens after which a declaration continues on
+// the next line; kotlinContinuationStart lists tokens that continue the
+// previous line when they start a new one.
+var (
+	kotlinContinuationEnd   = map[string]bool{",": true, ":": true, ".": true, "?.": true, "=": true, "->": true, "(": true, "<": true, "&&": true, "||": true, "+": true, "-": true, "*": true, "/": true, "?": true, "where": true, "by": true}
+	kotlinContinuationStart = map[string]bool{".": true, "?.": true, ",": true, ":": true, "=": true, "->": true, "?": true, ")": true, ">": true, "]": true, "where": true, "by": true, "&&": true, "||": true}
+)
+
+type kotlinScope struct {
+	name string // qualified enclosing class/object name
+	kind string
+}
+
+type kotlinDeclParser struct {
+	code      []byte
+	lex       *cLexResult
+	tokens    []cToken
+	pkg       string
+	imports   []string
+	functions []FunctionNode
+}
+
+// ExtractFunctions extracts classes, interfaces, objects, member functions,
+// constructors and top-level/extension functions from Kotlin source code.
+func (p *KotlinParser) ExtractFunctions(filePath string, code []byte) ([]FunctionNode, error) {
+	lex := lexCLike(code, cLexOptions{nestedComments: true, stringPrefix: kotlinString})
+	kp := &kotlinDeclParser{code: code, lex: lex, tokens: lex.tokens}
+	kp.parseHeader()
+	kp.parseDecls(0, len(lex.tokens), kotlinScope{})
+
+	sort.SliceStable(kp.functions, func(i, j int) bool {
+		return kp.functions[i].StartByte < kp.functions[j].StartByte
+	})
+	return kp.functions, nil
+}
+
+// startsLine reports whether tokens[i] is the first token on its line.
+func (p *kotlinDeclParser) startsLine(i int) bool {
+	return i == 0 || strings.IndexByte(string(p.code[p.tokens[i-1].end:p.tokens[i].start]), '\n') >= 0
+}
+
+func (p *kotlinDeclParser) parseHeader() {
+	for i := 0; i < len(p.tokens); i++ {
+		tok := p.tokens[i]
+		if tok.kind != cTokIdent || (tok.text != "package" && tok.text != "import") || !p.startsLine(i) {
+			continue
+		}
+		var b strings.Builder
+		j := i + 1
+		for ; j < len(p.tokens) && !p.startsLine(j) && p.tokens[j].text != ";"; j++ {
+			if p.tokens[j].text == "as" {
+				// Skip the alias.
+				for j+1 < len(p.tokens) && !p.startsLine(j+1) && p.tokens[j+1].text != ";" {
+					j++
+				}
+				continue
+			}
+			b.WriteString(p.tokens[j].text)
+		}
+		if tok.text == "package" {
+			p.pkg = b.String()
+		} else if path := b.String(); path != "" {
+			p.imports = append(p.imports, path)
+		}
+		i = j - 1
+	}
+	sort.Strings(p.imports)
+}
+
+// parseDecls walks declarations in tokens[from:to] and returns the names of
+// the functions and nested types declared at this level.
+func (p *kotlinDeclParser) parseDecls(from, to int, scope kotlinScope) []string {
+	var members []string
+	i := from
+	for i < to {
+		tok := p.tokens[i]
+		if tok.text == ";" {
+			i++
+			continue
+		}
+		if tok.kind == cTokIdent && (tok.text == "package" || tok.text == "import") && scope.name == "" {
+			i++
+			for i < to && !p.startsLine(i) {
+				i++
+			}
+			continue
+		}
+
+		declStart := i
+		annotations, next := collectJavaAnnotations(p.code, p.tokens, i, to)
+		i = next
+		modStart := i
+		mods := make(map[string]bool)
+		for i+1 < to && kotlinModifiers[p.tokens[i].text] && p.tokens[i+1].kind == cTokIdent {
+			mods[p.tokens[i].text] = true
+			i++
+		}
+		if i >= to {
+			break
+		}
+		exported := !mods["private"] && !mods["internal"] && !mods["protected"]
+
+		switch p.tokens[i].text {
+		case "fun":
+			if i+1 < to && p.tokens[i+1].text == "interface" {
+				end, name := p.parseClass(declStart, modStart, i+1, to, "interface", scope, annotations, exported)
+				members = appendNonEmpty(members, name)
+				i = end
+				continue
+			}
+			end, name := p.parseFunction(declStart, modStart, i, to, scope, annotations, mods, exported)
+			members = appendNonEmpty(members, name)
+			i = end
+			continue
+		case "class", "interface", "object":
+			kind := p.tokens[i].text
+			switch {
+			case mods["enum"]:
+				kind = "enum"
+			case mods["annotation"]:
+				kind = "annotation"
+			}
+			end, name := p.parseClass(declStart, modStart, i, to, kind, scope, annotations, exported)
+			members = appendNonEmpty(members, name)
+			i = end
+			continue
+		case "constructor":
+			if scope.name != "" {
+				end, name := p.parseFunction(declStart, modStart, i, to, scope, annotations, mods, exported)
+				members = appendNonEmpty(members, name)
+				i = end
+				continue
+			}
+		case "init":
+			if i+1 < to && p.tokens[i+1].text == "{" {
+				if end := matchingClose(p.tokens, i+1); end > 0 {
+					i = end + 1
+					continue
+				}
+			}
+		}
+		i = p.statementEnd(i+1, to, false)
+	}
+	return members
+}
+
+func appendNonEmpty(list []string, s string) []string {
+	if s == "" {
+		return list
+	}
+	return append(list, s)
+}
+
+// statementEnd returns the index of the first token after the declaration
+// or expression that continues from tokens[i-1]. In header mode a "{" at
+// depth zero ends the scan (it opens the body); otherwise braces are
+// skipped as lambdas or blocks.
+func (p *kotlinDeclParser) statementEnd(i, to int, header bool) int {
+	for i < to {
+		tok := p.tokens[i]
+		if i > 0 && p.startsLine(i) && !kotlinContinuationEnd[p.tokens[i-1].text] && !kotlinContinuationStart[tok.text] && !(header && tok.text == "{") {
+			return i
+		}
+		switch tok.text {
+		case ";", "}", ")", "]":
+			return i
+		case "{":
+			if header {
+				return i
+			}
+			fallthrough
+		case "(", "[":
+			end := matchingClose(p.tokens, i)
+			if end < 0 {
+				return to
+			}
+			i = end + 1
+			continue
+		case "<":
+			if end := matchingClose(p.tokens, i); end > 0 {
+				i = end + 1
+				continue
+			}
+		}
+		i++
+	}
+	return to
+}
+
+func (p *kotlinDeclParser) parseClass(declStart, modStart, kwIdx, to int, kind string, scope kotlinScope, annotations []string, exported bool) (int, string) {
+	j := kwIdx + 1
+	name := ""
+	if j < to && p.tokens[j].kind == cTokIdent && !p.startsLine(j) {
+		name = p.tokens[j].text
+		j++
+	} else if kind == "object" {
+		name = "Companion"
+	} else {
+		return j, ""
+	}
+	qualified := name
+	if scope.name != "" {
+		qualified = scope.name + "." + name
+	}
+
+	var typeParams []string
+	if j < to && p.tokens[j].text == "<" {
+		if end := matchingClose(p.tokens, j); end > 0 {
+			typeParams = tokenListTexts(p.code, p.tokens, j+1, end)
+			j = end + 1
+		}
+	}
+	headerEnd := p.statementEnd(j, to, true)
+	lastToken := headerEnd - 1
+	var members []string
+	if headerEnd < to && p.tokens[headerEnd].text == "{" {
+		bodyEnd := matchingClose(p.tokens, headerEnd)
+		if bodyEnd < 0 {
+			return to, ""
+		}
+		bodyStart := headerEnd + 1
+		if kind == "enum" {
+			var constants []string
+			constants, bodyStart = p.enumEntries(bodyStart, bodyEnd)
+			members = append(members, constants...)
+		}
+		members = append(members, p.parseDecls(bodyStart, bodyEnd, kotlinScope{name: qualified, kind: kind})...)
+		lastToken = bodyEnd
+	}
+	if lastToken < kwIdx {
+		lastToken = kwIdx
+	}
+
+	node := newCFunctionNode(p.code, p.lex.lineStarts, qualified, kind, p.tokens[declStart].start, p.tokens[lastToken].end)
+	node.PackageName = p.pkg
+	node.Imports = append([]string(nil), p.imports...)
+	node.Signature = tokenSpanText(p.code, p.tokens, modStart, headerEnd)
+	node.Receiver = scope.name
+	node.Doc = p.docBefore(declStart)
+	node.Decorators = annotations
+	node.TypeParams = typeParams
+	node.Members = members
+	node.Exported = exported
+	p.functions = append(p.functions, node)
+	return lastToken + 1, name
+}
+
+// enumEntries returns the entry names of an enum class body and the index
+// after the terminating ';'.
+func (p *kotlinDeclParser) enumEntries(from, to int) ([]string, int) {
+	var entries []string
+	expectName := true
+	for i := from; i < to; i++ {
+		tok := p.tokens[i]
+		switch {
+		case tok.text == ";":
+			return entries, i + 1
+		case tok.text == ",":
+			expectName = true
+		case tok.text == "(" || tok.text == "{":
+			if end := matchingClose(p.tokens, i); end > 0 {
+				i = end
+			}
+		case tok.text == "@":
+			_, next := collectJavaAnnotations(p.code, p.tokens, i, to)
+			i = next - 1
+		case tok.kind == cTokIdent && expectName:
+			if kotlinModifiers[tok.text] || tok.text == "fun" || tok.text == "val" || tok.text == "var" {
+				// An enum without a ';' has no members after its entries.
+				return entries, i
+			}
+			entries = append(entries, tok.text)
+			expectName = false
+		}
+	}
+	return entries, to
+}
+
+func (p *kotlinDeclParser) parseFunction(declStart, modStart, kwIdx, to int, scope kotlinScope, annotations []string, mods map[string]bool, exported bool) (int, string) {
+	j := kwIdx + 1
+	var typeParams []string
+	if p.tokens[kwIdx].text == "fun" && j < to && p.tokens[j].text == "<" {
+		if end := matchingClose(p.tokens, j); end > 0 {
+			typeParams = tokenListTexts(p.code, p.tokens, j+1, end)
+			j = end + 1
+		}
+	}
+
+	// Name, optionally preceded by an extension receiver type.
+	paren := j
+	for paren < to && p.tokens[paren].text != "(" {
+		switch p.tokens[paren].text {
+		case "<":
+			if end := matchingClose(p.tokens, paren); end > 0 {
+				paren = end
+			}
+		case "{", "=", ";", "}":
+			return p.statementEnd(j, to, false), ""
+		}
+		paren++
+	}
+	if paren >= to {
+		return to, ""
+	}
+
+	name, receiverType := "constructor", ""
+	if p.tokens[kwIdx].text == "fun" {
+		if paren == j || p.tokens[paren-1].kind != cTokIdent {
+			return p.statementEnd(paren, to, false), ""
+		}
+		name = p.tokens[paren-1].text
+		if paren-2 > j && p.tokens[paren-2].text == "." {
+			receiverType = tokenSpanText(p.code, p.tokens, j, paren-2)
+		}
+	}
+
+	paramsEnd := matchingClose(p.tokens, paren)
+	if paramsEnd < 0 {
+		return to, ""
+	}
+	paramTypes := kotlinParamTypes(p.code, p.tokens, paren+1, paramsEnd)
+
+	k := paramsEnd + 1
+	var returnTypes []string
+	// The return type may be wrapped onto the next line, as in an
+	// expression body "fun expr(x: Int)\n    : Int = x * 2".
+	if k < to && p.tokens[k].text == ":" && p.tokens[kwIdx].text == "fun" {
+		retStart := k + 1
+		k = retStart
+		for k < to && p.tokens[k].text != "{" && p.tokens[k].text != "=" && p.tokens[k].text != "where" && (k == retStart || !p.startsLine(k) || kotlinContinuationStart[p.tokens[k].text]) {
+			if p.tokens[k].text == "<" || p.tokens[k].text == "(" {
+				if end := matchingClose(p.tokens, k); end > 0 {
+					k = end
+				}
+			}
+			k++
+		}
+		if ret := tokenSpanText(p.code, p.tokens, retStart, k); ret != "" && ret != "Unit" {
+			returnTypes = []string{ret}
+		}
+	}
+	// where clauses, and delegation calls of secondary constructors.
+	if k < to && (p.tokens[k].text == "where" || p.tokens[k].text == ":") {
+		k = p.statementEnd(k+1, to, true)
+	}
+	sigEnd := k
+
+	var bodyStart, bodyEnd int
+	switch {
+	case k < to && p.tokens[k].text == "{":
+		bodyStart = k
+		bodyEnd = matchingClose(p.tokens, k)
+		if bodyEnd < 0 {
+			return to, name
+		}
+	case k < to && p.tokens[k].text == "=":
+		bodyStart = k
+		bodyEnd = p.statementEnd(k+1, to, false) - 1
+		if bodyEnd <= k {
+			return k + 1, name
+		}
+	default:
+		// Abstract or interface function without a body.
+		return k, name
+	}
+
+	qualified, nodeType, receiver := name, "function", ""
+	switch {
+	case receiverType != "":
+		qualified, nodeType, receiver = receiverType+"."+name, "extension_function", receiverType
+	case p.tokens[kwIdx].text == "constructor":
+		qualified, nodeType, receiver = scope.name+".constructor", "constructor", scope.name
+	case scope.name != "":
+		qualified, nodeType, receiver = scope.name+"."+name, "method", scope.name
+	}
+
+	raises := kotlinThrows(annotations)
+	node := newCFunctionNode(p.code, p.lex.lineStarts, qualified, nodeType, p.tokens[declStart].start, p.tokens[bodyEnd].end)
+	node.PackageName = p.pkg
+	node.Imports = append([]string(nil), p.imports...)
+	node.Signature = tokenSpanText(p.code, p.tokens, modStart, sigEnd)
+	node.Receiver = receiver
+	node.Doc = p.docBefore(declStart)
+	node.Callees = cCallees(p.tokens[bodyStart+1:bodyEnd+1], kotlinKeywords)
+	node.ParamTypes = paramTypes
+	node.ReturnTypes = returnTypes
+	node.Raises = raises
+	node.HasErrorReturn = len(raises) > 0 || (len(returnTypes) > 0 && rustBaseTypeName(returnTypes[0]) == "Result")
+	node.Decorators = annotations
+	node.IsAsync = mods["suspend"]
+	node.TypeParams = typeParams
+	node.Exported = exported
+	p.functions = append(p.functions, node)
+	return bodyEnd + 1, name
+}
+
+func (p *kotlinDeclParser) docBefore(tokenIdx int) string {
+	return docCommentBefore(p.code, p.lex.comments, p.tokens[tokenIdx].start, isJavadocComment)
+}
+
+// kotlinParamTypes returns the declared types of "name: Type = default"
+// parameters in tokens[from:to].
+func kotlinParamTypes(code []byte, tokens []cToken, from, to int) []string {
+	var types []string
+	for _, part := range splitTokensTopLevel(tokens, from, to, ",", true) {
+		colon, end := -1, part[1]
+		depth := 0
+		for k := part[0]; k < part[1]; k++ {
+			switch tokens[k].text {
+			case "(", "[", "<":
+				depth++
+			case ")", "]", ">":
+				depth--
+			case ":":
+				if depth == 0 && colon < 0 {
+					colon = k
+				}
+			case "=":
+				if depth == 0 && colon >= 0 {
+					end = k
+					k = part[1]
+				}
+			}
+		}
+		if colon < 0 {
+			continue
+		}
+		if t := tokenSpanText(code, tokens, colon+1, end); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// kotlinThrows extracts exception types from a @Throws(A::class, B::class)
+// annotation.
+func kotlinThrows(annotations []string) []string {
+	var raises []string
+	for _, a := range annotations {
+		if !strings.HasPrefix(a, "Throws(") && !strings.HasPrefix(a, "kotlin.jvm.Throws(") {
+			continue
+		}
+		args := a[strings.IndexByte(a, '(')+1 : len(a)-1]
+		for _, arg := range splitTopLevel(args, ',') {
+			if t := strings.TrimSuffix(arg, "::class"); t != "" {
+				raises = append(raises, t)
+			}
+		}
+	}
+	return raises
+}
+
+// kotlinString matches Kotlin string literals, including raw """ strings and
+// ${...} templates that may contain nested quotes.
+func kotlinString(code []byte, pos int) int {
+	if code[pos] != '"' {
+		return -1
+	}
+	raw := strings.HasPrefix(string(code[pos:min(pos+3, len(code))]), `"""`)
+	i := pos + 1
+	if raw {
+		i = pos + 3
+	}
+	for i < len(code) {
+		switch {
+		case !raw && code[i] == '\\':
+			i += 2
+			continue
+		case code[i] == '$' && i+1 < len(code) && code[i+1] == '{':
+			depth := 0
+			for i < len(code) {
+				if code[i] == '"' {
+					if end := kotlinString(code, i); end > i {
+						i = end
+						continue
+					}
+				}
+				if code[i] == '{' {
+					depth++
+				} else if code[i] == '}' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+				i++
+			}
+		case raw && strings.HasPrefix(string(code[i:min(i+3, len(code))]), `"""`):
+			i += 3
+			for i < len(code) && code[i] == '"' {
+				i++
+			}
+			return i
+		case !raw && code[i] == '"':
+			return i + 1
+		case !raw && code[i] == '\n':
+			return i
+		}
+		i++
+	}
+	return len(code)
+}
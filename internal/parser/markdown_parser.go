@@ -0,0 +1,311 @@
+package parser
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// MarkdownParser implements LanguageParser for Markdown documentation. The
+// document is split at its headings: every section becomes a
+// "documentation" chunk that carries its heading path and the languages of
+// its fenced code blocks.
+type MarkdownParser struct{}
+
+// NewMarkdownParser creates a new Markdown parser
+func NewMarkdownParser() *MarkdownParser {
+	return &MarkdownParser{}
+}
+
+// Language returns the language name
+func (p *MarkdownParser) Language() string {
+	return string(LanguageMarkdown)
+}
+
+// mdHeading is an ATX ("## Title") or setext ("Title\n-----") heading.
+type mdHeading struct {
+	level     int
+	title     string
+	startLine int // 0-indexed first line of the heading
+	endLine   int // 0-indexed last line (differs for setext headings)
+}
+
+// mdSection accumulates the lines of a section while scanning.
+type mdSection struct {
+	heading   *mdHeading
+	path      []string
+	startLine int
+	endLine   int
+	languages []string
+	children  []string
+}
+
+// ExtractFunctions splits a Markdown document into heading-based sections.
+func (p *MarkdownParser) ExtractFunctions(filePath string, code []byte) ([]FunctionNode, error) {
+	lines := strings.SplitAfter(string(code), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	headings, fenceLangs := scanMarkdown(lines)
+
+	docName := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	// A single level-1 heading opening the document is its title rather than
+	// a section, so it is not repeated in every heading path.
+	titleIndex := -1
+	h1Count := 0
+	for _, h := range headings {
+		if h.level == 1 {
+			h1Count++
+		}
+	}
+	if len(headings) > 0 && headings[0].level == 1 && h1Count == 1 {
+		titleIndex = 0
+	}
+
+	var sections []*mdSection
+	preamble := &mdSection{path: []string{docName}, startLine: 0}
+	sections = append(sections, preamble)
+	type openHeading struct {
+		level   int
+		section *mdSection
+	}
+	var stack []openHeading
+	for i := range headings {
+		h := &headings[i]
+		sections[len(sections)-1].endLine = h.startLine - 1
+
+		for len(stack) > 0 && stack[len(stack)-1].level >= h.level {
+			stack = stack[:len(stack)-1]
+		}
+		parent := preamble
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1].section
+		}
+		path := append([]string{}, parent.path...)
+		if i != titleIndex {
+			path = append(path, h.title)
+		}
+		section := &mdSection{heading: h, path: path, startLine: h.startLine}
+		if i != titleIndex {
+			parent.children = append(parent.children, h.title)
+		}
+		sections = append(sections, section)
+		stack = append(stack, openHeading{level: h.level, section: section})
+	}
+	sections[len(sections)-1].endLine = len(lines) - 1
+
+	for line, lang := range fenceLangs {
+		for j := len(sections) - 1; j >= 0; j-- {
+			if sections[j].startLine <= line {
+				if lang != "" && !slices.Contains(sections[j].languages, lang) {
+					sections[j].languages = append(sections[j].languages, lang)
+				}
+				break
+			}
+		}
+	}
+
+	lineStarts := buildLineOffsets(code)
+	var nodes []FunctionNode
+	for _, s := range sections {
+		// Trailing blank lines belong to no section.
+		for s.endLine >= s.startLine && strings.TrimSpace(lines[s.endLine]) == "" {
+			s.endLine--
+		}
+		if s.endLine < s.startLine {
+			continue
+		}
+		name := docName
+		signature := ""
+		if s.heading != nil {
+			name = s.heading.title
+			signature = strings.TrimSpace(strings.Join(lines[s.heading.startLine:s.heading.endLine+1], " "))
+		}
+		startByte := lineStarts[s.startLine]
+		// lineStarts ends with a sentinel past the end of the code.
+		endByte := min(lineStarts[s.endLine+1], len(code))
+		content := strings.TrimRight(string(code[startByte:endByte]), "\r\n")
+		nodes = append(nodes, FunctionNode{
+			Name:          name,
+			NodeType:      "documentation",
+			StartLine:     s.startLine + 1,
+			EndLine:       s.endLine + 1,
+			Content:       content,
+			StartByte:     startByte,
+			EndByte:       startByte + len(content),
+			Signature:     signature,
+			Members:       s.children,
+			Exported:      true,
+			HeadingPath:   strings.Join(s.path, " > "),
+			CodeLanguages: s.languages,
+		})
+	}
+	return nodes, nil
+}
+
+// scanMarkdown finds the headings of a document and the info-string language
+// of each fenced code block, keyed by the block's opening line. Lines inside
+// fences, HTML comments and a leading YAML front matter block are not
+// scanned for headings.
+func scanMarkdown(lines []string) ([]mdHeading, map[int]string) {
+	var headings []mdHeading
+	fenceLangs := make(map[int]string)
+
+	i := 0
+	if len(lines) > 0 && strings.TrimRight(lines[0], "\r\n") == "---" {
+		for j := 1; j < len(lines); j++ {
+			if t := strings.TrimRight(lines[j], " \t\r\n"); t == "---" || t == "..." {
+				i = j + 1
+				break
+			}
+		}
+	}
+
+	fence := ""
+	inComment := false
+	prevParagraph := false
+	for ; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r\n")
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		if fence != "" {
+			if indent < 4 && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if inComment {
+			if strings.Contains(line, "-->") {
+				inComment = false
+			}
+			continue
+		}
+		if indent < 4 {
+			if marker := mdFenceMarker(trimmed); marker != "" {
+				info := strings.TrimSpace(strings.TrimLeft(trimmed, marker[:1]))
+				lang := ""
+				if fields := strings.Fields(strings.Trim(info, "{}")); len(fields) > 0 {
+					lang = strings.ToLower(strings.TrimPrefix(strings.TrimSuffix(fields[0], ","), "."))
+				}
+				fenceLangs[i] = lang
+				fence = marker
+				prevParagraph = false
+				continue
+			}
+			if strings.HasPrefix(trimmed, "<!--") && !strings.Contains(trimmed, "-->") {
+				inComment = true
+				prevParagraph = false
+				continue
+			}
+			if level, title, ok := mdATXHeading(trimmed); ok {
+				headings = append(headings, mdHeading{level: level, title: title, startLine: i, endLine: i})
+				prevParagraph = false
+				continue
+			}
+			if prevParagraph && (mdSetextUnderline(trimmed, '=') || mdSetextUnderline(trimmed, '-')) {
+				level := 1
+				if trimmed[0] == '-' {
+					level = 2
+				}
+				headings = append(headings, mdHeading{
+					level:     level,
+					title:     mdHeadingText(strings.TrimSpace(lines[i-1])),
+					startLine: i - 1,
+					endLine:   i,
+				})
+				prevParagraph = false
+				continue
+			}
+		}
+		prevParagraph = trimmed != "" && !mdBlockStart(trimmed)
+	}
+	return headings, fenceLangs
+}
+
+// mdFenceMarker returns the opening run of a fenced code block ("```",
+// "~~~~", ...) or "" when line does not open one.
+func mdFenceMarker(line string) string {
+	for _, ch := range []byte{'`', '~'} {
+		n := 0
+		for n < len(line) && line[n] == ch {
+			n++
+		}
+		if n >= 3 {
+			// Backtick fences cannot have backticks in their info string.
+			if ch == '`' && strings.ContainsRune(line[n:], '`') {
+				return ""
+			}
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// mdATXHeading parses "## Title ##" style headings.
+func mdATXHeading(line string) (int, string, bool) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+	rest := line[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, "", false
+	}
+	rest = strings.TrimSpace(rest)
+	// A closing sequence of '#' is dropped when preceded by a space.
+	if trimmed := strings.TrimRight(rest, "#"); trimmed != rest && (trimmed == "" || strings.HasSuffix(trimmed, " ")) {
+		rest = strings.TrimSpace(trimmed)
+	}
+	title := mdHeadingText(rest)
+	if title == "" {
+		return 0, "", false
+	}
+	return level, title, true
+}
+
+func mdSetextUnderline(line string, ch byte) bool {
+	return len(line) > 0 && strings.Trim(line, string(ch)) == ""
+}
+
+// mdBlockStart reports whether a line starts a block that cannot be the text
+// of a setext heading (lists, quotes, tables, HTML, rules).
+func mdBlockStart(line string) bool {
+	switch line[0] {
+	case '>', '|', '<', '-', '*', '+':
+		return true
+	}
+	return false
+}
+
+// mdHeadingText strips inline markup from a heading: emphasis markers and
+// code span backticks are dropped, links and images keep only their text.
+func mdHeadingText(title string) string {
+	var b strings.Builder
+	for i := 0; i < len(title); i++ {
+		ch := title[i]
+		switch ch {
+		case '*', '`':
+			continue
+		case '!':
+			if i+1 < len(title) && title[i+1] == '[' {
+				continue
+			}
+		case ']':
+			// Drop the "(url)" of a link.
+			if i+1 < len(title) && title[i+1] == '(' {
+				if end := strings.IndexByte(title[i+1:], ')'); end >= 0 {
+					i += end + 1
+				}
+			}
+			continue
+		case '[':
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return collapseWhitespace(strings.TrimSpace(b.String()))
+}
@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// NotebookParser implements LanguageParser for Jupyter notebooks. Code cells
+// are parsed as Python (their functions plus one "cell" chunk per cell) and
+// markdown cells become documentation chunks. Line numbers are relative to
+// the cell, whose 1-based position in the notebook is stored in CellIndex.
+type NotebookParser struct {
+	python   *PythonParser
+	markdown *MarkdownParser
+}
+
+// NewNotebookParser creates a new Jupyter notebook parser
+func NewNotebookParser() *NotebookParser {
+	return &NotebookParser{
+		python:   NewPythonParser(),
+		markdown: NewMarkdownParser(),
+	}
+}
+
+// Language returns the language name
+func (p *NotebookParser) Language() string {
+	return string(LanguageNotebook)
+}
+
+// notebookFile is the part of the .ipynb format the parser reads.
+type notebookFile struct {
+	Cells []struct {
+		CellType string         `json:"cell_type"`
+		Source   notebookSource `json:"source"`
+	} `json:"cells"`
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+// notebookSource is a cell source, stored either as a single string or as a
+// list of lines that keep their trailing newlines.
+type notebookSource string
+
+func (s *notebookSource) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*s = notebookSource(text)
+		return nil
+	}
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return err
+	}
+	*s = notebookSource(strings.Join(lines, ""))
+	return nil
+}
+
+// ExtractFunctions extracts code and markdown cell chunks from a notebook.
+func (p *NotebookParser) ExtractFunctions(filePath string, code []byte) ([]FunctionNode, error) {
+	var nb notebookFile
+	if err := json.Unmarshal(code, &nb); err != nil {
+		return nil, fmt.Errorf("failed to parse notebook: %w", err)
+	}
+
+	kernel := strings.ToLower(nb.Metadata.Kernelspec.Language)
+	if kernel == "" {
+		kernel = strings.ToLower(nb.Metadata.LanguageInfo.Name)
+	}
+	isPython := kernel == "" || kernel == "python"
+
+	// Imports made in any cell are visible to every later cell, so they are
+	// collected across the whole notebook.
+	var imports []string
+	if isPython {
+		var all strings.Builder
+		for _, cell := range nb.Cells {
+			if cell.CellType == "code" {
+				all.WriteString(pythonCellSource(string(cell.Source)))
+				all.WriteString("\n")
+			}
+		}
+		imports = extractPythonImports(tokenizePython([]byte(all.String())))
+	}
+
+	pkgName := derivePythonPackageName(filePath)
+	var nodes []FunctionNode
+	for i, cell := range nb.Cells {
+		cellIndex := i + 1
+		source := strings.TrimRight(string(cell.Source), " \t\r\n")
+		if strings.TrimSpace(source) == "" {
+			continue
+		}
+
+		switch cell.CellType {
+		case "markdown":
+			sections, err := p.markdown.ExtractFunctions(filePath, []byte(source))
+			if err != nil {
+				return nil, err
+			}
+			for _, section := range sections {
+				if section.Signature == "" {
+					// Text before the first heading of the cell.
+					section.Name = fmt.Sprintf("cell %d", cellIndex)
+				}
+				section.CellIndex = cellIndex
+				nodes = append(nodes, section)
+			}
+
+		case "code":
+			cellNode := FunctionNode{
+				Name:        fmt.Sprintf("cell %d", cellIndex),
+				NodeType:    "cell",
+				StartLine:   1,
+				EndLine:     strings.Count(source, "\n") + 1,
+				Content:     source,
+				StartByte:   0,
+				EndByte:     len(source),
+				PackageName: pkgName,
+				Imports:     imports,
+				CellIndex:   cellIndex,
+			}
+			nodes = append(nodes, cellNode)
+			// Cell magics such as %%bash switch the whole cell to another
+			// language.
+			if !isPython || strings.HasPrefix(strings.TrimSpace(source), "%%") {
+				continue
+			}
+
+			pySource := pythonCellSource(source)
+			tokens := tokenizePython([]byte(pySource))
+			nodes[len(nodes)-1].Callees = extractPythonCallees(tokens)
+			functions, err := p.python.ExtractFunctions(filePath, []byte(pySource))
+			if err != nil {
+				return nil, err
+			}
+			for _, fn := range functions {
+				// Magic lines were only masked for parsing.
+				fn.Content = source[fn.StartByte:fn.EndByte]
+				fn.Imports = imports
+				fn.CellIndex = cellIndex
+				nodes = append(nodes, fn)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// pythonCellSource masks IPython line magics ("%matplotlib inline") and shell
+// escapes ("!pip install x") as comments so the cell tokenizes as Python. The
+// byte length and line structure of the source are unchanged.
+func pythonCellSource(source string) string {
+	lines := strings.SplitAfter(source, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(trimmed, "%") || strings.HasPrefix(trimmed, "!") {
+			indent := len(line) - len(trimmed)
+			lines[i] = line[:indent] + "#" + trimmed[1:]
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -1,6 +1,9 @@
 package parser
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -79,8 +82,9 @@ class Calculator:
 		t.Fatalf("Failed to parse Python code: %v", err)
 	}
 
-	if len(functions) != 3 {
-		t.Errorf("Expected 3 functions, got %d", len(functions))
+	// greet, the Calculator class itself, and its two methods.
+	if len(functions) != 4 {
+		t.Errorf("Expected 4 functions, got %d", len(functions))
 	}
 
 	for i, fn := range functions {
@@ -89,6 +93,199 @@ class Calculator:
 	}
 }
 
+func TestPythonParserTokenizerCases(t *testing.T) {
+	code := []byte(`import os
+from typing import (
+    Dict,
+    List as L,
+)
+
+TEMPLATE = """
+def not_a_function(x):
+    pass
+"""
+
+@app.get(
+    "/items/{item_id}",
+)
+async def read_item(
+    item_id: int,
+    q: str = "def",  # def in a comment
+) -> Dict[str, int]:
+    """Fetch one item.
+
+    Returns a mapping.
+    """
+    value = compute(item_id) + \
+        os.path.getsize(q)
+    return {"value": value}
+
+class Service(Base):
+    """Service docstring."""
+
+    @property
+    def name(self):
+        def helper():
+            return "x"
+        return helper()
+`)
+
+	functions, err := NewPythonParser().ExtractFunctions("svc.py", code)
+	if err != nil {
+		t.Fatalf("Failed to parse Python code: %v", err)
+	}
+
+	byName := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+	if _, ok := byName["not_a_function"]; ok {
+		t.Errorf("definition inside a string literal should be ignored")
+	}
+
+	item, ok := byName["read_item"]
+	if !ok {
+		t.Fatalf("read_item not found, got %v", functions)
+	}
+	if item.StartLine != 12 || item.EndLine != 25 {
+		t.Errorf("read_item lines = %d-%d, want 12-25", item.StartLine, item.EndLine)
+	}
+	if item.Doc != "Fetch one item.\n\nReturns a mapping." {
+		t.Errorf("read_item doc = %q", item.Doc)
+	}
+	if len(item.Decorators) != 1 || item.Decorators[0] != `app.get("/items/{item_id}",)` {
+		t.Errorf("read_item decorators = %q", item.Decorators)
+	}
+	if item.Signature != `async def read_item(item_id: int, q: str = "def",) -> Dict[str, int]` {
+		t.Errorf("read_item signature = %q", item.Signature)
+	}
+	if len(item.ReturnTypes) != 1 || item.ReturnTypes[0] != "Dict[str, int]" {
+		t.Errorf("read_item return types = %q", item.ReturnTypes)
+	}
+	if !containsString(item.Callees, "compute") || !containsString(item.Callees, "os.path.getsize") {
+		t.Errorf("read_item callees = %q", item.Callees)
+	}
+
+	class, ok := byName["Service"]
+	if !ok || class.NodeType != "class" || class.Doc != "Service docstring." {
+		t.Errorf("Service class chunk = %+v", class)
+	}
+	method, ok := byName["Service.name"]
+	if !ok || method.NodeType != "method" || method.Receiver != "Service" {
+		t.Errorf("Service.name method = %+v", method)
+	}
+	if len(method.Decorators) != 1 || method.Decorators[0] != "property" {
+		t.Errorf("Service.name decorators = %q", method.Decorators)
+	}
+
+	if got := extractPythonImports(tokenizePython(code)); len(got) != 3 || got[2] != "L=typing.List" {
+		t.Errorf("imports = %q", got)
+	}
+}
+
+func TestPythonParserSkipsShortDefinitions(t *testing.T) {
+	code := []byte(`def one_liner(): return 1
+
+def two_lines(x):
+    return x
+
+class Empty(Exception):
+    pass
+
+def three_lines(x):
+    y = x + 1
+    return y
+`)
+
+	functions, err := NewPythonParser().ExtractFunctions("short.py", code)
+	if err != nil {
+		t.Fatalf("Failed to parse Python code: %v", err)
+	}
+	// Definitions spanning fewer than three lines are not chunked.
+	if len(functions) != 1 || functions[0].Name != "three_lines" {
+		t.Errorf("functions = %+v, want only three_lines", functions)
+	}
+}
+
+func TestPythonParserMetadata(t *testing.T) {
+	code := []byte(`@router.post("/users")
+async def create_user(user: Annotated[UserIn, Body()], db: Session = Depends(get_db)) -> Optional[Dict[str, List[int]]]:
+    if not user.name:
+        raise HTTPException(status_code=400)
+    try:
+        return await db.save(user)
+    except KeyError as exc:
+        raise errors.NotFound("user") from exc
+
+def chunks(items: list[int], size: int = 10):
+    def inner():
+        raise RuntimeError("nested")
+    for i in range(0, len(items), size):
+        yield items[i:i + size]
+
+class Model:
+    @staticmethod
+    def build(
+        raw: dict,
+    ) -> "Model":
+        return Model()
+`)
+
+	functions, err := NewPythonParser().ExtractFunctions("api.py", code)
+	if err != nil {
+		t.Fatalf("Failed to parse Python code: %v", err)
+	}
+	byName := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+
+	create := byName["create_user"]
+	if !create.IsAsync || create.IsGenerator {
+		t.Errorf("create_user async=%v generator=%v", create.IsAsync, create.IsGenerator)
+	}
+	if len(create.ParamTypes) != 2 || create.ParamTypes[0] != "Annotated[UserIn, Body()]" || create.ParamTypes[1] != "Session" {
+		t.Errorf("create_user param types = %q", create.ParamTypes)
+	}
+	if len(create.ReturnTypes) != 1 || create.ReturnTypes[0] != "Optional[Dict[str, List[int]]]" {
+		t.Errorf("create_user return types = %q", create.ReturnTypes)
+	}
+	if len(create.Raises) != 2 || create.Raises[0] != "HTTPException" || create.Raises[1] != "errors.NotFound" {
+		t.Errorf("create_user raises = %q", create.Raises)
+	}
+	if !create.HasErrorReturn {
+		t.Errorf("create_user should report HasErrorReturn")
+	}
+	if len(create.Decorators) != 1 || create.Decorators[0] != `router.post("/users")` {
+		t.Errorf("create_user decorators = %q", create.Decorators)
+	}
+
+	gen := byName["chunks"]
+	if !gen.IsGenerator || gen.IsAsync {
+		t.Errorf("chunks async=%v generator=%v", gen.IsAsync, gen.IsGenerator)
+	}
+	if len(gen.Raises) != 0 {
+		t.Errorf("chunks should not inherit raises from nested functions, got %q", gen.Raises)
+	}
+
+	build := byName["Model.build"]
+	if len(build.Decorators) != 1 || build.Decorators[0] != "staticmethod" {
+		t.Errorf("Model.build decorators = %q", build.Decorators)
+	}
+	if len(build.ReturnTypes) != 1 || build.ReturnTypes[0] != `"Model"` {
+		t.Errorf("Model.build return types = %q", build.ReturnTypes)
+	}
+}
+
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
 func TestJavaScriptParser(t *testing.T) {
 	code := []byte(`function greet(name) {
     console.log("Hello, " + name);
@@ -169,6 +366,1324 @@ interface ICalculator {
 	}
 }
 
+func TestTypeScriptDeclarations(t *testing.T) {
+	code := []byte(`/** A user of the system. */
+export interface User<T extends object = {}> extends Base {
+  id: string;
+  name?: string, // optional
+  greet(msg: string,
+        loud: boolean): void
+}
+
+export type Status =
+  | "active"
+  | "disabled";
+
+type Point = { x: number; y: number };
+
+export const enum Color { Red = 1, Green }
+
+export namespace Geometry {
+  export const PI = 3.14;
+  export function area(r: number): number {
+    const squared = r * r;
+    return PI * squared;
+  }
+}
+`)
+
+	functions, err := NewTypeScriptParser().ExtractFunctions("model.ts", code)
+	if err != nil {
+		t.Fatalf("Failed to parse TypeScript code: %v", err)
+	}
+	byName := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+
+	user := byName["User"]
+	if user.NodeType != "interface" || !user.Exported || user.Doc != "A user of the system." {
+		t.Errorf("User interface = %+v", user)
+	}
+	if len(user.TypeParams) != 1 || user.TypeParams[0] != "T extends object = {}" {
+		t.Errorf("User type params = %q", user.TypeParams)
+	}
+	wantMembers := []string{"id: string", "name?: string", "greet(msg: string, loud: boolean): void"}
+	if len(user.Members) != len(wantMembers) {
+		t.Fatalf("User members = %q, want %q", user.Members, wantMembers)
+	}
+	for i, m := range wantMembers {
+		if user.Members[i] != m {
+			t.Errorf("User member %d = %q, want %q", i, user.Members[i], m)
+		}
+	}
+
+	status := byName["Status"]
+	if status.NodeType != "type_alias" || !status.Exported || len(status.Members) != 2 || status.EndLine != 11 {
+		t.Errorf("Status type alias = %+v", status)
+	}
+	point := byName["Point"]
+	if point.NodeType != "type_alias" || point.Exported || len(point.Members) != 2 {
+		t.Errorf("Point type alias = %+v", point)
+	}
+	color := byName["Color"]
+	if color.NodeType != "enum" || !color.Exported || len(color.Members) != 2 || color.Members[0] != "Red = 1" {
+		t.Errorf("Color enum = %+v", color)
+	}
+	geometry := byName["Geometry"]
+	if geometry.NodeType != "namespace" || len(geometry.Members) != 2 {
+		t.Errorf("Geometry namespace = %+v", geometry)
+	}
+	if area, ok := byName["area"]; !ok || !area.Exported {
+		t.Errorf("functions inside namespaces should be extracted and flagged as exported, got %+v", area)
+	}
+}
+
+func TestTypeScriptReactComponents(t *testing.T) {
+	code := []byte(`import React, { useState } from "react";
+
+interface ButtonProps { label: string }
+
+export const Button: React.FC<ButtonProps> = ({ label }) => (
+  <button className="btn">
+    {label} isn't {"<"} a tag
+  </button>
+);
+
+export function LoginPage({ onDone }: LoginProps) {
+  const [open, setOpen] = useState(false);
+  const user = useAuth();
+  if (open && user.count < 3) {
+    return <Modal onClose={() => setOpen(false)}><Form.Item /></Modal>;
+  }
+  return <></>;
+}
+
+function formatName(first, last) {
+  const full = first + " " + last;
+  return full.trim();
+}
+`)
+
+	functions, err := NewTypeScriptParser().ExtractFunctions("Login.tsx", code)
+	if err != nil {
+		t.Fatalf("Failed to parse TSX code: %v", err)
+	}
+	byName := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+
+	button := byName["Button"]
+	if button.NodeType != "component" || button.PropsType != "ButtonProps" || !button.Exported {
+		t.Errorf("Button component = %+v", button)
+	}
+	if len(button.JSXElements) != 1 || button.JSXElements[0] != "button" {
+		t.Errorf("Button elements = %q", button.JSXElements)
+	}
+
+	login := byName["LoginPage"]
+	if login.NodeType != "component" || login.PropsType != "LoginProps" {
+		t.Errorf("LoginPage component = %+v", login)
+	}
+	if !containsString(login.Hooks, "useState") || !containsString(login.Hooks, "useAuth") {
+		t.Errorf("LoginPage hooks = %q", login.Hooks)
+	}
+	if !containsString(login.JSXElements, "Modal") || !containsString(login.JSXElements, "Form.Item") {
+		t.Errorf("LoginPage elements = %q", login.JSXElements)
+	}
+
+	if format, ok := byName["formatName"]; !ok || format.NodeType != "function" {
+		t.Errorf("plain helpers should stay functions, got %+v", format)
+	}
+
+	// The same source in a .ts file is not treated as JSX.
+	functions, err = NewTypeScriptParser().ExtractFunctions("login.ts", []byte("export function Page() {\n  const a = 1;\n  return a;\n}\n"))
+	if err != nil || len(functions) != 1 || functions[0].NodeType != "function" {
+		t.Errorf("non-JSX file = %+v, %v", functions, err)
+	}
+}
+
+func TestIsJSXStartAt(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected bool
+	}{
+		{"return <div>", true},
+		{"const el = <Button label={x} />", true},
+		{"const frag = <>", true},
+		{"if (a <b) {", false},
+		// Generic arrow functions in TSX.
+		{"const id = <T,>(x: T) => x", false},
+		{"const pick = <T extends U>(x: T) => x", false},
+		{"const wide = <T , U>(x: T) => x", false},
+		{"return <Extender>", true},
+	}
+
+	for _, tt := range tests {
+		pos := strings.Index(tt.code, "<")
+		if result := isJSXStartAt([]byte(tt.code), pos); result != tt.expected {
+			t.Errorf("isJSXStartAt(%q) = %v, expected %v", tt.code, result, tt.expected)
+		}
+	}
+}
+
+func TestJSModuleResolution(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"tsconfig.json": `{
+  // comments and trailing commas are allowed
+  "compilerOptions": {
+    "baseUrl": ".",
+    "paths": { "@/*": ["src/*"], },
+  },
+}`,
+		"package.json":               `{"name": "app", "workspaces": ["packages/*"]}`,
+		"src/lib/api.ts":             "export const api = 1;\n",
+		"src/utils/index.ts":         "export const util = 1;\n",
+		"src/helpers.ts":             "export const helper = 1;\n",
+		"packages/ui/package.json":   `{"name": "@acme/ui", "exports": {".": {"types": "./src/index.ts"}, "./button": "./src/button.tsx"}}`,
+		"packages/ui/src/index.ts":   "export {};\n",
+		"packages/ui/src/button.tsx": "export {};\n",
+		"src/app/main.ts": `import { api } from "@/lib/api";
+import { util } from "../utils";
+import { helper } from "../helpers.js";
+import {
+  Button,
+} from "@acme/ui/button";
+export * from "@acme/ui";
+import React from "react";
+
+export function main() {
+  const a = api + util;
+  return helper + a;
+}
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mainPath := filepath.Join(root, "src", "app", "main.ts")
+	code, _ := os.ReadFile(mainPath)
+	functions, err := NewTypeScriptParser().ExtractFunctions(mainPath, code)
+	if err != nil || len(functions) != 1 {
+		t.Fatalf("ExtractFunctions = %+v, %v", functions, err)
+	}
+
+	var got []string
+	for _, p := range functions[0].ResolvedImports {
+		rel, _ := filepath.Rel(root, p)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := []string{
+		"packages/ui/src/button.tsx",
+		"packages/ui/src/index.ts",
+		"src/helpers.ts",
+		"src/lib/api.ts",
+		"src/utils/index.ts",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ResolvedImports = %q, want %q", got, want)
+	}
+}
+
+func TestRustParser(t *testing.T) {
+	code := []byte(`//! Crate docs
+#![allow(dead_code)]
+use std::collections::{HashMap, hash_map::Entry};
+use std::io::{self, Read as _};
+
+/// Parses the input.
+#[inline]
+pub fn parse<'a, T: Into<String>>(input: &'a str) -> Result<T, Error> where T: Default {
+    let raw = r#"fn fake() { "#;
+    let brace = '{';
+    helper::<u8>(input);
+    Ok(T::default())
+}
+
+impl Point {
+    async fn dist(&self, other: &Point) -> io::Result<f64> {
+        let dx = self.x - other.x;
+        Ok(dx.abs())
+    }
+}
+
+impl<T> fmt::Display for Wrapper<T> where T: fmt::Display {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        write!(f, "{}", self.0)
+    }
+}
+
+pub trait Shape: Send {
+    fn area(&self) -> f64;
+    fn name(&self) -> String { String::from("shape") }
+}
+`)
+
+	functions, err := NewRustParser().ExtractFunctions("lib.rs", code)
+	if err != nil {
+		t.Fatalf("Failed to parse Rust code: %v", err)
+	}
+	byName := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+	if len(functions) != 6 {
+		t.Fatalf("Expected 6 items, got %d: %+v", len(functions), byName)
+	}
+
+	parse := byName["parse"]
+	if parse.NodeType != "function" || !parse.Exported || !parse.HasErrorReturn || parse.Doc != "Parses the input." {
+		t.Errorf("parse = %+v", parse)
+	}
+	if parse.Signature != "pub fn parse<'a, T: Into<String>>(input: &'a str) -> Result<T, Error> where T: Default" {
+		t.Errorf("parse signature = %q", parse.Signature)
+	}
+	if len(parse.TypeParams) != 2 || !containsString(parse.Decorators, "inline") || !containsString(parse.Callees, "helper") {
+		t.Errorf("parse metadata = %+v", parse)
+	}
+	wantImports := []string{"std::collections::HashMap", "std::collections::hash_map::Entry", "std::io", "std::io::Read"}
+	if strings.Join(parse.Imports, ",") != strings.Join(wantImports, ",") {
+		t.Errorf("imports = %q, want %q", parse.Imports, wantImports)
+	}
+
+	dist := byName["Point::dist"]
+	if dist.NodeType != "method" || dist.Receiver != "Point" || !dist.IsAsync || !dist.HasErrorReturn {
+		t.Errorf("Point::dist = %+v", dist)
+	}
+	if len(dist.ParamTypes) != 1 || dist.ParamTypes[0] != "&Point" {
+		t.Errorf("Point::dist params = %q", dist.ParamTypes)
+	}
+
+	impl := byName["fmt::Display for Wrapper"]
+	if impl.NodeType != "trait_impl" || impl.Receiver != "Wrapper" || len(impl.Members) != 1 {
+		t.Errorf("trait impl = %+v", impl)
+	}
+	if fmtMethod := byName["Wrapper::fmt"]; fmtMethod.Receiver != "Wrapper" || !containsString(fmtMethod.Callees, "write!") {
+		t.Errorf("Wrapper::fmt = %+v", fmtMethod)
+	}
+
+	shape := byName["Shape"]
+	if shape.NodeType != "trait" || !shape.Exported || len(shape.Members) != 2 || shape.Members[0] != "fn area(&self) -> f64" {
+		t.Errorf("Shape trait = %+v", shape)
+	}
+	if name := byName["Shape::name"]; name.NodeType != "method" || name.Receiver != "Shape" {
+		t.Errorf("default trait method = %+v", name)
+	}
+}
+
+func TestJavaParser(t *testing.T) {
+	code := []byte(`package com.acme.users;
+
+import java.util.List;
+import static org.junit.Assert.assertEquals;
+
+/**
+ * REST controller for users.
+ */
+@RestController
+@RequestMapping("/users")
+public class UserController extends Base implements Api {
+    private final UserService service;
+    private int count = compute();
+
+    public UserController(UserService service) {
+        this.service = service;
+    }
+
+    /** Lists users. */
+    @GetMapping("/{id}")
+    public List<User> list(@PathVariable("id") final String id) throws IOException, SQLException {
+        String text = """
+            not a { brace
+            """;
+        return service.findAll(id);
+    }
+
+    @Override
+    public String toString() { return "users"; }
+
+    abstract void pending();
+
+    enum Status { ACTIVE, DISABLED; }
+}
+
+interface Repo<T> {
+    T find(String id);
+    default int size() { return 0; }
+}
+
+record Range(int lo, int hi) {
+    Range {
+        if (lo > hi) throw new IllegalArgumentException();
+    }
+}
+`)
+
+	functions, err := NewJavaParser().ExtractFunctions("UserController.java", code)
+	if err != nil {
+		t.Fatalf("Failed to parse Java code: %v", err)
+	}
+	byName := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+	if len(functions) != 9 {
+		t.Fatalf("Expected 9 declarations, got %d: %+v", len(functions), byName)
+	}
+
+	class := byName["UserController"]
+	if class.NodeType != "class" || class.PackageName != "com.acme.users" || class.Doc != "REST controller for users." {
+		t.Errorf("UserController class = %+v", class)
+	}
+	if !containsString(class.Decorators, `RequestMapping("/users")`) || !containsString(class.Members, "list") {
+		t.Errorf("UserController metadata = %+v", class)
+	}
+	wantImports := []string{"java.util.List", "org.junit.Assert.assertEquals"}
+	if strings.Join(class.Imports, ",") != strings.Join(wantImports, ",") {
+		t.Errorf("imports = %q, want %q", class.Imports, wantImports)
+	}
+
+	if ctor := byName["UserController.UserController"]; ctor.NodeType != "constructor" || ctor.Receiver != "UserController" {
+		t.Errorf("constructor = %+v", ctor)
+	}
+
+	list := byName["UserController.list"]
+	if list.NodeType != "method" || list.Doc != "Lists users." || !containsString(list.Decorators, `GetMapping("/{id}")`) {
+		t.Errorf("list = %+v", list)
+	}
+	if strings.Join(list.Raises, ",") != "IOException,SQLException" || !list.HasErrorReturn {
+		t.Errorf("list throws = %q", list.Raises)
+	}
+	if len(list.ParamTypes) != 1 || list.ParamTypes[0] != "String" || len(list.ReturnTypes) != 1 || list.ReturnTypes[0] != "List<User>" {
+		t.Errorf("list types = %q -> %q", list.ParamTypes, list.ReturnTypes)
+	}
+	if !containsString(list.Callees, "service.findAll") {
+		t.Errorf("list callees = %q", list.Callees)
+	}
+
+	if toString := byName["UserController.toString"]; !containsString(toString.Decorators, "Override") {
+		t.Errorf("toString = %+v", toString)
+	}
+	if status := byName["UserController.Status"]; status.NodeType != "enum" || strings.Join(status.Members, ",") != "ACTIVE,DISABLED" {
+		t.Errorf("Status enum = %+v", status)
+	}
+	if repo := byName["Repo"]; repo.NodeType != "interface" || len(repo.Members) != 2 || len(repo.TypeParams) != 1 {
+		t.Errorf("Repo interface = %+v", repo)
+	}
+	if size := byName["Repo.size"]; size.NodeType != "method" || !size.Exported {
+		t.Errorf("default interface method = %+v", size)
+	}
+	compact := byName["Range.Range"]
+	if compact.NodeType != "constructor" || compact.Signature != "Range(int lo, int hi)" || strings.Join(compact.ParamTypes, ",") != "int,int" {
+		t.Errorf("compact record constructor = %+v", compact)
+	}
+}
+
+func TestKotlinParser(t *testing.T) {
+	code := []byte(`package com.acme.app
+
+import kotlinx.coroutines.flow.Flow
+import com.acme.Foo as Bar
+
+/**
+ * Greets people.
+ */
+@Service
+class Greeter(private val repo: Repo) : Base(), Api {
+    constructor(x: Int) : this(Repo()) {
+        setup(x)
+    }
+
+    /** Says hello. */
+    @Throws(IOException::class)
+    suspend fun greet(who: String, times: Int = 1): String {
+        val s = "Hello ${who.let { "[$it]" }} }"
+        return repo.load(who)
+    }
+
+    fun twice(x: Int) = x * 2
+    fun thrice(x: Int)
+        : Int = x * 3
+
+    companion object {
+        fun create(): Greeter = Greeter(Repo())
+    }
+}
+
+data class Point(val x: Int, val y: Int)
+
+fun String.isEmail(): Boolean {
+    return contains("@")
+}
+
+@Test
+internal fun testIt() {
+    assertEquals(1, 1)
+}
+`)
+
+	functions, err := NewKotlinParser().ExtractFunctions("App.kt", code)
+	if err != nil {
+		t.Fatalf("Failed to parse Kotlin code: %v", err)
+	}
+	byName := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+	if len(functions) != 10 {
+		t.Fatalf("Expected 10 declarations, got %d: %+v", len(functions), byName)
+	}
+
+	greeter := byName["Greeter"]
+	if greeter.NodeType != "class" || greeter.PackageName != "com.acme.app" || greeter.Doc != "Greets people." || !containsString(greeter.Decorators, "Service") {
+		t.Errorf("Greeter class = %+v", greeter)
+	}
+	if strings.Join(greeter.Imports, ",") != "com.acme.Foo,kotlinx.coroutines.flow.Flow" {
+		t.Errorf("imports = %q", greeter.Imports)
+	}
+	if ctor := byName["Greeter.constructor"]; ctor.NodeType != "constructor" || len(ctor.ReturnTypes) != 0 {
+		t.Errorf("secondary constructor = %+v", ctor)
+	}
+
+	greet := byName["Greeter.greet"]
+	if greet.NodeType != "method" || greet.Receiver != "Greeter" || !greet.IsAsync || greet.Doc != "Says hello." {
+		t.Errorf("greet = %+v", greet)
+	}
+	if strings.Join(greet.Raises, ",") != "IOException" || strings.Join(greet.ParamTypes, ",") != "String,Int" || greet.ReturnTypes[0] != "String" {
+		t.Errorf("greet metadata = %+v", greet)
+	}
+	if !containsString(greet.Callees, "repo.load") || greet.EndLine != 20 {
+		t.Errorf("greet body = %+v", greet)
+	}
+
+	if twice := byName["Greeter.twice"]; twice.EndLine != 22 || twice.Signature != "fun twice(x: Int)" || len(twice.ReturnTypes) != 0 {
+		t.Errorf("expression body function = %+v", twice)
+	}
+	// Explicit return types of expression bodies, also on the next line.
+	if thrice := byName["Greeter.thrice"]; thrice.EndLine != 24 || thrice.Signature != "fun thrice(x: Int) : Int" || strings.Join(thrice.ReturnTypes, ",") != "Int" {
+		t.Errorf("expression body function with return type = %+v", thrice)
+	}
+	if create := byName["Greeter.Companion.create"]; create.Receiver != "Greeter.Companion" || strings.Join(create.ReturnTypes, ",") != "Greeter" {
+		t.Errorf("companion function = %+v", create)
+	}
+	if point := byName["Point"]; point.NodeType != "class" || point.StartLine != point.EndLine {
+		t.Errorf("data class = %+v", point)
+	}
+	if ext := byName["String.isEmail"]; ext.NodeType != "extension_function" || ext.Receiver != "String" {
+		t.Errorf("extension function = %+v", ext)
+	}
+	if testIt := byName["testIt"]; testIt.NodeType != "function" || testIt.Exported || !containsString(testIt.Decorators, "Test") {
+		t.Errorf("top-level function = %+v", testIt)
+	}
+}
+
+func TestCParser(t *testing.T) {
+	code := []byte(`#include <vector>
+#include "widget.h"
+
+namespace ui {
+
+/// A drawable widget.
+class Widget : public Base {
+    Q_OBJECT
+public:
+    explicit Widget(int w = 0);
+    virtual void draw() const = 0;
+    int width() const { return width_; }
+private:
+    int width_;
+};
+
+// Constructs a widget.
+Widget::Widget(int w) : Base(), width_{w} {
+    init(w);
+}
+
+#ifdef FAST
+int compute(int a) {
+#else
+int compute(int a, int b) {
+#endif
+    return helper(a);
+}
+
+template <typename T>
+T Stack<T>::pop() {
+    return items_.back();
+}
+
+} // namespace ui
+
+static struct node *node_new(const char *name, size_t len);
+`)
+
+	functions, err := NewCPPParser().ExtractFunctions("widget.cpp", code)
+	if err != nil {
+		t.Fatalf("Failed to parse C++ code: %v", err)
+	}
+	byName := make(map[string][]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = append(byName[fn.Name], fn)
+	}
+	if len(functions) != 8 {
+		t.Fatalf("Expected 8 declarations, got %d: %+v", len(functions), byName)
+	}
+
+	widget := byName["Widget"][0]
+	if widget.NodeType != "class" || widget.PackageName != "ui" || widget.Doc != "A drawable widget." {
+		t.Errorf("Widget class = %+v", widget)
+	}
+	if strings.Join(widget.Members, ",") != "Widget,draw,width" {
+		t.Errorf("Widget members = %q", widget.Members)
+	}
+	if strings.Join(widget.Imports, ",") != "vector,widget.h" {
+		t.Errorf("imports = %q", widget.Imports)
+	}
+
+	ctors := byName["Widget::Widget"]
+	if len(ctors) != 2 || ctors[0].NodeType != "declaration" || ctors[1].NodeType != "constructor" {
+		t.Fatalf("constructors = %+v", ctors)
+	}
+	if ctor := ctors[1]; ctor.Receiver != "Widget" || ctor.Doc != "Constructs a widget." || !containsString(ctor.Callees, "init") {
+		t.Errorf("out-of-line constructor = %+v", ctor)
+	}
+	if draw := byName["Widget::draw"][0]; draw.NodeType != "declaration" || draw.Receiver != "Widget" {
+		t.Errorf("pure virtual method = %+v", draw)
+	}
+	if width := byName["Widget::width"][0]; width.NodeType != "method" || !width.Exported || width.ReturnTypes[0] != "int" {
+		t.Errorf("inline method = %+v", width)
+	}
+
+	compute := byName["compute"][0]
+	if compute.NodeType != "function" || strings.Join(compute.ParamTypes, ",") != "int" || compute.EndLine != 28 {
+		t.Errorf("compute with preprocessor branches = %+v", compute)
+	}
+	if pop := byName["Stack::pop"][0]; pop.NodeType != "method" || pop.Receiver != "Stack" || strings.Join(pop.TypeParams, ",") != "typename T" {
+		t.Errorf("template method = %+v", pop)
+	}
+
+	nodeNew := byName["node_new"][0]
+	if nodeNew.NodeType != "declaration" || nodeNew.Exported || nodeNew.PackageName != "" {
+		t.Errorf("static prototype = %+v", nodeNew)
+	}
+	if strings.Join(nodeNew.ParamTypes, ",") != "const char *,size_t" || nodeNew.ReturnTypes[0] != "struct node *" {
+		t.Errorf("prototype types = %+v", nodeNew)
+	}
+}
+
+func TestCParserFunctionPointerReturn(t *testing.T) {
+	code := []byte(`static int double_it(int x) { return x * 2; }
+
+int (*get_handler(int kind))(int) {
+    if (kind == 0) { return 0; }
+    return double_it;
+}
+
+void (*signal(int sig, void (*func)(int)))(int);
+
+int (*handler)(int);
+
+int after(void) {
+    return get_handler(1)(2);
+}
+`)
+
+	functions, err := NewCParser().ExtractFunctions("handlers.c", code)
+	if err != nil {
+		t.Fatalf("Failed to parse C code: %v", err)
+	}
+	byName := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+	if len(functions) != 4 {
+		t.Fatalf("Expected 4 functions, got %d: %+v", len(functions), byName)
+	}
+
+	getHandler := byName["get_handler"]
+	if getHandler.NodeType != "function" || getHandler.StartLine != 3 || getHandler.EndLine != 6 {
+		t.Errorf("get_handler = %+v", getHandler)
+	}
+	if getHandler.Signature != "int (*get_handler(int kind))(int)" || strings.Join(getHandler.ReturnTypes, ",") != "int (*)(int)" || strings.Join(getHandler.ParamTypes, ",") != "int" {
+		t.Errorf("get_handler signature = %q, returns %q, params %q", getHandler.Signature, getHandler.ReturnTypes, getHandler.ParamTypes)
+	}
+	if sig := byName["signal"]; sig.NodeType != "declaration" || strings.Join(sig.ReturnTypes, ",") != "void (*)(int)" {
+		t.Errorf("signal = %+v", sig)
+	}
+	// The definitions that follow are still found.
+	if after := byName["after"]; after.NodeType != "function" || after.StartLine != 12 || !containsString(after.Callees, "get_handler") {
+		t.Errorf("after = %+v", after)
+	}
+}
+
+func TestCSharpParser(t *testing.T) {
+	code := []byte(`using System;
+using System.Threading.Tasks;
+using Json = System.Text.Json.JsonSerializer;
+
+namespace Acme.Users;
+
+/// <summary>
+/// Manages users.
+/// </summary>
+[ApiController]
+public class UserService<T> : IUserService where T : class, new()
+{
+    public int Count { get; set; }
+
+    public string Name
+    {
+        get { return _name ?? Load(); }
+    }
+
+    public UserService(IRepo repo) : base(repo)
+    {
+        _repo = repo;
+    }
+
+    /// <summary>Loads a user.</summary>
+    [HttpGet("{id}")]
+    public async Task<User> GetAsync(int id, CancellationToken ct = default)
+    {
+        var s = $"user {id} {(id > 0 ? "}" : "{")}";
+        if (id < 0) throw new ArgumentOutOfRangeException(nameof(id));
+        return await Fetch(id);
+
+        Task<User> Fetch(int key) => _repo.FindAsync(key, ct);
+    }
+
+    private void Reset() => Clear();
+}
+
+public record Point(int X, int Y);
+`)
+
+	functions, err := NewCSharpParser().ExtractFunctions("UserService.cs", code)
+	if err != nil {
+		t.Fatalf("Failed to parse C# code: %v", err)
+	}
+	byName := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+	if len(functions) != 7 {
+		t.Fatalf("Expected 7 declarations, got %d: %+v", len(functions), byName)
+	}
+
+	service := byName["UserService"]
+	if service.NodeType != "class" || service.PackageName != "Acme.Users" || service.Doc != "Manages users." || !containsString(service.Decorators, "ApiController") {
+		t.Errorf("UserService class = %+v", service)
+	}
+	if strings.Join(service.TypeParams, ",") != "T : class, new()" {
+		t.Errorf("generic constraints = %q", service.TypeParams)
+	}
+	if !containsString(service.Members, "Count") || !containsString(service.Members, "GetAsync") {
+		t.Errorf("members = %q", service.Members)
+	}
+	if strings.Join(service.Imports, ",") != "System,System.Text.Json.JsonSerializer,System.Threading.Tasks" {
+		t.Errorf("imports = %q", service.Imports)
+	}
+
+	if name := byName["UserService.Name"]; name.NodeType != "property" || !containsString(name.Callees, "Load") {
+		t.Errorf("property = %+v", name)
+	}
+	if _, ok := byName["UserService.Count"]; ok {
+		t.Errorf("auto-property should not be indexed as a chunk")
+	}
+	if ctor := byName["UserService.UserService"]; ctor.NodeType != "constructor" || ctor.Signature != "public UserService(IRepo repo)" {
+		t.Errorf("constructor = %+v", ctor)
+	}
+
+	get := byName["UserService.GetAsync"]
+	if get.NodeType != "method" || get.Receiver != "UserService" || !get.IsAsync || get.Doc != "Loads a user." {
+		t.Errorf("GetAsync = %+v", get)
+	}
+	if strings.Join(get.ParamTypes, ",") != "int,CancellationToken" || get.ReturnTypes[0] != "Task<User>" || !containsString(get.Decorators, `HttpGet("{id}")`) {
+		t.Errorf("GetAsync metadata = %+v", get)
+	}
+	if strings.Join(get.Raises, ",") != "ArgumentOutOfRangeException" || get.EndLine != 34 {
+		t.Errorf("GetAsync body = %+v", get)
+	}
+
+	fetch := byName["UserService.GetAsync.Fetch"]
+	if fetch.NodeType != "local_function" || !fetch.IsAsync || !containsString(fetch.Callees, "_repo.FindAsync") {
+		t.Errorf("local function = %+v", fetch)
+	}
+	if reset := byName["UserService.Reset"]; reset.Exported || reset.StartLine != reset.EndLine {
+		t.Errorf("expression-bodied method = %+v", reset)
+	}
+	if point := byName["Point"]; point.NodeType != "record" || strings.Join(point.ParamTypes, ",") != "int,int" {
+		t.Errorf("record = %+v", point)
+	}
+}
+
+func TestRubyParser(t *testing.T) {
+	code := []byte(`require "json"
+
+module Admin
+  # Manages users.
+  class UsersController < ApplicationController
+    before_action do
+      redirect_to root_path unless current_user&.admin?
+    end
+    scope :active, -> { where(active: true) }
+
+    # Shows a user.
+    def show
+      @user = User.find(params[:id])
+      raise ActiveRecord::RecordNotFound unless @user
+      render json: @user if @user.valid?
+      sql = <<~SQL
+        SELECT * FROM users WHERE note = 'end'
+      SQL
+      "#{@user.name} } end"
+    end
+
+    def self.create(attrs = {}) = new(attrs).tap(&:save)
+
+    private
+
+    def authenticate!
+      while true do
+        break
+      end
+    end
+  end
+end
+`)
+
+	functions, err := NewRubyParser().ExtractFunctions("users_controller.rb", code)
+	if err != nil {
+		t.Fatalf("Failed to parse Ruby code: %v", err)
+	}
+	byName := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+	if len(functions) != 7 {
+		t.Fatalf("Expected 7 declarations, got %d: %+v", len(functions), byName)
+	}
+
+	controller := byName["Admin::UsersController"]
+	if controller.NodeType != "class" || controller.Receiver != "Admin" || controller.Doc != "Manages users." || controller.EndLine != 31 {
+		t.Errorf("controller class = %+v", controller)
+	}
+	if strings.Join(controller.Members, ",") != "show,create,authenticate!" || strings.Join(controller.Imports, ",") != "json" {
+		t.Errorf("controller members = %q, imports = %q", controller.Members, controller.Imports)
+	}
+	if module := byName["Admin"]; module.NodeType != "module" || module.EndLine != 32 {
+		t.Errorf("module = %+v", module)
+	}
+
+	if before := byName["before_action"]; before.NodeType != "block" || before.Receiver != "Admin::UsersController" || before.EndLine != 8 {
+		t.Errorf("before_action block = %+v", before)
+	}
+	if scope := byName["scope :active"]; scope.NodeType != "block" || !containsString(scope.Callees, "where") {
+		t.Errorf("scope block = %+v", scope)
+	}
+
+	show := byName["Admin::UsersController#show"]
+	if show.NodeType != "method" || show.Receiver != "Admin::UsersController" || show.Doc != "Shows a user." || show.EndLine != 20 {
+		t.Errorf("show = %+v", show)
+	}
+	if strings.Join(show.Raises, ",") != "ActiveRecord::RecordNotFound" || !containsString(show.Callees, "User.find") {
+		t.Errorf("show metadata = %+v", show)
+	}
+	if create := byName["Admin::UsersController.create"]; create.NodeType != "singleton_method" || create.StartLine != create.EndLine {
+		t.Errorf("endless singleton method = %+v", create)
+	}
+	if auth := byName["Admin::UsersController#authenticate!"]; auth.Exported || auth.EndLine != 30 {
+		t.Errorf("private method = %+v", auth)
+	}
+}
+
+func TestPHPParser(t *testing.T) {
+	code := []byte(`<h1>{ inline html }</h1>
+<?php
+
+namespace App\Http\Controllers;
+
+use App\Models\User;
+use Illuminate\Support\{Str, Arr as A};
+
+/**
+ * Handles users.
+ */
+#[Route('/users')]
+final class UserController extends Controller
+{
+    use AuthorizesRequests;
+
+    public function __construct(private readonly UserRepository $repo) {}
+
+    /**
+     * Show a user.
+     * @throws NotFoundException
+     */
+    public function show(int $id, ?Request $request = null): View
+    {
+        if (!$id) {
+            throw new \InvalidArgumentException('bad }');
+        }
+        return view('users.show', ['user' => $this->repo->find($id)]);
+    }
+
+    abstract protected function guard(): string;
+
+    private static function cache(): array { return self::$items; }
+}
+
+trait Greets {
+    public function greet(): string { return "hi"; }
+}
+
+function helper($x) {
+    yield $x;
+}
+?>
+<p>after</p>
+`)
+
+	functions, err := NewPHPParser().ExtractFunctions("UserController.php", code)
+	if err != nil {
+		t.Fatalf("Failed to parse PHP code: %v", err)
+	}
+	byName := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+	if len(functions) != 7 {
+		t.Fatalf("Expected 7 declarations, got %d: %+v", len(functions), byName)
+	}
+
+	controller := byName["UserController"]
+	if controller.NodeType != "class" || controller.PackageName != `App\Http\Controllers` || controller.Doc != "Handles users." || !containsString(controller.Decorators, "Route('/users')") {
+		t.Errorf("controller class = %+v", controller)
+	}
+	if strings.Join(controller.Members, ",") != "__construct,show,guard,cache" {
+		t.Errorf("controller members = %q", controller.Members)
+	}
+	if strings.Join(controller.Imports, ",") != `App\Models\User,Illuminate\Support\Arr,Illuminate\Support\Str` {
+		t.Errorf("imports = %q", controller.Imports)
+	}
+
+	if ctor := byName["UserController::__construct"]; ctor.NodeType != "constructor" || strings.Join(ctor.ParamTypes, ",") != "UserRepository" {
+		t.Errorf("constructor = %+v", ctor)
+	}
+	show := byName["UserController::show"]
+	if show.NodeType != "method" || show.Receiver != "UserController" || !strings.HasPrefix(show.Doc, "Show a user.") || show.EndLine != 29 {
+		t.Errorf("show = %+v", show)
+	}
+	if strings.Join(show.ParamTypes, ",") != "int,?Request" || show.ReturnTypes[0] != "View" {
+		t.Errorf("show types = %+v", show)
+	}
+	if strings.Join(show.Raises, ",") != "InvalidArgumentException,NotFoundException" || !containsString(show.Callees, "view") {
+		t.Errorf("show body = %+v", show)
+	}
+	if cache := byName["UserController::cache"]; cache.Exported {
+		t.Errorf("private static method = %+v", cache)
+	}
+	if greet := byName["Greets::greet"]; greet.Receiver != "Greets" || byName["Greets"].NodeType != "trait" {
+		t.Errorf("trait method = %+v", greet)
+	}
+	if helper := byName["helper"]; helper.NodeType != "function" || !helper.IsGenerator {
+		t.Errorf("function = %+v", helper)
+	}
+}
+
+func TestSFCParser(t *testing.T) {
+	vue := []byte(`<template>
+  <div class="card">
+    <template v-if="user">
+      <UserAvatar :src="user.avatar" />
+    </template>
+    <button @click="$emit('close')">x</button>
+  </div>
+</template>
+
+<script setup lang="ts">
+import UserAvatar from './UserAvatar.vue'
+import { useAuth } from '@/composables/auth'
+
+interface Props {
+  user: User
+  compact?: boolean
+}
+
+const props = withDefaults(defineProps<Props>(), { compact: false })
+const emit = defineEmits<{
+  (e: 'select', id: number): void
+  (e: 'update:modelValue', value: string): void
+}>()
+const { isAdmin } = useAuth()
+
+function selectUser(id: number): void {
+  emit('select', id)
+}
+</script>
+
+<style scoped lang="scss">
+.card { padding: 1rem; }
+</style>
+`)
+
+	functions, err := NewVueParser().ExtractFunctions("components/user-card.vue", vue)
+	if err != nil {
+		t.Fatalf("Failed to parse Vue component: %v", err)
+	}
+	byName := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+	if len(functions) != 5 {
+		t.Fatalf("Expected 5 declarations, got %d: %+v", len(functions), byName)
+	}
+
+	card := byName["UserCard"]
+	if card.NodeType != "component" || card.PropsType != "Props" || strings.Join(card.PropNames, ",") != "user,compact" {
+		t.Errorf("vue component = %+v", card)
+	}
+	if strings.Join(card.Emits, ",") != "select,update:modelValue,close" || !containsString(card.Hooks, "useAuth") {
+		t.Errorf("vue component events/hooks = %q %q", card.Emits, card.Hooks)
+	}
+	if !containsString(card.JSXElements, "UserAvatar") || !containsString(card.Imports, "./UserAvatar.vue") {
+		t.Errorf("vue component template/imports = %+v", card)
+	}
+	if tmpl := byName["UserCard.template"]; tmpl.NodeType != "template" || tmpl.StartLine != 1 || tmpl.EndLine != 8 || tmpl.Receiver != "UserCard" {
+		t.Errorf("vue template = %+v", tmpl)
+	}
+	if style := byName["UserCard.style"]; style.NodeType != "style" || style.StartLine != 31 || style.Signature != `<style scoped lang="scss">` {
+		t.Errorf("vue style = %+v", style)
+	}
+	selectUser := byName["selectUser"]
+	if selectUser.StartLine != 26 || selectUser.EndLine != 28 || string(vue[selectUser.StartByte:selectUser.EndByte]) != selectUser.Content {
+		t.Errorf("script function not mapped to file positions: %+v", selectUser)
+	}
+	if strings.Join(selectUser.ParamTypes, ",") != "number" {
+		t.Errorf("script function types = %+v", selectUser)
+	}
+
+	svelte := []byte(`<script lang="ts">
+  import { createEventDispatcher } from 'svelte';
+  export let count = 0;
+  export let label: string;
+  const dispatch = createEventDispatcher<{ change: number; reset: void }>();
+
+  function increment() {
+    count += 1;
+    dispatch('change', count);
+  }
+</script>
+
+<button on:click={increment}>{label}: {count}</button>
+<Tooltip text="hi" />
+`)
+
+	functions, err = NewSvelteParser().ExtractFunctions("Counter.svelte", svelte)
+	if err != nil {
+		t.Fatalf("Failed to parse Svelte component: %v", err)
+	}
+	byName = make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+	counter := byName["Counter"]
+	if strings.Join(counter.PropNames, ",") != "count,label" || strings.Join(counter.Emits, ",") != "change,reset" {
+		t.Errorf("svelte component = %+v", counter)
+	}
+	if tmpl := byName["Counter.template"]; tmpl.StartLine != 13 || tmpl.EndLine != 14 || strings.Join(tmpl.JSXElements, ",") != "button,Tooltip" {
+		t.Errorf("svelte markup = %+v", tmpl)
+	}
+	if increment := byName["increment"]; increment.StartLine != 7 || increment.EndLine != 10 {
+		t.Errorf("svelte function = %+v", increment)
+	}
+}
+
+func TestMarkdownParser(t *testing.T) {
+	code := []byte(`---
+title: Codebase
+---
+# Codebase
+
+Semantic search for code.
+
+## Configuration
+
+Settings live in ~/.codebase/config.json.
+
+### Environment
+
+` + "```bash" + `
+# not a heading
+export QDRANT_URL=localhost:6334
+` + "```" + `
+
+Embedding models
+----------------
+
+` + "```json {title=config}" + `
+{"model": "text-embedding-3-small"}
+` + "```" + `
+
+## Usage ##
+
+Run ` + "`codebase index`" + `.
+`)
+
+	functions, err := NewMarkdownParser().ExtractFunctions("docs/README.md", code)
+	if err != nil {
+		t.Fatalf("Failed to parse Markdown: %v", err)
+	}
+	byPath := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		if fn.NodeType != "documentation" {
+			t.Errorf("%s: node type = %q", fn.Name, fn.NodeType)
+		}
+		byPath[fn.HeadingPath] = fn
+	}
+	if len(functions) != 6 {
+		t.Fatalf("Expected 6 sections, got %d: %+v", len(functions), byPath)
+	}
+
+	if front := functions[0]; front.HeadingPath != "README" || front.Name != "README" || front.EndLine != 3 {
+		t.Errorf("front matter = %+v", front)
+	}
+	title := functions[1]
+	if title.Name != "Codebase" || title.HeadingPath != "README" || strings.Join(title.Members, ",") != "Configuration,Embedding models,Usage" {
+		t.Errorf("title section = %+v", title)
+	}
+	config := byPath["README > Configuration"]
+	if config.StartLine != 8 || config.EndLine != 10 || config.Signature != "## Configuration" || strings.Join(config.Members, ",") != "Environment" {
+		t.Errorf("configuration = %+v", config)
+	}
+	env := byPath["README > Configuration > Environment"]
+	if env.StartLine != 12 || env.EndLine != 17 || strings.Join(env.CodeLanguages, ",") != "bash" {
+		t.Errorf("environment = %+v", env)
+	}
+	if models := byPath["README > Embedding models"]; models.StartLine != 19 || strings.Join(models.CodeLanguages, ",") != "json" {
+		t.Errorf("setext section = %+v", models)
+	}
+	usage := byPath["README > Usage"]
+	if usage.Name != "Usage" || usage.EndLine != 28 || string(code[usage.StartByte:usage.EndByte]) != usage.Content {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestSQLParser(t *testing.T) {
+	code := []byte(`-- +goose Up
+-- Application users.
+CREATE TABLE IF NOT EXISTS public.users (
+    id BIGSERIAL PRIMARY KEY,
+    org_id BIGINT NOT NULL REFERENCES orgs(id),
+    "email" VARCHAR(255) NOT NULL,
+    CONSTRAINT users_email_chk CHECK (email <> '')
+);
+
+CREATE UNIQUE INDEX users_email_key ON public.users (lower(email));
+
+ALTER TABLE users ADD COLUMN name text, RENAME COLUMN name TO full_name;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION touch_user(p_id bigint) RETURNS void AS $$
+BEGIN
+  UPDATE users SET updated_at = now() WHERE id = p_id;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+INSERT INTO orgs (id, name) VALUES (1, 'default; not a terminator');
+
+-- +goose Down
+DROP TABLE IF EXISTS users;
+`)
+
+	functions, err := NewSQLParser().ExtractFunctions("20240101_users.sql", code)
+	if err != nil {
+		t.Fatalf("Failed to parse SQL: %v", err)
+	}
+	byName := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+	if len(functions) != 8 {
+		t.Fatalf("Expected 8 chunks, got %d: %+v", len(functions), byName)
+	}
+
+	users := byName["public.users"]
+	if users.NodeType != "table" || users.StartLine != 3 || users.EndLine != 8 || users.Doc != "Application users." || users.Receiver != "20240101_users.up" {
+		t.Errorf("table = %+v", users)
+	}
+	if strings.Join(users.Columns, ",") != "id,org_id,email" || strings.Join(users.Tables, ",") != "public.users,orgs" {
+		t.Errorf("table metadata = %q %q", users.Tables, users.Columns)
+	}
+	if index := byName["users_email_key"]; index.NodeType != "index" || strings.Join(index.Columns, ",") != "email" || !strings.Contains(index.Signature, "UNIQUE") {
+		t.Errorf("index = %+v", index)
+	}
+	if alter := byName["users"]; alter.NodeType != "alter_table" || strings.Join(alter.Columns, ",") != "name,full_name" {
+		t.Errorf("alter table = %+v", alter)
+	}
+	touch := byName["touch_user"]
+	if touch.NodeType != "function" || touch.EndLine != 19 || strings.Join(touch.ParamTypes, ",") != "bigint" || touch.ReturnTypes[0] != "void" {
+		t.Errorf("function = %+v", touch)
+	}
+	if strings.Join(touch.Tables, ",") != "users" || !containsString(touch.Columns, "updated_at") {
+		t.Errorf("function body refs = %q %q", touch.Tables, touch.Columns)
+	}
+	if insert := byName["INSERT"]; insert.NodeType != "statements" || insert.StartLine != 22 || insert.EndLine != 22 {
+		t.Errorf("statement group = %+v", insert)
+	}
+	if up := byName["20240101_users.up"]; up.NodeType != "migration" || up.StartLine != 1 || up.EndLine != 22 || !containsString(up.Tables, "orgs") {
+		t.Errorf("up section = %+v", up)
+	}
+	if down := byName["20240101_users.down"]; down.StartLine != 24 || strings.Join(down.Tables, ",") != "users" {
+		t.Errorf("down section = %+v", down)
+	}
+
+	goCode := []byte(`package repo
+
+func (r *Repo) Rename(id int, name string) error {
+	_, err := r.db.Exec("UPDATE users SET full_name = ? " +
+		"WHERE id = ?", name, id)
+	log.Printf("Select the user from the list")
+	return err
+}
+`)
+	goFunctions, err := NewGoParser().ExtractFunctions("repo.go", goCode)
+	if err != nil || len(goFunctions) != 1 {
+		t.Fatalf("Failed to parse Go code: %v", err)
+	}
+	rename := goFunctions[0]
+	if len(rename.SQLQueries) != 1 || rename.SQLQueries[0] != "UPDATE users SET full_name = ? WHERE id = ?" {
+		t.Errorf("sql queries = %q", rename.SQLQueries)
+	}
+	if strings.Join(rename.Tables, ",") != "users" || strings.Join(rename.Columns, ",") != "full_name,id" {
+		t.Errorf("sql refs = %q %q", rename.Tables, rename.Columns)
+	}
+}
+
+func TestProtoParser(t *testing.T) {
+	code := []byte(`syntax = "proto3";
+
+package acme.users.v1;
+
+import "google/protobuf/timestamp.proto";
+
+// User is an account.
+message User {
+  int64 id = 1;
+  string email = 2 [(validate.rules).string.email = true];
+  oneof contact {
+    string phone = 3;
+  }
+  reserved 4;
+
+  enum Role {
+    option allow_alias = true;
+    ROLE_UNSPECIFIED = 0;
+  }
+}
+
+service UserService {
+  // Upsert creates or updates a user.
+  rpc Upsert(UpsertRequest) returns (User) {
+    option (google.api.http) = { post: "/v1/users" };
+  }
+  rpc Watch(stream WatchRequest) returns (stream User);
+}
+`)
+
+	functions, err := NewProtoParser().ExtractFunctions("users.proto", code)
+	if err != nil {
+		t.Fatalf("Failed to parse proto: %v", err)
+	}
+	byName := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+	if len(functions) != 5 {
+		t.Fatalf("Expected 5 chunks, got %d: %+v", len(functions), byName)
+	}
+
+	user := byName["User"]
+	if user.NodeType != "message" || user.StartLine != 8 || user.EndLine != 20 || user.Doc != "User is an account." {
+		t.Errorf("message = %+v", user)
+	}
+	if user.PackageName != "acme.users.v1" || len(user.Imports) != 1 || user.Imports[0] != "google/protobuf/timestamp.proto" {
+		t.Errorf("message file metadata = %q %q", user.PackageName, user.Imports)
+	}
+	if strings.Join(user.Members, "|") != "int64 id = 1|string email = 2 [(validate.rules).string.email = true]|string phone = 3" {
+		t.Errorf("message fields = %q", user.Members)
+	}
+	if role := byName["User.Role"]; role.NodeType != "enum" || role.Receiver != "User" || strings.Join(role.Members, ",") != "ROLE_UNSPECIFIED = 0" || strings.Join(role.Options, ",") != "allow_alias = true" {
+		t.Errorf("enum = %+v", role)
+	}
+	if service := byName["UserService"]; service.NodeType != "service" || strings.Join(service.Members, ",") != "Upsert,Watch" {
+		t.Errorf("service = %+v", service)
+	}
+	upsert := byName["UserService.Upsert"]
+	if upsert.NodeType != "rpc" || upsert.Receiver != "UserService" || upsert.StartLine != 24 || upsert.EndLine != 26 || upsert.Doc != "Upsert creates or updates a user." {
+		t.Errorf("rpc = %+v", upsert)
+	}
+	if upsert.Signature != "rpc Upsert(UpsertRequest) returns (User)" || len(upsert.Options) != 1 || !strings.HasPrefix(upsert.Options[0], "(google.api.http)") {
+		t.Errorf("rpc signature/options = %q %q", upsert.Signature, upsert.Options)
+	}
+	if watch := byName["UserService.Watch"]; watch.ParamTypes[0] != "stream WatchRequest" || watch.ReturnTypes[0] != "stream User" {
+		t.Errorf("streaming rpc = %+v", watch)
+	}
+}
+
+func TestNotebookParser(t *testing.T) {
+	code := []byte(`{
+ "cells": [
+  {"cell_type": "markdown", "metadata": {}, "source": ["# Churn analysis\n", "\n", "## Loading\n", "Load the data."]},
+  {"cell_type": "code", "metadata": {}, "outputs": [], "source": ["%matplotlib inline\n", "import pandas as pd\n"]},
+  {"cell_type": "code", "metadata": {}, "outputs": [], "source": "x = 1\n\ndef load(path):\n    \"\"\"Load the CSV.\"\"\"\n    !echo loading\n    return pd.read_csv(path)"},
+  {"cell_type": "code", "metadata": {}, "outputs": [], "source": []}
+ ],
+ "metadata": {"kernelspec": {"language": "python", "name": "python3"}},
+ "nbformat": 4
+}`)
+
+	functions, err := NewNotebookParser().ExtractFunctions("notebooks/churn.ipynb", code)
+	if err != nil {
+		t.Fatalf("Failed to parse notebook: %v", err)
+	}
+	byName := make(map[string]FunctionNode)
+	for _, fn := range functions {
+		byName[fn.Name] = fn
+	}
+	if len(functions) != 5 {
+		t.Fatalf("Expected 5 chunks, got %d: %+v", len(functions), byName)
+	}
+
+	if loading := byName["Loading"]; loading.NodeType != "documentation" || loading.CellIndex != 1 || loading.HeadingPath != "churn > Loading" || loading.Content != "## Loading\nLoad the data." {
+		t.Errorf("markdown cell = %+v", loading)
+	}
+	if cell := byName["cell 2"]; cell.NodeType != "cell" || cell.CellIndex != 2 || cell.EndLine != 2 {
+		t.Errorf("code cell = %+v", cell)
+	}
+	load := byName["load"]
+	if load.NodeType != "function" || load.CellIndex != 3 || load.StartLine != 3 || load.EndLine != 6 || load.Doc != "Load the CSV." {
+		t.Errorf("function = %+v", load)
+	}
+	if !strings.Contains(load.Content, "!echo loading") || !containsString(load.Callees, "pd.read_csv") || !containsString(load.Imports, "pd=pandas") {
+		t.Errorf("function metadata = %q %q %q", load.Content, load.Callees, load.Imports)
+	}
+
+	if _, err := NewNotebookParser().ExtractFunctions("broken.ipynb", []byte("{")); err == nil {
+		t.Errorf("Expected an error for invalid notebook JSON")
+	}
+}
+
 func TestDetectLanguage(t *testing.T) {
 	tests := []struct {
 		filePath string
@@ -180,6 +1695,23 @@ func TestDetectLanguage(t *testing.T) {
 		{"component.jsx", LanguageJavaScript},
 		{"types.ts", LanguageTypeScript},
 		{"component.tsx", LanguageTypeScript},
+		{"lib.rs", LanguageRust},
+		{"UserController.java", LanguageJava},
+		{"App.kt", LanguageKotlin},
+		{"build.gradle.kts", LanguageKotlin},
+		{"list.c", LanguageC},
+		{"list.h", LanguageC},
+		{"widget.cpp", LanguageCPP},
+		{"widget.hpp", LanguageCPP},
+		{"UserService.cs", LanguageCSharp},
+		{"users_controller.rb", LanguageRuby},
+		{"UserController.php", LanguagePHP},
+		{"UserCard.vue", LanguageVue},
+		{"Counter.svelte", LanguageSvelte},
+		{"README.md", LanguageMarkdown},
+		{"0001_init.up.sql", LanguageSQL},
+		{"users.proto", LanguageProto},
+		{"churn.ipynb", LanguageNotebook},
 		{"unknown.txt", ""},
 	}
 
@@ -0,0 +1,595 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+// PHPParser implements LanguageParser for PHP language
+type PHPParser struct{}
+
+// NewPHPParser creates a new PHP parser
+func NewPHPParser() *PHPParser {
+	return &PHPParser{}
+}
+
+// Language returns the language name
+func (p *PHPParser) Language() string {
+	return string(LanguagePHP)
+}
+
+// phpKeywords are never reported as callees.
+var phpKeywords = map[string]bool{
+	"if": true, "elseif": true, "for": true, "foreach": true, "while": true, "switch": true,
+	"match": true, "catch": true, "return": true, "new": true, "throw": true, "array": true,
+	"list": true, "isset": true, "unset": true, "empty": true, "function": true, "fn": true,
+	"echo": true, "print": true, "include": true, "require": true, "include_once": true,
+	"require_once": true, "use": true, "declare": true, "exit": true, "die": true,
+}
+
+// phpModifiers are declaration modifiers skipped before a member.
+var phpModifiers = map[string]bool{
+	"public": true, "protected": true, "private": true, "static": true, "abstract": true,
+	"final": true, "readonly": true, "var": true,
+}
+
+// phpTypeKeywords maps type declaration keywords to node types.
+var phpTypeKeywords = map[string]string{
+	"class":     "class",
+	"interface": "interface",
+	"trait":     "trait",
+	"enum":      "enum",
+}
+
+type phpScope struct {
+	name string // class, interface, trait or enum name
+	kind string
+}
+
+type phpDeclParser struct {
+	code      []byte // original source, used for chunk contents
+	masked    []byte // source with inline HTML blanked out
+	lex       *cLexResult
+	tokens    []cToken
+	namespace string
+	imports   []string
+	functions []FunctionNode
+}
+
+// ExtractFunctions extracts namespaces' classes, interfaces, traits, enums,
+// functions and methods from PHP source code. Inline HTML outside <?php ?>
+// tags is ignored.
+func (p *PHPParser) ExtractFunctions(filePath string, code []byte) ([]FunctionNode, error) {
+	masked := maskPHPInlineHTML(code)
+	lex := lexCLike(masked, cLexOptions{hashComments: true, identChars: "$", stringPrefix: phpHeredoc})
+	pp := &phpDeclParser{code: code, masked: masked, lex: lex, tokens: lex.tokens}
+	pp.imports = phpUseImports(masked, lex.tokens)
+	pp.parseStatements(0, len(lex.tokens), phpScope{})
+
+	sort.SliceStable(pp.functions, func(i, j int) bool {
+		return pp.functions[i].StartByte < pp.functions[j].StartByte
+	})
+	return pp.functions, nil
+}
+
+// parseStatements walks declarations in tokens[from:to] and returns the names
+// of the members declared at this level.
+func (p *phpDeclParser) parseStatements(from, to int, scope phpScope) []string {
+	var members []string
+	i := from
+	for i < to {
+		tok := p.tokens[i]
+		switch tok.text {
+		case ";", "}":
+			i++
+			continue
+		case "namespace":
+			if scope.name != "" || (i+1 < to && p.tokens[i+1].text == "\\") {
+				break
+			}
+			j := i + 1
+			for j < to && p.tokens[j].text != ";" && p.tokens[j].text != "{" {
+				j++
+			}
+			p.namespace = strings.ReplaceAll(tokenSpanText(p.masked, p.tokens, i+1, j), " ", "")
+			if j < to && p.tokens[j].text == "{" {
+				end := matchingClose(p.tokens, j)
+				if end < 0 {
+					end = to
+				}
+				p.parseStatements(j+1, end, scope)
+				p.namespace = ""
+				i = end + 1
+				continue
+			}
+			i = j + 1
+			continue
+		case "use":
+			// Namespace imports and trait uses; both end with ';' or a
+			// conflict resolution block.
+			i = p.skipStatement(i, to)
+			continue
+		}
+
+		declStart := i
+		var attributes []string
+		for i+1 < to && p.tokens[i].text == "#" && p.tokens[i+1].text == "[" {
+			end := matchingClose(p.tokens, i+1)
+			if end < 0 {
+				return members
+			}
+			attributes = append(attributes, tokenListTexts(p.masked, p.tokens, i+2, end)...)
+			i = end + 1
+		}
+		modStart := i
+		exported := true
+		for i < to && phpModifiers[p.tokens[i].text] {
+			if p.tokens[i].text == "private" || p.tokens[i].text == "protected" {
+				exported = false
+			}
+			i++
+		}
+		if i >= to {
+			break
+		}
+
+		switch {
+		case phpTypeKeywords[p.tokens[i].text] != "" && i+1 < to && p.tokens[i+1].kind == cTokIdent && scope.name == "":
+			end, name := p.parseType(declStart, modStart, i, to, attributes)
+			members = appendNonEmpty(members, name)
+			i = end
+		case p.tokens[i].text == "function" && i+1 < to && (p.tokens[i+1].kind == cTokIdent || p.tokens[i+1].text == "&"):
+			end, name := p.parseFunction(declStart, modStart, i, to, scope, attributes, exported)
+			members = appendNonEmpty(members, name)
+			i = end
+		case p.tokens[i].text == "case" && scope.kind == "enum" && i+1 < to && p.tokens[i+1].kind == cTokIdent:
+			members = append(members, p.tokens[i+1].text)
+			i = p.skipStatement(i, to)
+		default:
+			i = p.skipStatement(i, to)
+		}
+	}
+	return members
+}
+
+func (p *phpDeclParser) parseType(declStart, modStart, kwIdx, to int, attributes []string) (int, string) {
+	kind := phpTypeKeywords[p.tokens[kwIdx].text]
+	name := p.tokens[kwIdx+1].text
+	j := kwIdx + 2
+	for j < to && p.tokens[j].text != "{" && p.tokens[j].text != ";" {
+		j++
+	}
+	if j >= to || p.tokens[j].text != "{" {
+		return j + 1, ""
+	}
+	bodyEnd := matchingClose(p.tokens, j)
+	if bodyEnd < 0 {
+		return to, ""
+	}
+	members := p.parseStatements(j+1, bodyEnd, phpScope{name: name, kind: kind})
+
+	node := newCFunctionNode(p.code, p.lex.lineStarts, name, kind, p.tokens[declStart].start, p.tokens[bodyEnd].end)
+	node.PackageName = p.namespace
+	node.Imports = append([]string(nil), p.imports...)
+	node.Signature = tokenSpanText(p.masked, p.tokens, modStart, j)
+	node.Doc = p.docBefore(declStart)
+	node.Decorators = attributes
+	node.Members = members
+	node.Exported = true
+	p.functions = append(p.functions, node)
+	return bodyEnd + 1, name
+}
+
+// parseFunction handles "function name(...): type { ... }" at file level and
+// methods inside classes, interfaces, traits and enums.
+func (p *phpDeclParser) parseFunction(declStart, modStart, kwIdx, to int, scope phpScope, attributes []string, exported bool) (int, string) {
+	j := kwIdx + 1
+	if p.tokens[j].text == "&" {
+		j++
+	}
+	if j >= to || p.tokens[j].kind != cTokIdent || j+1 >= to || p.tokens[j+1].text != "(" {
+		return p.skipStatement(kwIdx, to), ""
+	}
+	name := p.tokens[j].text
+	open := j + 1
+	paramsEnd := matchingClose(p.tokens, open)
+	if paramsEnd < 0 {
+		return to, ""
+	}
+	k := paramsEnd + 1
+	var returnTypes []string
+	if k < to && p.tokens[k].text == ":" {
+		retStart := k + 1
+		k = retStart
+		for k < to && p.tokens[k].text != "{" && p.tokens[k].text != ";" {
+			k++
+		}
+		if ret := tokenSpanText(p.masked, p.tokens, retStart, k); ret != "" && ret != "void" {
+			returnTypes = []string{ret}
+		}
+	}
+	for k < to && p.tokens[k].text != "{" && p.tokens[k].text != ";" {
+		k++
+	}
+	if k >= to || p.tokens[k].text == ";" {
+		// Abstract or interface method without a body.
+		return k + 1, name
+	}
+	bodyEnd := matchingClose(p.tokens, k)
+	if bodyEnd < 0 {
+		return to, name
+	}
+
+	qualified := name
+	nodeType := "function"
+	if scope.name != "" {
+		qualified = scope.name + "::" + name
+		nodeType = "method"
+		if name == "__construct" {
+			nodeType = "constructor"
+		}
+	}
+	doc := p.docBefore(declStart)
+
+	node := newCFunctionNode(p.code, p.lex.lineStarts, qualified, nodeType, p.tokens[declStart].start, p.tokens[bodyEnd].end)
+	node.PackageName = p.namespace
+	node.Imports = append([]string(nil), p.imports...)
+	node.Signature = tokenSpanText(p.masked, p.tokens, modStart, k)
+	node.Receiver = scope.name
+	node.Doc = doc
+	node.Callees = cCallees(p.tokens[k+1:bodyEnd], phpKeywords)
+	node.ParamTypes = phpParamTypes(p.masked, p.tokens, open+1, paramsEnd)
+	node.ReturnTypes = returnTypes
+	if len(node.ReturnTypes) == 0 {
+		if ret := phpDocTag(doc, "@return"); ret != "" && ret != "void" {
+			node.ReturnTypes = []string{ret}
+		}
+	}
+	node.Raises = phpRaises(p.masked, p.tokens, k+1, bodyEnd, doc)
+	node.Decorators = attributes
+	node.IsGenerator = containsToken(p.tokens[k+1:bodyEnd], "yield")
+	node.Exported = exported
+	p.functions = append(p.functions, node)
+	return bodyEnd + 1, name
+}
+
+// skipStatement advances past a statement, a braced block or a closure call
+// such as Route::get('/', function () { ... });
+func (p *phpDeclParser) skipStatement(i, to int) int {
+	for i < to {
+		switch p.tokens[i].text {
+		case ";":
+			return i + 1
+		case "(", "[":
+			end := matchingClose(p.tokens, i)
+			if end < 0 {
+				return to
+			}
+			i = end
+		case "{":
+			end := matchingClose(p.tokens, i)
+			if end < 0 {
+				return to
+			}
+			if end+1 >= to || p.tokens[end+1].text != ";" && p.tokens[end+1].text != ")" && p.tokens[end+1].text != "," {
+				return end + 1
+			}
+			i = end
+		case "}":
+			return i
+		}
+		i++
+	}
+	return to
+}
+
+func (p *phpDeclParser) docBefore(tokenIdx int) string {
+	return docCommentBefore(p.masked, p.lex.comments, p.tokens[tokenIdx].start, isJavadocComment)
+}
+
+// phpUseImports returns the fully qualified names imported by top-level use
+// statements, expanding group uses (use App\{A, B as C};). Trait uses inside
+// class bodies and closure use clauses are skipped.
+func phpUseImports(code []byte, tokens []cToken) []string {
+	seen := make(map[string]struct{})
+	var imports []string
+	add := func(name string) {
+		name = strings.TrimPrefix(name, "\\")
+		if name == "" {
+			return
+		}
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			imports = append(imports, name)
+		}
+	}
+	depth := 0
+	namespaceBlock := -1
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i].text {
+		case "{":
+			if i > 0 && namespaceBlock < 0 && depth == 0 && isPHPNamespaceBrace(tokens, i) {
+				namespaceBlock = depth + 1
+			}
+			depth++
+			continue
+		case "}":
+			depth--
+			if depth < namespaceBlock {
+				namespaceBlock = -1
+			}
+			continue
+		case "use":
+		default:
+			continue
+		}
+		top := depth == 0 || depth == namespaceBlock
+		if !top || (i > 0 && tokens[i-1].text == ")") {
+			continue
+		}
+		end := i + 1
+		for end < len(tokens) && tokens[end].text != ";" {
+			end++
+		}
+		from := i + 1
+		if from < end && (tokens[from].text == "function" || tokens[from].text == "const") {
+			from++
+		}
+		for _, part := range splitTokensTopLevel(tokens, from, end, ",", false) {
+			brace := -1
+			for k := part[0]; k < part[1]; k++ {
+				if tokens[k].text == "{" {
+					brace = k
+					break
+				}
+			}
+			if brace < 0 {
+				add(phpImportName(code, tokens, part[0], part[1]))
+				continue
+			}
+			prefix := strings.TrimSuffix(phpImportName(code, tokens, part[0], brace), "\\")
+			closeIdx := matchingClose(tokens, brace)
+			if closeIdx < 0 {
+				closeIdx = part[1]
+			}
+			for _, inner := range splitTokensTopLevel(tokens, brace+1, closeIdx, ",", false) {
+				if name := phpImportName(code, tokens, inner[0], inner[1]); name != "" {
+					add(prefix + "\\" + name)
+				}
+			}
+		}
+		i = end
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+// isPHPNamespaceBrace reports whether the "{" at tokens[i] opens a
+// "namespace Name { ... }" block.
+func isPHPNamespaceBrace(tokens []cToken, i int) bool {
+	for k := i - 1; k >= 0; k-- {
+		switch tokens[k].text {
+		case "namespace":
+			return true
+		case ";", "{", "}", ")":
+			return false
+		}
+	}
+	return false
+}
+
+// phpImportName returns the name in tokens[from:to] without an "as" alias
+// or the function/const keyword of a group member.
+func phpImportName(code []byte, tokens []cToken, from, to int) string {
+	if from < to && (tokens[from].text == "function" || tokens[from].text == "const") {
+		from++
+	}
+	for k := from; k < to; k++ {
+		if tokens[k].text == "as" {
+			to = k
+			break
+		}
+	}
+	return strings.ReplaceAll(tokenSpanText(code, tokens, from, to), " ", "")
+}
+
+// phpParamTypes returns the declared parameter types in tokens[from:to],
+// dropping attributes, promotion modifiers, variables and default values.
+// Untyped parameters are skipped.
+func phpParamTypes(code []byte, tokens []cToken, from, to int) []string {
+	var types []string
+	for _, part := range splitTokensTopLevel(tokens, from, to, ",", false) {
+		start, end := part[0], part[1]
+		for start+1 < end && tokens[start].text == "#" && tokens[start+1].text == "[" {
+			close := matchingClose(tokens, start+1)
+			if close < 0 {
+				break
+			}
+			start = close + 1
+		}
+		for start < end && phpModifiers[tokens[start].text] {
+			start++
+		}
+		for k := start; k < end; k++ {
+			if strings.HasPrefix(tokens[k].text, "$") {
+				end = k
+				break
+			}
+		}
+		for end > start && (tokens[end-1].text == "&" || tokens[end-1].text == "...") {
+			end--
+		}
+		if t := tokenSpanText(code, tokens, start, end); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// phpRaises returns the exception types thrown in tokens[from:to] or
+// documented with @throws, sorted and deduplicated.
+func phpRaises(code []byte, tokens []cToken, from, to int, doc string) []string {
+	seen := make(map[string]struct{})
+	var raises []string
+	add := func(name string) {
+		name = strings.TrimPrefix(name, "\\")
+		if _, ok := seen[name]; name != "" && !ok {
+			seen[name] = struct{}{}
+			raises = append(raises, name)
+		}
+	}
+	for i := from; i+2 < to; i++ {
+		if tokens[i].text != "throw" || tokens[i+1].text != "new" {
+			continue
+		}
+		end := i + 2
+		for end < to && (tokens[end].kind == cTokIdent || tokens[end].text == "\\") {
+			end++
+		}
+		add(strings.ReplaceAll(tokenSpanText(code, tokens, i+2, end), " ", ""))
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "@throws" {
+			for _, name := range strings.Split(fields[1], "|") {
+				add(name)
+			}
+		}
+	}
+	sort.Strings(raises)
+	return raises
+}
+
+// phpDocTag returns the first word following tag in a PHPDoc comment.
+func phpDocTag(doc, tag string) string {
+	for _, line := range strings.Split(doc, "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == tag {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+func containsToken(tokens []cToken, text string) bool {
+	for _, tok := range tokens {
+		if tok.kind == cTokIdent && tok.text == text {
+			return true
+		}
+	}
+	return false
+}
+
+// maskPHPInlineHTML returns a copy of code in which everything outside
+// <?php ... ?> (and <?= ... ?>) blocks, including the tags themselves, is
+// replaced by spaces. Newlines are kept so offsets and line numbers match
+// the original source.
+func maskPHPInlineHTML(code []byte) []byte {
+	masked := make([]byte, len(code))
+	copy(masked, code)
+	blank := func(from, to int) {
+		for k := from; k < to && k < len(masked); k++ {
+			if masked[k] != '\n' {
+				masked[k] = ' '
+			}
+		}
+	}
+
+	pos := 0
+	for pos < len(code) {
+		open := strings.Index(string(code[pos:]), "<?")
+		if open < 0 {
+			blank(pos, len(code))
+			break
+		}
+		open += pos
+		tagEnd := open + 2
+		if strings.HasPrefix(string(code[tagEnd:min(tagEnd+3, len(code))]), "php") {
+			tagEnd += 3
+		} else if tagEnd < len(code) && code[tagEnd] == '=' {
+			tagEnd++
+		}
+		blank(pos, tagEnd)
+
+		// Find the closing ?> outside strings and comments.
+		i := tagEnd
+	scan:
+		for i < len(code) {
+			ch := code[i]
+			switch {
+			case ch == '?' && i+1 < len(code) && code[i+1] == '>':
+				blank(i, i+2)
+				i += 2
+				break scan
+			case ch == '\'' || ch == '"' || ch == '`':
+				i = skipCQuoted(code, i, ch)
+				continue
+			case ch == '/' && i+1 < len(code) && code[i+1] == '*':
+				i = skipCBlockComment(code, i, false)
+				continue
+			case ch == '#' || ch == '/' && i+1 < len(code) && code[i+1] == '/':
+				// Line comments end at a newline or at ?>.
+				for i < len(code) && code[i] != '\n' && !(code[i] == '?' && i+1 < len(code) && code[i+1] == '>') {
+					i++
+				}
+				continue
+			case ch == '<' && strings.HasPrefix(string(code[i:min(i+3, len(code))]), "<<<"):
+				if end := phpHeredoc(code, i); end > i {
+					i = end
+					continue
+				}
+			}
+			i++
+		}
+		pos = i
+	}
+	return masked
+}
+
+// phpHeredoc matches heredoc and nowdoc literals: <<<ID, <<<"ID", <<<'ID'.
+// The closing identifier may be indented (PHP 7.3+).
+func phpHeredoc(code []byte, pos int) int {
+	if !strings.HasPrefix(string(code[pos:min(pos+3, len(code))]), "<<<") {
+		return -1
+	}
+	i := pos + 3
+	for i < len(code) && (code[i] == ' ' || code[i] == '\t') {
+		i++
+	}
+	quote := byte(0)
+	if i < len(code) && (code[i] == '"' || code[i] == '\'') {
+		quote = code[i]
+		i++
+	}
+	idStart := i
+	for i < len(code) && isCIdentPart(code[i], "") {
+		i++
+	}
+	if i == idStart {
+		return -1
+	}
+	id := string(code[idStart:i])
+	if quote != 0 {
+		if i >= len(code) || code[i] != quote {
+			return -1
+		}
+		i++
+	}
+	if i >= len(code) || (code[i] != '\n' && code[i] != '\r') {
+		return -1
+	}
+	for i < len(code) {
+		lineStart := i + 1
+		lineEnd := lineStart
+		for lineEnd < len(code) && code[lineEnd] != '\n' {
+			lineEnd++
+		}
+		if lineStart > len(code) {
+			break
+		}
+		trimmed := strings.TrimLeft(string(code[lineStart:lineEnd]), " \t")
+		if strings.HasPrefix(trimmed, id) && (len(trimmed) == len(id) || !isCIdentPart(trimmed[len(id)], "")) {
+			return lineEnd - len(trimmed) + len(id)
+		}
+		i = lineEnd
+	}
+	return len(code)
+}
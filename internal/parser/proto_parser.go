@@ -0,0 +1,270 @@
+package parser
+
+import (
+	"strings"
+)
+
+// ProtoParser implements LanguageParser for Protocol Buffers definitions.
+// Messages, enums, services and rpcs become chunks; fields keep their
+// numbers and options, and rpcs record their request and response types.
+type ProtoParser struct{}
+
+// NewProtoParser creates a new Protocol Buffers parser
+func NewProtoParser() *ProtoParser {
+	return &ProtoParser{}
+}
+
+// Language returns the language name
+func (p *ProtoParser) Language() string {
+	return string(LanguageProto)
+}
+
+type protoDeclParser struct {
+	code       []byte
+	tokens     []cToken
+	comments   []cComment
+	lineStarts []int
+	pkg        string
+	imports    []string
+	functions  []FunctionNode
+}
+
+// ExtractFunctions extracts messages, enums, services and rpcs from a .proto
+// file.
+func (p *ProtoParser) ExtractFunctions(filePath string, code []byte) ([]FunctionNode, error) {
+	lexed := lexCLike(code, cLexOptions{})
+	d := &protoDeclParser{
+		code:       code,
+		tokens:     lexed.tokens,
+		comments:   lexed.comments,
+		lineStarts: lexed.lineStarts,
+	}
+
+	// Package and imports apply to every chunk, wherever they appear.
+	for i := 0; i < len(d.tokens); i++ {
+		switch d.tokens[i].text {
+		case "package":
+			end := d.statementEnd(i)
+			d.pkg = strings.ReplaceAll(tokenSpanText(code, d.tokens, i+1, end), " ", "")
+			i = end
+		case "import":
+			end := d.statementEnd(i)
+			for j := i + 1; j < end; j++ {
+				if d.tokens[j].kind == cTokString {
+					d.imports = append(d.imports, strings.Trim(d.tokens[j].text, `"'`))
+				}
+			}
+			i = end
+		case "{":
+			if end := matchingClose(d.tokens, i); end > i {
+				i = end
+			}
+		}
+	}
+
+	d.parseScope(0, len(d.tokens), "")
+	for i := range d.functions {
+		d.functions[i].PackageName = d.pkg
+		d.functions[i].Imports = d.imports
+		d.functions[i].Exported = true
+	}
+	return d.functions, nil
+}
+
+// statementEnd returns the index of the ";" ending the statement at i, or
+// the last token.
+func (d *protoDeclParser) statementEnd(i int) int {
+	for j := i; j < len(d.tokens); j++ {
+		switch d.tokens[j].text {
+		case ";":
+			return j
+		case "{", "[", "(":
+			if end := matchingClose(d.tokens, j); end > j {
+				j = end
+			}
+		}
+	}
+	return len(d.tokens) - 1
+}
+
+// parseScope parses the declarations in tokens[from:to]: the file or the
+// body of a message. prefix is the enclosing message name plus "." and the
+// returned members and options belong to that message.
+func (d *protoDeclParser) parseScope(from, to int, prefix string) (members, options []string) {
+	for i := from; i < to; i++ {
+		tok := d.tokens[i]
+		if tok.kind != cTokIdent {
+			continue
+		}
+		switch tok.text {
+		case "message", "enum", "service":
+			if i+2 >= to || d.tokens[i+1].kind != cTokIdent || d.tokens[i+2].text != "{" {
+				break
+			}
+			end := matchingClose(d.tokens, i+2)
+			if end < 0 {
+				end = to - 1
+			}
+			d.parseDefinition(i, end, prefix)
+			i = end
+			continue
+		case "oneof":
+			if i+2 < to && d.tokens[i+2].text == "{" {
+				if end := matchingClose(d.tokens, i+2); end > i {
+					oneofMembers, oneofOptions := d.parseScope(i+3, end, prefix)
+					members = append(members, oneofMembers...)
+					options = append(options, oneofOptions...)
+					i = end
+					continue
+				}
+			}
+		case "extend", "group":
+			// Extensions and proto2 groups are skipped with their bodies.
+			for j := i + 1; j < to; j++ {
+				if d.tokens[j].text == "{" {
+					if end := matchingClose(d.tokens, j); end > j {
+						i = end
+					}
+					break
+				}
+				if d.tokens[j].text == ";" {
+					i = j
+					break
+				}
+			}
+			continue
+		}
+
+		end := min(d.statementEnd(i), to)
+		if prefix == "" {
+			// File-level statements: syntax, package, import, option.
+			i = end
+			continue
+		}
+		switch tok.text {
+		case "option":
+			options = append(options, tokenSpanText(d.code, d.tokens, i+1, end))
+		case "reserved", "extensions":
+		default:
+			if text := tokenSpanText(d.code, d.tokens, i, end); text != "" {
+				members = append(members, text)
+			}
+		}
+		i = end
+	}
+	return members, options
+}
+
+// parseDefinition emits the chunk of the message, enum or service whose
+// keyword is at tokens[i] and whose body closes at tokens[end].
+func (d *protoDeclParser) parseDefinition(i, end int, prefix string) {
+	kind := d.tokens[i].text
+	name := prefix + d.tokens[i+1].text
+	node := newCFunctionNode(d.code, d.lineStarts, name, kind, d.tokens[i].start, d.tokens[end].end)
+	node.Doc = docCommentBefore(d.code, d.comments, d.tokens[i].start, func(string) bool { return true })
+	node.Signature = kind + " " + name
+	if prefix != "" {
+		node.Receiver = strings.TrimSuffix(prefix, ".")
+	}
+	index := len(d.functions)
+	d.functions = append(d.functions, node)
+
+	switch kind {
+	case "message":
+		members, options := d.parseScope(i+3, end, name+".")
+		d.functions[index].Members = members
+		d.functions[index].Options = options
+	case "enum":
+		members, options := d.parseEnumBody(i+3, end)
+		d.functions[index].Members = members
+		d.functions[index].Options = options
+	case "service":
+		d.functions[index].Options = d.parseService(i+3, end, name)
+		for _, fn := range d.functions[index+1:] {
+			if fn.NodeType == "rpc" && fn.Receiver == name {
+				d.functions[index].Members = append(d.functions[index].Members, strings.TrimPrefix(fn.Name, name+"."))
+			}
+		}
+	}
+}
+
+// parseEnumBody returns the values ("ACTIVE = 1") and options of an enum.
+func (d *protoDeclParser) parseEnumBody(from, to int) (values, options []string) {
+	for i := from; i < to; i++ {
+		if d.tokens[i].text == ";" {
+			continue
+		}
+		end := min(d.statementEnd(i), to)
+		switch d.tokens[i].text {
+		case "option":
+			options = append(options, tokenSpanText(d.code, d.tokens, i+1, end))
+		case "reserved":
+		default:
+			values = append(values, tokenSpanText(d.code, d.tokens, i, end))
+		}
+		i = end
+	}
+	return values, options
+}
+
+// parseService emits the rpcs of a service and returns the service options.
+func (d *protoDeclParser) parseService(from, to int, service string) []string {
+	var options []string
+	for i := from; i < to; i++ {
+		switch d.tokens[i].text {
+		case "option":
+			end := min(d.statementEnd(i), to)
+			options = append(options, tokenSpanText(d.code, d.tokens, i+1, end))
+			i = end
+		case "rpc":
+			i = d.parseRPC(i, to, service)
+		}
+	}
+	return options
+}
+
+// parseRPC emits the rpc at tokens[i] and returns the index of its last
+// token: "rpc Get(GetRequest) returns (GetResponse);" or a body with options.
+func (d *protoDeclParser) parseRPC(i, to int, service string) int {
+	if i+2 >= to || d.tokens[i+1].kind != cTokIdent || d.tokens[i+2].text != "(" {
+		return i
+	}
+	method := d.tokens[i+1].text
+	reqEnd := matchingClose(d.tokens, i+2)
+	if reqEnd < 0 || reqEnd+2 >= to || d.tokens[reqEnd+1].text != "returns" || d.tokens[reqEnd+2].text != "(" {
+		return i
+	}
+	respEnd := matchingClose(d.tokens, reqEnd+2)
+	if respEnd < 0 {
+		return i
+	}
+	last := respEnd
+	var options []string
+	if respEnd+1 < to {
+		switch d.tokens[respEnd+1].text {
+		case ";":
+			last = respEnd + 1
+		case "{":
+			if end := matchingClose(d.tokens, respEnd+1); end > respEnd {
+				for j := respEnd + 2; j < end; j++ {
+					if d.tokens[j].text == "option" {
+						stmtEnd := min(d.statementEnd(j), end)
+						options = append(options, tokenSpanText(d.code, d.tokens, j+1, stmtEnd))
+						j = stmtEnd
+					}
+				}
+				last = end
+			}
+		}
+	}
+
+	node := newCFunctionNode(d.code, d.lineStarts, service+"."+method, "rpc", d.tokens[i].start, d.tokens[last].end)
+	node.Doc = docCommentBefore(d.code, d.comments, d.tokens[i].start, func(string) bool { return true })
+	node.Receiver = service
+	node.Signature = tokenSpanText(d.code, d.tokens, i, respEnd+1)
+	node.ParamTypes = []string{tokenSpanText(d.code, d.tokens, i+3, reqEnd)}
+	node.ReturnTypes = []string{tokenSpanText(d.code, d.tokens, reqEnd+3, respEnd)}
+	node.Options = options
+	d.functions = append(d.functions, node)
+	return last
+}
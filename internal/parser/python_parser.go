@@ -2,7 +2,7 @@ package parser
 
 import (
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -19,176 +19,365 @@ func (p *PythonParser) Language() string {
 	return string(LanguagePython)
 }
 
-var (
-	pyFuncRegex  = regexp.MustCompile(`^\s*def\s+([A-Za-z_]\w*)\s*\(`)
-	pyClassRegex = regexp.MustCompile(`^\s*class\s+([A-Za-z_]\w*)`)
-)
-
-type pythonLine struct {
-	text       string
-	indent     int
-	startByte  int
-	endByte    int
-	lineNumber int
+// pythonScope is an enclosing class or function definition.
+type pythonScope struct {
+	name    string
+	isClass bool
 }
 
-type pythonBlock struct {
-	indent int
-	name   string
+// pythonDefinitionParser walks the token stream produced by tokenizePython and
+// records every def/class statement, including nested ones.
+type pythonDefinitionParser struct {
+	code      []byte
+	tokens    []pyToken
+	pos       int
+	pkgName   string
+	imports   []string
+	functions []FunctionNode
 }
 
-// ExtractFunctions extracts function and class method definitions from Python source code.
+// ExtractFunctions extracts function, method and class definitions from Python
+// source code.
 func (p *PythonParser) ExtractFunctions(filePath string, code []byte) ([]FunctionNode, error) {
-	lines := splitPythonLines(code)
-	var functions []FunctionNode
-	var classStack []pythonBlock
-
-	// Derive a lightweight package/module name from the file path
-	pkgName := derivePythonPackageName(filePath)
-	imports := extractPythonImports(lines)
-
-	for i := 0; i < len(lines); i++ {
-		line := lines[i]
-		trimmed := strings.TrimSpace(line.text)
-		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+	tokens := tokenizePython(code)
+	dp := &pythonDefinitionParser{
+		code:    code,
+		tokens:  tokens,
+		pkgName: derivePythonPackageName(filePath),
+		imports: extractPythonImports(tokens),
+	}
+	dp.parseBlock(nil)
+
+	sort.SliceStable(dp.functions, func(i, j int) bool {
+		return dp.functions[i].StartByte < dp.functions[j].StartByte
+	})
+	return dp.functions, nil
+}
+
+func (dp *pythonDefinitionParser) peek() pyToken {
+	if dp.pos >= len(dp.tokens) {
+		return pyToken{kind: pyTokenEOF}
+	}
+	return dp.tokens[dp.pos]
+}
+
+func (dp *pythonDefinitionParser) peekAt(offset int) pyToken {
+	if dp.pos+offset >= len(dp.tokens) {
+		return pyToken{kind: pyTokenEOF}
+	}
+	return dp.tokens[dp.pos+offset]
+}
+
+// parseBlock consumes statements until the DEDENT closing the current block
+// (which is also consumed) or EOF.
+func (dp *pythonDefinitionParser) parseBlock(scopes []pythonScope) {
+	for {
+		tok := dp.peek()
+		switch tok.kind {
+		case pyTokenEOF:
+			return
+		case pyTokenDedent:
+			dp.pos++
+			return
+		case pyTokenNewline:
+			dp.pos++
+			continue
+		case pyTokenIndent:
+			// Unexpected indentation; treat it as a nested block so its
+			// DEDENT does not terminate the enclosing one.
+			dp.pos++
+			dp.parseBlock(scopes)
 			continue
 		}
+		dp.parseStatement(scopes)
+	}
+}
+
+func (dp *pythonDefinitionParser) parseStatement(scopes []pythonScope) {
+	start := dp.peek()
+	var decorators []string
+	for dp.peek().kind == pyTokenOp && dp.peek().text == "@" {
+		dp.pos++
+		exprStart := dp.pos
+		dp.skipToNewline()
+		decorators = append(decorators, joinPythonTokens(dp.tokens[exprStart:dp.pos]))
+		dp.consumeNewline()
+	}
+
+	tok := dp.peek()
+	isAsync := false
+	if tok.kind == pyTokenName && tok.text == "async" && dp.peekAt(1).kind == pyTokenName && dp.peekAt(1).text == "def" {
+		isAsync = true
+		dp.pos++
+		tok = dp.peek()
+	}
+
+	if tok.kind == pyTokenName && (tok.text == "def" || tok.text == "class") && dp.peekAt(1).kind == pyTokenName {
+		dp.parseDefinition(start, tok.text == "class", isAsync, decorators, scopes)
+		return
+	}
 
-		for len(classStack) > 0 && line.indent <= classStack[len(classStack)-1].indent {
-			classStack = classStack[:len(classStack)-1]
+	// Any other simple or compound statement. Definitions nested inside
+	// if/for/with/try blocks are still attributed to the enclosing scope.
+	dp.skipToNewline()
+	dp.consumeNewline()
+	if dp.peek().kind == pyTokenIndent {
+		dp.pos++
+		dp.parseBlock(scopes)
+	}
+}
+
+func (dp *pythonDefinitionParser) parseDefinition(start pyToken, isClass, isAsync bool, decorators []string, scopes []pythonScope) {
+	keywordIdx := dp.pos
+	dp.pos++
+	name := dp.peek().text
+	dp.pos++
+
+	// The header runs up to the ':' that opens the body; brackets never
+	// contain NEWLINE tokens, so the first top-level ':' is the right one.
+	headerStart := keywordIdx
+	if isAsync {
+		headerStart--
+	}
+	bodyColon := -1
+	depth := 0
+	for dp.peek().kind != pyTokenEOF && dp.peek().kind != pyTokenNewline {
+		t := dp.peek()
+		if t.kind == pyTokenOp {
+			switch t.text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				depth--
+			case ":":
+				if depth == 0 {
+					bodyColon = dp.pos
+				}
+			}
+		}
+		dp.pos++
+		if bodyColon >= 0 {
+			break
 		}
+	}
+	if bodyColon < 0 {
+		dp.consumeNewline()
+		return
+	}
+	header := dp.tokens[headerStart:bodyColon]
 
-		if matches := pyClassRegex.FindStringSubmatch(line.text); matches != nil {
-			classStack = append(classStack, pythonBlock{
-				indent: line.indent,
-				name:   matches[1],
-			})
-			continue
+	qualified := name
+	if len(scopes) > 0 {
+		names := make([]string, 0, len(scopes)+1)
+		for _, s := range scopes {
+			names = append(names, s.name)
 		}
+		qualified = strings.Join(append(names, name), ".")
+	}
 
-		if matches := pyFuncRegex.FindStringSubmatch(line.text); matches != nil {
-			name := matches[1]
-			nodeType := "function"
-			if len(classStack) > 0 {
-				nodeType = "method"
-				name = classStack[len(classStack)-1].name + "." + name
-			}
+	bodyStartIdx := dp.pos
+	lastIdx := bodyColon
+	inner := append(append([]pythonScope(nil), scopes...), pythonScope{name: name, isClass: isClass})
+	if dp.peek().kind == pyTokenNewline {
+		// Indented suite.
+		dp.pos++
+		if dp.peek().kind == pyTokenIndent {
+			dp.pos++
+			bodyStartIdx = dp.pos
+			dp.parseBlock(inner)
+			lastIdx = dp.lastCodeTokenBefore(dp.pos)
+		}
+	} else {
+		// Single-line suite: "def f(): return 1".
+		dp.skipToNewline()
+		lastIdx = dp.pos - 1
+		dp.consumeNewline()
+	}
 
-			endIdx := findPythonBlockEnd(lines, i, line.indent)
-			startOffset := line.startByte
-			endOffset := lines[endIdx].endByte
-			if endOffset <= startOffset {
-				continue
-			}
+	startOffset := start.startByte
+	endOffset := dp.tokens[lastIdx].endByte
+	startLine := start.line
+	endLine := dp.tokens[lastIdx].endLine
+	// As in the Go and JavaScript parsers, definitions spanning fewer than
+	// three lines are not chunked: one-liners and short stubs carry too little
+	// code to be worth an embedding of their own.
+	if endOffset <= startOffset || endLine-startLine < 2 {
+		return
+	}
 
-			startLine := line.lineNumber
-			endLine := lines[endIdx].lineNumber
-			if endLine-startLine < 2 {
-				continue
-			}
+	node := FunctionNode{
+		Name:        qualified,
+		StartLine:   startLine,
+		EndLine:     endLine,
+		Content:     string(dp.code[startOffset:endOffset]),
+		StartByte:   startOffset,
+		EndByte:     endOffset,
+		PackageName: dp.pkgName,
+		Imports:     append([]string(nil), dp.imports...),
+		Signature:   joinPythonTokens(header),
+		Doc:         dp.docstringAt(bodyStartIdx),
+		Decorators:  decorators,
+	}
 
-			// Best-effort extraction of signature, parameter types and return
-			// annotation from the "def" line.
-			signature, paramTypes, returnTypes := parsePythonSignature(line.text)
-
-			// Collect simple callee names from the function body to enrich
-			// cross-reference metadata.
-			callees := extractPythonCallees(code, startOffset, endOffset)
-
-			functions = append(functions, FunctionNode{
-				Name:        name,
-				NodeType:    nodeType,
-				StartLine:   startLine,
-				EndLine:     endLine,
-				Content:     string(code[startOffset:endOffset]),
-				StartByte:   startOffset,
-				EndByte:     endOffset,
-				PackageName: pkgName,
-				Imports:     append([]string(nil), imports...),
-				Signature:   signature,
-				// Receiver is not modeled separately for Python; methods are
-				// encoded via the qualified Name (Class.method).
-				Doc:            "", // Python docstrings are not yet extracted
-				Callees:        callees,
-				ParamTypes:     paramTypes,
-				ReturnTypes:    returnTypes,
-				HasErrorReturn: false,
-			})
-		}
-	}
-
-	return functions, nil
+	if isClass {
+		node.NodeType = "class"
+	} else {
+		node.NodeType = "function"
+		if len(scopes) > 0 && scopes[len(scopes)-1].isClass {
+			node.NodeType = "method"
+			node.Receiver = strings.TrimSuffix(qualified, "."+name)
+		}
+		body := dp.tokens[bodyStartIdx : lastIdx+1]
+		node.ParamTypes, node.ReturnTypes = parsePythonSignature(header)
+		node.Callees = extractPythonCallees(body)
+		node.IsAsync = isAsync
+		node.Raises, node.IsGenerator = analyzePythonBody(body)
+		// Raising is Python's error channel, so surface it through the same
+		// flag Go functions use for error returns.
+		node.HasErrorReturn = len(node.Raises) > 0
+	}
+	dp.functions = append(dp.functions, node)
 }
 
-func splitPythonLines(code []byte) []pythonLine {
-	var lines []pythonLine
-	start := 0
-	lineNumber := 1
-	for i := 0; i < len(code); i++ {
-		if code[i] == '\n' {
-			lines = append(lines, buildPythonLine(code, start, i+1, lineNumber))
-			start = i + 1
-			lineNumber++
-		}
-	}
-	if start < len(code) {
-		lines = append(lines, buildPythonLine(code, start, len(code), lineNumber))
-	} else if len(code) == 0 {
-		lines = append(lines, pythonLine{
-			text:       "",
-			indent:     0,
-			startByte:  0,
-			endByte:    0,
-			lineNumber: 1,
-		})
-	}
-	return lines
+// skipToNewline advances to the NEWLINE token ending the current logical line
+// without consuming it.
+func (dp *pythonDefinitionParser) skipToNewline() {
+	for {
+		kind := dp.peek().kind
+		if kind == pyTokenEOF || kind == pyTokenNewline {
+			return
+		}
+		dp.pos++
+	}
 }
 
-func buildPythonLine(code []byte, start, end, lineNumber int) pythonLine {
-	length := end - start
-	text := code[start:end]
-	if length > 0 && text[length-1] == '\r' {
-		text = text[:length-1]
+func (dp *pythonDefinitionParser) consumeNewline() {
+	if dp.peek().kind == pyTokenNewline {
+		dp.pos++
 	}
-	return pythonLine{
-		text:       string(text),
-		indent:     countPythonIndent(text),
-		startByte:  start,
-		endByte:    end,
-		lineNumber: lineNumber,
+}
+
+// lastCodeTokenBefore returns the index of the last token before end that
+// carries source text (skipping NEWLINE/INDENT/DEDENT markers).
+func (dp *pythonDefinitionParser) lastCodeTokenBefore(end int) int {
+	for i := end - 1; i >= 0; i-- {
+		switch dp.tokens[i].kind {
+		case pyTokenNewline, pyTokenIndent, pyTokenDedent, pyTokenEOF:
+			continue
+		}
+		return i
 	}
+	return 0
 }
 
-func countPythonIndent(line []byte) int {
-	count := 0
-	for _, b := range line {
-		if b == ' ' {
-			count++
-		} else if b == '\t' {
-			count += 4
-		} else {
+// docstringAt returns the cleaned docstring if the statement starting at idx
+// is a bare string literal.
+func (dp *pythonDefinitionParser) docstringAt(idx int) string {
+	var parts []string
+	for i := idx; i < len(dp.tokens); i++ {
+		tok := dp.tokens[i]
+		if tok.kind == pyTokenString {
+			parts = append(parts, pythonStringValue(tok.text))
+			continue
+		}
+		if tok.kind == pyTokenNewline || tok.kind == pyTokenEOF {
+			break
+		}
+		return ""
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return cleanPythonDocstring(strings.Join(parts, ""))
+}
+
+// pythonStringValue strips the prefix and quotes from a string literal token.
+// Escape sequences are left as written, which is sufficient for docs.
+func pythonStringValue(literal string) string {
+	i := 0
+	for i < len(literal) && literal[i] != '\'' && literal[i] != '"' {
+		i++
+	}
+	body := literal[i:]
+	for _, q := range []string{`"""`, `'''`, `"`, `'`} {
+		if strings.HasPrefix(body, q) {
+			body = strings.TrimPrefix(body, q)
+			body = strings.TrimSuffix(body, q)
 			break
 		}
 	}
-	return count
+	return body
 }
 
-func findPythonBlockEnd(lines []pythonLine, startIdx, indent int) int {
-	endIdx := startIdx
-	for i := startIdx + 1; i < len(lines); i++ {
-		line := lines[i]
-		trimmed := strings.TrimSpace(line.text)
-		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
-			endIdx = i
+// cleanPythonDocstring mirrors inspect.cleandoc: it trims surrounding blank
+// lines and removes the common indentation of all lines after the first.
+func cleanPythonDocstring(doc string) string {
+	lines := strings.Split(strings.ReplaceAll(doc, "\r\n", "\n"), "\n")
+	margin := -1
+	for _, line := range lines[1:] {
+		stripped := strings.TrimLeft(line, " \t")
+		if stripped == "" {
 			continue
 		}
-		if line.indent <= indent {
-			break
+		indent := len(line) - len(stripped)
+		if margin < 0 || indent < margin {
+			margin = indent
+		}
+	}
+	lines[0] = strings.TrimSpace(lines[0])
+	if margin > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= margin {
+				lines[i] = lines[i][margin:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// joinPythonTokens renders a token slice as compact single-line source, which
+// normalizes multi-line signatures and decorator expressions.
+func joinPythonTokens(tokens []pyToken) string {
+	var b strings.Builder
+	var prev *pyToken
+	for i := range tokens {
+		tok := &tokens[i]
+		switch tok.kind {
+		case pyTokenNewline, pyTokenIndent, pyTokenDedent, pyTokenEOF:
+			continue
+		}
+		if prev != nil && needsPythonSpace(prev, tok) {
+			b.WriteByte(' ')
 		}
-		endIdx = i
+		b.WriteString(tok.text)
+		prev = tok
 	}
-	return endIdx
+	return b.String()
+}
+
+func needsPythonSpace(prev, next *pyToken) bool {
+	if prev.kind == pyTokenOp {
+		switch prev.text {
+		case "(", "[", "{", ".", "@", "**", "*":
+			return false
+		}
+	}
+	if next.kind == pyTokenOp {
+		switch next.text {
+		case ")", "]", "}", ",", ":", ".":
+			return false
+		case "(", "[":
+			// Calls and subscripts bind tightly; grouping parens do not.
+			return prev.kind == pyTokenOp && !isPythonClosingBracket(prev.text)
+		}
+	}
+	return true
+}
+
+func isPythonClosingBracket(text string) bool {
+	return text == ")" || text == "]" || text == "}"
 }
 
 // derivePythonPackageName computes a simple module name from the file path,
@@ -207,163 +396,229 @@ func derivePythonPackageName(filePath string) string {
 
 // extractPythonImports collects import/module references at file level so
 // they can be attached to each FunctionNode for richer retrieval context.
-func extractPythonImports(lines []pythonLine) []string {
+// Only statements at the start of a logical line are considered, so
+// parenthesized multi-line imports are handled and strings mentioning
+// "import" are ignored.
+func extractPythonImports(tokens []pyToken) []string {
 	var imports []string
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line.text)
-		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+	lineStart := true
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.kind == pyTokenNewline || tok.kind == pyTokenIndent || tok.kind == pyTokenDedent {
+			lineStart = true
+			continue
+		}
+		if !lineStart {
 			continue
 		}
-		if !strings.HasPrefix(trimmed, "import ") && !strings.HasPrefix(trimmed, "from ") {
+		lineStart = false
+		if tok.kind != pyTokenName || (tok.text != "import" && tok.text != "from") {
 			continue
 		}
 
-		if strings.HasPrefix(trimmed, "import ") {
+		end := i
+		for end < len(tokens) && tokens[end].kind != pyTokenNewline && tokens[end].kind != pyTokenEOF {
+			end++
+		}
+		stmt := tokens[i:end]
+		i = end - 1
+
+		if tok.text == "import" {
 			// Handle patterns like "import a", "import a as b", "import a, b"
-			rest := strings.TrimSpace(strings.TrimPrefix(trimmed, "import "))
-			parts := strings.Split(rest, ",")
-			for _, part := range parts {
-				part = strings.TrimSpace(part)
-				if part == "" {
+			for _, part := range splitPythonTokens(stmt[1:], ",") {
+				module, alias := splitPythonAlias(part)
+				if module == "" {
 					continue
 				}
-				if strings.Contains(part, " as ") {
-					aliasParts := strings.SplitN(part, " as ", 2)
-					mod := strings.TrimSpace(aliasParts[0])
-					alias := strings.TrimSpace(aliasParts[1])
-					if alias != "" && mod != "" {
-						imports = append(imports, alias+"="+mod)
-						continue
-					}
+				if alias != "" {
+					imports = append(imports, alias+"="+module)
+				} else {
+					imports = append(imports, module)
 				}
-				imports = append(imports, part)
 			}
 			continue
 		}
 
 		// from pkg.subpkg import a, b as c
-		if strings.HasPrefix(trimmed, "from ") {
-			rest := strings.TrimSpace(strings.TrimPrefix(trimmed, "from "))
-			parts := strings.SplitN(rest, " import ", 2)
-			if len(parts) != 2 {
-				continue
+		importIdx := -1
+		for j, t := range stmt {
+			if t.kind == pyTokenName && t.text == "import" {
+				importIdx = j
+				break
 			}
-			module := strings.TrimSpace(parts[0])
-			namesPart := strings.TrimSpace(parts[1])
-			if module == "" || namesPart == "" {
+		}
+		if importIdx < 0 {
+			continue
+		}
+		module := joinPythonTokens(stmt[1:importIdx])
+		names := stmt[importIdx+1:]
+		// Remove enclosing parentheses if any: from x import (a, b)
+		if len(names) > 1 && names[0].text == "(" && names[len(names)-1].text == ")" {
+			names = names[1 : len(names)-1]
+		}
+		if module == "" {
+			continue
+		}
+		for _, part := range splitPythonTokens(names, ",") {
+			name, alias := splitPythonAlias(part)
+			if name == "" {
 				continue
 			}
-			// Remove enclosing parentheses if any: from x import (a, b)
-			if strings.HasPrefix(namesPart, "(") && strings.HasSuffix(namesPart, ")") {
-				namesPart = strings.TrimSpace(namesPart[1 : len(namesPart)-1])
-			}
-			for _, nameSpec := range strings.Split(namesPart, ",") {
-				nameSpec = strings.TrimSpace(nameSpec)
-				if nameSpec == "" {
-					continue
-				}
-				alias := ""
-				name := nameSpec
-				if strings.Contains(nameSpec, " as ") {
-					aliasParts := strings.SplitN(nameSpec, " as ", 2)
-					name = strings.TrimSpace(aliasParts[0])
-					alias = strings.TrimSpace(aliasParts[1])
-				}
-				full := module
-				if name != "*" && name != "" {
-					full = module + "." + name
-				}
-				if alias != "" {
-					imports = append(imports, alias+"="+full)
+			full := module
+			if name != "*" {
+				if strings.HasSuffix(module, ".") {
+					full = module + name
 				} else {
-					imports = append(imports, full)
+					full = module + "." + name
 				}
 			}
+			if alias != "" {
+				imports = append(imports, alias+"="+full)
+			} else {
+				imports = append(imports, full)
+			}
 		}
 	}
 	return imports
 }
 
-// parsePythonSignature extracts a readable signature along with simple
-// parameter and return type information from a "def" line.
-func parsePythonSignature(lineText string) (string, []string, []string) {
-	trimmed := strings.TrimSpace(lineText)
-	if !strings.HasPrefix(trimmed, "def ") {
-		return "", nil, nil
+func splitPythonAlias(tokens []pyToken) (string, string) {
+	for i, t := range tokens {
+		if t.kind == pyTokenName && t.text == "as" {
+			return joinPythonTokens(tokens[:i]), joinPythonTokens(tokens[i+1:])
+		}
 	}
+	return joinPythonTokens(tokens), ""
+}
+
+// splitPythonTokens splits tokens on a top-level separator operator.
+func splitPythonTokens(tokens []pyToken, sep string) [][]pyToken {
+	var parts [][]pyToken
+	depth := 0
+	start := 0
+	for i, t := range tokens {
+		if t.kind != pyTokenOp {
+			continue
+		}
+		switch t.text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(tokens) {
+		parts = append(parts, tokens[start:])
+	}
+	return parts
+}
 
-	// Use the portion from "def" up to the trailing colon as the signature.
-	sigEnd := strings.Index(trimmed, ":")
-	if sigEnd < 0 {
-		sigEnd = len(trimmed)
+// parsePythonSignature extracts simple parameter and return type information
+// from the tokens of a def header ("def name(params) -> T").
+func parsePythonSignature(header []pyToken) ([]string, []string) {
+	open := -1
+	for i, t := range header {
+		if t.kind == pyTokenOp && t.text == "(" {
+			open = i
+			break
+		}
+	}
+	if open < 0 {
+		return nil, nil
+	}
+	close := -1
+	depth := 0
+	for i := open; i < len(header); i++ {
+		t := header[i]
+		if t.kind != pyTokenOp {
+			continue
+		}
+		if t.text == "(" || t.text == "[" || t.text == "{" {
+			depth++
+		} else if t.text == ")" || t.text == "]" || t.text == "}" {
+			depth--
+			if depth == 0 {
+				close = i
+				break
+			}
+		}
+	}
+	if close < 0 {
+		return nil, nil
 	}
-	signature := trimmed[:sigEnd]
 
-	open := strings.Index(trimmed, "(")
-	close := strings.LastIndex(trimmed, ")")
 	var paramTypes []string
-	var returnTypes []string
-	if open >= 0 && close > open {
-		paramsPart := trimmed[open+1 : close]
-		for _, p := range strings.Split(paramsPart, ",") {
-			p = strings.TrimSpace(p)
-			if p == "" {
+	for _, param := range splitPythonTokens(header[open+1:close], ",") {
+		if len(param) == 0 {
+			continue
+		}
+		// Strip default value
+		if parts := splitPythonTokens(param, "="); len(parts) > 0 {
+			param = parts[0]
+		}
+		parts := splitPythonTokens(param, ":")
+		if len(parts) == 2 {
+			if typePart := joinPythonTokens(parts[1]); typePart != "" {
+				paramTypes = append(paramTypes, typePart)
 				continue
 			}
-			// Strip default value
-			if eq := strings.Index(p, "="); eq >= 0 {
-				p = strings.TrimSpace(p[:eq])
-			}
-			if colon := strings.Index(p, ":"); colon >= 0 {
-				typePart := strings.TrimSpace(p[colon+1:])
-				if typePart != "" {
-					paramTypes = append(paramTypes, typePart)
-					continue
-				}
-			}
-			// No explicit type; still record a placeholder for arity.
-			paramTypes = append(paramTypes, "")
 		}
+		// Bare "*" and "/" markers are not parameters.
+		if len(param) == 1 && param[0].kind == pyTokenOp && (param[0].text == "*" || param[0].text == "/") {
+			continue
+		}
+		// No explicit type; still record a placeholder for arity.
+		paramTypes = append(paramTypes, "")
+	}
 
-		// Look for return annotation: ") -> T:"
-		arrowIdx := strings.Index(trimmed[close:], "->")
-		if arrowIdx >= 0 {
-			arrowStart := close + arrowIdx + len("->")
-			retPart := strings.TrimSpace(trimmed[arrowStart:])
-			if colon := strings.Index(retPart, ":"); colon >= 0 {
-				retPart = strings.TrimSpace(retPart[:colon])
-			}
-			if retPart != "" {
-				returnTypes = append(returnTypes, retPart)
-			}
+	var returnTypes []string
+	rest := header[close+1:]
+	if len(rest) > 1 && rest[0].kind == pyTokenOp && rest[0].text == "->" {
+		if ret := joinPythonTokens(rest[1:]); ret != "" {
+			returnTypes = append(returnTypes, ret)
 		}
 	}
+	return paramTypes, returnTypes
+}
 
-	return signature, paramTypes, returnTypes
+var pythonKeywords = map[string]bool{
+	"and": true, "as": true, "assert": true, "async": true, "await": true,
+	"break": true, "class": true, "continue": true, "def": true, "del": true,
+	"elif": true, "else": true, "except": true, "finally": true, "for": true,
+	"from": true, "global": true, "if": true, "import": true, "in": true,
+	"is": true, "lambda": true, "nonlocal": true, "not": true, "or": true,
+	"pass": true, "raise": true, "return": true, "try": true, "while": true,
+	"with": true, "yield": true, "match": true, "case": true,
 }
 
-// extractPythonCallees performs a lightweight scan inside a function body
-// to collect simple call-site identifiers like "foo" in "foo(bar)".
-func extractPythonCallees(code []byte, startOffset, endOffset int) []string {
-	if startOffset < 0 || endOffset > len(code) || endOffset <= startOffset {
-		return nil
-	}
-	body := string(code[startOffset:endOffset])
-	callRe := regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*)\s*\(`)
-	matches := callRe.FindAllStringSubmatch(body, -1)
-	if len(matches) == 0 {
-		return nil
-	}
+// extractPythonCallees collects call-site names such as "foo" in "foo(bar)"
+// or "self.client.get" in "self.client.get(url)" from a function body.
+func extractPythonCallees(tokens []pyToken) []string {
 	seen := make(map[string]struct{})
 	var callees []string
-	for _, m := range matches {
-		if len(m) < 2 {
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].kind != pyTokenName || tokens[i+1].kind != pyTokenOp || tokens[i+1].text != "(" {
 			continue
 		}
-		name := m[1]
-		if name == "if" || name == "for" || name == "while" || name == "return" {
+		if pythonKeywords[tokens[i].text] {
 			continue
 		}
+		// Skip the names of nested definitions: "def name(".
+		if i > 0 && tokens[i-1].kind == pyTokenName && (tokens[i-1].text == "def" || tokens[i-1].text == "class") {
+			continue
+		}
+
+		// Walk back over "a.b.c" attribute chains.
+		start := i
+		for start >= 2 && tokens[start-1].text == "." && tokens[start-2].kind == pyTokenName {
+			start -= 2
+		}
+		name := joinPythonTokens(tokens[start : i+1])
 		if _, ok := seen[name]; ok {
 			continue
 		}
@@ -372,3 +627,71 @@ func extractPythonCallees(code []byte, startOffset, endOffset int) []string {
 	}
 	return callees
 }
+
+// analyzePythonBody scans a function body for raised exception types and
+// yield expressions. Nested def/class/lambda bodies are skipped because their
+// raises and yields belong to the nested definition.
+func analyzePythonBody(tokens []pyToken) ([]string, bool) {
+	var raises []string
+	seen := make(map[string]struct{})
+	isGenerator := false
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.kind != pyTokenName {
+			continue
+		}
+		switch tok.text {
+		case "def", "class":
+			i = skipPythonNestedDefinition(tokens, i)
+		case "lambda":
+			for i+1 < len(tokens) && !(tokens[i+1].kind == pyTokenOp && tokens[i+1].text == ":") {
+				i++
+			}
+		case "yield":
+			isGenerator = true
+		case "raise":
+			end := i + 1
+			for end < len(tokens) && (tokens[end].kind == pyTokenName || tokens[end].text == ".") {
+				if tokens[end].kind == pyTokenName && tokens[end].text == "from" {
+					break
+				}
+				end++
+			}
+			name := joinPythonTokens(tokens[i+1 : end])
+			if name == "" || strings.HasSuffix(name, ".") {
+				continue
+			}
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			raises = append(raises, name)
+		}
+	}
+	return raises, isGenerator
+}
+
+// skipPythonNestedDefinition returns the index of the last token belonging to
+// the def/class statement starting at idx.
+func skipPythonNestedDefinition(tokens []pyToken, idx int) int {
+	i := idx
+	for i < len(tokens) && tokens[i].kind != pyTokenNewline {
+		i++
+	}
+	if i+1 >= len(tokens) || tokens[i+1].kind != pyTokenIndent {
+		return i
+	}
+	depth := 0
+	for i++; i < len(tokens); i++ {
+		switch tokens[i].kind {
+		case pyTokenIndent:
+			depth++
+		case pyTokenDedent:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens) - 1
+}
@@ -0,0 +1,340 @@
+package parser
+
+import "bytes"
+
+type pyTokenKind int
+
+const (
+	pyTokenEOF pyTokenKind = iota
+	pyTokenName
+	pyTokenNumber
+	pyTokenString
+	pyTokenOp
+	pyTokenNewline
+	pyTokenIndent
+	pyTokenDedent
+)
+
+// pyToken is a single lexical token produced by pythonTokenizer. Offsets are
+// byte positions into the original source and lines are 1-indexed.
+type pyToken struct {
+	kind      pyTokenKind
+	text      string
+	startByte int
+	endByte   int
+	line      int
+	endLine   int
+}
+
+// pythonTokenizer is a small Python lexer modeled after CPython's tokenize
+// module. It understands indentation, implicit line joining inside brackets,
+// explicit backslash continuations, comments and all string literal forms
+// (prefixed, raw and triple-quoted), which is everything needed to find
+// definitions and their block boundaries reliably.
+type pythonTokenizer struct {
+	code        []byte
+	pos         int
+	line        int
+	parenDepth  int
+	indents     []int
+	atLineStart bool
+	lineHasCode bool
+	tokens      []pyToken
+}
+
+func tokenizePython(code []byte) []pyToken {
+	t := &pythonTokenizer{
+		code:        code,
+		line:        1,
+		indents:     []int{0},
+		atLineStart: true,
+	}
+	t.run()
+	return t.tokens
+}
+
+func (t *pythonTokenizer) run() {
+	for t.pos < len(t.code) {
+		if t.atLineStart && t.parenDepth == 0 {
+			t.atLineStart = false
+			if !t.handleIndentation() {
+				continue
+			}
+		}
+
+		ch := t.code[t.pos]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\f':
+			t.pos++
+		case ch == '\r' || ch == '\n':
+			t.consumeNewline()
+		case ch == '#':
+			t.skipComment()
+		case ch == '\\' && t.isLineContinuation():
+			t.pos++
+			t.skipRawNewline()
+		case isPythonStringStart(t.code, t.pos):
+			t.scanString()
+		case isPythonIdentStart(ch):
+			t.scanName()
+		case isASCIIDigit(ch) || (ch == '.' && t.pos+1 < len(t.code) && isASCIIDigit(t.code[t.pos+1])):
+			t.scanNumber()
+		default:
+			t.scanOperator()
+		}
+	}
+
+	if t.lineHasCode {
+		t.emit(pyTokenNewline, "", t.pos, t.pos, t.line)
+		t.lineHasCode = false
+	}
+	for len(t.indents) > 1 {
+		t.indents = t.indents[:len(t.indents)-1]
+		t.emit(pyTokenDedent, "", t.pos, t.pos, t.line)
+	}
+	t.emit(pyTokenEOF, "", t.pos, t.pos, t.line)
+}
+
+// handleIndentation measures the indentation of the line starting at t.pos
+// and emits INDENT/DEDENT tokens. Blank and comment-only lines do not affect
+// indentation; for those it consumes the line and returns false.
+func (t *pythonTokenizer) handleIndentation() bool {
+	col := 0
+	pos := t.pos
+measure:
+	for ; pos < len(t.code); pos++ {
+		switch t.code[pos] {
+		case ' ':
+			col++
+		case '\t':
+			col = (col/8 + 1) * 8
+		case '\f':
+			col = 0
+		default:
+			break measure
+		}
+	}
+	t.pos = pos
+	if pos >= len(t.code) {
+		return false
+	}
+	switch t.code[pos] {
+	case '#':
+		t.skipComment()
+		if t.pos < len(t.code) {
+			t.skipRawNewline()
+		}
+		t.atLineStart = true
+		return false
+	case '\r', '\n':
+		t.skipRawNewline()
+		t.atLineStart = true
+		return false
+	}
+
+	current := t.indents[len(t.indents)-1]
+	if col > current {
+		t.indents = append(t.indents, col)
+		t.emit(pyTokenIndent, "", pos, pos, t.line)
+		return true
+	}
+	for len(t.indents) > 1 && col < t.indents[len(t.indents)-1] {
+		t.indents = t.indents[:len(t.indents)-1]
+		t.emit(pyTokenDedent, "", pos, pos, t.line)
+	}
+	return true
+}
+
+func (t *pythonTokenizer) consumeNewline() {
+	start := t.pos
+	line := t.line
+	t.skipRawNewline()
+	if t.parenDepth > 0 {
+		return
+	}
+	if t.lineHasCode {
+		t.emit(pyTokenNewline, "\n", start, start+1, line)
+		t.lineHasCode = false
+	}
+	t.atLineStart = true
+}
+
+func (t *pythonTokenizer) skipRawNewline() {
+	if t.pos < len(t.code) && t.code[t.pos] == '\r' {
+		t.pos++
+	}
+	if t.pos < len(t.code) && t.code[t.pos] == '\n' {
+		t.pos++
+	}
+	t.line++
+}
+
+func (t *pythonTokenizer) skipComment() {
+	for t.pos < len(t.code) && t.code[t.pos] != '\n' && t.code[t.pos] != '\r' {
+		t.pos++
+	}
+}
+
+func (t *pythonTokenizer) isLineContinuation() bool {
+	next := t.pos + 1
+	return next < len(t.code) && (t.code[next] == '\n' || t.code[next] == '\r')
+}
+
+func (t *pythonTokenizer) scanName() {
+	start := t.pos
+	for t.pos < len(t.code) && isPythonIdentPart(t.code[t.pos]) {
+		t.pos++
+	}
+	t.emit(pyTokenName, string(t.code[start:t.pos]), start, t.pos, t.line)
+}
+
+func (t *pythonTokenizer) scanNumber() {
+	start := t.pos
+	for t.pos < len(t.code) {
+		ch := t.code[t.pos]
+		if isPythonIdentPart(ch) || ch == '.' {
+			t.pos++
+			continue
+		}
+		// Exponent sign, e.g. 1e-5 (but not hex literals like 0xE-1).
+		if (ch == '+' || ch == '-') && t.pos > start {
+			prev := t.code[t.pos-1]
+			isHex := t.pos-start > 1 && (t.code[start+1] == 'x' || t.code[start+1] == 'X')
+			if (prev == 'e' || prev == 'E') && !isHex {
+				t.pos++
+				continue
+			}
+		}
+		break
+	}
+	t.emit(pyTokenNumber, string(t.code[start:t.pos]), start, t.pos, t.line)
+}
+
+func (t *pythonTokenizer) scanString() {
+	start := t.pos
+	startLine := t.line
+	for t.code[t.pos] != '\'' && t.code[t.pos] != '"' {
+		t.pos++
+	}
+	quote := t.code[t.pos]
+	triple := t.pos+2 < len(t.code) && t.code[t.pos+1] == quote && t.code[t.pos+2] == quote
+	if triple {
+		t.pos += 3
+	} else {
+		t.pos++
+	}
+
+	for t.pos < len(t.code) {
+		ch := t.code[t.pos]
+		if ch == '\\' {
+			if t.pos+1 < len(t.code) && t.code[t.pos+1] == '\n' {
+				t.line++
+			}
+			t.pos += 2
+			continue
+		}
+		if ch == '\n' {
+			if !triple {
+				// Unterminated single-quoted string; stop at end of line.
+				break
+			}
+			t.line++
+		}
+		if ch == quote {
+			if !triple {
+				t.pos++
+				break
+			}
+			if t.pos+2 < len(t.code) && t.code[t.pos+1] == quote && t.code[t.pos+2] == quote {
+				t.pos += 3
+				break
+			}
+		}
+		t.pos++
+	}
+	if t.pos > len(t.code) {
+		t.pos = len(t.code)
+	}
+	tok := pyToken{
+		kind:      pyTokenString,
+		text:      string(t.code[start:t.pos]),
+		startByte: start,
+		endByte:   t.pos,
+		line:      startLine,
+		endLine:   t.line,
+	}
+	t.tokens = append(t.tokens, tok)
+	t.lineHasCode = true
+}
+
+var pythonOperators = []string{
+	"**=", "//=", ">>=", "<<=", "...",
+	"->", "**", "//", "==", "!=", "<=", ">=", "<<", ">>", ":=",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "@=",
+}
+
+func (t *pythonTokenizer) scanOperator() {
+	start := t.pos
+	for _, op := range pythonOperators {
+		if bytes.HasPrefix(t.code[t.pos:], []byte(op)) {
+			t.pos += len(op)
+			t.emit(pyTokenOp, op, start, t.pos, t.line)
+			return
+		}
+	}
+
+	ch := t.code[t.pos]
+	switch ch {
+	case '(', '[', '{':
+		t.parenDepth++
+	case ')', ']', '}':
+		if t.parenDepth > 0 {
+			t.parenDepth--
+		}
+	}
+	t.pos++
+	t.emit(pyTokenOp, string(ch), start, t.pos, t.line)
+}
+
+func (t *pythonTokenizer) emit(kind pyTokenKind, text string, start, end, line int) {
+	t.tokens = append(t.tokens, pyToken{
+		kind:      kind,
+		text:      text,
+		startByte: start,
+		endByte:   end,
+		line:      line,
+		endLine:   line,
+	})
+	if kind != pyTokenNewline && kind != pyTokenIndent && kind != pyTokenDedent && kind != pyTokenEOF {
+		t.lineHasCode = true
+	}
+}
+
+// isPythonStringStart reports whether a string literal (optionally prefixed
+// with r, b, u, f or a two-letter combination) begins at pos.
+func isPythonStringStart(code []byte, pos int) bool {
+	for i := 0; i < 3 && pos+i < len(code); i++ {
+		ch := code[pos+i]
+		if ch == '\'' || ch == '"' {
+			return true
+		}
+		switch ch {
+		case 'r', 'R', 'b', 'B', 'u', 'U', 'f', 'F':
+			continue
+		}
+		return false
+	}
+	return false
+}
+
+func isPythonIdentStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch >= 0x80
+}
+
+func isPythonIdentPart(ch byte) bool {
+	return isPythonIdentStart(ch) || isASCIIDigit(ch)
+}
+
+func isASCIIDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
@@ -0,0 +1,972 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+// RubyParser implements LanguageParser for Ruby language
+type RubyParser struct{}
+
+// NewRubyParser creates a new Ruby parser
+func NewRubyParser() *RubyParser {
+	return &RubyParser{}
+}
+
+// Language returns the language name
+func (p *RubyParser) Language() string {
+	return string(LanguageRuby)
+}
+
+type rbTokenKind int
+
+const (
+	rbTokIdent rbTokenKind = iota // identifiers and keywords
+	rbTokConst                    // capitalized identifiers
+	rbTokVar                      // @ivar, @@cvar, $global
+	rbTokNumber
+	rbTokString // strings, heredocs, regexps, percent literals
+	rbTokSymbol
+	rbTokLabel // hash keys and keyword arguments: "name:"
+	rbTokPunct
+)
+
+type rbToken struct {
+	kind        rbTokenKind
+	text        string
+	start       int
+	end         int
+	line        int
+	endLine     int
+	spaceBefore bool
+}
+
+// rbValueKeywords end an expression, so a following "if" is a modifier.
+var rbValueKeywords = map[string]bool{
+	"end": true, "self": true, "nil": true, "true": true, "false": true, "return": true,
+	"break": true, "next": true, "redo": true, "retry": true, "yield": true, "super": true,
+	"__method__": true, "__FILE__": true, "__LINE__": true,
+}
+
+// rbKeywords are never reported as callees or DSL method names.
+var rbKeywords = map[string]bool{
+	"alias": true, "and": true, "begin": true, "break": true, "case": true, "class": true,
+	"def": true, "defined?": true, "do": true, "else": true, "elsif": true, "end": true,
+	"ensure": true, "false": true, "for": true, "if": true, "in": true, "module": true,
+	"next": true, "nil": true, "not": true, "or": true, "redo": true, "rescue": true,
+	"retry": true, "return": true, "self": true, "super": true, "then": true, "true": true,
+	"undef": true, "unless": true, "until": true, "when": true, "while": true, "yield": true,
+	"raise": true, "fail": true, "lambda": true, "proc": true, "puts": true, "require": true,
+	"require_relative": true, "private": true, "protected": true, "public": true,
+}
+
+// rbPuncts are multi-character operators, longest first.
+var rbPuncts = []string{"**=", "<=>", "===", "...", "||=", "&&=", "<<=", ">>=", "::", "->", "=>", "==", "!=", ">=", "<=", "&&", "||", "<<", ">>", "**", "+=", "-=", "*=", "/=", "&.", "..", "=~", "!~"}
+
+type rubyContext struct {
+	class     string // qualified class or module name ("Admin::UsersController")
+	container bool   // class/module/top-level or DSL block body
+	singleton bool   // inside "class << self"
+	closer    string // "end" or "}"
+}
+
+type rubyDefinitionParser struct {
+	code      []byte
+	tokens    []rbToken
+	comments  []cComment
+	lines     []int
+	imports   []string
+	functions []FunctionNode
+}
+
+// ExtractFunctions extracts modules, classes, methods (def and def self.) and
+// blocks passed to DSL methods (scope, before_action, describe, ...) from
+// Ruby source code.
+func (p *RubyParser) ExtractFunctions(filePath string, code []byte) ([]FunctionNode, error) {
+	tokens, comments := tokenizeRuby(code)
+	rp := &rubyDefinitionParser{code: code, tokens: tokens, comments: comments, lines: buildLineOffsets(code)}
+	rp.imports = rubyRequires(tokens)
+	rp.parseBody(0, rubyContext{container: true})
+
+	sort.SliceStable(rp.functions, func(i, j int) bool {
+		return rp.functions[i].StartByte < rp.functions[j].StartByte
+	})
+	return rp.functions, nil
+}
+
+// parseBody walks statements from tokens[i] until the context's closing
+// "end" or "}" and returns the index after it, plus the names of the methods
+// and types defined directly in the body.
+func (p *rubyDefinitionParser) parseBody(i int, ctx rubyContext) (int, []string) {
+	var members []string
+	visibility := "public"
+	nextPrivate := false
+	for i < len(p.tokens) {
+		tok := p.tokens[i]
+		if tok.kind == rbTokPunct {
+			switch tok.text {
+			case "}":
+				if ctx.closer == "}" {
+					return i + 1, members
+				}
+			case "{":
+				i, _ = p.parseBody(i+1, rubyContext{class: ctx.class, singleton: ctx.singleton, closer: "}"})
+				continue
+			}
+			i++
+			continue
+		}
+		if tok.kind != rbTokIdent || p.afterDot(i) {
+			i++
+			continue
+		}
+
+		switch tok.text {
+		case "end":
+			if ctx.closer == "end" {
+				return i + 1, members
+			}
+			i++
+			continue
+		case "class":
+			if i+1 < len(p.tokens) && p.tokens[i+1].text == "<<" {
+				// class << self: singleton methods of the enclosing class.
+				end, names := p.parseBody(p.lineEnd(i), rubyContext{class: ctx.class, container: true, singleton: true, closer: "end"})
+				members = append(members, names...)
+				i = end
+				continue
+			}
+			end, name := p.parseClass(i, "class", ctx)
+			members = appendNonEmpty(members, name)
+			i = end
+			continue
+		case "module":
+			end, name := p.parseClass(i, "module", ctx)
+			members = appendNonEmpty(members, name)
+			i = end
+			continue
+		case "def":
+			exported := visibility == "public" && !nextPrivate
+			nextPrivate = false
+			end, name := p.parseDef(i, ctx, exported)
+			members = appendNonEmpty(members, name)
+			i = end
+			continue
+		case "if", "unless", "while", "until", "for":
+			if p.isModifier(i) {
+				i++
+				continue
+			}
+			i, _ = p.parseBody(p.headerEnd(i), rubyContext{class: ctx.class, container: ctx.container, singleton: ctx.singleton, closer: "end"})
+			continue
+		case "case", "begin":
+			i, _ = p.parseBody(i+1, rubyContext{class: ctx.class, container: ctx.container, singleton: ctx.singleton, closer: "end"})
+			continue
+		case "do":
+			i, _ = p.parseBody(i+1, rubyContext{class: ctx.class, singleton: ctx.singleton, closer: "end"})
+			continue
+		case "private", "protected", "public", "private_class_method":
+			if ctx.container && p.statementStart(i) {
+				next := i + 1
+				switch {
+				case next >= len(p.tokens) || p.tokens[next].line > tok.endLine || p.tokens[next].text == ";":
+					// Bare "private": applies to the following definitions.
+					visibility = tok.text
+				case p.tokens[next].text == "def":
+					nextPrivate = tok.text != "public"
+				}
+				i++
+				continue
+			}
+		}
+
+		if ctx.container && p.statementStart(i) && !rbKeywords[tok.text] {
+			if end, ok := p.parseDSLBlock(i, ctx); ok {
+				i = end
+				continue
+			}
+		}
+		i++
+	}
+	return len(p.tokens), members
+}
+
+// parseClass handles "class Name < Base" and "module Name" definitions.
+func (p *rubyDefinitionParser) parseClass(i int, kind string, ctx rubyContext) (int, string) {
+	j := i + 1
+	for j < len(p.tokens) && (p.tokens[j].kind == rbTokConst || p.tokens[j].text == "::") && p.tokens[j].line == p.tokens[i].line {
+		j++
+	}
+	name := p.spanText(i+1, j)
+	if name == "" {
+		return i + 1, ""
+	}
+	qualified := name
+	if ctx.class != "" && !strings.HasPrefix(name, "::") {
+		qualified = ctx.class + "::" + name
+	}
+	qualified = strings.TrimPrefix(qualified, "::")
+	headerEnd := p.lineEnd(i)
+	end, members := p.parseBody(headerEnd, rubyContext{class: qualified, container: true, closer: "end"})
+
+	node := p.newNode(i, end-1, qualified, kind)
+	node.Signature = p.spanText(i, headerEnd)
+	node.Members = members
+	node.Exported = true
+	if ctx.class != "" {
+		node.Receiver = ctx.class
+	}
+	p.functions = append(p.functions, node)
+	return end, name
+}
+
+// parseDef handles "def name(args) ... end", "def self.name", operator and
+// setter methods and endless definitions ("def name = expr").
+func (p *rubyDefinitionParser) parseDef(i int, ctx rubyContext, exported bool) (int, string) {
+	j := i + 1
+	if j >= len(p.tokens) {
+		return j, ""
+	}
+	singleton := ctx.singleton
+	if j+1 < len(p.tokens) && p.tokens[j+1].text == "." && (p.tokens[j].text == "self" || p.tokens[j].kind == rbTokConst) {
+		singleton = true
+		j += 2
+	}
+	if j >= len(p.tokens) {
+		return j, ""
+	}
+	name := p.tokens[j].text
+	j++
+	switch {
+	case name == "[" && j < len(p.tokens) && p.tokens[j].text == "]":
+		name = "[]"
+		j++
+		if j < len(p.tokens) && p.tokens[j].text == "=" && !p.tokens[j].spaceBefore {
+			name = "[]="
+			j++
+		}
+	case p.tokens[j-1].kind == rbTokIdent && j+1 < len(p.tokens) && p.tokens[j].text == "=" && !p.tokens[j].spaceBefore && p.tokens[j+1].text == "(":
+		// Setter: def name=(value)
+		name += "="
+		j++
+	case p.tokens[j-1].kind == rbTokLabel:
+		// "def name:" cannot happen; the tokenizer produced a label from
+		// a keyword argument. Treat the name as plain.
+		name = strings.TrimSuffix(name, ":")
+	}
+
+	// Parameters: parenthesized or bare until the end of the line.
+	if j < len(p.tokens) && p.tokens[j].text == "(" && p.tokens[j].line == p.tokens[i].line {
+		j = p.matchingParen(j) + 1
+	} else {
+		for j < len(p.tokens) && p.tokens[j].line == p.tokens[i].endLine && p.tokens[j].text != ";" && p.tokens[j].text != "=" {
+			j++
+		}
+	}
+	sigEnd := j
+
+	var end, bodyStart, bodyEnd int
+	if j < len(p.tokens) && p.tokens[j].text == "=" && p.tokens[j].line == p.tokens[sigEnd-1].endLine {
+		// Endless method: the body is the rest of the statement.
+		bodyStart = j + 1
+		end = p.statementEnd(bodyStart)
+		bodyEnd = end
+	} else {
+		bodyStart = j
+		end, _ = p.parseBody(j, rubyContext{class: ctx.class, singleton: ctx.singleton, closer: "end"})
+		bodyEnd = end - 1
+	}
+	if end > len(p.tokens) {
+		end = len(p.tokens)
+	}
+	if bodyEnd > len(p.tokens) {
+		bodyEnd = len(p.tokens)
+	}
+	if bodyStart > bodyEnd {
+		bodyStart = bodyEnd
+	}
+
+	qualified := name
+	nodeType := "function"
+	switch {
+	case ctx.class != "" && singleton:
+		qualified = ctx.class + "." + name
+		nodeType = "singleton_method"
+	case ctx.class != "":
+		qualified = ctx.class + "#" + name
+		nodeType = "method"
+	case singleton:
+		nodeType = "singleton_method"
+	}
+
+	node := p.newNode(i, end-1, qualified, nodeType)
+	node.Signature = p.spanText(i, sigEnd)
+	node.Receiver = ctx.class
+	node.Callees = p.callees(bodyStart, bodyEnd)
+	node.Raises = p.raises(bodyStart, bodyEnd)
+	node.IsGenerator = p.yields(bodyStart, bodyEnd)
+	node.Exported = exported && name != "initialize"
+	p.functions = append(p.functions, node)
+	return end, name
+}
+
+// parseDSLBlock records a block passed to a method call at class or module
+// level, e.g. "scope :active, -> { where(active: true) }", "before_action do"
+// or RSpec's "describe User do". It reports false when the statement starting
+// at tokens[i] has no block.
+func (p *rubyDefinitionParser) parseDSLBlock(i int, ctx rubyContext) (int, bool) {
+	opener := -1
+	depth := 0
+	j := i + 1
+	for ; j < len(p.tokens); j++ {
+		tok := p.tokens[j]
+		if depth == 0 && tok.line > p.tokens[j-1].endLine && !p.continuesLine(j) {
+			break
+		}
+		if tok.kind == rbTokPunct {
+			switch tok.text {
+			case "(", "[":
+				depth++
+			case ")", "]":
+				depth--
+			case ";":
+				if depth == 0 {
+					j = len(p.tokens)
+				}
+			case "{":
+				prev := p.tokens[j-1]
+				if depth == 0 && (j == i+1 || prev.text == ")" || prev.text == "->") {
+					opener = j
+				} else {
+					// Hash literal argument.
+					if end := p.matchingBrace(j); end > j {
+						j = end
+					}
+				}
+			}
+		} else if depth == 0 && tok.kind == rbTokIdent && tok.text == "do" && !p.afterDot(j) {
+			opener = j
+		}
+		if opener >= 0 {
+			break
+		}
+	}
+	if opener < 0 {
+		return 0, false
+	}
+
+	closer := "end"
+	if p.tokens[opener].text == "{" {
+		closer = "}"
+	}
+	end, members := p.parseBody(opener+1, rubyContext{class: ctx.class, container: closer == "end", closer: closer})
+
+	// Name: the method plus its first argument, e.g. `scope :active`.
+	name := p.tokens[i].text
+	argStart := i + 1
+	if argStart < opener && p.tokens[argStart].text == "(" && !p.tokens[argStart].spaceBefore {
+		argStart++
+	}
+	argEnd := argStart
+	depth = 0
+	for argEnd < opener {
+		text := p.tokens[argEnd].text
+		if depth == 0 && (text == "," || text == ")" || text == "->" || text == "do") {
+			break
+		}
+		switch text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		}
+		argEnd++
+	}
+	if arg := p.spanText(argStart, argEnd); arg != "" {
+		name += " " + arg
+	}
+
+	node := p.newNode(i, end-1, name, "block")
+	node.Signature = p.spanText(i, opener+1)
+	node.Receiver = ctx.class
+	node.Members = members
+	node.Callees = p.callees(opener+1, end-1)
+	node.Raises = p.raises(opener+1, end-1)
+	p.functions = append(p.functions, node)
+	return end, true
+}
+
+func (p *rubyDefinitionParser) newNode(first, last int, name, nodeType string) FunctionNode {
+	if last < first {
+		last = first
+	}
+	if last >= len(p.tokens) {
+		last = len(p.tokens) - 1
+	}
+	node := newCFunctionNode(p.code, p.lines, name, nodeType, p.tokens[first].start, p.tokens[last].end)
+	node.Imports = append([]string(nil), p.imports...)
+	node.Doc = docCommentBefore(p.code, p.comments, p.tokens[first].start, func(text string) bool {
+		return strings.HasPrefix(text, "#")
+	})
+	return node
+}
+
+// afterDot reports whether tokens[i] is a method name in a call such as
+// "range.end" or "obj&.class", where keywords lose their meaning.
+func (p *rubyDefinitionParser) afterDot(i int) bool {
+	return i > 0 && (p.tokens[i-1].text == "." || p.tokens[i-1].text == "&." || p.tokens[i-1].text == "::")
+}
+
+// isModifier reports whether the if/unless/while/until at tokens[i] follows
+// an expression on the same line ("return if done"), in which case it does
+// not open a block.
+func (p *rubyDefinitionParser) isModifier(i int) bool {
+	if i == 0 {
+		return false
+	}
+	prev := p.tokens[i-1]
+	if prev.endLine != p.tokens[i].line {
+		return false
+	}
+	switch prev.kind {
+	case rbTokConst, rbTokVar, rbTokNumber, rbTokString, rbTokSymbol:
+		return true
+	case rbTokIdent:
+		return !rbKeywords[prev.text] || rbValueKeywords[prev.text]
+	case rbTokPunct:
+		return prev.text == ")" || prev.text == "]" || prev.text == "}"
+	}
+	return false
+}
+
+// statementStart reports whether tokens[i] begins a statement.
+func (p *rubyDefinitionParser) statementStart(i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := p.tokens[i-1]
+	if prev.text == ";" {
+		return true
+	}
+	return prev.endLine < p.tokens[i].line && !p.continuesLine(i)
+}
+
+// continuesLine reports whether tokens[i], the first token on its line,
+// continues the previous line's expression: after a trailing comma,
+// operator or backslash, or before a leading ".method" chain.
+func (p *rubyDefinitionParser) continuesLine(i int) bool {
+	if i == 0 {
+		return false
+	}
+	if p.tokens[i].text == "." || p.tokens[i].text == "&." {
+		return true
+	}
+	prev := p.tokens[i-1]
+	if prev.kind != rbTokPunct {
+		return prev.kind == rbTokIdent && (prev.text == "and" || prev.text == "or" || prev.text == "not")
+	}
+	switch prev.text {
+	case ")", "]", "}", ";":
+		return false
+	}
+	return true
+}
+
+// headerEnd returns the index of the first body token after the condition of
+// an if/unless/while/until/for statement, skipping "then" or "do".
+func (p *rubyDefinitionParser) headerEnd(i int) int {
+	end := p.statementEnd(i + 1)
+	for k := i + 1; k < end; k++ {
+		if p.tokens[k].kind == rbTokIdent && (p.tokens[k].text == "do" || p.tokens[k].text == "then") && !p.afterDot(k) {
+			return k + 1
+		}
+		if p.tokens[k].text == "{" {
+			if close := p.matchingBrace(k); close > k {
+				k = close
+			}
+		}
+	}
+	return end
+}
+
+// statementEnd returns the index of the first token after the logical line
+// that contains tokens[i].
+func (p *rubyDefinitionParser) statementEnd(i int) int {
+	depth := 0
+	for j := i; j < len(p.tokens); j++ {
+		if j > i && depth <= 0 && p.tokens[j].line > p.tokens[j-1].endLine && !p.continuesLine(j) {
+			return j
+		}
+		switch p.tokens[j].text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		case ";":
+			if depth <= 0 {
+				return j
+			}
+		}
+	}
+	return len(p.tokens)
+}
+
+// lineEnd returns the index of the first token after the line containing
+// tokens[i] (or after a ';').
+func (p *rubyDefinitionParser) lineEnd(i int) int {
+	line := p.tokens[i].endLine
+	for j := i + 1; j < len(p.tokens); j++ {
+		if p.tokens[j].text == ";" {
+			return j + 1
+		}
+		if p.tokens[j].line > line {
+			return j
+		}
+	}
+	return len(p.tokens)
+}
+
+func (p *rubyDefinitionParser) matchingParen(i int) int {
+	depth := 0
+	for j := i; j < len(p.tokens); j++ {
+		switch p.tokens[j].text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return len(p.tokens) - 1
+}
+
+func (p *rubyDefinitionParser) matchingBrace(i int) int {
+	depth := 0
+	for j := i; j < len(p.tokens); j++ {
+		switch p.tokens[j].text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// spanText returns the source text of tokens[from:to] with whitespace
+// collapsed.
+func (p *rubyDefinitionParser) spanText(from, to int) string {
+	if from >= to || from >= len(p.tokens) {
+		return ""
+	}
+	if to > len(p.tokens) {
+		to = len(p.tokens)
+	}
+	return strings.Join(strings.Fields(string(p.code[p.tokens[from].start:p.tokens[to-1].end])), " ")
+}
+
+// callees collects method calls in tokens[from:to]: "name(" and
+// "receiver.name", sorted and deduplicated.
+func (p *rubyDefinitionParser) callees(from, to int) []string {
+	seen := make(map[string]struct{})
+	var callees []string
+	for i := from; i < to && i < len(p.tokens); i++ {
+		tok := p.tokens[i]
+		if tok.kind != rbTokIdent && tok.kind != rbTokConst {
+			continue
+		}
+		var name string
+		switch {
+		case p.afterDot(i) && p.tokens[i-1].text != "::":
+			name = tok.text
+			if recv := p.tokens[i-2]; i >= 2 && (recv.kind == rbTokIdent || recv.kind == rbTokConst || recv.kind == rbTokVar) {
+				name = recv.text + "." + tok.text
+			}
+		case tok.kind == rbTokIdent && i+1 < to && p.tokens[i+1].text == "(" && !p.tokens[i+1].spaceBefore && !rbKeywords[tok.text]:
+			name = tok.text
+		default:
+			continue
+		}
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			callees = append(callees, name)
+		}
+	}
+	sort.Strings(callees)
+	return callees
+}
+
+// raises returns the exception classes named by raise/fail in
+// tokens[from:to], sorted and deduplicated.
+func (p *rubyDefinitionParser) raises(from, to int) []string {
+	seen := make(map[string]struct{})
+	var raises []string
+	for i := from; i+1 < to && i+1 < len(p.tokens); i++ {
+		tok := p.tokens[i]
+		if tok.kind != rbTokIdent || (tok.text != "raise" && tok.text != "fail") || p.afterDot(i) {
+			continue
+		}
+		j := i + 1
+		if p.tokens[j].text == "(" {
+			j++
+		}
+		start := j
+		for j < len(p.tokens) && (p.tokens[j].kind == rbTokConst || p.tokens[j].text == "::") {
+			j++
+		}
+		if j == start {
+			continue
+		}
+		name := p.spanText(start, j)
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			raises = append(raises, name)
+		}
+	}
+	sort.Strings(raises)
+	return raises
+}
+
+// yields reports whether tokens[from:to] contain a yield.
+func (p *rubyDefinitionParser) yields(from, to int) bool {
+	for i := from; i < to && i < len(p.tokens); i++ {
+		if p.tokens[i].kind == rbTokIdent && p.tokens[i].text == "yield" && !p.afterDot(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// rubyRequires returns the paths loaded by require and require_relative.
+func rubyRequires(tokens []rbToken) []string {
+	seen := make(map[string]struct{})
+	var requires []string
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].kind != rbTokIdent || (tokens[i].text != "require" && tokens[i].text != "require_relative") {
+			continue
+		}
+		j := i + 1
+		if tokens[j].text == "(" && j+1 < len(tokens) {
+			j++
+		}
+		if tokens[j].kind != rbTokString {
+			continue
+		}
+		path := strings.Trim(tokens[j].text, `"'`)
+		if path == "" || strings.Contains(path, "#{") {
+			continue
+		}
+		if _, ok := seen[path]; !ok {
+			seen[path] = struct{}{}
+			requires = append(requires, path)
+		}
+	}
+	sort.Strings(requires)
+	return requires
+}
+
+// tokenizeRuby splits Ruby source into tokens and collects comments. String
+// literals with interpolation, heredocs, percent literals, regexps and
+// =begin/=end blocks become single tokens or comments so that keywords inside
+// them never affect block structure.
+func tokenizeRuby(code []byte) ([]rbToken, []cComment) {
+	var tokens []rbToken
+	var comments []cComment
+	lines := buildLineOffsets(code)
+	lineAt := func(offset int) int {
+		return sort.Search(len(lines), func(i int) bool { return lines[i] > offset })
+	}
+	type heredoc struct {
+		id       string
+		indented bool
+	}
+	var pending []heredoc
+	atLineStart := true
+	spaceBefore := false
+
+	// valueBefore reports whether the previous token ends an expression, so
+	// that "/", "%", "<<", "?" and ":" are operators rather than literals.
+	valueBefore := func(next byte) bool {
+		if len(tokens) == 0 {
+			return false
+		}
+		prev := tokens[len(tokens)-1]
+		switch prev.kind {
+		case rbTokIdent:
+			if rbKeywords[prev.text] && !rbValueKeywords[prev.text] {
+				return false
+			}
+			// "puts /re/" and "execute <<~SQL": a method call whose
+			// argument follows a space; "x / 2" is a division.
+			return !spaceBefore || next == ' ' || next == '=' || next == '\n'
+
+		case rbTokPunct:
+			return prev.text == ")" || prev.text == "]" || prev.text == "}"
+		case rbTokLabel:
+			return false
+		}
+		return true
+	}
+	emit := func(kind rbTokenKind, start, end int) {
+		tokens = append(tokens, rbToken{kind: kind, text: string(code[start:end]), start: start, end: end, line: lineAt(start), endLine: lineAt(max(end-1, start)), spaceBefore: spaceBefore})
+		spaceBefore = false
+		atLineStart = false
+	}
+
+	pos := 0
+	for pos < len(code) {
+		ch := code[pos]
+		if ch == '\n' {
+			pos++
+			// Heredoc bodies start on the line after their opener.
+			for _, h := range pending {
+				for pos < len(code) {
+					lineEnd := pos
+					for lineEnd < len(code) && code[lineEnd] != '\n' {
+						lineEnd++
+					}
+					line := string(code[pos:lineEnd])
+					pos = min(lineEnd+1, len(code))
+					if strings.TrimRight(line, "\r") == h.id || h.indented && strings.TrimSpace(line) == h.id {
+						break
+					}
+				}
+			}
+			pending = nil
+			atLineStart = true
+			spaceBefore = true
+			continue
+		}
+		if ch == ' ' || ch == '\t' || ch == '\r' {
+			pos++
+			spaceBefore = true
+			continue
+		}
+		if ch == '\\' && pos+1 < len(code) && code[pos+1] == '\n' {
+			pos += 2
+			spaceBefore = true
+			continue
+		}
+		start := pos
+
+		if atLineStart && strings.HasPrefix(string(code[pos:min(pos+6, len(code))]), "=begin") {
+			end := strings.Index(string(code[pos:]), "\n=end")
+			if end < 0 {
+				pos = len(code)
+			} else {
+				pos += end + len("\n=end")
+				for pos < len(code) && code[pos] != '\n' {
+					pos++
+				}
+			}
+			comments = append(comments, cComment{text: string(code[start:pos]), start: start, end: pos, line: lineAt(start), endLine: lineAt(pos - 1)})
+			continue
+		}
+		if atLineStart && strings.HasPrefix(string(code[pos:min(pos+7, len(code))]), "__END__") {
+			break
+		}
+
+		switch {
+		case ch == '#':
+			for pos < len(code) && code[pos] != '\n' {
+				pos++
+			}
+			comments = append(comments, cComment{text: string(code[start:pos]), start: start, end: pos, line: lineAt(start), endLine: lineAt(start)})
+			continue
+		case ch == '"' || ch == '`':
+			pos = skipRubyString(code, pos+1, ch, ch, true)
+			emit(rbTokString, start, pos)
+		case ch == '\'':
+			pos = skipRubyString(code, pos+1, '\'', '\'', false)
+			emit(rbTokString, start, pos)
+		case ch == '%' && pos+1 < len(code) && !valueBefore(code[pos+1]):
+			j := pos + 1
+			interpolate := true
+			if strings.IndexByte("qwisQWIrx", code[j]) >= 0 {
+				interpolate = strings.IndexByte("QWIrx", code[j]) >= 0
+				j++
+			}
+			if j >= len(code) || isCIdentPart(code[j], "") || code[j] == ' ' || code[j] == '\n' || code[j] == '=' {
+				pos++
+				emit(rbTokPunct, start, pos)
+				continue
+			}
+			pos = skipRubyString(code, j+1, code[j], rubyClosingDelimiter(code[j]), interpolate)
+			emit(rbTokString, start, pos)
+		case ch == '/' && !valueBefore(byteAt(code, pos+1)):
+			pos = skipRubyString(code, pos+1, '/', '/', true)
+			for pos < len(code) && isCIdentPart(code[pos], "") {
+				pos++ // flags
+			}
+			emit(rbTokString, start, pos)
+		case ch == '<' && pos+2 < len(code) && code[pos+1] == '<' && !valueBefore(code[pos+2]) && (code[pos+2] == '~' || code[pos+2] == '-' || code[pos+2] == '"' || code[pos+2] == '\'' || code[pos+2] >= 'A' && code[pos+2] <= 'Z'):
+			j := pos + 2
+			indented := false
+			if code[j] == '~' || code[j] == '-' {
+				indented = true
+				j++
+			}
+			quote := byte(0)
+			if j < len(code) && (code[j] == '"' || code[j] == '\'' || code[j] == '`') {
+				quote = code[j]
+				j++
+			}
+			idStart := j
+			for j < len(code) && isCIdentPart(code[j], "") {
+				j++
+			}
+			if j == idStart {
+				pos += 2
+				emit(rbTokPunct, start, pos)
+				continue
+			}
+			id := string(code[idStart:j])
+			if quote != 0 && j < len(code) && code[j] == quote {
+				j++
+			}
+			pending = append(pending, heredoc{id: id, indented: indented})
+			pos = j
+			emit(rbTokString, start, pos)
+		case ch == ':' && pos+1 < len(code) && code[pos+1] == '"':
+			pos = skipRubyString(code, pos+2, '"', '"', true)
+			emit(rbTokSymbol, start, pos)
+		case ch == ':' && pos+1 < len(code) && (isCIdentStart(code[pos+1], "@$") || code[pos+1] >= 0x80) && (len(tokens) == 0 || spaceBefore || !valueBefore(code[pos+1])):
+			pos++
+			for pos < len(code) && (isCIdentPart(code[pos], "@$") || code[pos] >= 0x80) {
+				pos++
+			}
+			if pos < len(code) && (code[pos] == '?' || code[pos] == '!' || code[pos] == '=') && !(pos+1 < len(code) && (code[pos+1] == '=' || code[pos+1] == '>')) {
+				pos++
+			}
+			emit(rbTokSymbol, start, pos)
+		case ch == '?' && pos+1 < len(code) && !valueBefore(code[pos+1]) && code[pos+1] != ' ' && code[pos+1] != '\n' && (pos+2 >= len(code) || !isCIdentPart(code[pos+2], "")):
+			// Character literal: ?a
+			pos += 2
+			emit(rbTokString, start, pos)
+		case ch == '@' || ch == '$':
+			pos++
+			if pos < len(code) && code[pos] == '@' {
+				pos++
+			}
+			if ch == '$' && pos < len(code) && !isCIdentStart(code[pos], "") {
+				pos++ // $!, $1, $~
+			}
+			for pos < len(code) && (isCIdentPart(code[pos], "") || code[pos] >= 0x80) {
+				pos++
+			}
+			emit(rbTokVar, start, pos)
+		case isCIdentStart(ch, "") || ch >= 0x80:
+			for pos < len(code) && (isCIdentPart(code[pos], "") || code[pos] >= 0x80) {
+				pos++
+			}
+			if pos < len(code) && (code[pos] == '?' || code[pos] == '!') && !(pos+1 < len(code) && code[pos+1] == '=' && !(pos+2 < len(code) && code[pos+2] == '=')) {
+				pos++
+			}
+			kind := rbTokIdent
+			if ch >= 'A' && ch <= 'Z' {
+				kind = rbTokConst
+			}
+			// Labels: "name:" but not "Foo::Bar" or the ternary "a ? b : c".
+			if pos+1 < len(code) && code[pos] == ':' && code[pos+1] != ':' && !(len(tokens) > 0 && tokens[len(tokens)-1].text == "?") {
+				pos++
+				kind = rbTokLabel
+			}
+			emit(kind, start, pos)
+		case isASCIIDigit(ch):
+			for pos < len(code) && (isCIdentPart(code[pos], "") || code[pos] == '.' && pos+1 < len(code) && isASCIIDigit(code[pos+1])) {
+				pos++
+			}
+			emit(rbTokNumber, start, pos)
+		default:
+			text := string(ch)
+			for _, punct := range rbPuncts {
+				if strings.HasPrefix(string(code[pos:min(pos+len(punct), len(code))]), punct) {
+					text = punct
+					break
+				}
+			}
+			pos += len(text)
+			emit(rbTokPunct, start, pos)
+		}
+	}
+	return tokens, comments
+}
+
+// skipRubyString returns the offset after a literal whose body starts at pos
+// and ends with closing. Bracket delimiters nest, and #{...} interpolation
+// may contain further strings.
+func skipRubyString(code []byte, pos int, opening, closing byte, interpolate bool) int {
+	depth := 1
+	for pos < len(code) {
+		ch := code[pos]
+		switch {
+		case ch == '\\':
+			pos += 2
+			continue
+		case interpolate && ch == '#' && pos+1 < len(code) && code[pos+1] == '{':
+			pos = skipRubyInterpolation(code, pos+2)
+			continue
+		case ch == closing:
+			depth--
+			if depth == 0 {
+				return pos + 1
+			}
+		case ch == opening && opening != closing:
+			depth++
+		}
+		pos++
+	}
+	return len(code)
+}
+
+// skipRubyInterpolation returns the offset after the '}' closing a #{ ... }
+// interpolation that starts at pos.
+func skipRubyInterpolation(code []byte, pos int) int {
+	depth := 1
+	for pos < len(code) {
+		switch code[pos] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return pos + 1
+			}
+		case '"', '`':
+			pos = skipRubyString(code, pos+1, code[pos], code[pos], true)
+			continue
+		case '\'':
+			pos = skipRubyString(code, pos+1, '\'', '\'', false)
+			continue
+		}
+		pos++
+	}
+	return len(code)
+}
+
+func byteAt(code []byte, pos int) byte {
+	if pos < len(code) {
+		return code[pos]
+	}
+	return 0
+}
+
+// rubyClosingDelimiter returns the closing delimiter of a percent literal.
+func rubyClosingDelimiter(open byte) byte {
+	switch open {
+	case '(':
+		return ')'
+	case '[':
+		return ']'
+	case '{':
+		return '}'
+	case '<':
+		return '>'
+	}
+	return open
+}
@@ -0,0 +1,600 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RustParser implements LanguageParser for Rust language
+type RustParser struct{}
+
+// NewRustParser creates a new Rust parser
+func NewRustParser() *RustParser {
+	return &RustParser{}
+}
+
+// Language returns the language name
+func (p *RustParser) Language() string {
+	return string(LanguageRust)
+}
+
+// rustKeywords are never reported as callees.
+var rustKeywords = map[string]bool{
+	"as": true, "async": true, "await": true, "break": true, "const": true, "continue": true,
+	"crate": true, "dyn": true, "else": true, "enum": true, "extern": true, "fn": true,
+	"for": true, "if": true, "impl": true, "in": true, "let": true, "loop": true,
+	"match": true, "mod": true, "move": true, "mut": true, "pub": true, "ref": true,
+	"return": true, "static": true, "struct": true, "trait": true, "type": true,
+	"unsafe": true, "use": true, "where": true, "while": true,
+}
+
+// rustScope describes the item container currently being parsed.
+type rustScope struct {
+	selfType string // receiver of methods inside an impl block
+	trait    string // trait being defined or implemented
+}
+
+// rustMember is a function declared directly inside a trait or impl body.
+type rustMember struct {
+	name      string
+	signature string
+}
+
+type rustItemParser struct {
+	code      []byte
+	lex       *cLexResult
+	tokens    []cToken
+	imports   []string
+	functions []FunctionNode
+}
+
+// ExtractFunctions extracts free functions, impl methods, trait definitions
+// and trait impls from Rust source code.
+func (p *RustParser) ExtractFunctions(filePath string, code []byte) ([]FunctionNode, error) {
+	lex := lexCLike(code, cLexOptions{
+		nestedComments: true,
+		lifetimes:      true,
+		stringPrefix:   rustRawString,
+	})
+	rp := &rustItemParser{code: code, lex: lex, tokens: lex.tokens}
+	rp.imports = rustUseImports(lex.tokens)
+	rp.parseItems(0, len(lex.tokens), rustScope{})
+
+	sort.SliceStable(rp.functions, func(i, j int) bool {
+		return rp.functions[i].StartByte < rp.functions[j].StartByte
+	})
+	return rp.functions, nil
+}
+
+// parseItems walks the items in tokens[from:to] and returns the functions
+// declared at this level (used as trait and impl members).
+func (p *rustItemParser) parseItems(from, to int, scope rustScope) []rustMember {
+	var members []rustMember
+	i := from
+items:
+	for i < to {
+		itemStart := i
+
+		// Outer attributes belong to the item; inner attributes (#![...])
+		// belong to the enclosing module and are skipped.
+		var attrs []string
+		for i+1 < to && p.tokens[i].text == "#" {
+			j := i + 1
+			inner := false
+			if p.tokens[j].text == "!" {
+				inner = true
+				j++
+			}
+			if j >= to || p.tokens[j].text != "[" {
+				break
+			}
+			end := matchingClose(p.tokens, j)
+			if end < 0 || end >= to {
+				return members
+			}
+			if !inner {
+				attrs = append(attrs, tokenSpanText(p.code, p.tokens, j+1, end))
+			}
+			i = end + 1
+			if inner {
+				itemStart = i
+			}
+		}
+		if i >= to {
+			break
+		}
+
+		modStart := i
+		exported, isAsync := false, false
+		for i < to && p.tokens[i].kind == cTokIdent {
+			switch p.tokens[i].text {
+			case "pub":
+				exported = true
+				i++
+				if i < to && p.tokens[i].text == "(" {
+					if end := matchingClose(p.tokens, i); end > 0 {
+						i = end + 1
+					}
+				}
+				continue
+			case "async":
+				isAsync = true
+				i++
+				continue
+			case "unsafe", "default", "auto":
+				i++
+				continue
+			case "const":
+				// "const fn" is a modifier; "const NAME: T = ...;" is an item.
+				if i+1 < to && (p.tokens[i+1].text == "fn" || p.tokens[i+1].text == "unsafe" || p.tokens[i+1].text == "async" || p.tokens[i+1].text == "extern") {
+					i++
+					continue
+				}
+			case "extern":
+				i++
+				if i < to && p.tokens[i].kind == cTokString {
+					i++
+				}
+				if i < to && p.tokens[i].text == "{" {
+					// extern "C" { ... } block of foreign declarations.
+					end := matchingClose(p.tokens, i)
+					if end < 0 {
+						return members
+					}
+					p.parseItems(i+1, end, scope)
+					i = end + 1
+					continue items
+				}
+				continue
+			}
+			break
+		}
+		if i >= to {
+			break
+		}
+
+		switch p.tokens[i].text {
+		case "fn":
+			end, member := p.parseFunction(itemStart, modStart, i, to, scope, attrs, exported, isAsync)
+			if member.name != "" {
+				members = append(members, member)
+			}
+			i = end
+			continue
+		case "impl":
+			i = p.parseImpl(itemStart, modStart, i, to, attrs)
+			continue
+		case "trait":
+			i = p.parseTrait(itemStart, modStart, i, to, attrs, exported)
+			continue
+		case "mod":
+			if i+2 < to && p.tokens[i+2].text == "{" {
+				end := matchingClose(p.tokens, i+2)
+				if end < 0 {
+					return members
+				}
+				p.parseItems(i+3, end, rustScope{})
+				i = end + 1
+				continue
+			}
+		}
+		i = p.skipItem(i, to)
+	}
+	return members
+}
+
+// skipItem advances past an item that is not extracted: up to the next ';'
+// or the end of the first top-level brace block.
+func (p *rustItemParser) skipItem(i, to int) int {
+	start := i
+	for i < to {
+		switch p.tokens[i].text {
+		case ";":
+			return i + 1
+		case "(", "[":
+			end := matchingClose(p.tokens, i)
+			if end < 0 {
+				return to
+			}
+			i = end + 1
+			continue
+		case "{":
+			end := matchingClose(p.tokens, i)
+			if end < 0 {
+				return to
+			}
+			i = end + 1
+			if i < to && p.tokens[i].text == ";" {
+				i++
+			}
+			return i
+		case "}":
+			if i == start {
+				return i + 1
+			}
+			return i
+		}
+		i++
+	}
+	return to
+}
+
+func (p *rustItemParser) parseFunction(itemStart, modStart, fnIdx, to int, scope rustScope, attrs []string, exported, isAsync bool) (int, rustMember) {
+	if fnIdx+1 >= to || p.tokens[fnIdx+1].kind != cTokIdent {
+		return fnIdx + 1, rustMember{}
+	}
+	name := p.tokens[fnIdx+1].text
+	j := fnIdx + 2
+
+	var typeParams []string
+	if j < to && p.tokens[j].text == "<" {
+		end := matchingClose(p.tokens, j)
+		if end < 0 {
+			return p.skipItem(fnIdx, to), rustMember{}
+		}
+		typeParams = tokenListTexts(p.code, p.tokens, j+1, end)
+		j = end + 1
+	}
+	if j >= to || p.tokens[j].text != "(" {
+		return p.skipItem(fnIdx, to), rustMember{}
+	}
+	paramsEnd := matchingClose(p.tokens, j)
+	if paramsEnd < 0 {
+		return to, rustMember{}
+	}
+	paramTypes := p.rustParamTypes(j+1, paramsEnd)
+	j = paramsEnd + 1
+
+	var returnTypes []string
+	if j < to && p.tokens[j].text == "->" {
+		retStart := j + 1
+		j = p.scanTypeEnd(retStart, to)
+		if ret := tokenSpanText(p.code, p.tokens, retStart, j); ret != "" {
+			returnTypes = []string{ret}
+		}
+	}
+	if j < to && p.tokens[j].text == "where" {
+		j = p.scanTypeEnd(j+1, to)
+	}
+
+	signature := tokenSpanText(p.code, p.tokens, modStart, j)
+	member := rustMember{name: name, signature: signature}
+	if j >= to || p.tokens[j].text != "{" {
+		// Declaration without a body (trait method or foreign function).
+		if j < to && p.tokens[j].text == ";" {
+			j++
+		}
+		return j, member
+	}
+	bodyEnd := matchingClose(p.tokens, j)
+	if bodyEnd < 0 {
+		return to, member
+	}
+
+	qualified, nodeType, receiver := name, "function", ""
+	if scope.selfType != "" {
+		receiver = scope.selfType
+	} else if scope.trait != "" {
+		receiver = scope.trait
+	}
+	if receiver != "" {
+		qualified = receiver + "::" + name
+		nodeType = "method"
+	}
+
+	node := newCFunctionNode(p.code, p.lex.lineStarts, qualified, nodeType, p.tokens[itemStart].start, p.tokens[bodyEnd].end)
+	node.Imports = append([]string(nil), p.imports...)
+	node.Signature = signature
+	node.Receiver = receiver
+	node.Doc = p.docBefore(itemStart)
+	node.Callees = cCallees(p.tokens[j+1:bodyEnd], rustKeywords)
+	node.ParamTypes = paramTypes
+	node.ReturnTypes = returnTypes
+	node.HasErrorReturn = len(returnTypes) > 0 && rustIsResultType(returnTypes[0])
+	node.Decorators = attrs
+	node.IsAsync = isAsync
+	node.TypeParams = typeParams
+	node.Exported = exported
+	p.functions = append(p.functions, node)
+	return bodyEnd + 1, member
+}
+
+func (p *rustItemParser) parseImpl(itemStart, modStart, implIdx, to int, attrs []string) int {
+	j := implIdx + 1
+	var typeParams []string
+	if j < to && p.tokens[j].text == "<" {
+		end := matchingClose(p.tokens, j)
+		if end < 0 {
+			return p.skipItem(implIdx, to)
+		}
+		typeParams = tokenListTexts(p.code, p.tokens, j+1, end)
+		j = end + 1
+	}
+
+	headerStart := j
+	headerEnd := p.scanTypeEnd(j, to)
+	forIdx := -1
+	depth := 0
+	for k := headerStart; k < headerEnd; k++ {
+		switch p.tokens[k].text {
+		case "<", "(", "[":
+			depth++
+		case ">", ")", "]":
+			depth--
+		case "for":
+			if depth == 0 && (k+1 >= headerEnd || p.tokens[k+1].text != "<") {
+				forIdx = k
+			}
+		}
+	}
+
+	selfStart, traitText := headerStart, ""
+	if forIdx >= 0 {
+		traitText = tokenSpanText(p.code, p.tokens, headerStart, forIdx)
+		selfStart = forIdx + 1
+	}
+	receiver := rustBaseTypeName(tokenSpanText(p.code, p.tokens, selfStart, headerEnd))
+
+	j = headerEnd
+	if j < to && p.tokens[j].text == "where" {
+		j = p.scanTypeEnd(j+1, to)
+	}
+	if j >= to || p.tokens[j].text != "{" {
+		return p.skipItem(j, to)
+	}
+	bodyEnd := matchingClose(p.tokens, j)
+	if bodyEnd < 0 {
+		return to
+	}
+
+	members := p.parseItems(j+1, bodyEnd, rustScope{selfType: receiver, trait: traitText})
+	if traitText == "" {
+		// Inherent impls are represented by their methods.
+		return bodyEnd + 1
+	}
+
+	node := newCFunctionNode(p.code, p.lex.lineStarts, fmt.Sprintf("%s for %s", traitText, receiver), "trait_impl", p.tokens[itemStart].start, p.tokens[bodyEnd].end)
+	node.Imports = append([]string(nil), p.imports...)
+	node.Signature = tokenSpanText(p.code, p.tokens, modStart, j)
+	node.Receiver = receiver
+	node.Doc = p.docBefore(itemStart)
+	node.Decorators = attrs
+	node.TypeParams = typeParams
+	for _, m := range members {
+		node.Members = append(node.Members, m.name)
+	}
+	p.functions = append(p.functions, node)
+	return bodyEnd + 1
+}
+
+func (p *rustItemParser) parseTrait(itemStart, modStart, traitIdx, to int, attrs []string, exported bool) int {
+	if traitIdx+1 >= to || p.tokens[traitIdx+1].kind != cTokIdent {
+		return traitIdx + 1
+	}
+	name := p.tokens[traitIdx+1].text
+	j := traitIdx + 2
+	var typeParams []string
+	if j < to && p.tokens[j].text == "<" {
+		end := matchingClose(p.tokens, j)
+		if end < 0 {
+			return p.skipItem(traitIdx, to)
+		}
+		typeParams = tokenListTexts(p.code, p.tokens, j+1, end)
+		j = end + 1
+	}
+	// Supertraits and where clauses run up to the body.
+	j = p.scanTypeEnd(j, to)
+	if j < to && p.tokens[j].text == "where" {
+		j = p.scanTypeEnd(j+1, to)
+	}
+	if j >= to || p.tokens[j].text != "{" {
+		return p.skipItem(j, to)
+	}
+	bodyEnd := matchingClose(p.tokens, j)
+	if bodyEnd < 0 {
+		return to
+	}
+
+	members := p.parseItems(j+1, bodyEnd, rustScope{trait: name})
+
+	node := newCFunctionNode(p.code, p.lex.lineStarts, name, "trait", p.tokens[itemStart].start, p.tokens[bodyEnd].end)
+	node.Imports = append([]string(nil), p.imports...)
+	node.Signature = tokenSpanText(p.code, p.tokens, modStart, j)
+	node.Doc = p.docBefore(itemStart)
+	node.Decorators = attrs
+	node.TypeParams = typeParams
+	node.Exported = exported
+	for _, m := range members {
+		node.Members = append(node.Members, m.signature)
+	}
+	p.functions = append(p.functions, node)
+	return bodyEnd + 1
+}
+
+// scanTypeEnd returns the index of the first token at bracket depth zero
+// that ends a type in item headers: "{", ";", "where" or "=".
+func (p *rustItemParser) scanTypeEnd(i, to int) int {
+	depth := 0
+	for ; i < to; i++ {
+		tok := p.tokens[i]
+		switch tok.text {
+		case "<", "(", "[":
+			depth++
+		case ">", ")", "]":
+			if depth > 0 {
+				depth--
+			}
+		case "{", ";", "=":
+			if depth == 0 {
+				return i
+			}
+			if tok.text == "{" {
+				// Braces never appear inside types.
+				return i
+			}
+		case "where":
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return to
+}
+
+// rustParamTypes returns the declared types of the parameters in
+// tokens[from:to], skipping self receivers.
+func (p *rustItemParser) rustParamTypes(from, to int) []string {
+	var types []string
+	for _, part := range splitTokensTopLevel(p.tokens, from, to, ",", true) {
+		depth := 0
+		for k := part[0]; k < part[1]; k++ {
+			switch p.tokens[k].text {
+			case "(", "[", "<":
+				depth++
+			case ")", "]", ">":
+				depth--
+			case ":":
+				if depth == 0 {
+					if t := tokenSpanText(p.code, p.tokens, k+1, part[1]); t != "" {
+						types = append(types, t)
+					}
+					k = part[1]
+				}
+			}
+		}
+	}
+	return types
+}
+
+func (p *rustItemParser) docBefore(tokenIdx int) string {
+	return docCommentBefore(p.code, p.lex.comments, p.tokens[tokenIdx].start, func(text string) bool {
+		return (strings.HasPrefix(text, "///") && !strings.HasPrefix(text, "////")) ||
+			(strings.HasPrefix(text, "/**") && !strings.HasPrefix(text, "/***") && text != "/**/")
+	})
+}
+
+// rustIsResultType reports whether a return type is Result or an alias path
+// ending in Result (io::Result<T>, anyhow::Result<T>).
+func rustIsResultType(ret string) bool {
+	return rustBaseTypeName(ret) == "Result"
+}
+
+// rustBaseTypeName reduces a type expression to its last path segment
+// without references or generic arguments: "&mut crate::db::Pool<T>" -> "Pool".
+func rustBaseTypeName(typ string) string {
+	typ = strings.TrimSpace(typ)
+	for {
+		trimmed := strings.TrimLeft(typ, "&*! ")
+		for _, prefix := range []string{"mut ", "dyn ", "const "} {
+			trimmed = strings.TrimPrefix(trimmed, prefix)
+		}
+		if strings.HasPrefix(trimmed, "'") {
+			if idx := strings.IndexByte(trimmed, ' '); idx >= 0 {
+				trimmed = trimmed[idx+1:]
+			}
+		}
+		if trimmed == typ {
+			break
+		}
+		typ = trimmed
+	}
+	if idx := strings.IndexByte(typ, '<'); idx >= 0 {
+		typ = typ[:idx]
+	}
+	if idx := strings.LastIndex(typ, "::"); idx >= 0 {
+		typ = typ[idx+2:]
+	}
+	return strings.TrimSpace(typ)
+}
+
+// rustUseImports collects the paths imported by `use` declarations, expanding
+// grouped imports: "use std::{fs, io::Read};" -> "std::fs", "std::io::Read".
+func rustUseImports(tokens []cToken) []string {
+	seen := make(map[string]struct{})
+	var imports []string
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].kind != cTokIdent || tokens[i].text != "use" {
+			continue
+		}
+		var b strings.Builder
+		j := i + 1
+		for ; j < len(tokens) && tokens[j].text != ";"; j++ {
+			if tokens[j].text == "as" {
+				b.WriteString(" as ")
+				continue
+			}
+			b.WriteString(tokens[j].text)
+		}
+		for _, path := range expandRustUseTree("", b.String()) {
+			if _, ok := seen[path]; ok {
+				continue
+			}
+			seen[path] = struct{}{}
+			imports = append(imports, path)
+		}
+		i = j
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+func expandRustUseTree(prefix, tree string) []string {
+	tree = strings.TrimSpace(tree)
+	join := func(a, b string) string {
+		switch {
+		case a == "":
+			return b
+		case b == "":
+			return a
+		}
+		return a + "::" + b
+	}
+	if open := strings.IndexByte(tree, '{'); open >= 0 && strings.HasSuffix(tree, "}") {
+		head := strings.TrimSuffix(strings.TrimSpace(tree[:open]), "::")
+		var out []string
+		for _, part := range splitTopLevel(tree[open+1:len(tree)-1], ',') {
+			out = append(out, expandRustUseTree(join(prefix, head), part)...)
+		}
+		return out
+	}
+	if idx := strings.Index(tree, " as "); idx >= 0 {
+		tree = strings.TrimSpace(tree[:idx])
+	}
+	if tree == "" {
+		return nil
+	}
+	if tree == "self" {
+		return []string{prefix}
+	}
+	return []string{join(prefix, tree)}
+}
+
+// rustRawString matches raw and byte string literals (r"..", r#".."#,
+// br"..", b"..") that the generic lexer cannot handle.
+func rustRawString(code []byte, pos int) int {
+	i := pos
+	if i < len(code) && code[i] == 'b' {
+		i++
+	}
+	if i < len(code) && code[i] == '"' && i > pos {
+		return skipCQuoted(code, i, '"')
+	}
+	if i >= len(code) || code[i] != 'r' {
+		return -1
+	}
+	i++
+	hashes := 0
+	for i < len(code) && code[i] == '#' {
+		hashes++
+		i++
+	}
+	if i >= len(code) || code[i] != '"' {
+		return -1
+	}
+	closing := "\"" + strings.Repeat("#", hashes)
+	if end := strings.Index(string(code[i+1:]), closing); end >= 0 {
+		return i + 1 + end + len(closing)
+	}
+	return len(code)
+}
@@ -0,0 +1,680 @@
+package parser
+
+import (
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	sfcOptionListRegex   = regexp.MustCompile(`\b(props|emits)\s*:\s*[\[{]`)
+	sfcEmitCallRegex     = regexp.MustCompile(`(?:\$emit|\bemit|\bdispatch)\(\s*['"` + "`" + `]([\w:.-]+)`)
+	svelteExportLetRegex = regexp.MustCompile(`\bexport\s+let\s+([A-Za-z_$][\w$]*)`)
+	svelteRunePropsRegex = regexp.MustCompile(`(?s)\blet\s*\{(.*?)\}\s*(?::\s*([^=]+?))?\s*=\s*\$props\(`)
+	sfcTemplateTagRegex  = regexp.MustCompile(`<([A-Za-z][\w.:-]*)`)
+)
+
+// SFCParser implements LanguageParser for single-file UI components: Vue
+// (.vue) and Svelte (.svelte). The file is split into script, template and
+// style blocks; scripts go through the JS/TS extractor and the component
+// itself is recorded with its props and emitted events.
+type SFCParser struct {
+	lang Language
+}
+
+// NewVueParser creates a parser for Vue single-file components
+func NewVueParser() *SFCParser {
+	return &SFCParser{lang: LanguageVue}
+}
+
+// NewSvelteParser creates a parser for Svelte components
+func NewSvelteParser() *SFCParser {
+	return &SFCParser{lang: LanguageSvelte}
+}
+
+// Language returns the language name
+func (p *SFCParser) Language() string {
+	return string(p.lang)
+}
+
+// sfcBlock is a top-level <script>, <template> or <style> element.
+type sfcBlock struct {
+	tag        string
+	attrs      map[string]string
+	start, end int // element, including its tags
+	bodyStart  int // content between the tags
+	bodyEnd    int
+}
+
+// ExtractFunctions extracts the component, its template and style sections
+// and the functions declared in its script blocks.
+func (p *SFCParser) ExtractFunctions(filePath string, code []byte) ([]FunctionNode, error) {
+	blocks := splitSFCBlocks(code, p.lang == LanguageVue)
+	lineStarts := buildLineOffsets(code)
+	component := sfcComponentName(filePath)
+
+	// Everything outside the script blocks is blanked (newlines are kept),
+	// so the extractor reports offsets and lines of the original file.
+	masked := make([]byte, len(code))
+	for i, ch := range code {
+		if ch == '\n' {
+			masked[i] = '\n'
+		} else {
+			masked[i] = ' '
+		}
+	}
+	var script strings.Builder
+	tsAware, jsxAware := false, false
+	for _, b := range blocks {
+		if b.tag != "script" {
+			continue
+		}
+		copy(masked[b.bodyStart:b.bodyEnd], code[b.bodyStart:b.bodyEnd])
+		script.Write(code[b.bodyStart:b.bodyEnd])
+		script.WriteByte('\n')
+		switch b.attrs["lang"] {
+		case "ts":
+			tsAware = true
+		case "tsx":
+			tsAware, jsxAware = true, true
+		case "jsx":
+			jsxAware = true
+		}
+	}
+	scriptText := script.String()
+
+	markup := sfcMarkup(code, blocks, p.lang == LanguageVue)
+	imports := extractJSImports([]byte(scriptText))
+	propsType, props := sfcProps(scriptText, p.lang)
+
+	functions := []FunctionNode{{
+		Name:        component,
+		NodeType:    "component",
+		StartLine:   1,
+		EndLine:     len(lineStarts),
+		Content:     string(code),
+		StartByte:   0,
+		EndByte:     len(code),
+		Imports:     imports,
+		Signature:   sfcComponentSignature(component, props, propsType),
+		Callees:     extractJSCallees(scriptText),
+		Exported:    true,
+		PropsType:   propsType,
+		PropNames:   props,
+		Emits:       sfcEmits(scriptText, markup, p.lang),
+		Hooks:       collectHooks(extractJSCallees(scriptText)),
+		JSXElements: sfcTemplateElements(markup),
+	}}
+
+	for _, b := range blocks {
+		if b.tag == "script" {
+			continue
+		}
+		functions = append(functions, newSFCSection(code, lineStarts, component, b.tag, b.start, b.end))
+	}
+	if p.lang == LanguageSvelte {
+		if start, end, ok := svelteMarkupSpan(code, blocks); ok {
+			functions = append(functions, newSFCSection(code, lineStarts, component, "template", start, end))
+		}
+	}
+	for i := range functions {
+		if functions[i].NodeType == "template" {
+			functions[i].JSXElements = sfcTemplateElements(string(code[functions[i].StartByte:functions[i].EndByte]))
+		}
+	}
+
+	for _, fn := range extractJSFunctions(masked, tsAware, jsxAware) {
+		fn.Imports = imports
+		functions = append(functions, fn)
+	}
+	setResolvedImports(filePath, functions)
+	return functions, nil
+}
+
+// newSFCSection builds the chunk of a template or style section.
+func newSFCSection(code []byte, lineStarts []int, component, kind string, start, end int) FunctionNode {
+	signature := ""
+	if code[start] == '<' {
+		if gt := strings.IndexByte(string(code[start:end]), '>'); gt >= 0 {
+			signature = collapseWhitespace(string(code[start : start+gt+1]))
+		}
+	}
+	return FunctionNode{
+		Name:      component + "." + kind,
+		NodeType:  kind,
+		StartLine: sfcLineAt(lineStarts, start),
+		EndLine:   sfcLineAt(lineStarts, end-1),
+		Content:   string(code[start:end]),
+		StartByte: start,
+		EndByte:   end,
+		Signature: signature,
+		Receiver:  component,
+	}
+}
+
+func sfcLineAt(lineStarts []int, offset int) int {
+	line := 1
+	for line < len(lineStarts) && lineStarts[line] <= offset {
+		line++
+	}
+	return line
+}
+
+// sfcComponentName derives the component name from the file name, the way
+// bundlers register it: "user-card.vue" becomes "UserCard".
+func sfcComponentName(filePath string) string {
+	base := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	var b strings.Builder
+	upper := true
+	for _, r := range base {
+		if r == '-' || r == '_' || r == '.' || r == ' ' {
+			upper = true
+			continue
+		}
+		if upper {
+			b.WriteString(strings.ToUpper(string(r)))
+			upper = false
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return base
+	}
+	return b.String()
+}
+
+func sfcComponentSignature(component string, props []string, propsType string) string {
+	switch {
+	case len(props) > 0:
+		return component + "(" + strings.Join(props, ", ") + ")"
+	case propsType != "":
+		return component + "(" + propsType + ")"
+	}
+	return component + "()"
+}
+
+// splitSFCBlocks returns the top-level script, style and (for Vue) template
+// elements. Nested <template> tags are matched so a Vue template ends at its
+// own closing tag; HTML comments are skipped.
+func splitSFCBlocks(code []byte, vue bool) []sfcBlock {
+	var blocks []sfcBlock
+	for i := 0; i < len(code); i++ {
+		if code[i] != '<' {
+			continue
+		}
+		if hasPrefixAt(code, i, "<!--") {
+			end := strings.Index(string(code[i+4:]), "-->")
+			if end < 0 {
+				break
+			}
+			i += 4 + end + 2
+			continue
+		}
+		tag := ""
+		for _, name := range []string{"script", "style", "template"} {
+			if (name != "template" || vue) && sfcTagAt(code, i, "<"+name) {
+				tag = name
+				break
+			}
+		}
+		if tag == "" {
+			continue
+		}
+		attrs, openEnd, selfClosing := parseSFCAttributes(code, i+1+len(tag))
+		if openEnd < 0 {
+			break
+		}
+		if selfClosing {
+			i = openEnd - 1
+			continue
+		}
+		bodyEnd := sfcClosingTag(code, openEnd, tag)
+		end := len(code)
+		if bodyEnd < len(code) {
+			if gt := strings.IndexByte(string(code[bodyEnd:]), '>'); gt >= 0 {
+				end = bodyEnd + gt + 1
+			}
+		}
+		blocks = append(blocks, sfcBlock{
+			tag:       tag,
+			attrs:     attrs,
+			start:     i,
+			end:       end,
+			bodyStart: openEnd,
+			bodyEnd:   bodyEnd,
+		})
+		i = end - 1
+	}
+	return blocks
+}
+
+func hasPrefixAt(code []byte, pos int, prefix string) bool {
+	return pos+len(prefix) <= len(code) && string(code[pos:pos+len(prefix)]) == prefix
+}
+
+// sfcTagAt reports whether an opening or closing tag named like open ("<x"
+// or "</x") starts at pos.
+func sfcTagAt(code []byte, pos int, open string) bool {
+	if pos+len(open) > len(code) || !strings.EqualFold(string(code[pos:pos+len(open)]), open) {
+		return false
+	}
+	if pos+len(open) == len(code) {
+		return true
+	}
+	switch code[pos+len(open)] {
+	case ' ', '\t', '\r', '\n', '>', '/':
+		return true
+	}
+	return false
+}
+
+// parseSFCAttributes reads the attributes of an opening tag from pos and
+// returns the offset just past its '>'.
+func parseSFCAttributes(code []byte, pos int) (map[string]string, int, bool) {
+	attrs := map[string]string{}
+	for pos < len(code) {
+		ch := code[pos]
+		switch {
+		case ch == '>':
+			return attrs, pos + 1, false
+		case ch == '/' && pos+1 < len(code) && code[pos+1] == '>':
+			return attrs, pos + 2, true
+		case isJSWhitespace(ch) || ch == '/':
+			pos++
+			continue
+		}
+		start := pos
+		for pos < len(code) && !isJSWhitespace(code[pos]) && code[pos] != '=' && code[pos] != '>' && code[pos] != '/' {
+			pos++
+		}
+		name := strings.ToLower(string(code[start:pos]))
+		value := ""
+		if pos < len(code) && code[pos] == '=' {
+			pos++
+			if pos < len(code) && (code[pos] == '"' || code[pos] == '\'') {
+				quote := code[pos]
+				end := strings.IndexByte(string(code[pos+1:]), quote)
+				if end < 0 {
+					return attrs, -1, false
+				}
+				value = string(code[pos+1 : pos+1+end])
+				pos += end + 2
+			} else {
+				start := pos
+				for pos < len(code) && !isJSWhitespace(code[pos]) && code[pos] != '>' {
+					pos++
+				}
+				value = string(code[start:pos])
+			}
+		}
+		if name != "" {
+			attrs[name] = value
+		}
+	}
+	return attrs, -1, false
+}
+
+// sfcClosingTag returns the offset of the tag closing the element whose
+// content starts at pos. Script and style contents are raw text, templates
+// may nest.
+func sfcClosingTag(code []byte, pos int, tag string) int {
+	depth := 1
+	for i := pos; i < len(code); i++ {
+		if code[i] != '<' {
+			continue
+		}
+		if sfcTagAt(code, i, "</"+tag) {
+			depth--
+			if depth == 0 {
+				return i
+			}
+			continue
+		}
+		if tag == "template" && sfcTagAt(code, i, "<"+tag) {
+			if _, end, selfClosing := parseSFCAttributes(code, i+1+len(tag)); end > 0 && !selfClosing {
+				depth++
+			}
+		}
+	}
+	return len(code)
+}
+
+// sfcMarkup returns the component's template source: the <template> block
+// for Vue, everything outside script and style blocks for Svelte.
+func sfcMarkup(code []byte, blocks []sfcBlock, vue bool) string {
+	var b strings.Builder
+	if vue {
+		for _, block := range blocks {
+			if block.tag == "template" {
+				b.Write(code[block.bodyStart:block.bodyEnd])
+			}
+		}
+		return b.String()
+	}
+	pos := 0
+	for _, block := range blocks {
+		b.Write(code[pos:block.start])
+		pos = block.end
+	}
+	b.Write(code[pos:])
+	return b.String()
+}
+
+// svelteMarkupSpan returns the range from the first to the last non-blank
+// byte outside the script and style blocks.
+func svelteMarkupSpan(code []byte, blocks []sfcBlock) (int, int, bool) {
+	start, end := -1, -1
+	pos := 0
+	visit := func(from, to int) {
+		for i := from; i < to; i++ {
+			if !isJSWhitespace(code[i]) {
+				if start < 0 {
+					start = i
+				}
+				end = i + 1
+			}
+		}
+	}
+	for _, block := range blocks {
+		visit(pos, block.start)
+		pos = block.end
+	}
+	visit(pos, len(code))
+	return start, end, start >= 0
+}
+
+// sfcTemplateElements lists the elements and components used in a template,
+// in order of first appearance.
+func sfcTemplateElements(markup string) []string {
+	var elements []string
+	for _, m := range sfcTemplateTagRegex.FindAllStringSubmatch(markup, -1) {
+		if m[1] == "template" || slices.Contains(elements, m[1]) {
+			continue
+		}
+		elements = append(elements, m[1])
+	}
+	return elements
+}
+
+// sfcProps returns the props type and the prop names declared in a script:
+// defineProps / withDefaults and the options API "props:" for Vue,
+// "export let" and the $props() rune for Svelte.
+func sfcProps(script string, lang Language) (string, []string) {
+	var propsType string
+	var props []string
+	if lang == LanguageVue {
+		if typeArg, arg, ok := sfcMacroCall(script, "defineProps"); ok {
+			propsType = collapseWhitespace(typeArg)
+			props = sfcDeclaredKeys(script, typeArg, arg)
+		} else if m := sfcOptionList(script, "props"); m != "" {
+			props = sfcDeclaredKeys(script, "", m)
+		}
+		return propsType, props
+	}
+	for _, m := range svelteExportLetRegex.FindAllStringSubmatch(script, -1) {
+		props = appendUnique(props, m[1])
+	}
+	if m := svelteRunePropsRegex.FindStringSubmatch(script); m != nil {
+		propsType = strings.TrimSpace(m[2])
+		for _, key := range sfcMemberKeys(m[1]) {
+			props = appendUnique(props, key)
+		}
+	}
+	return propsType, props
+}
+
+// sfcEmits returns the events a component emits: the defineEmits / emits:
+// declarations (Vue), the createEventDispatcher type (Svelte) and the
+// names passed to emit, $emit and dispatch calls.
+func sfcEmits(script, markup string, lang Language) []string {
+	var emits []string
+	macro := "createEventDispatcher"
+	if lang == LanguageVue {
+		macro = "defineEmits"
+	}
+	if typeArg, arg, ok := sfcMacroCall(script, macro); ok {
+		emits = sfcDeclaredKeys(script, typeArg, arg)
+	} else if m := sfcOptionList(script, "emits"); m != "" && lang == LanguageVue {
+		emits = sfcDeclaredKeys(script, "", m)
+	}
+	for _, src := range []string{script, markup} {
+		for _, m := range sfcEmitCallRegex.FindAllStringSubmatch(src, -1) {
+			emits = appendUnique(emits, m[1])
+		}
+	}
+	return emits
+}
+
+func appendUnique(list []string, value string) []string {
+	if slices.Contains(list, value) {
+		return list
+	}
+	return append(list, value)
+}
+
+// sfcMacroCall finds a call like "defineProps<T>(arg)" and returns its type
+// argument and call argument.
+func sfcMacroCall(script, name string) (string, string, bool) {
+	code := []byte(script)
+	for from := 0; ; {
+		idx := strings.Index(script[from:], name)
+		if idx < 0 {
+			return "", "", false
+		}
+		pos := from + idx
+		from = pos + len(name)
+		if pos > 0 && isIdentifierPart(code[pos-1]) || from < len(code) && isIdentifierPart(code[from]) {
+			continue
+		}
+		i := skipSFCSpace(code, from)
+		typeArg := ""
+		if i < len(code) && code[i] == '<' {
+			end := sfcTypeArgEnd(code, i)
+			if end < 0 {
+				return "", "", false
+			}
+			typeArg = strings.TrimSpace(script[i+1 : end-1])
+			i = skipSFCSpace(code, end)
+		}
+		if i >= len(code) || code[i] != '(' {
+			continue
+		}
+		end := skipBalancedFrom(code, i, '(', ')')
+		if end < 0 {
+			return "", "", false
+		}
+		return typeArg, strings.TrimSpace(script[i+1 : end-1]), true
+	}
+}
+
+func skipSFCSpace(code []byte, pos int) int {
+	for pos < len(code) && isJSWhitespace(code[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// sfcTypeArgEnd returns the offset just past the '>' closing the type
+// argument list opened at start. Arrows ("=>") do not close it.
+func sfcTypeArgEnd(code []byte, start int) int {
+	depth := 0
+	for i := start; i < len(code); i++ {
+		switch code[i] {
+		case '"', '\'', '`':
+			i = skipStringLiteralFrom(code, i) - 1
+		case '<':
+			depth++
+		case '>':
+			if i > 0 && code[i-1] == '=' {
+				continue
+			}
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// sfcOptionList returns the object or array literal of an options API entry
+// such as "props: { ... }" or "emits: [...]".
+func sfcOptionList(script, key string) string {
+	code := []byte(script)
+	for _, loc := range sfcOptionListRegex.FindAllStringSubmatchIndex(script, -1) {
+		if script[loc[2]:loc[3]] != key {
+			continue
+		}
+		open := loc[1] - 1
+		closeCh := byte('}')
+		if code[open] == '[' {
+			closeCh = ']'
+		}
+		if end := skipBalancedFrom(code, open, code[open], closeCh); end > 0 {
+			return script[open:end]
+		}
+	}
+	return ""
+}
+
+// sfcDeclaredKeys lists the names declared by a props or emits macro: the
+// members of its type argument (inline or a named interface/type alias),
+// or of its object or array argument.
+func sfcDeclaredKeys(script, typeArg, arg string) []string {
+	if typeArg != "" {
+		body := typeArg
+		if isSimpleIdentifier(typeArg) {
+			body = sfcTypeDeclaration(script, typeArg)
+		}
+		if strings.HasPrefix(body, "{") && strings.HasSuffix(body, "}") {
+			return sfcMemberKeys(body[1 : len(body)-1])
+		}
+		return nil
+	}
+	arg = strings.TrimSpace(arg)
+	switch {
+	case strings.HasPrefix(arg, "{") && strings.HasSuffix(arg, "}"):
+		return sfcMemberKeys(arg[1 : len(arg)-1])
+	case strings.HasPrefix(arg, "[") && strings.HasSuffix(arg, "]"):
+		var keys []string
+		for _, item := range splitJSParameters(arg[1 : len(arg)-1]) {
+			if name := sfcStringLiteral(item); name != "" {
+				keys = append(keys, name)
+			}
+		}
+		return keys
+	}
+	return nil
+}
+
+func isSimpleIdentifier(s string) bool {
+	if s == "" || !isIdentifierStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isIdentifierPart(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sfcTypeDeclaration returns the "{ ... }" body of an interface or object
+// type alias declared in the script.
+func sfcTypeDeclaration(script, name string) string {
+	re := regexp.MustCompile(`\b(?:interface\s+` + regexp.QuoteMeta(name) + `\b[^{]*|type\s+` + regexp.QuoteMeta(name) + `\s*=\s*)\{`)
+	loc := re.FindStringIndex(script)
+	if loc == nil {
+		return ""
+	}
+	end := skipBalancedFrom([]byte(script), loc[1]-1, '{', '}')
+	if end < 0 {
+		return ""
+	}
+	return script[loc[1]-1 : end]
+}
+
+// sfcMemberKeys returns the keys of an object literal, type literal or
+// destructuring pattern body. Call signatures such as
+// "(e: 'change', id: number): void" contribute their event literal.
+func sfcMemberKeys(body string) []string {
+	code := []byte(body)
+	var keys []string
+	expectKey := true
+	for i := 0; i < len(code); i++ {
+		ch := code[i]
+		switch {
+		case ch == ',' || ch == ';' || ch == '\n':
+			expectKey = true
+			continue
+		case isJSWhitespace(ch):
+			continue
+		case ch == '/' && i+1 < len(code) && (code[i+1] == '/' || code[i+1] == '*'):
+			i = skipCommentFrom(code, i) - 1
+			continue
+		}
+		if !expectKey {
+			switch ch {
+			case '"', '\'':
+				i = skipStringLiteralFrom(code, i) - 1
+			case '`':
+				i = skipTemplateLiteralFrom(code, i) - 1
+			case '{', '(', '[':
+				closeCh := map[byte]byte{'{': '}', '(': ')', '[': ']'}[ch]
+				if end := skipBalancedFrom(code, i, ch, closeCh); end > 0 {
+					i = end - 1
+				}
+			}
+			continue
+		}
+		expectKey = false
+		switch {
+		case ch == '(':
+			end := skipBalancedFrom(code, i, '(', ')')
+			if end < 0 {
+				return keys
+			}
+			for _, param := range splitJSParameters(body[i+1 : end-1]) {
+				if idx := findTopLevelColon(param); idx >= 0 {
+					if name := sfcStringLiteral(param[idx+1:]); name != "" {
+						keys = appendUnique(keys, name)
+						break
+					}
+				}
+			}
+			i = end - 1
+		case ch == '"' || ch == '\'':
+			end := skipStringLiteralFrom(code, i)
+			keys = appendUnique(keys, body[i+1:end-1])
+			i = end - 1
+		case isIdentifierStart(ch):
+			start := i
+			for i < len(code) && isIdentifierPart(code[i]) {
+				i++
+			}
+			name := body[start:i]
+			if name == "readonly" {
+				expectKey = true
+			} else {
+				keys = appendUnique(keys, name)
+			}
+			i--
+		case ch == '.' && strings.HasPrefix(body[i:], "..."):
+			// Rest elements are not named props.
+			i += 2
+		}
+	}
+	return keys
+}
+
+// sfcStringLiteral returns the content of a quoted string literal, or ""
+// when s is not one.
+func sfcStringLiteral(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"' || s[0] == '`') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return ""
+}
@@ -0,0 +1,1103 @@
+package parser
+
+import (
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// SQLParser implements LanguageParser for SQL schema and migration files.
+// CREATE TABLE/INDEX/VIEW/FUNCTION/PROCEDURE/TRIGGER and ALTER TABLE
+// statements become chunks carrying the tables and columns they touch;
+// other statements are grouped, and migration up/down sections (goose,
+// dbmate, sql-migrate or "*.up.sql" files) get a chunk of their own.
+type SQLParser struct{}
+
+// NewSQLParser creates a new SQL parser
+func NewSQLParser() *SQLParser {
+	return &SQLParser{}
+}
+
+// Language returns the language name
+func (p *SQLParser) Language() string {
+	return string(LanguageSQL)
+}
+
+const (
+	// sqlTokEnd terminates a statement: ";", a DELIMITER-defined string or a
+	// "GO" batch separator.
+	sqlTokEnd cTokenKind = cTokPunct + 1 + iota
+	// sqlTokMarker is a migration annotation comment; its text is "up",
+	// "down", "begin" or "end".
+	sqlTokMarker
+)
+
+// sqlMarkerRegex matches the migration annotations of goose
+// ("-- +goose Up", "-- +goose StatementBegin"), dbmate ("-- migrate:up")
+// and sql-migrate ("-- +migrate Down").
+var sqlMarkerRegex = regexp.MustCompile(`(?i)^--\s*(?:\+goose\s+|\+migrate\s+|migrate:)(up|down|statementbegin|statementend)\b`)
+
+// sqlKeywords are words that never name a table or column.
+var sqlKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "AND": true, "OR": true, "NOT": true,
+	"IN": true, "EXISTS": true, "JOIN": true, "ON": true, "AS": true, "ANY": true,
+	"ALL": true, "SOME": true, "LATERAL": true, "UNION": true, "INTERSECT": true,
+	"EXCEPT": true, "VALUES": true, "SET": true, "INTO": true, "UPDATE": true,
+	"DELETE": true, "INSERT": true, "WITH": true, "CASE": true, "WHEN": true,
+	"THEN": true, "ELSE": true, "END": true, "IS": true, "NULL": true, "LIKE": true,
+	"ILIKE": true, "BETWEEN": true, "ORDER": true, "GROUP": true, "BY": true,
+	"HAVING": true, "LIMIT": true, "OFFSET": true, "RETURNING": true, "USING": true,
+	"TABLE": true, "IF": true, "ONLY": true, "DISTINCT": true, "TRUE": true,
+	"FALSE": true, "DEFAULT": true, "ASC": true, "DESC": true, "NULLS": true,
+	"FIRST": true, "LAST": true, "COLLATE": true, "OVER": true, "PARTITION": true,
+	"LEFT": true, "RIGHT": true, "INNER": true, "OUTER": true, "FULL": true,
+	"CROSS": true, "NATURAL": true, "RECURSIVE": true, "CONFLICT": true, "DO": true,
+	"NOTHING": true, "REFERENCES": true, "PRIMARY": true, "KEY": true, "FOREIGN": true,
+	"UNIQUE": true, "CHECK": true, "CONSTRAINT": true, "RETURN": true, "BEGIN": true,
+}
+
+// sqlConstraintWords start a table element or ALTER action that is a
+// constraint rather than a column.
+var sqlConstraintWords = map[string]bool{
+	"CONSTRAINT": true, "PRIMARY": true, "FOREIGN": true, "UNIQUE": true, "CHECK": true,
+	"INDEX": true, "KEY": true, "EXCLUDE": true, "LIKE": true, "FULLTEXT": true,
+	"SPATIAL": true, "PERIOD": true,
+}
+
+// sqlStatement is a statement span tokens[from:to] inside section (-1 when
+// the file has no migration sections).
+type sqlStatement struct {
+	from, to int
+	end      int // byte offset just past the statement, including ";"
+	section  int
+}
+
+// sqlSection is a migration up or down section.
+type sqlSection struct {
+	direction  string
+	start, end int
+	tables     []string
+	columns    []string
+}
+
+// sqlAnalysis is what a recognized statement contributes to its chunk.
+type sqlAnalysis struct {
+	name        string
+	nodeType    string
+	tables      []string
+	columns     []string
+	paramTypes  []string
+	returnTypes []string
+	callees     []string
+	signature   string
+}
+
+// ExtractFunctions extracts schema statements, routines and migration
+// sections from SQL source.
+func (p *SQLParser) ExtractFunctions(filePath string, code []byte) ([]FunctionNode, error) {
+	tokens, comments, lineStarts := lexSQL(code)
+	stem := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+
+	var sections []sqlSection
+	lowerStem := strings.ToLower(stem)
+	if strings.HasSuffix(lowerStem, ".up") || strings.HasSuffix(lowerStem, ".down") {
+		direction := lowerStem[strings.LastIndex(lowerStem, ".")+1:]
+		sections = append(sections, sqlSection{direction: direction})
+	}
+	statements := splitSQLStatements(tokens, &sections)
+
+	sectionName := func(i int) string {
+		if i < 0 {
+			return ""
+		}
+		if lower := strings.ToLower(stem); strings.HasSuffix(lower, "."+sections[i].direction) {
+			return stem
+		}
+		return stem + "." + sections[i].direction
+	}
+
+	var functions []FunctionNode
+	var group []sqlStatement
+	flushGroup := func() {
+		if len(group) == 0 {
+			return
+		}
+		first, last := group[0], group[len(group)-1]
+		var verbs, tables, columns []string
+		for _, st := range group {
+			verbs = appendUnique(verbs, strings.ToUpper(tokens[st.from].text))
+			tables = appendAllUnique(tables, sqlTableRefs(tokens[st.from:st.to])...)
+			columns = appendAllUnique(columns, sqlColumnRefs(tokens[st.from:st.to])...)
+		}
+		node := newCFunctionNode(code, lineStarts, strings.Join(verbs, "/"), "statements", tokens[first.from].start, last.end)
+		node.Receiver = sectionName(first.section)
+		node.Tables = tables
+		node.Columns = columns
+		functions = append(functions, node)
+		group = nil
+	}
+
+	for _, st := range statements {
+		ts := tokens[st.from:st.to]
+		a := analyzeSQLStatement(code, ts)
+		if st.section >= 0 {
+			sec := &sections[st.section]
+			if a != nil {
+				sec.tables = appendAllUnique(sec.tables, a.tables...)
+				sec.columns = appendAllUnique(sec.columns, a.columns...)
+			} else {
+				sec.tables = appendAllUnique(sec.tables, sqlTableRefs(ts)...)
+				sec.columns = appendAllUnique(sec.columns, sqlColumnRefs(ts)...)
+			}
+		}
+		if a == nil {
+			if len(group) > 0 && group[0].section != st.section {
+				flushGroup()
+			}
+			group = append(group, st)
+			continue
+		}
+		flushGroup()
+		node := newCFunctionNode(code, lineStarts, a.name, a.nodeType, ts[0].start, st.end)
+		node.Receiver = sectionName(st.section)
+		node.Doc = docCommentBefore(code, comments, ts[0].start, func(text string) bool {
+			return !sqlMarkerRegex.MatchString(text)
+		})
+		node.Signature = a.signature
+		node.Tables = a.tables
+		node.Columns = a.columns
+		node.ParamTypes = a.paramTypes
+		node.ReturnTypes = a.returnTypes
+		node.Callees = a.callees
+		functions = append(functions, node)
+	}
+	flushGroup()
+
+	for i, sec := range sections {
+		if sec.end <= sec.start {
+			continue
+		}
+		node := newCFunctionNode(code, lineStarts, sectionName(i), "migration", sec.start, sec.end)
+		node.Tables = sec.tables
+		node.Columns = sec.columns
+		functions = append(functions, node)
+	}
+	return functions, nil
+}
+
+func appendAllUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		list = appendUnique(list, v)
+	}
+	return list
+}
+
+// lexSQL tokenizes SQL source. Line comments ("--") and block comments are
+// returned separately, except migration annotations which become marker
+// tokens. Single-quoted and dollar-quoted ($$ ... $$, $tag$ ... $tag$)
+// strings are string tokens; double-quoted, backquoted and bracketed
+// identifiers keep their quotes. "DELIMITER x" lines (MySQL) change the
+// statement terminator and "GO" lines (T-SQL) end a batch.
+func lexSQL(code []byte) ([]cToken, []cComment, []int) {
+	lineStarts := buildLineOffsets(code)
+	var tokens []cToken
+	var comments []cComment
+	delimiter := ";"
+	line := 1
+	lineStart := true
+
+	emit := func(kind cTokenKind, text string, start, end int) {
+		tokens = append(tokens, cToken{kind: kind, text: text, start: start, end: end, line: line})
+	}
+
+	for pos := 0; pos < len(code); {
+		ch := code[pos]
+		if ch == '\n' {
+			line++
+			lineStart = true
+			pos++
+			continue
+		}
+		if ch == ' ' || ch == '\t' || ch == '\r' {
+			pos++
+			continue
+		}
+		atLineStart := lineStart
+		lineStart = false
+		start := pos
+
+		if atLineStart {
+			eol := pos
+			for eol < len(code) && code[eol] != '\n' {
+				eol++
+			}
+			fields := strings.Fields(string(code[pos:eol]))
+			if len(fields) == 2 && strings.EqualFold(fields[0], "DELIMITER") {
+				emit(sqlTokEnd, "", pos, eol)
+				delimiter = fields[1]
+				pos = eol
+				continue
+			}
+			if len(fields) >= 1 && len(fields) <= 2 && strings.EqualFold(fields[0], "GO") {
+				emit(sqlTokEnd, "GO", pos, eol)
+				pos = eol
+				continue
+			}
+		}
+
+		switch {
+		case ch == '-' && pos+1 < len(code) && code[pos+1] == '-':
+			for pos < len(code) && code[pos] != '\n' {
+				pos++
+			}
+			text := strings.TrimRight(string(code[start:pos]), "\r")
+			if m := sqlMarkerRegex.FindStringSubmatch(text); m != nil {
+				marker := strings.ToLower(m[1])
+				switch marker {
+				case "statementbegin":
+					marker = "begin"
+				case "statementend":
+					marker = "end"
+				}
+				emit(sqlTokMarker, marker, start, pos)
+				continue
+			}
+			comments = append(comments, cComment{text: text, start: start, end: pos, line: line, endLine: line})
+		case ch == '/' && pos+1 < len(code) && code[pos+1] == '*':
+			pos = skipCBlockComment(code, pos, false)
+			startLine := line
+			line += strings.Count(string(code[start:pos]), "\n")
+			comments = append(comments, cComment{text: string(code[start:pos]), start: start, end: pos, line: startLine, endLine: line})
+		case delimiter != ";" && strings.HasPrefix(string(code[pos:min(len(code), pos+len(delimiter))]), delimiter):
+			pos += len(delimiter)
+			emit(sqlTokEnd, delimiter, start, pos)
+		case ch == ';':
+			pos++
+			if delimiter == ";" {
+				emit(sqlTokEnd, ";", start, pos)
+			} else {
+				emit(cTokPunct, ";", start, pos)
+			}
+		case ch == '\'':
+			pos = skipSQLQuoted(code, pos, '\'')
+			line += strings.Count(string(code[start:pos]), "\n")
+			emit(cTokString, string(code[start:pos]), start, pos)
+		case ch == '"' || ch == '`':
+			pos = skipSQLQuoted(code, pos, ch)
+			emit(cTokIdent, string(code[start:pos]), start, pos)
+		case ch == '[' && pos+1 < len(code) && isCIdentStart(code[pos+1], ""):
+			// T-SQL bracketed identifier; array subscripts start with a
+			// number or an expression and stay punctuation.
+			end := pos + 1
+			for end < len(code) && code[end] != ']' && code[end] != '\n' {
+				end++
+			}
+			if end < len(code) && code[end] == ']' {
+				pos = end + 1
+				emit(cTokIdent, string(code[start:pos]), start, pos)
+			} else {
+				pos++
+				emit(cTokPunct, "[", start, pos)
+			}
+		case ch == '$' && sqlDollarTag(code, pos) != "":
+			tag := sqlDollarTag(code, pos)
+			end := strings.Index(string(code[pos+len(tag):]), tag)
+			if end < 0 {
+				pos = len(code)
+			} else {
+				pos += len(tag) + end + len(tag)
+			}
+			line += strings.Count(string(code[start:pos]), "\n")
+			emit(cTokString, string(code[start:pos]), start, pos)
+		case isCIdentStart(ch, "@#") || ch == '$' || ch == ':' && pos+1 < len(code) && isCIdentStart(code[pos+1], ""):
+			// Identifiers, T-SQL @variables and #temp tables, and bind
+			// parameters such as $1 or :name.
+			pos++
+			for pos < len(code) && isCIdentPart(code[pos], "$@#") {
+				pos++
+			}
+			emit(cTokIdent, string(code[start:pos]), start, pos)
+		case ch >= '0' && ch <= '9':
+			for pos < len(code) && (isCIdentPart(code[pos], "") || code[pos] == '.') {
+				pos++
+			}
+			emit(cTokNumber, string(code[start:pos]), start, pos)
+		default:
+			text := string(ch)
+			for _, op := range []string{"<>", "!=", "<=", ">=", "::", "||", ":=", "=>"} {
+				if strings.HasPrefix(string(code[pos:min(len(code), pos+2)]), op) {
+					text = op
+					break
+				}
+			}
+			pos += len(text)
+			emit(cTokPunct, text, start, pos)
+		}
+	}
+	return tokens, comments, lineStarts
+}
+
+// skipSQLQuoted skips a quoted string or identifier; the quote is escaped by
+// doubling it (and by a backslash inside single-quoted strings).
+func skipSQLQuoted(code []byte, pos int, quote byte) int {
+	pos++
+	for pos < len(code) {
+		switch code[pos] {
+		case '\\':
+			if quote == '\'' {
+				pos += 2
+				continue
+			}
+		case quote:
+			if pos+1 < len(code) && code[pos+1] == quote {
+				pos += 2
+				continue
+			}
+			return pos + 1
+		}
+		pos++
+	}
+	return len(code)
+}
+
+// sqlDollarTag returns the "$tag$" opening a dollar-quoted string at pos, or
+// "" when pos starts a positional parameter such as $1.
+func sqlDollarTag(code []byte, pos int) string {
+	end := pos + 1
+	for end < len(code) && (isCIdentStart(code[end], "") || end > pos+1 && code[end] >= '0' && code[end] <= '9') {
+		end++
+	}
+	if end < len(code) && code[end] == '$' {
+		return string(code[pos : end+1])
+	}
+	return ""
+}
+
+// splitSQLStatements splits the token stream into statements and records the
+// migration sections opened by marker tokens. Semicolons inside BEGIN ... END
+// blocks of routines and between StatementBegin/StatementEnd markers do not
+// end a statement.
+func splitSQLStatements(tokens []cToken, sections *[]sqlSection) []sqlStatement {
+	var statements []sqlStatement
+	section := len(*sections) - 1
+	start := -1
+	depth := 0
+	verbatim := false
+
+	finish := func(to, end int) {
+		if start >= 0 && to > start {
+			statements = append(statements, sqlStatement{from: start, to: to, end: end, section: section})
+		}
+		start = -1
+		depth = 0
+	}
+
+	for i, tok := range tokens {
+		switch tok.kind {
+		case sqlTokMarker:
+			switch tok.text {
+			case "up", "down":
+				if start >= 0 {
+					finish(i, tokens[i-1].end)
+				}
+				*sections = append(*sections, sqlSection{direction: tok.text, start: tok.start, end: tok.end})
+				section = len(*sections) - 1
+			case "begin":
+				verbatim = true
+			case "end":
+				verbatim = false
+				if start >= 0 {
+					finish(i, tokens[i-1].end)
+				}
+			}
+			continue
+		case sqlTokEnd:
+			if start < 0 {
+				continue
+			}
+			if verbatim || depth > 0 && tok.text == ";" && sqlIsRoutine(tokens[start:i]) {
+				continue
+			}
+			end := tokens[i-1].end
+			if tok.text == ";" {
+				end = tok.end
+			}
+			finish(i, end)
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		if tok.kind != cTokIdent {
+			continue
+		}
+		switch strings.ToUpper(tok.text) {
+		case "BEGIN":
+			next := ""
+			if i+1 < len(tokens) {
+				next = strings.ToUpper(tokens[i+1].text)
+			}
+			if i+1 < len(tokens) && tokens[i+1].kind != sqlTokEnd && next != "TRANSACTION" && next != "WORK" && next != "TRAN" {
+				depth++
+			}
+		case "CASE":
+			depth++
+		case "END":
+			if i+1 < len(tokens) {
+				switch strings.ToUpper(tokens[i+1].text) {
+				case "IF", "LOOP", "WHILE", "REPEAT", "FOR":
+					continue
+				}
+			}
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	if start >= 0 {
+		finish(len(tokens), tokens[len(tokens)-1].end)
+	}
+	for _, st := range statements {
+		if st.section >= 0 {
+			sec := &(*sections)[st.section]
+			sec.end = max(sec.end, st.end)
+		}
+	}
+	return statements
+}
+
+// sqlIsRoutine reports whether the statement creates a function, procedure,
+// trigger or event, whose bodies may contain semicolons.
+func sqlIsRoutine(ts []cToken) bool {
+	_, kind := sqlCreateObject(ts)
+	switch kind {
+	case "FUNCTION", "PROCEDURE", "PROC", "TRIGGER", "EVENT":
+		return true
+	}
+	return false
+}
+
+// sqlCreateObject returns the index of the object keyword of a CREATE
+// statement (TABLE, INDEX, VIEW, ...) and the keyword itself, skipping
+// modifiers such as OR REPLACE, TEMPORARY, UNIQUE, MATERIALIZED or a MySQL
+// DEFINER clause.
+func sqlCreateObject(ts []cToken) (int, string) {
+	if len(ts) == 0 || !strings.EqualFold(ts[0].text, "CREATE") {
+		return -1, ""
+	}
+	for i := 1; i < len(ts) && i < 16; i++ {
+		word := strings.ToUpper(ts[i].text)
+		switch word {
+		case "TABLE", "INDEX", "VIEW", "FUNCTION", "PROCEDURE", "PROC", "TRIGGER", "EVENT":
+			return i, word
+		case "OR", "REPLACE", "ALTER", "TEMP", "TEMPORARY", "UNLOGGED", "GLOBAL", "LOCAL",
+			"UNIQUE", "MATERIALIZED", "RECURSIVE", "CLUSTERED", "NONCLUSTERED", "FULLTEXT",
+			"SPATIAL", "BITMAP", "VIRTUAL", "CONSTRAINT", "SQL", "SECURITY", "INVOKER",
+			"DEFINER", "ALGORITHM", "UNDEFINED", "MERGE", "TEMPTABLE", "=", "@", "EXTERNAL":
+			continue
+		}
+		// Values of DEFINER = user@host and ALGORITHM = x clauses.
+		if i > 0 && (ts[i-1].text == "=" || ts[i-1].text == "@") {
+			continue
+		}
+		return -1, ""
+	}
+	return -1, ""
+}
+
+// analyzeSQLStatement recognizes the statements that get a chunk of their
+// own and extracts their name and metadata; it returns nil otherwise.
+func analyzeSQLStatement(code []byte, ts []cToken) *sqlAnalysis {
+	if len(ts) < 3 {
+		return nil
+	}
+	if strings.EqualFold(ts[0].text, "ALTER") && strings.EqualFold(ts[1].text, "TABLE") {
+		return analyzeSQLAlterTable(ts)
+	}
+	idx, kind := sqlCreateObject(ts)
+	if idx < 0 {
+		return nil
+	}
+	i := sqlSkipWords(ts, idx+1, "IF", "NOT", "EXISTS", "CONCURRENTLY")
+	switch kind {
+	case "TABLE":
+		return analyzeSQLCreateTable(code, ts, i)
+	case "INDEX":
+		return analyzeSQLCreateIndex(code, ts, i)
+	case "VIEW":
+		name, next := sqlName(ts, i)
+		if name == "" {
+			return nil
+		}
+		a := &sqlAnalysis{name: name, nodeType: "view"}
+		if next < len(ts) && ts[next].text == "(" {
+			if end := matchingClose(ts, next); end > next {
+				for _, part := range splitTokensTopLevel(ts, next+1, end, ",", false) {
+					if col, _ := sqlName(ts, part[0]); col != "" {
+						a.columns = append(a.columns, col)
+					}
+				}
+				next = end + 1
+			}
+		}
+		a.signature = tokenSpanText(code, ts, 0, next)
+		a.tables = sqlTableRefs(ts[next:])
+		a.columns = appendAllUnique(a.columns, sqlColumnRefs(ts[next:])...)
+		return a
+	case "FUNCTION", "PROCEDURE", "PROC":
+		return analyzeSQLRoutine(code, ts, i, kind)
+	case "TRIGGER":
+		name, next := sqlName(ts, i)
+		if name == "" {
+			return nil
+		}
+		a := &sqlAnalysis{name: name, nodeType: "trigger"}
+		for j := next; j < len(ts); j++ {
+			word := strings.ToUpper(ts[j].text)
+			if word == "ON" && len(a.tables) == 0 {
+				if table, _ := sqlName(ts, j+1); table != "" {
+					a.tables = append(a.tables, table)
+					a.signature = tokenSpanText(code, ts, 0, j+2)
+				}
+			}
+			if (word == "FUNCTION" || word == "PROCEDURE") && j > 0 && strings.EqualFold(ts[j-1].text, "EXECUTE") {
+				if fn, _ := sqlName(ts, j+1); fn != "" {
+					a.callees = append(a.callees, fn)
+				}
+			}
+		}
+		a.tables = appendAllUnique(a.tables, sqlTableRefs(ts[next:])...)
+		return a
+	}
+	return nil
+}
+
+func analyzeSQLCreateTable(code []byte, ts []cToken, i int) *sqlAnalysis {
+	name, next := sqlName(ts, i)
+	if name == "" {
+		return nil
+	}
+	a := &sqlAnalysis{name: name, nodeType: "table", tables: []string{name}}
+	a.signature = tokenSpanText(code, ts, 0, next)
+	if next < len(ts) && ts[next].text == "(" {
+		end := matchingClose(ts, next)
+		if end < 0 {
+			end = len(ts)
+		}
+		for _, part := range splitTokensTopLevel(ts, next+1, end, ",", false) {
+			element := ts[part[0]:part[1]]
+			if len(element) == 0 {
+				continue
+			}
+			if !sqlConstraintWords[strings.ToUpper(element[0].text)] {
+				if col, _ := sqlName(element, 0); col != "" {
+					a.columns = append(a.columns, col)
+				}
+			}
+			a.tables = appendAllUnique(a.tables, sqlReferencedTables(element)...)
+		}
+		next = end + 1
+	}
+	// CREATE TABLE ... AS SELECT, LIKE other and PARTITION OF parent.
+	if next < len(ts) {
+		a.tables = appendAllUnique(a.tables, sqlTableRefs(ts[next:])...)
+		for j := next; j+1 < len(ts); j++ {
+			if strings.EqualFold(ts[j].text, "PARTITION") && strings.EqualFold(ts[j+1].text, "OF") {
+				if parent, _ := sqlName(ts, j+2); parent != "" {
+					a.tables = appendUnique(a.tables, parent)
+				}
+			}
+		}
+	}
+	return a
+}
+
+func analyzeSQLCreateIndex(code []byte, ts []cToken, i int) *sqlAnalysis {
+	name := ""
+	if i < len(ts) && !strings.EqualFold(ts[i].text, "ON") {
+		name, i = sqlName(ts, i)
+	}
+	if i >= len(ts) || !strings.EqualFold(ts[i].text, "ON") {
+		return nil
+	}
+	table, next := sqlName(ts, sqlSkipWords(ts, i+1, "ONLY"))
+	if table == "" {
+		return nil
+	}
+	a := &sqlAnalysis{name: name, nodeType: "index", tables: []string{table}}
+	a.signature = tokenSpanText(code, ts, 0, len(ts))
+	for j := next; j < len(ts); j++ {
+		if ts[j].text != "(" {
+			continue
+		}
+		end := matchingClose(ts, j)
+		if end < 0 {
+			break
+		}
+		// Key columns and INCLUDE columns; expressions contribute the
+		// columns they reference.
+		for _, part := range splitTokensTopLevel(ts, j+1, end, ",", false) {
+			for _, col := range sqlExpressionColumns(ts[part[0]:part[1]]) {
+				a.columns = appendUnique(a.columns, col)
+			}
+		}
+		j = end
+	}
+	if a.name == "" {
+		a.name = table + "(" + strings.Join(a.columns, ", ") + ")"
+	}
+	return a
+}
+
+func analyzeSQLRoutine(code []byte, ts []cToken, i int, kind string) *sqlAnalysis {
+	name, next := sqlName(ts, i)
+	if name == "" {
+		return nil
+	}
+	nodeType := "function"
+	if kind != "FUNCTION" {
+		nodeType = "procedure"
+	}
+	a := &sqlAnalysis{name: name, nodeType: nodeType}
+	sigEnd := next
+	if next < len(ts) && ts[next].text == "(" {
+		if end := matchingClose(ts, next); end > next {
+			for _, part := range splitTokensTopLevel(ts, next+1, end, ",", false) {
+				if typ := sqlParamType(code, ts[part[0]:part[1]]); typ != "" {
+					a.paramTypes = append(a.paramTypes, typ)
+				}
+			}
+			next = end + 1
+			sigEnd = next
+		}
+	}
+	bodyStart := next
+	for j := next; j < len(ts); j++ {
+		word := strings.ToUpper(ts[j].text)
+		if ts[j].kind == cTokIdent && (word == "RETURNS" || word == "RETURN") && j == next {
+			k := j + 1
+			for k < len(ts) && !sqlRoutineClause(ts[k]) {
+				if ts[k].text == "(" {
+					if end := matchingClose(ts, k); end > k {
+						k = end
+					}
+				}
+				k++
+			}
+			if typ := tokenSpanText(code, ts, j+1, k); typ != "" {
+				a.returnTypes = []string{typ}
+			}
+			sigEnd = k
+			bodyStart = k
+			break
+		}
+	}
+	a.signature = tokenSpanText(code, ts, 0, sigEnd)
+
+	for j := bodyStart; j < len(ts); j++ {
+		if ts[j].kind == cTokString && strings.HasPrefix(ts[j].text, "$") {
+			tag := sqlDollarTag([]byte(ts[j].text), 0)
+			body := strings.TrimSuffix(strings.TrimPrefix(ts[j].text, tag), tag)
+			inner, _, _ := lexSQL([]byte(body))
+			a.tables = appendAllUnique(a.tables, sqlTableRefs(inner)...)
+			a.columns = appendAllUnique(a.columns, sqlColumnRefs(inner)...)
+		}
+	}
+	a.tables = appendAllUnique(a.tables, sqlTableRefs(ts[bodyStart:])...)
+	a.columns = appendAllUnique(a.columns, sqlColumnRefs(ts[bodyStart:])...)
+	return a
+}
+
+// sqlRoutineClause reports whether tok starts a routine attribute or body
+// after the return type.
+func sqlRoutineClause(tok cToken) bool {
+	if tok.kind == cTokString {
+		return true
+	}
+	switch strings.ToUpper(tok.text) {
+	case "LANGUAGE", "AS", "IS", "BEGIN", "IMMUTABLE", "STABLE", "VOLATILE", "DETERMINISTIC",
+		"NOT", "CONTAINS", "READS", "MODIFIES", "SECURITY", "COST", "ROWS", "PARALLEL",
+		"STRICT", "CALLED", "SET", "WITH", "COMMENT", "NO", "LEAKPROOF", "SUPPORT", "WINDOW", "RETURN":
+		return true
+	}
+	return false
+}
+
+// sqlParamType returns the type of a routine parameter: "IN id integer" and
+// "@id INT" yield the type, a lone type stays as is.
+func sqlParamType(code []byte, param []cToken) string {
+	i := sqlSkipWords(param, 0, "IN", "OUT", "INOUT", "VARIADIC")
+	end := len(param)
+	for j := i; j < len(param); j++ {
+		if strings.EqualFold(param[j].text, "DEFAULT") || param[j].text == "=" {
+			end = j
+			break
+		}
+	}
+	if end-i >= 2 {
+		i++
+	}
+	return tokenSpanText(code, param, i, end)
+}
+
+func analyzeSQLAlterTable(ts []cToken) *sqlAnalysis {
+	i := sqlSkipWords(ts, 2, "IF", "EXISTS", "ONLY")
+	name, next := sqlName(ts, i)
+	if name == "" {
+		return nil
+	}
+	a := &sqlAnalysis{name: name, nodeType: "alter_table", tables: []string{name}}
+	a.signature = "ALTER TABLE " + name
+	for _, part := range splitTokensTopLevel(ts, next, len(ts), ",", false) {
+		action := ts[part[0]:part[1]]
+		if len(action) == 0 {
+			continue
+		}
+		verb := strings.ToUpper(action[0].text)
+		j := 1
+		switch verb {
+		case "ADD", "DROP", "ALTER", "MODIFY", "CHANGE":
+			j = sqlSkipWords(action, j, "COLUMN")
+			j = sqlSkipWords(action, j, "IF", "NOT", "EXISTS")
+			if j < len(action) && sqlConstraintWords[strings.ToUpper(action[j].text)] {
+				// ADD CONSTRAINT name UNIQUE (a, b) / FOREIGN KEY (a) REFERENCES t (b)
+				for k := j; k < len(action); k++ {
+					if action[k].text == "(" {
+						if end := matchingClose(action, k); end > k {
+							for _, col := range splitTokensTopLevel(action, k+1, end, ",", false) {
+								for _, c := range sqlExpressionColumns(action[col[0]:col[1]]) {
+									a.columns = appendUnique(a.columns, c)
+								}
+							}
+						}
+						break
+					}
+				}
+			} else if col, _ := sqlName(action, j); col != "" {
+				a.columns = appendUnique(a.columns, col)
+				// MySQL CHANGE old new
+				if verb == "CHANGE" {
+					if renamed, _ := sqlName(action, j+1); renamed != "" && !sqlKeywords[strings.ToUpper(renamed)] {
+						a.columns = appendUnique(a.columns, renamed)
+					}
+				}
+			}
+		case "RENAME":
+			j = sqlSkipWords(action, j, "COLUMN")
+			if j < len(action) && strings.EqualFold(action[j].text, "TO") {
+				if renamed, _ := sqlName(action, j+1); renamed != "" {
+					a.tables = appendUnique(a.tables, renamed)
+				}
+				continue
+			}
+			if col, k := sqlName(action, j); col != "" {
+				a.columns = appendUnique(a.columns, col)
+				if k < len(action) && strings.EqualFold(action[k].text, "TO") {
+					if renamed, _ := sqlName(action, k+1); renamed != "" {
+						a.columns = appendUnique(a.columns, renamed)
+					}
+				}
+			}
+		}
+		a.tables = appendAllUnique(a.tables, sqlReferencedTables(action)...)
+	}
+	return a
+}
+
+// sqlSkipWords skips any of the given (case-insensitive) words from i.
+func sqlSkipWords(ts []cToken, i int, words ...string) int {
+	for i < len(ts) && ts[i].kind == cTokIdent && slices.ContainsFunc(words, func(w string) bool {
+		return strings.EqualFold(ts[i].text, w)
+	}) {
+		i++
+	}
+	return i
+}
+
+// sqlName reads a possibly qualified, possibly quoted name ("public.users",
+// `db`.`t`, [dbo].[Users]) at ts[i] and returns it without quotes along
+// with the index after it.
+func sqlName(ts []cToken, i int) (string, int) {
+	var parts []string
+	for i < len(ts) && ts[i].kind == cTokIdent {
+		parts = append(parts, sqlUnquote(ts[i].text))
+		i++
+		if i+1 < len(ts) && ts[i].text == "." && ts[i+1].kind == cTokIdent {
+			i++
+			continue
+		}
+		break
+	}
+	return strings.Join(parts, "."), i
+}
+
+func sqlUnquote(ident string) string {
+	if len(ident) >= 2 {
+		switch {
+		case ident[0] == '"' && ident[len(ident)-1] == '"',
+			ident[0] == '`' && ident[len(ident)-1] == '`',
+			ident[0] == '[' && ident[len(ident)-1] == ']':
+			return ident[1 : len(ident)-1]
+		}
+	}
+	return ident
+}
+
+// sqlReferencedTables returns the tables named after REFERENCES.
+func sqlReferencedTables(ts []cToken) []string {
+	var tables []string
+	for i := range ts {
+		if strings.EqualFold(ts[i].text, "REFERENCES") {
+			if table, _ := sqlName(ts, i+1); table != "" {
+				tables = appendUnique(tables, table)
+			}
+		}
+	}
+	return tables
+}
+
+// sqlExpressionColumns returns the columns referenced by an index key or
+// constraint element: a plain column or the identifiers of an expression
+// that are not function names or keywords.
+func sqlExpressionColumns(ts []cToken) []string {
+	var cols []string
+	for i := 0; i < len(ts); i++ {
+		if ts[i].kind != cTokIdent {
+			continue
+		}
+		name, next := sqlName(ts, i)
+		if next < len(ts) && ts[next].text == "(" || sqlKeywords[strings.ToUpper(name)] || i > 0 && ts[i-1].text == "::" {
+			i = next - 1
+			continue
+		}
+		if dot := strings.LastIndex(name, "."); dot >= 0 {
+			name = name[dot+1:]
+		}
+		switch strings.ToUpper(name) {
+		case "ASC", "DESC", "NULLS", "FIRST", "LAST", "COLLATE", "INCLUDE":
+		default:
+			cols = appendUnique(cols, name)
+		}
+		i = next - 1
+	}
+	return cols
+}
+
+// sqlTableRefs returns the tables a statement reads or writes: names after
+// FROM, JOIN, INTO, UPDATE, TABLE and REFERENCES, excluding common table
+// expressions, subqueries and FROM inside function calls such as
+// EXTRACT(YEAR FROM ts).
+func sqlTableRefs(ts []cToken) []string {
+	ctes := map[string]bool{}
+	for i := 1; i+2 < len(ts); i++ {
+		if ts[i].kind == cTokIdent && strings.EqualFold(ts[i+1].text, "AS") && ts[i+2].text == "(" {
+			switch strings.ToUpper(ts[i-1].text) {
+			case "WITH", "RECURSIVE", ",":
+				ctes[strings.ToLower(sqlUnquote(ts[i].text))] = true
+			}
+		}
+	}
+
+	var tables []string
+	var callParens []bool
+	for i := 0; i < len(ts); i++ {
+		tok := ts[i]
+		switch tok.text {
+		case "(":
+			call := i > 0 && ts[i-1].kind == cTokIdent && !sqlKeywords[strings.ToUpper(ts[i-1].text)]
+			callParens = append(callParens, call)
+			continue
+		case ")":
+			if len(callParens) > 0 {
+				callParens = callParens[:len(callParens)-1]
+			}
+			continue
+		}
+		if tok.kind != cTokIdent || len(callParens) > 0 && callParens[len(callParens)-1] {
+			continue
+		}
+		word := strings.ToUpper(tok.text)
+		switch word {
+		case "FROM", "JOIN", "INTO", "UPDATE", "TABLE", "REFERENCES":
+		default:
+			continue
+		}
+		if word == "UPDATE" && i > 0 {
+			switch strings.ToUpper(ts[i-1].text) {
+			case "ON", "FOR", "DO":
+				continue
+			}
+		}
+		if word == "INTO" && !sqlInsertInto(ts, i) {
+			continue
+		}
+		j := sqlSkipWords(ts, i+1, "ONLY", "LATERAL", "IF", "NOT", "EXISTS")
+		for {
+			name, next := sqlName(ts, j)
+			if name == "" || sqlKeywords[strings.ToUpper(name)] || strings.HasPrefix(name, "@") || strings.HasPrefix(name, ":") || strings.HasPrefix(name, "$") {
+				break
+			}
+			if !ctes[strings.ToLower(name)] {
+				tables = appendUnique(tables, name)
+			}
+			if word != "FROM" && word != "TABLE" {
+				break
+			}
+			// FROM a x, b AS y / DROP TABLE a, b
+			next = sqlSkipWords(ts, next, "AS")
+			if next < len(ts) && ts[next].kind == cTokIdent && !sqlKeywords[strings.ToUpper(ts[next].text)] {
+				next++
+			}
+			if next >= len(ts) || ts[next].text != "," {
+				break
+			}
+			j = next + 1
+		}
+	}
+	return tables
+}
+
+// sqlInsertInto reports whether the INTO at ts[i] names the target table of
+// an INSERT, REPLACE or MERGE rather than the variables of SELECT ... INTO.
+func sqlInsertInto(ts []cToken, i int) bool {
+	if i == 0 {
+		return false
+	}
+	switch strings.ToUpper(ts[i-1].text) {
+	case "INSERT", "REPLACE", "MERGE", "IGNORE", "LOW_PRIORITY", "DELAYED", "HIGH_PRIORITY":
+		return true
+	}
+	return false
+}
+
+// sqlColumnRefs returns the columns a DML statement names: INSERT column
+// lists, UPDATE ... SET targets, plain select-list items, RETURNING lists and
+// the left-hand side of WHERE/ON comparisons.
+func sqlColumnRefs(ts []cToken) []string {
+	var cols []string
+	add := func(name string) {
+		if dot := strings.LastIndex(name, "."); dot >= 0 {
+			name = name[dot+1:]
+		}
+		if name != "" && name != "*" && !sqlKeywords[strings.ToUpper(name)] && !strings.ContainsAny(name[:1], "@:$") {
+			cols = appendUnique(cols, name)
+		}
+	}
+	for i := 0; i < len(ts); i++ {
+		if ts[i].kind != cTokIdent {
+			continue
+		}
+		switch strings.ToUpper(ts[i].text) {
+		case "INTO":
+			if !sqlInsertInto(ts, i) {
+				continue
+			}
+			_, next := sqlName(ts, i+1)
+			if next > i+1 && next < len(ts) && ts[next].text == "(" {
+				if end := matchingClose(ts, next); end > next {
+					for _, part := range splitTokensTopLevel(ts, next+1, end, ",", false) {
+						if col, k := sqlName(ts, part[0]); k == part[1] {
+							add(col)
+						}
+					}
+				}
+			}
+		case "SET":
+			for _, part := range sqlClauseItems(ts, i+1) {
+				if col, k := sqlName(ts, part[0]); k < part[1] && ts[k].text == "=" {
+					add(col)
+				}
+			}
+		case "SELECT", "RETURNING":
+			for _, part := range sqlClauseItems(ts, i+1) {
+				j := sqlSkipWords(ts, part[0], "DISTINCT")
+				col, k := sqlName(ts, j)
+				if col == "" {
+					continue
+				}
+				if k == part[1] || strings.EqualFold(ts[k].text, "AS") || k+1 == part[1] && ts[k].kind == cTokIdent {
+					add(col)
+				}
+			}
+		case "WHERE", "AND", "OR", "ON":
+			col, k := sqlName(ts, sqlSkipWords(ts, i+1, "NOT"))
+			if col == "" || k >= len(ts) {
+				continue
+			}
+			switch strings.ToUpper(ts[k].text) {
+			case "=", "<", ">", "<=", ">=", "<>", "!=", "IN", "LIKE", "ILIKE", "IS", "BETWEEN", "NOT":
+				add(col)
+			}
+		}
+	}
+	return cols
+}
+
+// sqlClauseItems splits the comma-separated items of a clause starting at i,
+// up to the next clause keyword or closing bracket at the same depth.
+func sqlClauseItems(ts []cToken, i int) [][2]int {
+	end := i
+	depth := 0
+scan:
+	for ; end < len(ts); end++ {
+		switch ts[end].text {
+		case "(", "[":
+			depth++
+		case ")", "]":
+			if depth == 0 {
+				break scan
+			}
+			depth--
+		}
+		if depth == 0 && ts[end].kind == cTokIdent {
+			switch strings.ToUpper(ts[end].text) {
+			case "FROM", "WHERE", "INTO", "RETURNING", "ORDER", "GROUP", "HAVING", "LIMIT",
+				"UNION", "EXCEPT", "INTERSECT", "ON", "WINDOW", "OFFSET", "FOR":
+				break scan
+			}
+		}
+	}
+	return splitTokensTopLevel(ts, i, end, ",", false)
+}
+
+// sqlLiteralRegex recognizes the start of a SQL statement embedded in a
+// string literal.
+var sqlLiteralRegex = regexp.MustCompile(`(?is)^\s*(?:` +
+	`select\s.+\bfrom\b|` +
+	`insert\s+(?:ignore\s+)?into\s|` +
+	`replace\s+into\s|` +
+	`update\s+\S+\s+set\s|` +
+	`delete\s+from\s|` +
+	`with\s+(?:recursive\s+)?\w+\s+as\s*\(|` +
+	`merge\s+into\s|` +
+	`create\s+(?:\w+\s+){0,3}(?:table|index|view)\s|` +
+	`alter\s+table\s|` +
+	`drop\s+(?:table|index|view)\s|` +
+	`truncate\s)`)
+
+// sqlLiteralHintRegex matches SQL syntax that prose does not have; it tells
+// "SELECT id FROM users" apart from "Select the option from below".
+var sqlLiteralHintRegex = regexp.MustCompile(`(?i)[*=?$,(;]|\b(?:where|join|group\s+by|order\s+by|limit|values|set)\b`)
+
+// isSQLLiteral reports whether a string literal holds a SQL statement: it
+// starts like one and either uses an upper-case verb or SQL punctuation
+// and clauses.
+func isSQLLiteral(s string) bool {
+	if !sqlLiteralRegex.MatchString(s) {
+		return false
+	}
+	verb := strings.Fields(s)[0]
+	return verb == strings.ToUpper(verb) || sqlLiteralHintRegex.MatchString(s)
+}
+
+// sqlQueryRefs returns the tables and columns referenced by SQL statements.
+func sqlQueryRefs(queries []string) ([]string, []string) {
+	var tables, columns []string
+	for _, q := range queries {
+		tokens, _, _ := lexSQL([]byte(q))
+		tables = appendAllUnique(tables, sqlTableRefs(tokens)...)
+		columns = appendAllUnique(columns, sqlColumnRefs(tokens)...)
+	}
+	return tables, columns
+}
@@ -0,0 +1,469 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// tsNamespaceMemberRegex matches a declaration at the start of a line inside
+// a namespace body and captures its name.
+var tsNamespaceMemberRegex = regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?(?:default\s+)?(?:abstract\s+)?(?:async\s+)?(?:const\s+enum|const|let|var|function\*?|class|interface|type|enum|namespace|module)\s+([A-Za-z_$][\w$]*)`)
+
+// tryMatchTypeDeclaration captures TypeScript interface, type alias, enum and
+// namespace declarations as first-class chunks. Namespace bodies are scanned
+// further so functions and types declared inside them are extracted too.
+func (e *jsFunctionExtractor) tryMatchTypeDeclaration() bool {
+	saved := e.pos
+	keywordStart := e.pos
+	var kind string
+	switch {
+	case e.matchKeyword("interface") >= 0:
+		kind = "interface"
+	case e.matchKeyword("type") >= 0:
+		kind = "type_alias"
+	case e.matchKeyword("enum") >= 0 || e.matchConstEnum():
+		kind = "enum"
+	case e.matchAnyKeyword("namespace", "module") >= 0:
+		kind = "namespace"
+	default:
+		return false
+	}
+
+	e.skipWhitespaceComments()
+	name, ok := e.readDeclarationName(kind == "namespace")
+	if !ok {
+		e.pos = saved
+		return false
+	}
+
+	start, exported := e.declarationModifiers(keywordStart)
+
+	e.skipWhitespaceComments()
+	var typeParams []string
+	if kind != "namespace" && e.pos < len(e.code) && e.code[e.pos] == '<' {
+		end := e.scanTypeParameters(e.pos)
+		if end < 0 {
+			e.pos = saved
+			return false
+		}
+		typeParams = splitTypeParameters(string(e.code[e.pos+1 : end-1]))
+		e.pos = end
+		e.skipWhitespaceComments()
+	}
+
+	switch kind {
+	case "type_alias":
+		return e.captureTypeAlias(name, start, keywordStart, exported, typeParams, saved)
+	default:
+		return e.captureBracedDeclaration(kind, name, start, keywordStart, exported, typeParams, saved)
+	}
+}
+
+// matchConstEnum matches "const enum", leaving the position unchanged when
+// "const" starts an ordinary variable declaration.
+func (e *jsFunctionExtractor) matchConstEnum() bool {
+	saved := e.pos
+	if e.matchKeyword("const") >= 0 {
+		e.skipWhitespaceComments()
+		if e.matchKeyword("enum") >= 0 {
+			return true
+		}
+	}
+	e.pos = saved
+	return false
+}
+
+// captureBracedDeclaration handles interface, enum and namespace declarations,
+// all of which carry a "{ ... }" body after an optional heritage clause.
+func (e *jsFunctionExtractor) captureBracedDeclaration(kind, name string, start, keywordStart int, exported bool, typeParams []string, saved int) bool {
+	for e.pos < len(e.code) && e.code[e.pos] != '{' {
+		ch := e.code[e.pos]
+		// Only "extends A, B<C>" may appear between the name and the body.
+		if ch == ';' || ch == '=' || ch == '(' || ch == '}' {
+			e.pos = saved
+			return false
+		}
+		if ch == '<' {
+			end := e.scanTypeParameters(e.pos)
+			if end < 0 {
+				break
+			}
+			e.pos = end
+			continue
+		}
+		e.pos++
+	}
+	if e.pos >= len(e.code) {
+		e.pos = saved
+		return false
+	}
+	headerEnd := e.pos
+	bodyStart := e.pos
+	bodyEnd := e.scanBalanced(bodyStart, '{', '}')
+	if bodyEnd < 0 {
+		e.pos = saved
+		return false
+	}
+
+	body := string(e.code[bodyStart+1 : bodyEnd-1])
+	var members []string
+	switch kind {
+	case "namespace":
+		members = namespaceMemberNames(body)
+	default:
+		members = splitTSMembers(body)
+	}
+
+	signature := collapseWhitespace(string(e.code[keywordStart:headerEnd]))
+	e.appendDeclaration(name, kind, start, bodyEnd, signature, exported, typeParams, members)
+
+	if kind == "namespace" {
+		// Keep scanning inside the namespace for nested declarations.
+		e.pos = bodyStart + 1
+	} else {
+		e.pos = bodyEnd
+	}
+	return true
+}
+
+func (e *jsFunctionExtractor) captureTypeAlias(name string, start, keywordStart int, exported bool, typeParams []string, saved int) bool {
+	if e.pos >= len(e.code) || e.code[e.pos] != '=' {
+		e.pos = saved
+		return false
+	}
+	signature := collapseWhitespace(string(e.code[keywordStart:e.pos]))
+	e.pos++
+	valueStart := e.pos
+	end := e.scanTypeAliasEnd(valueStart)
+	value := strings.TrimSpace(string(e.code[valueStart:end]))
+	value = strings.TrimSuffix(value, ";")
+
+	var members []string
+	trimmed := strings.TrimSpace(value)
+	if strings.HasPrefix(trimmed, "{") && strings.HasSuffix(trimmed, "}") {
+		members = splitTSMembers(trimmed[1 : len(trimmed)-1])
+	} else if parts := splitTopLevel(trimmed, '|'); len(parts) > 1 {
+		members = parts
+	}
+
+	e.appendDeclaration(name, "type_alias", start, end, signature, exported, typeParams, members)
+	e.pos = end
+	return true
+}
+
+// scanTypeAliasEnd finds the end of a type alias value: a top-level ';', or a
+// line break that is not followed by a continuation such as "| 'b'".
+func (e *jsFunctionExtractor) scanTypeAliasEnd(pos int) int {
+	depth := 0
+	for pos < len(e.code) {
+		ch := e.code[pos]
+		switch ch {
+		case '"', '\'':
+			pos = skipStringLiteralFrom(e.code, pos)
+			continue
+		case '`':
+			pos = skipTemplateLiteralFrom(e.code, pos)
+			continue
+		case '/':
+			if next := skipCommentFrom(e.code, pos); next != pos {
+				pos = next
+				continue
+			}
+		case '(', '[', '{', '<':
+			depth++
+		case ')', ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		case '>':
+			if depth > 0 && (pos == 0 || e.code[pos-1] != '=') {
+				depth--
+			}
+		case ';':
+			if depth == 0 {
+				return pos + 1
+			}
+		case '\n':
+			if depth == 0 && !e.typeContinuesAfter(pos) {
+				return pos
+			}
+		}
+		pos++
+	}
+	return pos
+}
+
+// typeContinuesAfter reports whether the type expression broken at the
+// newline at pos continues on the next line.
+func (e *jsFunctionExtractor) typeContinuesAfter(pos int) bool {
+	before := strings.TrimRight(string(e.code[:pos]), " \t\r")
+	if before == "" {
+		return false
+	}
+	switch before[len(before)-1] {
+	case '=', '|', '&', ',', ':', '?', '(', '<', '{', '[':
+		return true
+	case '>':
+		if strings.HasSuffix(before, "=>") {
+			return true
+		}
+	}
+	rest := strings.TrimLeft(string(e.code[pos+1:]), " \t\r\n")
+	if rest == "" {
+		return false
+	}
+	switch rest[0] {
+	case '|', '&', '?', ':', '.':
+		return true
+	}
+	return strings.HasPrefix(rest, "=>") || strings.HasPrefix(rest, "extends ")
+}
+
+func (e *jsFunctionExtractor) appendDeclaration(name, nodeType string, start, end int, signature string, exported bool, typeParams, members []string) {
+	if end <= start {
+		return
+	}
+	e.functions = append(e.functions, FunctionNode{
+		Name:       name,
+		NodeType:   nodeType,
+		StartLine:  e.lineForOffset(start),
+		EndLine:    e.lineForOffset(end - 1),
+		Content:    string(e.code[start:end]),
+		StartByte:  start,
+		EndByte:    end,
+		Imports:    append([]string(nil), e.imports...),
+		Signature:  signature,
+		Doc:        e.extractDocComment(start),
+		Members:    members,
+		TypeParams: typeParams,
+		Exported:   exported,
+	})
+}
+
+// readDeclarationName reads a possibly dotted declaration name. Ambient
+// module declarations may use a string literal ("declare module 'x'").
+func (e *jsFunctionExtractor) readDeclarationName(allowDotted bool) (string, bool) {
+	if allowDotted && e.pos < len(e.code) && (e.code[e.pos] == '"' || e.code[e.pos] == '\'') {
+		end := skipStringLiteralFrom(e.code, e.pos)
+		name := string(e.code[e.pos+1 : end-1])
+		e.pos = end
+		return name, name != ""
+	}
+	name, ok := e.readIdentifier()
+	if !ok {
+		return "", false
+	}
+	for allowDotted && e.pos+1 < len(e.code) && e.code[e.pos] == '.' && isIdentifierStart(e.code[e.pos+1]) {
+		e.pos++
+		part, _ := e.readIdentifier()
+		name += "." + part
+	}
+	return name, true
+}
+
+// declarationModifiers walks backwards from a declaration keyword over
+// modifiers such as "export", "declare" and "default". It returns the offset
+// where the declaration (including its modifiers) starts and whether it is
+// exported.
+func (e *jsFunctionExtractor) declarationModifiers(keywordStart int) (int, bool) {
+	start := keywordStart
+	exported := false
+	for {
+		found := false
+		for _, mod := range []string{"export", "declare", "default", "abstract"} {
+			if modStart, ok := e.precedingKeyword(start, mod); ok {
+				start = modStart
+				if mod == "export" {
+					exported = true
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return start, exported
+		}
+	}
+}
+
+// precedingKeyword reports whether word appears immediately before pos,
+// separated only by horizontal whitespace, and returns its offset.
+func (e *jsFunctionExtractor) precedingKeyword(pos int, word string) (int, bool) {
+	i := pos
+	for i > 0 && (e.code[i-1] == ' ' || e.code[i-1] == '\t') {
+		i--
+	}
+	wordStart := i - len(word)
+	if wordStart < 0 || string(e.code[wordStart:i]) != word {
+		return 0, false
+	}
+	if !e.keywordBoundaryBefore(wordStart) {
+		return 0, false
+	}
+	return wordStart, true
+}
+
+// scanTypeParameters returns the offset just past the '>' matching the '<'
+// at start, or -1. Arrow tokens ("=>") inside defaults are not closers.
+func (e *jsFunctionExtractor) scanTypeParameters(start int) int {
+	depth := 0
+	for pos := start; pos < len(e.code); pos++ {
+		switch e.code[pos] {
+		case '<':
+			depth++
+		case '>':
+			if pos > 0 && e.code[pos-1] == '=' {
+				continue
+			}
+			depth--
+			if depth == 0 {
+				return pos + 1
+			}
+		case ';':
+			return -1
+		case '"', '\'':
+			pos = skipStringLiteralFrom(e.code, pos) - 1
+		}
+	}
+	return -1
+}
+
+func splitTypeParameters(params string) []string {
+	return splitTopLevel(params, ',')
+}
+
+// splitTSMembers splits the body of an interface, object type or enum into
+// its members, one per top-level ';', ',' or line break.
+func splitTSMembers(body string) []string {
+	var members []string
+	var current strings.Builder
+	depth := 0
+	flush := func() {
+		if m := collapseWhitespace(current.String()); m != "" {
+			members = append(members, m)
+		}
+		current.Reset()
+	}
+	code := []byte(body)
+	for i := 0; i < len(code); i++ {
+		ch := code[i]
+		switch ch {
+		case '"', '\'':
+			end := skipStringLiteralFrom(code, i)
+			current.Write(code[i:end])
+			i = end - 1
+			continue
+		case '`':
+			end := skipTemplateLiteralFrom(code, i)
+			current.Write(code[i:end])
+			i = end - 1
+			continue
+		case '/':
+			if next := skipCommentFrom(code, i); next != i {
+				i = next - 1
+				continue
+			}
+		case '(', '[', '{', '<':
+			depth++
+		case ')', ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		case '>':
+			if depth > 0 && (i == 0 || code[i-1] != '=') {
+				depth--
+			}
+		case ';', ',', '\n':
+			if depth == 0 {
+				flush()
+				continue
+			}
+		}
+		current.WriteByte(ch)
+	}
+	flush()
+	return members
+}
+
+// splitTopLevel splits s on sep occurring outside brackets and strings.
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth := 0
+	last := 0
+	code := []byte(s)
+	for i := 0; i < len(code); i++ {
+		ch := code[i]
+		switch ch {
+		case '"', '\'':
+			i = skipStringLiteralFrom(code, i) - 1
+		case '`':
+			i = skipTemplateLiteralFrom(code, i) - 1
+		case '(', '[', '{', '<':
+			depth++
+		case ')', ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		case '>':
+			if depth > 0 && (i == 0 || code[i-1] != '=') {
+				depth--
+			}
+		default:
+			if ch == sep && depth == 0 {
+				if part := collapseWhitespace(s[last:i]); part != "" {
+					parts = append(parts, part)
+				}
+				last = i + 1
+			}
+		}
+	}
+	if part := collapseWhitespace(s[last:]); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// namespaceMemberNames lists the names declared directly inside a namespace
+// body, ignoring declarations nested in functions or classes.
+func namespaceMemberNames(body string) []string {
+	var names []string
+	code := []byte(body)
+	depth := 0
+	checkLine := func(from int) {
+		end := from
+		for end < len(code) && code[end] != '\n' {
+			end++
+		}
+		if m := tsNamespaceMemberRegex.FindSubmatch(code[from:end]); m != nil {
+			names = append(names, string(m[1]))
+		}
+	}
+	checkLine(0)
+	for i := 0; i < len(code); i++ {
+		switch code[i] {
+		case '"', '\'':
+			i = skipStringLiteralFrom(code, i) - 1
+		case '`':
+			i = skipTemplateLiteralFrom(code, i) - 1
+		case '/':
+			if next := skipCommentFrom(code, i); next != i {
+				i = next - 1
+			}
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+			}
+		case '\n':
+			if depth == 0 {
+				checkLine(i + 1)
+			}
+		}
+	}
+	return names
+}
+
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
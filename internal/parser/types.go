@@ -2,22 +2,42 @@ package parser
 
 // FunctionNode represents a parsed function or method from source code
 type FunctionNode struct {
-	Name           string   // Function/method name
-	NodeType       string   // "function", "method", "class", etc.
-	StartLine      int      // Starting line number (1-indexed)
-	EndLine        int      // Ending line number (1-indexed)
-	Content        string   // Full source code of the function
-	StartByte      int      // Starting byte offset in file
-	EndByte        int      // Ending byte offset in file
-	PackageName    string   // Declaring package
-	Imports        []string // File-level imports
-	Signature      string   // Fully formatted function signature
-	Receiver       string   // Method receiver type (if any)
-	Doc            string   // Associated doc comment
-	Callees        []string // Direct callees referenced inside the body
-	ParamTypes     []string // Parameter types
-	ReturnTypes    []string // Return value types
-	HasErrorReturn bool     // Whether function returns an error
+	Name            string   // Function/method name
+	NodeType        string   // "function", "method", "class", etc.
+	StartLine       int      // Starting line number (1-indexed)
+	EndLine         int      // Ending line number (1-indexed)
+	Content         string   // Full source code of the function
+	StartByte       int      // Starting byte offset in file
+	EndByte         int      // Ending byte offset in file
+	PackageName     string   // Declaring package
+	Imports         []string // File-level imports
+	ResolvedImports []string // Absolute paths of project files the imports resolve to
+	Signature       string   // Fully formatted function signature
+	Receiver        string   // Method receiver type (if any)
+	Doc             string   // Associated doc comment
+	Callees         []string // Direct callees referenced inside the body
+	ParamTypes      []string // Parameter types
+	ReturnTypes     []string // Return value types
+	HasErrorReturn  bool     // Whether function returns an error
+	Decorators      []string // Decorator expressions applied to the definition
+	IsAsync         bool     // Whether the function is declared async
+	IsGenerator     bool     // Whether the function body yields
+	Raises          []string // Exception types raised in the body
+	Members         []string // Members of a type-level declaration (fields, enum values, ...)
+	TypeParams      []string // Generic type parameters
+	Exported        bool     // Whether the declaration is exported from its module
+	PropsType       string   // Props type of a UI component
+	Hooks           []string // Hooks or composables (use*) called by a component
+	JSXElements     []string // JSX elements rendered by a component
+	PropNames       []string // Props declared by a UI component
+	Emits           []string // Events emitted by a UI component
+	HeadingPath     string   // Heading hierarchy of a documentation section ("README > Configuration")
+	CodeLanguages   []string // Languages of the fenced code blocks in a documentation section
+	Tables          []string // Database tables a statement or function touches
+	Columns         []string // Database columns a statement or function touches
+	SQLQueries      []string // SQL statements embedded as string literals
+	Options         []string // Options declared on a definition (protobuf)
+	CellIndex       int      // 1-based notebook cell the chunk comes from (0 outside notebooks)
 }
 
 // LanguageParser defines the interface for language-specific parsers
@@ -37,4 +57,18 @@ const (
 	LanguagePython     Language = "python"
 	LanguageJavaScript Language = "javascript"
 	LanguageTypeScript Language = "typescript"
+	LanguageRust       Language = "rust"
+	LanguageJava       Language = "java"
+	LanguageKotlin     Language = "kotlin"
+	LanguageC          Language = "c"
+	LanguageCPP        Language = "cpp"
+	LanguageCSharp     Language = "csharp"
+	LanguageRuby       Language = "ruby"
+	LanguagePHP        Language = "php"
+	LanguageVue        Language = "vue"
+	LanguageSvelte     Language = "svelte"
+	LanguageMarkdown   Language = "markdown"
+	LanguageSQL        Language = "sql"
+	LanguageProto      Language = "proto"
+	LanguageNotebook   Language = "notebook"
 )
@@ -15,6 +15,7 @@ func (p *TypeScriptParser) Language() string {
 
 // ExtractFunctions extracts function, method, and arrow function definitions from TypeScript source code.
 func (p *TypeScriptParser) ExtractFunctions(filePath string, code []byte) ([]FunctionNode, error) {
-	functions := extractJSFunctions(code, true)
+	functions := extractJSFunctions(code, true, isJSXFile(filePath))
+	setResolvedImports(filePath, functions)
 	return functions, nil
 }
@@ -124,9 +124,7 @@ func (c *Client) Close() error {
 	return c.grpcConn.Close()
 }
 
-func (c *Client) EnsureCollection(name string, vectorSize uint64) error {
-	ctx := context.Background()
-
+func (c *Client) EnsureCollection(ctx context.Context, name string, vectorSize uint64) error {
 	info, err := c.collections.Get(ctx, &qdrant.GetCollectionInfoRequest{
 		CollectionName: name,
 	})
@@ -169,16 +167,38 @@ func (c *Client) EnsureCollection(name string, vectorSize uint64) error {
 }
 
 // DeleteCollection removes the entire collection and all its points from Qdrant.
-func (c *Client) DeleteCollection(name string) error {
-	ctx := context.Background()
+func (c *Client) DeleteCollection(ctx context.Context, name string) error {
 	_, err := c.collections.Delete(ctx, &qdrant.DeleteCollection{
 		CollectionName: name,
 	})
 	return err
 }
 
-func (c *Client) Upsert(collectionName string, points []*qdrant.PointStruct) error {
-	ctx := context.Background()
+// CollectionExists reports whether a collection has been created.
+func (c *Client) CollectionExists(ctx context.Context, name string) (bool, error) {
+	resp, err := c.collections.CollectionExists(ctx, &qdrant.CollectionExistsRequest{
+		CollectionName: name,
+	})
+	if err != nil {
+		return false, err
+	}
+	return resp.GetResult().GetExists(), nil
+}
+
+// Count returns the exact number of points in a collection.
+func (c *Client) Count(ctx context.Context, collectionName string) (uint64, error) {
+	exact := true
+	resp, err := c.client.Count(ctx, &qdrant.CountPoints{
+		CollectionName: collectionName,
+		Exact:          &exact,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.GetResult().GetCount(), nil
+}
+
+func (c *Client) Upsert(ctx context.Context, collectionName string, points []*qdrant.PointStruct) error {
 	wait := true
 
 	// Split into batches to avoid hitting gRPC message size limits or timeouts
@@ -197,7 +217,11 @@ func (c *Client) Upsert(collectionName string, points []*qdrant.PointStruct) err
 		
 		for attempt := 0; attempt < maxRetries; attempt++ {
 			if attempt > 0 {
-				time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
+				select {
+				case <-time.After(time.Duration(attempt) * 500 * time.Millisecond):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
 			}
 			
 			_, lastErr = c.client.Upsert(ctx, &qdrant.UpsertPoints{
@@ -226,22 +250,31 @@ func (c *Client) Upsert(collectionName string, points []*qdrant.PointStruct) err
 	return nil
 }
 
-func (c *Client) Search(collectionName string, vector []float32, limit uint64) ([]*qdrant.ScoredPoint, error) {
-	ctx := context.Background()
-	
+func (c *Client) Search(ctx context.Context, collectionName string, vector []float32, limit uint64) ([]*qdrant.ScoredPoint, error) {
+	return c.SearchWithFilter(ctx, collectionName, vector, limit, nil)
+}
+
+// SearchWithFilter runs a similarity search restricted to the points whose
+// payload matches filter. A nil filter searches the whole collection.
+func (c *Client) SearchWithFilter(ctx context.Context, collectionName string, vector []float32, limit uint64, filter *qdrant.Filter) ([]*qdrant.ScoredPoint, error) {
 	var resp *qdrant.SearchResponse
 	var err error
 	const maxRetries = 3
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		if attempt > 0 {
-			time.Sleep(time.Duration(attempt) * 200 * time.Millisecond)
+			select {
+			case <-time.After(time.Duration(attempt) * 200 * time.Millisecond):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
 		}
 
 		resp, err = c.client.Search(ctx, &qdrant.SearchPoints{
 			CollectionName: collectionName,
 			Vector:         vector,
 			Limit:          limit,
+			Filter:         filter,
 			WithPayload:    &qdrant.WithPayloadSelector{SelectorOptions: &qdrant.WithPayloadSelector_Enable{Enable: true}},
 		})
 		
@@ -253,8 +286,7 @@ func (c *Client) Search(collectionName string, vector []float32, limit uint64) (
 	return nil, err
 }
 
-func (c *Client) Scroll(collectionName string, limit uint32, offset *qdrant.PointId) ([]*qdrant.RetrievedPoint, *qdrant.PointId, error) {
-	ctx := context.Background()
+func (c *Client) Scroll(ctx context.Context, collectionName string, limit uint32, offset *qdrant.PointId) ([]*qdrant.RetrievedPoint, *qdrant.PointId, error) {
 	resp, err := c.client.Scroll(ctx, &qdrant.ScrollPoints{
 		CollectionName: collectionName,
 		Limit:          &limit,
@@ -268,8 +300,28 @@ func (c *Client) Scroll(collectionName string, limit uint32, offset *qdrant.Poin
 	return resp.Result, resp.NextPageOffset, nil
 }
 
-func (c *Client) DeleteByFilter(collectionName string, filter *qdrant.Filter) error {
-	ctx := context.Background()
+// ScrollWithFilter pages through the points whose payload matches filter,
+// returning payloads only. When fields is non-empty, only those payload
+// fields are returned.
+func (c *Client) ScrollWithFilter(ctx context.Context, collectionName string, filter *qdrant.Filter, fields []string, limit uint32, offset *qdrant.PointId) ([]*qdrant.RetrievedPoint, *qdrant.PointId, error) {
+	withPayload := qdrant.NewWithPayload(true)
+	if len(fields) > 0 {
+		withPayload = qdrant.NewWithPayloadInclude(fields...)
+	}
+	resp, err := c.client.Scroll(ctx, &qdrant.ScrollPoints{
+		CollectionName: collectionName,
+		Filter:         filter,
+		Limit:          &limit,
+		Offset:         offset,
+		WithPayload:    withPayload,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Result, resp.NextPageOffset, nil
+}
+
+func (c *Client) DeleteByFilter(ctx context.Context, collectionName string, filter *qdrant.Filter) error {
 	_, err := c.client.Delete(ctx, &qdrant.DeletePoints{
 		CollectionName: collectionName,
 		Points: &qdrant.PointsSelector{
@@ -302,6 +354,12 @@ func valueToInterface(v *qdrant.Value) interface{} {
 		return val.DoubleValue
 	case *qdrant.Value_BoolValue:
 		return val.BoolValue
+	case *qdrant.Value_ListValue:
+		items := make([]interface{}, 0, len(val.ListValue.GetValues()))
+		for _, item := range val.ListValue.GetValues() {
+			items = append(items, valueToInterface(item))
+		}
+		return items
 	default:
 		return fmt.Sprintf("%v", v)
 	}
@@ -327,6 +385,14 @@ func interfaceToValue(i interface{}) *qdrant.Value {
 		return &qdrant.Value{Kind: &qdrant.Value_DoubleValue{DoubleValue: v}}
 	case bool:
 		return &qdrant.Value{Kind: &qdrant.Value_BoolValue{BoolValue: v}}
+	case []string:
+		// Store string slices as native lists so keyword filters match
+		// individual elements (e.g. a single decorator or callee).
+		values := make([]*qdrant.Value, 0, len(v))
+		for _, item := range v {
+			values = append(values, interfaceToValue(item))
+		}
+		return &qdrant.Value{Kind: &qdrant.Value_ListValue{ListValue: &qdrant.ListValue{Values: values}}}
 	default:
 		return &qdrant.Value{Kind: &qdrant.Value_StringValue{StringValue: fmt.Sprintf("%v", v)}}
 	}
@@ -22,12 +22,35 @@ var excludedDirs = map[string]bool{
 }
 
 var languageExts = map[string]string{
-	".go":  "go",
-	".py":  "python",
-	".ts":  "typescript",
-	".tsx": "typescript",
-	".js":  "javascript",
-	".jsx": "javascript",
+	".go":       "go",
+	".py":       "python",
+	".ts":       "typescript",
+	".tsx":      "typescript",
+	".js":       "javascript",
+	".jsx":      "javascript",
+	".rs":       "rust",
+	".java":     "java",
+	".kt":       "kotlin",
+	".kts":      "kotlin",
+	".c":        "c",
+	".h":        "c",
+	".cc":       "cpp",
+	".cpp":      "cpp",
+	".cxx":      "cpp",
+	".hh":       "cpp",
+	".hpp":      "cpp",
+	".hxx":      "cpp",
+	".cs":       "csharp",
+	".rb":       "ruby",
+	".rake":     "ruby",
+	".php":      "php",
+	".vue":      "vue",
+	".svelte":   "svelte",
+	".md":       "markdown",
+	".markdown": "markdown",
+	".sql":      "sql",
+	".proto":    "proto",
+	".ipynb":    "notebook",
 }
 
 func GetAllSourceFiles(rootPath string) ([]string, error) {
@@ -136,6 +159,23 @@ func ShouldSkipDir(relPath string, dirName string, patterns []string) bool {
 	return isIgnoredPath(relPath, patterns)
 }
 
+// IsSourceFile reports whether GetAllSourceFiles lists the file at relPath,
+// a path relative to the project root: it has a source extension, and
+// neither it nor a directory above it is excluded or ignored.
+func IsSourceFile(relPath string, patterns []string) bool {
+	relPath = filepath.ToSlash(relPath)
+	if _, ok := languageExts[filepath.Ext(relPath)]; !ok {
+		return false
+	}
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if ShouldSkipDir(strings.Join(parts[:i], "/"), parts[i-1], patterns) {
+			return false
+		}
+	}
+	return !isIgnoredPath(relPath, patterns)
+}
+
 // isIgnoredPath applies a minimal subset of .gitignore semantics suitable for
 // skipping heavy directories like node_modules/ and common file patterns. It
 // treats patterns as root-relative against the provided relPath.
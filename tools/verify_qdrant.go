@@ -3,6 +3,7 @@ package main
 import (
 	"codebase/internal/config"
 	"codebase/internal/qdrant"
+	"context"
 	"fmt"
 	"os"
 
@@ -29,7 +30,7 @@ func main() {
 	fmt.Printf("Checking collection: %s\n", collectionName)
 
 	for {
-		points, nextOffset, err := qc.Scroll(collectionName, limit, offset)
+		points, nextOffset, err := qc.Scroll(context.Background(), collectionName, limit, offset)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error scrolling: %v\n", err)
 			break